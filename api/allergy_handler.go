@@ -0,0 +1,337 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/RohanDSkaria/hospital-management-system/internal/model"
+	"github.com/RohanDSkaria/hospital-management-system/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type AllergyHandler struct {
+	allergyService service.AllergyService
+}
+
+// NewAllergyHandler creates a new AllergyHandler
+func NewAllergyHandler(allergyService service.AllergyService) *AllergyHandler {
+	return &AllergyHandler{allergyService: allergyService}
+}
+
+// AllergyRequest defines the structure for the create and update allergy request body
+type AllergyRequest struct {
+	Substance string                `json:"substance" binding:"required" example:"Penicillin"`
+	Category  model.AllergyCategory `json:"category" binding:"required" example:"medication"`
+	Reaction  string                `json:"reaction" example:"Hives"`
+	Severity  model.AllergySeverity `json:"severity" binding:"required" example:"moderate"`
+	Status    model.AllergyStatus   `json:"status" example:"active"`
+}
+
+// input converts the request body for the service
+func (req AllergyRequest) input() service.AllergyInput {
+	return service.AllergyInput{
+		Substance: req.Substance,
+		Category:  req.Category,
+		Reaction:  req.Reaction,
+		Severity:  req.Severity,
+		Status:    req.Status,
+	}
+}
+
+// @Summary      List a patient's allergies
+// @Description  Lists a patient's allergies, most severe first, and their no known allergies assertion (null if not asserted). Without status, allergies entered in error are left out; status may be repeated or comma-separated to pick statuses. Only doctors and nurses on the patient's care team can see allergies. Requires the patient:read permission.
+// @Tags         Allergies
+// @Produce      json
+// @Param        patient_id path string true "Patient ID" format(uuid)
+// @Param        status query []string false "Statuses to list: active, inactive, resolved or entered_in_error" collectionFormat(multi)
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      401  {object}  map[string]interface{}
+// @Failure      403  {object}  map[string]interface{}
+// @Failure      404  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Router       /patients/{patient_id}/allergies [get]
+// ListAllergies handles GET requests to list a patient's allergies
+func (h *AllergyHandler) ListAllergies(c *gin.Context) {
+	patientID, err := uuid.Parse(c.Param("patient_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid patient ID"})
+		return
+	}
+	var statuses []model.AllergyStatus
+	for _, value := range c.QueryArray("status") {
+		for _, status := range strings.Split(value, ",") {
+			statuses = append(statuses, model.AllergyStatus(strings.TrimSpace(status)))
+		}
+	}
+
+	allergies, assertion, err := h.allergyService.ListAllergies(actorFromContext(c), patientID, statuses)
+	if respondAllergyError(c, err, "failed to fetch allergies") {
+		return
+	}
+	data := make([]gin.H, 0, len(allergies))
+	for i := range allergies {
+		data = append(data, allergyResponse(&allergies[i]))
+	}
+	var noKnownAllergies gin.H
+	if assertion != nil {
+		noKnownAllergies = noKnownAllergiesResponse(assertion)
+	}
+	c.JSON(http.StatusOK, gin.H{"data": data, "no_known_allergies": noKnownAllergies})
+}
+
+// @Summary      Record an allergy
+// @Description  Records an allergy of a patient. The category must be food, medication, environment or biologic, the severity mild, moderate or severe, and the status, active by default, active, inactive, resolved or entered_in_error. A patient cannot have two active allergies to the same substance. Recording an active allergy withdraws the no known allergies assertion. Only doctors and nurses on the patient's care team can record allergies. Requires the patient:write permission.
+// @Tags         Allergies
+// @Accept       json
+// @Produce      json
+// @Param        patient_id path string true "Patient ID" format(uuid)
+// @Param        allergy body AllergyRequest true "Allergy"
+// @Success      201  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      401  {object}  map[string]interface{}
+// @Failure      403  {object}  map[string]interface{}
+// @Failure      404  {object}  map[string]interface{}
+// @Failure      409  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Router       /patients/{patient_id}/allergies [post]
+// CreateAllergy handles POST requests to record a patient's allergy
+func (h *AllergyHandler) CreateAllergy(c *gin.Context) {
+	patientID, err := uuid.Parse(c.Param("patient_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid patient ID"})
+		return
+	}
+	var req AllergyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	allergy, err := h.allergyService.CreateAllergy(actorFromContext(c), patientID, req.input())
+	if respondAllergyError(c, err, "failed to record allergy") {
+		return
+	}
+	c.JSON(http.StatusCreated, allergyResponse(allergy))
+}
+
+// @Summary      Get an allergy
+// @Description  Retrieves one of a patient's allergies, whatever its status. Only doctors and nurses on the patient's care team can see allergies. Requires the patient:read permission.
+// @Tags         Allergies
+// @Produce      json
+// @Param        patient_id path string true "Patient ID" format(uuid)
+// @Param        allergy_id path string true "Allergy ID" format(uuid)
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      401  {object}  map[string]interface{}
+// @Failure      403  {object}  map[string]interface{}
+// @Failure      404  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Router       /patients/{patient_id}/allergies/{allergy_id} [get]
+// GetAllergy handles GET requests for one of a patient's allergies
+func (h *AllergyHandler) GetAllergy(c *gin.Context) {
+	patientID, allergyID, ok := allergyIDs(c)
+	if !ok {
+		return
+	}
+	allergy, err := h.allergyService.GetAllergy(actorFromContext(c), patientID, allergyID)
+	if respondAllergyError(c, err, "failed to fetch allergy") {
+		return
+	}
+	c.JSON(http.StatusOK, allergyResponse(allergy))
+}
+
+// @Summary      Update an allergy
+// @Description  Replaces the details of one of a patient's allergies, for example to mark it resolved. Making it active withdraws the no known allergies assertion. Refused with 409 if it would be a second active allergy to the substance or someone else changed the allergy at the same time. Only doctors and nurses on the patient's care team can change allergies. Requires the patient:write permission.
+// @Tags         Allergies
+// @Accept       json
+// @Produce      json
+// @Param        patient_id path string true "Patient ID" format(uuid)
+// @Param        allergy_id path string true "Allergy ID" format(uuid)
+// @Param        allergy body AllergyRequest true "Allergy"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      401  {object}  map[string]interface{}
+// @Failure      403  {object}  map[string]interface{}
+// @Failure      404  {object}  map[string]interface{}
+// @Failure      409  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Router       /patients/{patient_id}/allergies/{allergy_id} [put]
+// UpdateAllergy handles PUT requests to change one of a patient's allergies
+func (h *AllergyHandler) UpdateAllergy(c *gin.Context) {
+	patientID, allergyID, ok := allergyIDs(c)
+	if !ok {
+		return
+	}
+	var req AllergyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	allergy, err := h.allergyService.UpdateAllergy(actorFromContext(c), patientID, allergyID, req.input())
+	if respondAllergyError(c, err, "failed to update allergy") {
+		return
+	}
+	c.JSON(http.StatusOK, allergyResponse(allergy))
+}
+
+// @Summary      Delete an allergy
+// @Description  Marks one of a patient's allergies as entered in error. It is kept for the record and can still be listed with status=entered_in_error. Refused with 409 if someone else changed the allergy at the same time. Only doctors and nurses on the patient's care team can change allergies. Requires the patient:write permission.
+// @Tags         Allergies
+// @Produce      json
+// @Param        patient_id path string true "Patient ID" format(uuid)
+// @Param        allergy_id path string true "Allergy ID" format(uuid)
+// @Success      204  {string}  string "No Content"
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      401  {object}  map[string]interface{}
+// @Failure      403  {object}  map[string]interface{}
+// @Failure      404  {object}  map[string]interface{}
+// @Failure      409  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Router       /patients/{patient_id}/allergies/{allergy_id} [delete]
+// DeleteAllergy handles DELETE requests to mark one of a patient's allergies as entered in error
+func (h *AllergyHandler) DeleteAllergy(c *gin.Context) {
+	patientID, allergyID, ok := allergyIDs(c)
+	if !ok {
+		return
+	}
+	_, err := h.allergyService.DeleteAllergy(actorFromContext(c), patientID, allergyID)
+	if respondAllergyError(c, err, "failed to delete allergy") {
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// @Summary      Assert no known allergies
+// @Description  Records that the caller checked with the patient and found no allergies, replacing an earlier assertion. Refused with 409 while the patient has active allergies. Only doctors and nurses on the patient's care team can assert it. Requires the patient:write permission.
+// @Tags         Allergies
+// @Produce      json
+// @Param        patient_id path string true "Patient ID" format(uuid)
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      401  {object}  map[string]interface{}
+// @Failure      403  {object}  map[string]interface{}
+// @Failure      404  {object}  map[string]interface{}
+// @Failure      409  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Router       /patients/{patient_id}/allergies/none [put]
+// AssertNoKnownAllergies handles PUT requests to assert that a patient has no known allergies
+func (h *AllergyHandler) AssertNoKnownAllergies(c *gin.Context) {
+	patientID, err := uuid.Parse(c.Param("patient_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid patient ID"})
+		return
+	}
+	assertion, err := h.allergyService.AssertNoKnownAllergies(actorFromContext(c), patientID)
+	if respondAllergyError(c, err, "failed to assert no known allergies") {
+		return
+	}
+	c.JSON(http.StatusOK, noKnownAllergiesResponse(assertion))
+}
+
+// @Summary      Withdraw no known allergies
+// @Description  Removes a patient's no known allergies assertion, leaving their allergies unknown. Only doctors and nurses on the patient's care team can withdraw it. Requires the patient:write permission.
+// @Tags         Allergies
+// @Produce      json
+// @Param        patient_id path string true "Patient ID" format(uuid)
+// @Success      204  {string}  string "No Content"
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      401  {object}  map[string]interface{}
+// @Failure      403  {object}  map[string]interface{}
+// @Failure      404  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Router       /patients/{patient_id}/allergies/none [delete]
+// WithdrawNoKnownAllergies handles DELETE requests to withdraw a patient's no known allergies assertion
+func (h *AllergyHandler) WithdrawNoKnownAllergies(c *gin.Context) {
+	patientID, err := uuid.Parse(c.Param("patient_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid patient ID"})
+		return
+	}
+	err = h.allergyService.WithdrawNoKnownAllergies(actorFromContext(c), patientID)
+	if respondAllergyError(c, err, "failed to withdraw no known allergies") {
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// allergyIDs reads the patient and allergy IDs from the path. It writes a
+// 400 response if either is invalid and reports whether the caller may go on.
+func allergyIDs(c *gin.Context) (uuid.UUID, uuid.UUID, bool) {
+	patientID, err := uuid.Parse(c.Param("patient_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid patient ID"})
+		return uuid.Nil, uuid.Nil, false
+	}
+	allergyID, err := uuid.Parse(c.Param("allergy_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid allergy ID"})
+		return uuid.Nil, uuid.Nil, false
+	}
+	return patientID, allergyID, true
+}
+
+// respondAllergyError writes the error response for a failed allergy call,
+// with message for unexpected errors, and reports whether there was an error
+func respondAllergyError(c *gin.Context, err error, message string) bool {
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "patient not found"})
+	case errors.Is(err, service.ErrAllergyNotFound) || errors.Is(err, service.ErrNoKnownAllergiesNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrInvalidAllergy):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrDuplicateAllergy) || errors.Is(err, service.ErrActiveAllergies) || errors.Is(err, service.ErrAllergyVersionMismatch):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case respondPatientForbidden(c, err):
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+	return true
+}
+
+// allergyResponse formats an allergy for the response body
+func allergyResponse(allergy *model.Allergy) gin.H {
+	return gin.H{
+		"id":             allergy.ID,
+		"patient_id":     allergy.PatientID,
+		"substance":      allergy.Substance,
+		"category":       allergy.Category,
+		"reaction":       allergy.Reaction,
+		"severity":       allergy.Severity,
+		"status":         allergy.Status,
+		"recorded_by_id": allergy.RecordedByID,
+		"recorded_at":    allergy.RecordedAt,
+		"updated_by_id":  allergy.UpdatedByID,
+		"updated_at":     allergy.UpdatedAt,
+		"version":        allergy.Version,
+	}
+}
+
+// noKnownAllergiesResponse formats a no known allergies assertion for the response body
+func noKnownAllergiesResponse(assertion *model.NoKnownAllergies) gin.H {
+	return gin.H{
+		"asserted_by_id": assertion.AssertedByID,
+		"asserted_at":    assertion.AssertedAt,
+	}
+}
@@ -0,0 +1,208 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/RohanDSkaria/hospital-management-system/internal/model"
+	"github.com/RohanDSkaria/hospital-management-system/internal/repository"
+	"github.com/RohanDSkaria/hospital-management-system/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type APIKeyHandler struct {
+	apiKeyService service.APIKeyService
+}
+
+// NewAPIKeyHandler creates a new APIKeyHandler
+func NewAPIKeyHandler(apiKeyService service.APIKeyService) *APIKeyHandler {
+	return &APIKeyHandler{apiKeyService: apiKeyService}
+}
+
+// CreateServiceAccountRequest defines the structure for the create service account request body
+type CreateServiceAccountRequest struct {
+	Name string     `json:"name" binding:"required"`
+	Role model.Role `json:"role" binding:"required"`
+}
+
+// @Summary      Create a service account
+// @Description  Creates a non-human account for an integration. It cannot log in and is used through API keys. Service accounts cannot be admins. Only accessible by admins.
+// @Tags         API Keys
+// @Accept       json
+// @Produce      json
+// @Param        account body CreateServiceAccountRequest true "Service Account Information"
+// @Success      201  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      401  {object}  map[string]interface{}
+// @Failure      403  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Security     BearerAuth
+// @Router       /admin/service-accounts [post]
+// CreateServiceAccount handles POST requests to create a service account
+func (h *APIKeyHandler) CreateServiceAccount(c *gin.Context) {
+	var req CreateServiceAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	account, err := h.apiKeyService.CreateServiceAccount(req.Name, req.Role)
+	if errors.Is(err, service.ErrInvalidRole) || errors.Is(err, service.ErrInvalidServiceAccountRole) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create service account"})
+		return
+	}
+	c.JSON(http.StatusCreated, userResponse(account))
+}
+
+// CreateAPIKeyRequest defines the structure for the create API key request body
+type CreateAPIKeyRequest struct {
+	Name      string             `json:"name" binding:"required,max=100"`
+	OwnerID   uuid.UUID          `json:"owner_id" binding:"required"`
+	Scopes    []model.Permission `json:"scopes" binding:"required"`
+	ExpiresAt *time.Time         `json:"expires_at"`
+}
+
+// @Summary      Create an API key
+// @Description  Creates an API key that acts as a user or service account, limited to the given scopes. Scopes must be permissions of the owner's role. Without expires_at the key expires after 90 days; at most 365 days are allowed. The key is only returned once. Only accessible by admins.
+// @Tags         API Keys
+// @Accept       json
+// @Produce      json
+// @Param        key body CreateAPIKeyRequest true "API Key Information"
+// @Success      201  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      401  {object}  map[string]interface{}
+// @Failure      403  {object}  map[string]interface{}
+// @Failure      404  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Security     BearerAuth
+// @Router       /admin/api-keys [post]
+// CreateAPIKey handles POST requests to create an API key
+func (h *APIKeyHandler) CreateAPIKey(c *gin.Context) {
+	var req CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	actorIDStr, _ := c.Get("userID")
+	actorID, _ := uuid.Parse(actorIDStr.(string))
+
+	key, rawKey, err := h.apiKeyService.CreateKey(req.OwnerID, req.Name, req.Scopes, req.ExpiresAt, actorID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "owner not found"})
+		return
+	}
+	if errors.Is(err, service.ErrInvalidScope) || errors.Is(err, service.ErrInvalidExpiry) || errors.Is(err, service.ErrInactiveOwner) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create API key"})
+		return
+	}
+
+	response := apiKeyResponse(key)
+	response["key"] = rawKey
+	c.JSON(http.StatusCreated, response)
+}
+
+// @Summary      List API keys
+// @Description  Lists API keys, newest first. Secrets are never returned. Only accessible by admins.
+// @Tags         API Keys
+// @Produce      json
+// @Param        owner_id         query  string  false  "Filter by owner" format(uuid)
+// @Param        include_revoked  query  bool    false  "Include revoked keys"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      401  {object}  map[string]interface{}
+// @Failure      403  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Security     BearerAuth
+// @Router       /admin/api-keys [get]
+// ListAPIKeys handles GET requests to list API keys
+func (h *APIKeyHandler) ListAPIKeys(c *gin.Context) {
+	var filter repository.APIKeyFilter
+	if ownerID := c.Query("owner_id"); ownerID != "" {
+		id, err := uuid.Parse(ownerID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid owner ID"})
+			return
+		}
+		filter.OwnerID = &id
+	}
+	if includeRevoked := c.Query("include_revoked"); includeRevoked != "" {
+		value, err := strconv.ParseBool(includeRevoked)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid include_revoked filter"})
+			return
+		}
+		filter.IncludeRevoked = value
+	}
+
+	keys, err := h.apiKeyService.ListKeys(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch API keys"})
+		return
+	}
+	data := make([]gin.H, 0, len(keys))
+	for i := range keys {
+		data = append(data, apiKeyResponse(&keys[i]))
+	}
+	c.JSON(http.StatusOK, gin.H{"data": data})
+}
+
+// @Summary      Revoke an API key
+// @Description  Revokes an API key. It stops working immediately. Only accessible by admins.
+// @Tags         API Keys
+// @Produce      json
+// @Param        key_id path string true "API Key ID" format(uuid)
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      401  {object}  map[string]interface{}
+// @Failure      403  {object}  map[string]interface{}
+// @Failure      404  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Security     BearerAuth
+// @Router       /admin/api-keys/{key_id} [delete]
+// RevokeAPIKey handles DELETE requests to revoke an API key
+func (h *APIKeyHandler) RevokeAPIKey(c *gin.Context) {
+	keyID, err := uuid.Parse(c.Param("key_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid API key ID"})
+		return
+	}
+	key, err := h.apiKeyService.RevokeKey(keyID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "API key not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to revoke API key"})
+		return
+	}
+	c.JSON(http.StatusOK, apiKeyResponse(key))
+}
+
+// apiKeyResponse formats an API key for the response body without its secret
+func apiKeyResponse(key *model.APIKey) gin.H {
+	return gin.H{
+		"id":            key.ID,
+		"name":          key.Name,
+		"prefix":        service.APIKeyPrefix + key.Prefix,
+		"scopes":        key.Scopes,
+		"owner_id":      key.OwnerID,
+		"created_by_id": key.CreatedByID,
+		"expires_at":    key.ExpiresAt,
+		"last_used_at":  key.LastUsedAt,
+		"last_used_ip":  key.LastUsedIP,
+		"revoked_at":    key.RevokedAt,
+		"created_at":    key.CreatedAt,
+	}
+}
@@ -0,0 +1,136 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/RohanDSkaria/hospital-management-system/internal/model"
+	"github.com/RohanDSkaria/hospital-management-system/internal/repository"
+	"github.com/RohanDSkaria/hospital-management-system/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type AuditHandler struct {
+	auditService service.AuditService
+}
+
+// NewAuditHandler creates a new AuditHandler
+func NewAuditHandler(auditService service.AuditService) *AuditHandler {
+	return &AuditHandler{auditService: auditService}
+}
+
+// @Summary      List audit entries
+// @Description  Lists audit log entries for patient data, newest first. Each entry records who did what to which patient, when, from where, and which fields changed. Values of clinical fields are never shown. Only accessible by admins.
+// @Tags         Audit
+// @Produce      json
+// @Param        patient_id  query  string  false  "Filter by patient" format(uuid)
+// @Param        user_id     query  string  false  "Filter by user" format(uuid)
+// @Param        action      query  string  false  "Filter by action, e.g. patient.view"
+// @Param        from        query  string  false  "Entries at or after this time (RFC 3339)"
+// @Param        to          query  string  false  "Entries before this time (RFC 3339)"
+// @Param        limit       query  int     false  "Page size (1-200, default 50)"
+// @Param        offset      query  int     false  "Number of entries to skip"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      401  {object}  map[string]interface{}
+// @Failure      403  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Security     BearerAuth
+// @Router       /admin/audit [get]
+// ListAuditEntries handles GET requests for the patient audit log
+func (h *AuditHandler) ListAuditEntries(c *gin.Context) {
+	limit, offset, err := parseLimitOffset(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	filter := repository.AuditFilter{Action: c.Query("action"), Limit: limit, Offset: offset}
+	if value := c.Query("patient_id"); value != "" {
+		id, err := uuid.Parse(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid patient ID"})
+			return
+		}
+		filter.PatientID = &id
+	}
+	if value := c.Query("user_id"); value != "" {
+		id, err := uuid.Parse(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user ID"})
+			return
+		}
+		filter.ActorID = &id
+	}
+	if value := c.Query("from"); value != "" {
+		from, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "from must be an RFC 3339 time"})
+			return
+		}
+		filter.From = &from
+	}
+	if value := c.Query("to"); value != "" {
+		to, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "to must be an RFC 3339 time"})
+			return
+		}
+		filter.To = &to
+	}
+
+	entries, total, err := h.auditService.List(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch audit entries"})
+		return
+	}
+	data := make([]gin.H, 0, len(entries))
+	for i := range entries {
+		data = append(data, auditEntryResponse(&entries[i]))
+	}
+	c.JSON(http.StatusOK, gin.H{"data": data, "total": total, "limit": limit, "offset": offset})
+}
+
+// @Summary      Verify the audit log
+// @Description  Recomputes the audit log's hash chain and reports the first entry that was altered, removed or inserted. Only accessible by admins.
+// @Tags         Audit
+// @Produce      json
+// @Success      200  {object}  service.AuditVerification
+// @Failure      401  {object}  map[string]interface{}
+// @Failure      403  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Security     BearerAuth
+// @Router       /admin/audit/verify [get]
+// VerifyAuditLog handles GET requests to check the audit log for tampering
+func (h *AuditHandler) VerifyAuditLog(c *gin.Context) {
+	result, err := h.auditService.Verify()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to verify audit log"})
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// auditEntryResponse formats an audit entry for the response body
+func auditEntryResponse(entry *model.AuditEntry) gin.H {
+	var changes json.RawMessage
+	if entry.Changes != "" {
+		changes = json.RawMessage(entry.Changes)
+	}
+	return gin.H{
+		"id":                    entry.ID,
+		"sequence":              entry.Sequence,
+		"actor_id":              entry.ActorID,
+		"actor_role":            entry.ActorRole,
+		"action":                entry.Action,
+		"outcome":               entry.Outcome,
+		"patient_id":            entry.PatientID,
+		"break_glass_access_id": entry.BreakGlassAccessID,
+		"request_id":            entry.RequestID,
+		"client_ip":             entry.ClientIP,
+		"changes":               changes,
+		"hash":                  entry.Hash,
+		"created_at":            entry.CreatedAt,
+	}
+}
@@ -1,11 +1,14 @@
 package api
 
 import (
+	"errors"
+	"math"
 	"net/http"
+	"strconv"
 
-	"github.com/RohanDSkaria/hospital-management-system/internal/model"
 	"github.com/RohanDSkaria/hospital-management-system/internal/service"
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 )
 
 type AuthHandler struct {
@@ -19,14 +22,13 @@ func NewAuthHandler(authService service.AuthService) *AuthHandler {
 
 // RegisterRequest defines the structure for the user registration request body
 type RegisterRequest struct {
-	FullName string     `json:"full_name" binding:"required"`
-	Email    string     `json:"email" binding:"required,email"`
-	Password string     `json:"password" binding:"required,min=8"`
-	Role     model.Role `json:"role" binding:"required"`
+	InviteToken string `json:"invite_token" binding:"required"`
+	FullName    string `json:"full_name" binding:"required"`
+	Password    string `json:"password" binding:"required"`
 }
 
 // @Summary      Register a new user
-// @Description  Creates a new user account (receptionist or doctor).
+// @Description  Creates a new staff account from an invitation issued by an admin. The email and role are taken from the invitation. The password must follow the password policy.
 // @Tags         Authentication
 // @Accept       json
 // @Produce      json
@@ -46,17 +48,18 @@ func (h *AuthHandler) RegisterHandler(c *gin.Context) {
 		return
 	}
 
-	// Quick validation for role
-	if req.Role != model.Doctor && req.Role != model.Receptionist {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid role specified"})
-		return
-	}
-
 	// 2. Call the service to perform the business logic
-	user, err := h.authService.RegisterUser(req.FullName, req.Email, req.Password, req.Role)
+	user, err := h.authService.RegisterUser(req.InviteToken, req.FullName, req.Password)
 	if err != nil {
-		// Check for specific error from the service layer
-		if err.Error() == "user with this email already exists" {
+		// Check for specific errors from the service layer
+		if respondPasswordRejected(c, err) {
+			return
+		}
+		if errors.Is(err, service.ErrInvalidInvitation) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, service.ErrUserExists) {
 			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
 			return
 		}
@@ -66,14 +69,7 @@ func (h *AuthHandler) RegisterHandler(c *gin.Context) {
 	}
 
 	// 3. Format and send the success response
-	response := gin.H{
-		"id":         user.ID,
-		"full_name":  user.FullName,
-		"email":      user.Email,
-		"role":       user.Role,
-		"created_at": user.CreatedAt,
-	}
-	c.JSON(http.StatusCreated, response)
+	c.JSON(http.StatusCreated, userResponse(user))
 }
 
 // LoginRequest defines the structure for the user login request body
@@ -83,7 +79,7 @@ type LoginRequest struct {
 }
 
 // @Summary      Login user
-// @Description  Authenticates a user and returns a JWT token for access to protected endpoints.
+// @Description  Authenticates a user and returns a short-lived access token and a refresh token. If the user has MFA enabled, or their role requires it, an mfa_token is returned instead and must be exchanged at /login/mfa.
 // @Tags         Authentication
 // @Accept       json
 // @Produce      json
@@ -91,6 +87,8 @@ type LoginRequest struct {
 // @Success      200  {object}  map[string]interface{}
 // @Failure      400  {object}  map[string]interface{}
 // @Failure      401  {object}  map[string]interface{}
+// @Failure      429  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
 // @Router       /login [post]
 // LoginHandler handles the user login endpoint
 func (h *AuthHandler) LoginHandler(c *gin.Context) {
@@ -103,13 +101,222 @@ func (h *AuthHandler) LoginHandler(c *gin.Context) {
 	}
 
 	// Call the service to perform login logic
-	token, err := h.authService.LoginUser(req.Email, req.Password)
+	result, err := h.authService.LoginUser(req.Email, req.Password, c.Request.UserAgent(), c.ClientIP())
+	if err != nil {
+		if respondLocked(c, err) {
+			return
+		}
+		if errors.Is(err, service.ErrInvalidCredentials) || errors.Is(err, service.ErrAccountDeactivated) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to login"})
+		return
+	}
+
+	// A second factor is needed before any tokens are issued
+	if result.Tokens == nil {
+		c.JSON(http.StatusOK, gin.H{
+			"mfa_required":            true,
+			"mfa_token":               result.MFAToken,
+			"mfa_enrollment_required": result.MFAEnrollmentRequired,
+			"expires_in":              int(result.MFAExpiresIn.Seconds()),
+		})
+		return
+	}
+
+	// Send the tokens back in the response
+	c.JSON(http.StatusOK, tokenResponse(result.Tokens))
+}
+
+// MFALoginRequest defines the structure for the second login step request body
+type MFALoginRequest struct {
+	MFAToken string `json:"mfa_token" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+}
+
+// @Summary      Complete login with a second factor
+// @Description  Exchanges the mfa_token returned by /login and a TOTP or recovery code for an access token and a refresh token. If the user was enrolling, the code confirms the enrollment and recovery_codes are returned once.
+// @Tags         Authentication
+// @Accept       json
+// @Produce      json
+// @Param        request body MFALoginRequest true "MFA Token and Code"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      401  {object}  map[string]interface{}
+// @Failure      429  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Router       /login/mfa [post]
+// MFALoginHandler handles the second step of a login
+func (h *AuthHandler) MFALoginHandler(c *gin.Context) {
+	var req MFALoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tokens, recoveryCodes, err := h.authService.CompleteMFALogin(req.MFAToken, req.Code, c.Request.UserAgent(), c.ClientIP())
+	if err != nil {
+		if respondLocked(c, err) {
+			return
+		}
+		if errors.Is(err, service.ErrInvalidMFAChallenge) || errors.Is(err, service.ErrInvalidMFACode) || errors.Is(err, service.ErrAccountDeactivated) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, service.ErrMFAEnrollmentNotStarted) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to login"})
+		return
+	}
+
+	response := tokenResponse(tokens)
+	if recoveryCodes != nil {
+		response["recovery_codes"] = recoveryCodes
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// MFAEnrollRequest defines the structure for the login enrollment request body
+type MFAEnrollRequest struct {
+	MFAToken string `json:"mfa_token" binding:"required"`
+}
+
+// @Summary      Enroll in MFA during login
+// @Description  For users whose role requires MFA but who have not enrolled yet. Returns a TOTP secret and an otpauth:// URI to show as a QR code; the login is then completed at /login/mfa with a code from the authenticator app.
+// @Tags         Authentication
+// @Accept       json
+// @Produce      json
+// @Param        request body MFAEnrollRequest true "MFA Token"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      401  {object}  map[string]interface{}
+// @Failure      409  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Router       /login/mfa/enroll [post]
+// MFAEnrollHandler handles MFA enrollment during login
+func (h *AuthHandler) MFAEnrollHandler(c *gin.Context) {
+	var req MFAEnrollRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	enrollment, err := h.authService.BeginMFAEnrollment(req.MFAToken)
 	if err != nil {
-		// For any error from the service (e.g., "invalid credentials"), return Unauthorized
-		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		if errors.Is(err, service.ErrInvalidMFAChallenge) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, service.ErrMFAAlreadyEnabled) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to start MFA enrollment"})
 		return
 	}
+	c.JSON(http.StatusOK, enrollmentResponse(enrollment))
+}
+
+// RefreshRequest defines the structure for the token refresh request body
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
 
-	// Send the token back in the response
-	c.JSON(http.StatusOK, gin.H{"token": token})
+// @Summary      Refresh access token
+// @Description  Exchanges a refresh token for a new access token and a new refresh token. Each refresh token can only be used once; reusing one revokes the whole session.
+// @Tags         Authentication
+// @Accept       json
+// @Produce      json
+// @Param        token body RefreshRequest true "Refresh Token"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      401  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Router       /token/refresh [post]
+// RefreshHandler handles the token refresh endpoint
+func (h *AuthHandler) RefreshHandler(c *gin.Context) {
+	var req RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tokens, err := h.authService.RefreshToken(req.RefreshToken)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidRefreshToken) || errors.Is(err, service.ErrRefreshTokenReused) || errors.Is(err, service.ErrAccountDeactivated) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to refresh token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, tokenResponse(tokens))
+}
+
+// @Summary      Logout
+// @Description  Revokes the current session. The access token and all refresh tokens of this session stop working immediately.
+// @Tags         Authentication
+// @Produce      json
+// @Success      204  {string}  string "No Content"
+// @Failure      401  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Security     BearerAuth
+// @Router       /logout [post]
+// LogoutHandler handles the logout endpoint
+func (h *AuthHandler) LogoutHandler(c *gin.Context) {
+	sessionIDStr, _ := c.Get("sessionID")
+	sessionID, _ := uuid.Parse(sessionIDStr.(string))
+
+	if err := h.authService.Logout(sessionID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to logout"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// @Summary      Logout from all devices
+// @Description  Revokes every session of the current user, including the one making this request.
+// @Tags         Authentication
+// @Produce      json
+// @Success      204  {string}  string "No Content"
+// @Failure      401  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Security     BearerAuth
+// @Router       /logout/all [post]
+// LogoutAllHandler handles the logout from all devices endpoint
+func (h *AuthHandler) LogoutAllHandler(c *gin.Context) {
+	userIDStr, _ := c.Get("userID")
+	userID, _ := uuid.Parse(userIDStr.(string))
+
+	if err := h.authService.LogoutAll(userID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to logout"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// respondLocked writes a 429 response with a Retry-After header if err is a
+// login lockout, and reports whether it did
+func respondLocked(c *gin.Context, err error) bool {
+	var locked *service.LoginLockedError
+	if !errors.As(err, &locked) {
+		return false
+	}
+	c.Header("Retry-After", strconv.Itoa(int(math.Ceil(locked.RetryAfter.Seconds()))))
+	c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+	return true
+}
+
+// tokenResponse formats a token pair for the response body
+func tokenResponse(tokens *service.TokenPair) gin.H {
+	return gin.H{
+		"access_token":  tokens.AccessToken,
+		"refresh_token": tokens.RefreshToken,
+		"token_type":    "Bearer",
+		"expires_in":    int(tokens.ExpiresIn.Seconds()),
+	}
 }
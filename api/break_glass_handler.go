@@ -0,0 +1,245 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/RohanDSkaria/hospital-management-system/internal/model"
+	"github.com/RohanDSkaria/hospital-management-system/internal/repository"
+	"github.com/RohanDSkaria/hospital-management-system/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type BreakGlassHandler struct {
+	breakGlassService service.BreakGlassService
+}
+
+// NewBreakGlassHandler creates a new BreakGlassHandler
+func NewBreakGlassHandler(breakGlassService service.BreakGlassService) *BreakGlassHandler {
+	return &BreakGlassHandler{breakGlassService: breakGlassService}
+}
+
+// BreakGlassRequest defines the structure for the break-glass request body
+type BreakGlassRequest struct {
+	Reason string `json:"reason" binding:"required,min=10,max=2000"`
+}
+
+// @Summary      Break the glass
+// @Description  Gives a doctor or nurse who is not on the patient's care team time-boxed access to the patient's chart in an emergency. A reason is required, every use of the access is recorded, and the access is queued for admin review. Cannot be used with an API key.
+// @Tags         Break-Glass
+// @Accept       json
+// @Produce      json
+// @Param        patient_id path string true "Patient ID" format(uuid)
+// @Param        request body BreakGlassRequest true "Justification"
+// @Success      201  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      401  {object}  map[string]interface{}
+// @Failure      403  {object}  map[string]interface{}
+// @Failure      404  {object}  map[string]interface{}
+// @Failure      409  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Security     BearerAuth
+// @Router       /patients/{patient_id}/break-glass [post]
+// RequestBreakGlass handles POST requests for emergency access to a patient's chart
+func (h *BreakGlassHandler) RequestBreakGlass(c *gin.Context) {
+	patientID, err := uuid.Parse(c.Param("patient_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid patient ID"})
+		return
+	}
+	var req BreakGlassRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	access, err := h.breakGlassService.Request(actorFromContext(c), patientID, req.Reason, c.ClientIP())
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "patient not found"})
+	case errors.Is(err, service.ErrBreakGlassNotClinician):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrBreakGlassNotNeeded):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to grant break-glass access"})
+	default:
+		c.JSON(http.StatusCreated, breakGlassResponse(access))
+	}
+}
+
+// @Summary      List break-glass accesses
+// @Description  Lists break-glass accesses, oldest first. By default only those pending review are returned. Only accessible by admins.
+// @Tags         Break-Glass
+// @Produce      json
+// @Param        reviewed    query  bool    false  "Reviewed (default false)"
+// @Param        patient_id  query  string  false  "Filter by patient" format(uuid)
+// @Param        user_id     query  string  false  "Filter by clinician" format(uuid)
+// @Param        limit       query  int     false  "Page size (1-200, default 50)"
+// @Param        offset      query  int     false  "Number of accesses to skip"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      401  {object}  map[string]interface{}
+// @Failure      403  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Security     BearerAuth
+// @Router       /admin/break-glass [get]
+// ListBreakGlass handles GET requests for the break-glass review queue
+func (h *BreakGlassHandler) ListBreakGlass(c *gin.Context) {
+	limit, offset, err := parseLimitOffset(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	reviewed := false
+	filter := repository.BreakGlassFilter{Reviewed: &reviewed, Limit: limit, Offset: offset}
+	if value := c.Query("reviewed"); value != "" {
+		reviewed, err = strconv.ParseBool(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid reviewed filter"})
+			return
+		}
+	}
+	if value := c.Query("patient_id"); value != "" {
+		id, err := uuid.Parse(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid patient ID"})
+			return
+		}
+		filter.PatientID = &id
+	}
+	if value := c.Query("user_id"); value != "" {
+		id, err := uuid.Parse(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user ID"})
+			return
+		}
+		filter.UserID = &id
+	}
+
+	accesses, total, err := h.breakGlassService.List(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch break-glass accesses"})
+		return
+	}
+	data := make([]gin.H, 0, len(accesses))
+	for i := range accesses {
+		data = append(data, breakGlassResponse(&accesses[i]))
+	}
+	c.JSON(http.StatusOK, gin.H{"data": data, "total": total, "limit": limit, "offset": offset})
+}
+
+// @Summary      Get a break-glass access
+// @Description  Returns a break-glass access with every action taken under it. Only accessible by admins.
+// @Tags         Break-Glass
+// @Produce      json
+// @Param        access_id path string true "Break-Glass Access ID" format(uuid)
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      401  {object}  map[string]interface{}
+// @Failure      403  {object}  map[string]interface{}
+// @Failure      404  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Security     BearerAuth
+// @Router       /admin/break-glass/{access_id} [get]
+// GetBreakGlass handles GET requests for a single break-glass access
+func (h *BreakGlassHandler) GetBreakGlass(c *gin.Context) {
+	accessID, err := uuid.Parse(c.Param("access_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid break-glass access ID"})
+		return
+	}
+	access, err := h.breakGlassService.Get(accessID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "break-glass access not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch break-glass access"})
+		return
+	}
+	c.JSON(http.StatusOK, breakGlassDetailResponse(access))
+}
+
+// ReviewBreakGlassRequest defines the structure for the break-glass review request body
+type ReviewBreakGlassRequest struct {
+	Outcome model.BreakGlassOutcome `json:"outcome" binding:"required"`
+	Note    string                  `json:"note" binding:"max=2000"`
+}
+
+// @Summary      Review a break-glass access
+// @Description  Signs off a break-glass access as justified or unjustified and removes it from the review queue. Each access can be reviewed once. Only accessible by admins.
+// @Tags         Break-Glass
+// @Accept       json
+// @Produce      json
+// @Param        access_id path string true "Break-Glass Access ID" format(uuid)
+// @Param        review body ReviewBreakGlassRequest true "Review"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      401  {object}  map[string]interface{}
+// @Failure      403  {object}  map[string]interface{}
+// @Failure      404  {object}  map[string]interface{}
+// @Failure      409  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Security     BearerAuth
+// @Router       /admin/break-glass/{access_id}/review [post]
+// ReviewBreakGlass handles POST requests to sign off a break-glass access
+func (h *BreakGlassHandler) ReviewBreakGlass(c *gin.Context) {
+	accessID, err := uuid.Parse(c.Param("access_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid break-glass access ID"})
+		return
+	}
+	var req ReviewBreakGlassRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	access, err := h.breakGlassService.Review(accessID, actorFromContext(c).UserID, req.Outcome, req.Note)
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "break-glass access not found"})
+	case errors.Is(err, service.ErrInvalidBreakGlassOutcome):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrBreakGlassAlreadyReviewed):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to review break-glass access"})
+	default:
+		c.JSON(http.StatusOK, breakGlassDetailResponse(access))
+	}
+}
+
+// breakGlassResponse formats a break-glass access for the response body
+func breakGlassResponse(access *model.BreakGlassAccess) gin.H {
+	return gin.H{
+		"id":             access.ID,
+		"patient_id":     access.PatientID,
+		"patient_name":   access.Patient.FullName,
+		"user_id":        access.UserID,
+		"user_name":      access.User.FullName,
+		"reason":         access.Reason,
+		"client_ip":      access.ClientIP,
+		"expires_at":     access.ExpiresAt,
+		"reviewed_at":    access.ReviewedAt,
+		"reviewed_by_id": access.ReviewedByID,
+		"outcome":        access.Outcome,
+		"review_note":    access.ReviewNote,
+		"created_at":     access.CreatedAt,
+	}
+}
+
+// breakGlassDetailResponse formats a break-glass access with its recorded uses
+func breakGlassDetailResponse(access *model.BreakGlassAccess) gin.H {
+	response := breakGlassResponse(access)
+	events := make([]gin.H, 0, len(access.Events))
+	for _, event := range access.Events {
+		events = append(events, gin.H{"action": event.Action, "at": event.CreatedAt})
+	}
+	response["events"] = events
+	return response
+}
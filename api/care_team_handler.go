@@ -0,0 +1,167 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/RohanDSkaria/hospital-management-system/internal/model"
+	"github.com/RohanDSkaria/hospital-management-system/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type CareTeamHandler struct {
+	careTeamService service.CareTeamService
+}
+
+// NewCareTeamHandler creates a new CareTeamHandler
+func NewCareTeamHandler(careTeamService service.CareTeamService) *CareTeamHandler {
+	return &CareTeamHandler{careTeamService: careTeamService}
+}
+
+// @Summary      List a patient's care team
+// @Description  Lists the doctors and nurses assigned to a patient. Requires the patient:read permission; doctors and nurses must be on the care team themselves.
+// @Tags         Care Teams
+// @Produce      json
+// @Param        patient_id path string true "Patient ID" format(uuid)
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      401  {object}  map[string]interface{}
+// @Failure      403  {object}  map[string]interface{}
+// @Failure      404  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Router       /patients/{patient_id}/care-team [get]
+// ListCareTeam handles GET requests to list a patient's care team
+func (h *CareTeamHandler) ListCareTeam(c *gin.Context) {
+	patientID, err := uuid.Parse(c.Param("patient_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid patient ID"})
+		return
+	}
+	members, err := h.careTeamService.List(actorFromContext(c), patientID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "patient not found"})
+		return
+	}
+	if respondPatientForbidden(c, err) {
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch care team"})
+		return
+	}
+	data := make([]gin.H, 0, len(members))
+	for i := range members {
+		data = append(data, careTeamMemberResponse(&members[i]))
+	}
+	c.JSON(http.StatusOK, gin.H{"data": data})
+}
+
+// AssignCareTeamMemberRequest defines the structure for the assign care team member request body
+type AssignCareTeamMemberRequest struct {
+	UserID uuid.UUID          `json:"user_id" binding:"required"`
+	Role   model.CareTeamRole `json:"role" binding:"required"`
+}
+
+// @Summary      Assign a care team member
+// @Description  Assigns a doctor (attending or consulting) or a nurse to a patient's care team. Requires the care_team:manage permission.
+// @Tags         Care Teams
+// @Accept       json
+// @Produce      json
+// @Param        patient_id path string true "Patient ID" format(uuid)
+// @Param        member body AssignCareTeamMemberRequest true "Care Team Member"
+// @Success      201  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      401  {object}  map[string]interface{}
+// @Failure      403  {object}  map[string]interface{}
+// @Failure      404  {object}  map[string]interface{}
+// @Failure      409  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Router       /patients/{patient_id}/care-team [post]
+// AssignCareTeamMember handles POST requests to add a member to a patient's care team
+func (h *CareTeamHandler) AssignCareTeamMember(c *gin.Context) {
+	patientID, err := uuid.Parse(c.Param("patient_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid patient ID"})
+		return
+	}
+	var req AssignCareTeamMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	member, err := h.careTeamService.Assign(actorFromContext(c), patientID, req.UserID, req.Role)
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "patient not found"})
+	case errors.Is(err, service.ErrCareTeamUserNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrInvalidCareTeamRole) || errors.Is(err, service.ErrIneligibleCareTeamMember):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrAlreadyOnCareTeam):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to assign care team member"})
+	default:
+		c.JSON(http.StatusCreated, careTeamMemberResponse(member))
+	}
+}
+
+// @Summary      Unassign a care team member
+// @Description  Takes a doctor or nurse off a patient's care team. They lose access to the patient immediately. Requires the care_team:manage permission.
+// @Tags         Care Teams
+// @Produce      json
+// @Param        patient_id path string true "Patient ID" format(uuid)
+// @Param        user_id path string true "User ID" format(uuid)
+// @Success      204  {string}  string "No Content"
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      401  {object}  map[string]interface{}
+// @Failure      403  {object}  map[string]interface{}
+// @Failure      404  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Router       /patients/{patient_id}/care-team/{user_id} [delete]
+// UnassignCareTeamMember handles DELETE requests to remove a member from a patient's care team
+func (h *CareTeamHandler) UnassignCareTeamMember(c *gin.Context) {
+	patientID, err := uuid.Parse(c.Param("patient_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid patient ID"})
+		return
+	}
+	userID, err := uuid.Parse(c.Param("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user ID"})
+		return
+	}
+
+	err = h.careTeamService.Unassign(actorFromContext(c), patientID, userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "user is not on this patient's care team"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to unassign care team member"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// careTeamMemberResponse formats a care team member for the response body
+func careTeamMemberResponse(member *model.CareTeamMember) gin.H {
+	return gin.H{
+		"patient_id":     member.PatientID,
+		"user_id":        member.UserID,
+		"full_name":      member.User.FullName,
+		"user_role":      member.User.Role,
+		"role":           member.Role,
+		"assigned_by_id": member.AssignedByID,
+		"assigned_at":    member.CreatedAt,
+	}
+}
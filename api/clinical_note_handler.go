@@ -0,0 +1,411 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/RohanDSkaria/hospital-management-system/internal/model"
+	"github.com/RohanDSkaria/hospital-management-system/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type ClinicalNoteHandler struct {
+	noteService service.ClinicalNoteService
+}
+
+// NewClinicalNoteHandler creates a new ClinicalNoteHandler
+func NewClinicalNoteHandler(noteService service.ClinicalNoteService) *ClinicalNoteHandler {
+	return &ClinicalNoteHandler{noteService: noteService}
+}
+
+// NoteRequest defines the structure for the create and update clinical note request body
+type NoteRequest struct {
+	EncounterType model.EncounterType `json:"encounter_type" binding:"required" example:"outpatient"`
+	EncounterAt   *time.Time          `json:"encounter_at" example:"2024-05-01T09:30:00Z"`
+	Subjective    string              `json:"subjective" example:"Cough for three days, no fever"`
+	Objective     string              `json:"objective" example:"Chest clear, SpO2 98%"`
+	Assessment    string              `json:"assessment" example:"Viral upper respiratory infection"`
+	Plan          string              `json:"plan" example:"Fluids, rest, review in a week if not better"`
+}
+
+// input converts the request body for the service
+func (req NoteRequest) input() service.NoteInput {
+	input := service.NoteInput{
+		EncounterType: req.EncounterType,
+		Subjective:    req.Subjective,
+		Objective:     req.Objective,
+		Assessment:    req.Assessment,
+		Plan:          req.Plan,
+	}
+	if req.EncounterAt != nil {
+		input.EncounterAt = *req.EncounterAt
+	}
+	return input
+}
+
+// AddendumRequest defines the structure for the add addendum request body
+type AddendumRequest struct {
+	Text string `json:"text" binding:"required" example:"Correction: SpO2 was 96%, not 98%"`
+}
+
+// @Summary      List a patient's clinical notes
+// @Description  Lists a patient's clinical notes with their addenda, newest encounter first. Your own drafts are included; other people's drafts are never shown. status may be repeated or comma-separated. Only doctors and nurses on the patient's care team can see notes. Requires the patient:read permission.
+// @Tags         Clinical Notes
+// @Produce      json
+// @Param        patient_id path string true "Patient ID" format(uuid)
+// @Param        status query []string false "Statuses to list: draft, signed or amended" collectionFormat(multi)
+// @Param        limit query int false "Page size (1-200, default 50)"
+// @Param        offset query int false "Number of notes to skip"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      401  {object}  map[string]interface{}
+// @Failure      403  {object}  map[string]interface{}
+// @Failure      404  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Router       /patients/{patient_id}/notes [get]
+// ListNotes handles GET requests to list a patient's clinical notes
+func (h *ClinicalNoteHandler) ListNotes(c *gin.Context) {
+	patientID, err := uuid.Parse(c.Param("patient_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid patient ID"})
+		return
+	}
+	limit, offset, err := parseLimitOffset(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	query := service.NoteListQuery{Limit: limit, Offset: offset}
+	for _, value := range c.QueryArray("status") {
+		for _, status := range strings.Split(value, ",") {
+			query.Statuses = append(query.Statuses, model.NoteStatus(strings.TrimSpace(status)))
+		}
+	}
+
+	notes, total, err := h.noteService.ListNotes(actorFromContext(c), patientID, query)
+	if respondNoteError(c, err, "failed to fetch clinical notes") {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": noteResponses(notes), "total": total, "limit": limit, "offset": offset})
+}
+
+// @Summary      Patient timeline
+// @Description  Lists a patient's signed and amended clinical notes in the order the encounters happened, oldest first, each with its addenda. from and to limit the encounter times. Only doctors and nurses on the patient's care team can see notes. Requires the patient:read permission.
+// @Tags         Clinical Notes
+// @Produce      json
+// @Param        patient_id path string true "Patient ID" format(uuid)
+// @Param        from query string false "Encounters at or after this RFC 3339 time"
+// @Param        to query string false "Encounters before this RFC 3339 time"
+// @Param        limit query int false "Page size (1-200, default 50)"
+// @Param        offset query int false "Number of notes to skip"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      401  {object}  map[string]interface{}
+// @Failure      403  {object}  map[string]interface{}
+// @Failure      404  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Router       /patients/{patient_id}/timeline [get]
+// GetTimeline handles GET requests for a patient's chronological timeline of clinical notes
+func (h *ClinicalNoteHandler) GetTimeline(c *gin.Context) {
+	patientID, err := uuid.Parse(c.Param("patient_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid patient ID"})
+		return
+	}
+	limit, offset, err := parseLimitOffset(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	from, to, ok := parseTimeRange(c)
+	if !ok {
+		return
+	}
+	query := service.TimelineQuery{From: from, To: to, Limit: limit, Offset: offset}
+
+	notes, total, err := h.noteService.Timeline(actorFromContext(c), patientID, query)
+	if respondNoteError(c, err, "failed to fetch timeline") {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": noteResponses(notes), "total": total, "limit": limit, "offset": offset})
+}
+
+// @Summary      Start a clinical note
+// @Description  Starts a draft note documenting an encounter in SOAP form (subjective, objective, assessment, plan). The encounter type must be outpatient, inpatient, emergency or telehealth; encounter_at defaults to now and cannot be in the future. Only the author sees and can change the draft until it is signed. Only doctors and nurses on the patient's care team can write notes. Requires the patient:write permission.
+// @Tags         Clinical Notes
+// @Accept       json
+// @Produce      json
+// @Param        patient_id path string true "Patient ID" format(uuid)
+// @Param        note body NoteRequest true "Clinical Note"
+// @Success      201  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      401  {object}  map[string]interface{}
+// @Failure      403  {object}  map[string]interface{}
+// @Failure      404  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Router       /patients/{patient_id}/notes [post]
+// CreateNote handles POST requests to start a draft clinical note
+func (h *ClinicalNoteHandler) CreateNote(c *gin.Context) {
+	patientID, err := uuid.Parse(c.Param("patient_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid patient ID"})
+		return
+	}
+	var req NoteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	note, err := h.noteService.CreateNote(actorFromContext(c), patientID, req.input())
+	if respondNoteError(c, err, "failed to create clinical note") {
+		return
+	}
+	c.JSON(http.StatusCreated, noteResponse(note))
+}
+
+// @Summary      Get a clinical note
+// @Description  Retrieves one of a patient's clinical notes with its addenda. Other people's drafts are not found. Only doctors and nurses on the patient's care team can see notes. Requires the patient:read permission.
+// @Tags         Clinical Notes
+// @Produce      json
+// @Param        patient_id path string true "Patient ID" format(uuid)
+// @Param        note_id path string true "Note ID" format(uuid)
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      401  {object}  map[string]interface{}
+// @Failure      403  {object}  map[string]interface{}
+// @Failure      404  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Router       /patients/{patient_id}/notes/{note_id} [get]
+// GetNote handles GET requests for one of a patient's clinical notes
+func (h *ClinicalNoteHandler) GetNote(c *gin.Context) {
+	patientID, noteID, ok := noteIDs(c)
+	if !ok {
+		return
+	}
+	note, err := h.noteService.GetNote(actorFromContext(c), patientID, noteID)
+	if respondNoteError(c, err, "failed to fetch clinical note") {
+		return
+	}
+	c.JSON(http.StatusOK, noteResponse(note))
+}
+
+// @Summary      Update a draft clinical note
+// @Description  Replaces the encounter details and SOAP sections of your own draft. Signed notes cannot change and are refused with 409; add an addendum instead. Requires the patient:write permission.
+// @Tags         Clinical Notes
+// @Accept       json
+// @Produce      json
+// @Param        patient_id path string true "Patient ID" format(uuid)
+// @Param        note_id path string true "Note ID" format(uuid)
+// @Param        note body NoteRequest true "Clinical Note"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      401  {object}  map[string]interface{}
+// @Failure      403  {object}  map[string]interface{}
+// @Failure      404  {object}  map[string]interface{}
+// @Failure      409  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Router       /patients/{patient_id}/notes/{note_id} [put]
+// UpdateNote handles PUT requests to change a draft clinical note
+func (h *ClinicalNoteHandler) UpdateNote(c *gin.Context) {
+	patientID, noteID, ok := noteIDs(c)
+	if !ok {
+		return
+	}
+	var req NoteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	note, err := h.noteService.UpdateNote(actorFromContext(c), patientID, noteID, req.input())
+	if respondNoteError(c, err, "failed to update clinical note") {
+		return
+	}
+	c.JSON(http.StatusOK, noteResponse(note))
+}
+
+// @Summary      Discard a draft clinical note
+// @Description  Deletes your own draft for good. Signed notes cannot be deleted and are refused with 409. Requires the patient:write permission.
+// @Tags         Clinical Notes
+// @Produce      json
+// @Param        patient_id path string true "Patient ID" format(uuid)
+// @Param        note_id path string true "Note ID" format(uuid)
+// @Success      204  {string}  string "No Content"
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      401  {object}  map[string]interface{}
+// @Failure      403  {object}  map[string]interface{}
+// @Failure      404  {object}  map[string]interface{}
+// @Failure      409  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Router       /patients/{patient_id}/notes/{note_id} [delete]
+// DiscardNote handles DELETE requests to discard a draft clinical note
+func (h *ClinicalNoteHandler) DiscardNote(c *gin.Context) {
+	patientID, noteID, ok := noteIDs(c)
+	if !ok {
+		return
+	}
+	err := h.noteService.DiscardNote(actorFromContext(c), patientID, noteID)
+	if respondNoteError(c, err, "failed to discard clinical note") {
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// @Summary      Sign a clinical note
+// @Description  Signs your own draft, making it part of the patient's record. The note needs at least one SOAP section. From then on it cannot change; corrections are added as addenda. Requires the patient:write permission.
+// @Tags         Clinical Notes
+// @Produce      json
+// @Param        patient_id path string true "Patient ID" format(uuid)
+// @Param        note_id path string true "Note ID" format(uuid)
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      401  {object}  map[string]interface{}
+// @Failure      403  {object}  map[string]interface{}
+// @Failure      404  {object}  map[string]interface{}
+// @Failure      409  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Router       /patients/{patient_id}/notes/{note_id}/sign [post]
+// SignNote handles POST requests to sign a draft clinical note
+func (h *ClinicalNoteHandler) SignNote(c *gin.Context) {
+	patientID, noteID, ok := noteIDs(c)
+	if !ok {
+		return
+	}
+	note, err := h.noteService.SignNote(actorFromContext(c), patientID, noteID)
+	if respondNoteError(c, err, "failed to sign clinical note") {
+		return
+	}
+	c.JSON(http.StatusOK, noteResponse(note))
+}
+
+// @Summary      Add an addendum to a clinical note
+// @Description  Corrects or adds to a signed note without changing it; the note becomes amended. Any doctor or nurse on the patient's care team can add one. Addenda cannot be changed or removed. Drafts are refused with 409. Requires the patient:write permission.
+// @Tags         Clinical Notes
+// @Accept       json
+// @Produce      json
+// @Param        patient_id path string true "Patient ID" format(uuid)
+// @Param        note_id path string true "Note ID" format(uuid)
+// @Param        addendum body AddendumRequest true "Addendum"
+// @Success      201  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      401  {object}  map[string]interface{}
+// @Failure      403  {object}  map[string]interface{}
+// @Failure      404  {object}  map[string]interface{}
+// @Failure      409  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Router       /patients/{patient_id}/notes/{note_id}/addenda [post]
+// AddAddendum handles POST requests to add an addendum to a signed clinical note
+func (h *ClinicalNoteHandler) AddAddendum(c *gin.Context) {
+	patientID, noteID, ok := noteIDs(c)
+	if !ok {
+		return
+	}
+	var req AddendumRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	note, err := h.noteService.AddAddendum(actorFromContext(c), patientID, noteID, req.Text)
+	if respondNoteError(c, err, "failed to add addendum") {
+		return
+	}
+	c.JSON(http.StatusCreated, noteResponse(note))
+}
+
+// noteIDs reads the patient and note IDs from the path. It writes a 400
+// response if either is invalid and reports whether the caller may go on.
+func noteIDs(c *gin.Context) (uuid.UUID, uuid.UUID, bool) {
+	patientID, err := uuid.Parse(c.Param("patient_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid patient ID"})
+		return uuid.Nil, uuid.Nil, false
+	}
+	noteID, err := uuid.Parse(c.Param("note_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid note ID"})
+		return uuid.Nil, uuid.Nil, false
+	}
+	return patientID, noteID, true
+}
+
+// respondNoteError writes the error response for a failed clinical note
+// call, with message for unexpected errors, and reports whether there was an
+// error
+func respondNoteError(c *gin.Context, err error, message string) bool {
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "patient not found"})
+	case errors.Is(err, service.ErrNoteNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrInvalidNote):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrNoteSigned) || errors.Is(err, service.ErrNoteNotSigned):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case respondPatientForbidden(c, err):
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+	return true
+}
+
+// noteResponses formats clinical notes for the response body
+func noteResponses(notes []model.ClinicalNote) []gin.H {
+	data := make([]gin.H, 0, len(notes))
+	for i := range notes {
+		data = append(data, noteResponse(&notes[i]))
+	}
+	return data
+}
+
+// noteResponse formats a clinical note and its addenda for the response body
+func noteResponse(note *model.ClinicalNote) gin.H {
+	addenda := make([]gin.H, 0, len(note.Addenda))
+	for _, addendum := range note.Addenda {
+		addenda = append(addenda, gin.H{
+			"id":         addendum.ID,
+			"author_id":  addendum.AuthorID,
+			"text":       addendum.Text,
+			"created_at": addendum.CreatedAt,
+		})
+	}
+	return gin.H{
+		"id":             note.ID,
+		"patient_id":     note.PatientID,
+		"author_id":      note.AuthorID,
+		"encounter_type": note.EncounterType,
+		"encounter_at":   note.EncounterAt,
+		"subjective":     note.Subjective,
+		"objective":      note.Objective,
+		"assessment":     note.Assessment,
+		"plan":           note.Plan,
+		"status":         note.Status,
+		"signed_at":      note.SignedAt,
+		"addenda":        addenda,
+		"created_at":     note.CreatedAt,
+		"updated_at":     note.UpdatedAt,
+	}
+}
@@ -0,0 +1,153 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/RohanDSkaria/hospital-management-system/internal/model"
+	"github.com/RohanDSkaria/hospital-management-system/internal/repository"
+	"github.com/RohanDSkaria/hospital-management-system/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type LockoutHandler struct {
+	lockoutService service.LockoutService
+}
+
+// NewLockoutHandler creates a new LockoutHandler
+func NewLockoutHandler(lockoutService service.LockoutService) *LockoutHandler {
+	return &LockoutHandler{lockoutService: lockoutService}
+}
+
+// @Summary      List login lockouts
+// @Description  Lists accounts and client IPs that are locked out or have recent failed logins. Only accessible by admins.
+// @Tags         Security
+// @Produce      json
+// @Success      200  {object}  map[string]interface{}
+// @Failure      401  {object}  map[string]interface{}
+// @Failure      403  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Security     BearerAuth
+// @Router       /admin/lockouts [get]
+// ListLockouts handles GET requests to list login lockouts
+func (h *LockoutHandler) ListLockouts(c *gin.Context) {
+	throttles, err := h.lockoutService.ListLockouts()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch lockouts"})
+		return
+	}
+	now := time.Now()
+	data := make([]gin.H, 0, len(throttles))
+	for i := range throttles {
+		throttle := &throttles[i]
+		data = append(data, gin.H{
+			"id":              throttle.ID,
+			"scope":           throttle.Scope,
+			"subject":         throttle.Subject,
+			"failures":        throttle.Failures,
+			"last_failure_at": throttle.LastFailureAt,
+			"locked_until":    throttle.LockedUntil,
+			"locked":          throttle.IsLocked(now),
+		})
+	}
+	c.JSON(http.StatusOK, gin.H{"data": data})
+}
+
+// @Summary      Clear a login lockout
+// @Description  Removes a lockout and resets its failure count so the account or IP can log in again immediately. Only accessible by admins.
+// @Tags         Security
+// @Produce      json
+// @Param        lockout_id path string true "Lockout ID" format(uuid)
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      401  {object}  map[string]interface{}
+// @Failure      403  {object}  map[string]interface{}
+// @Failure      404  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Security     BearerAuth
+// @Router       /admin/lockouts/{lockout_id} [delete]
+// ClearLockout handles DELETE requests to clear a login lockout
+func (h *LockoutHandler) ClearLockout(c *gin.Context) {
+	lockoutID, err := uuid.Parse(c.Param("lockout_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid lockout ID"})
+		return
+	}
+	err = h.lockoutService.ClearLockout(lockoutID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "lockout not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to clear lockout"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "lockout cleared"})
+}
+
+// @Summary      List login attempts
+// @Description  Lists recorded login attempts, newest first. Only accessible by admins.
+// @Tags         Security
+// @Produce      json
+// @Param        email      query  string  false  "Filter by email"
+// @Param        client_ip  query  string  false  "Filter by client IP"
+// @Param        success    query  bool    false  "Filter by outcome"
+// @Param        limit      query  int     false  "Page size (default 50, max 200)"
+// @Param        offset     query  int     false  "Number of attempts to skip"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      401  {object}  map[string]interface{}
+// @Failure      403  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Security     BearerAuth
+// @Router       /admin/login-attempts [get]
+// ListLoginAttempts handles GET requests to list login attempts
+func (h *LockoutHandler) ListLoginAttempts(c *gin.Context) {
+	limit, offset, err := parseLimitOffset(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	filter := repository.LoginAttemptFilter{
+		Email:    c.Query("email"),
+		ClientIP: c.Query("client_ip"),
+		Limit:    limit,
+		Offset:   offset,
+	}
+	if success := c.Query("success"); success != "" {
+		value, err := strconv.ParseBool(success)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid success filter"})
+			return
+		}
+		filter.Success = &value
+	}
+
+	attempts, total, err := h.lockoutService.ListAttempts(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch login attempts"})
+		return
+	}
+	data := make([]gin.H, 0, len(attempts))
+	for i := range attempts {
+		data = append(data, loginAttemptResponse(&attempts[i]))
+	}
+	c.JSON(http.StatusOK, gin.H{"data": data, "total": total, "limit": limit, "offset": offset})
+}
+
+// loginAttemptResponse formats a login attempt for the response body
+func loginAttemptResponse(attempt *model.LoginAttempt) gin.H {
+	return gin.H{
+		"id":         attempt.ID,
+		"email":      attempt.Email,
+		"user_id":    attempt.UserID,
+		"client_ip":  attempt.ClientIP,
+		"success":    attempt.Success,
+		"reason":     attempt.Reason,
+		"created_at": attempt.CreatedAt,
+	}
+}
@@ -0,0 +1,210 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/RohanDSkaria/hospital-management-system/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type MFAHandler struct {
+	mfaService service.MFAService
+}
+
+// NewMFAHandler creates a new MFAHandler
+func NewMFAHandler(mfaService service.MFAService) *MFAHandler {
+	return &MFAHandler{mfaService: mfaService}
+}
+
+// MFACodeRequest defines the structure for request bodies carrying an authentication code
+type MFACodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// DisableMFARequest defines the structure for the disable MFA request body
+type DisableMFARequest struct {
+	Password string `json:"password" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+}
+
+// @Summary      Start MFA enrollment
+// @Description  Generates a new TOTP secret for the current user and returns it with an otpauth:// URI to show as a QR code. MFA is enabled once a code is confirmed.
+// @Tags         Profile
+// @Produce      json
+// @Success      200  {object}  map[string]interface{}
+// @Failure      401  {object}  map[string]interface{}
+// @Failure      409  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Security     BearerAuth
+// @Router       /profile/mfa/enroll [post]
+// BeginEnrollment handles POST requests to start an MFA enrollment
+func (h *MFAHandler) BeginEnrollment(c *gin.Context) {
+	userIDStr, _ := c.Get("userID")
+	userID, _ := uuid.Parse(userIDStr.(string))
+
+	enrollment, err := h.mfaService.BeginEnrollment(userID)
+	if errors.Is(err, service.ErrMFAAlreadyEnabled) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to start MFA enrollment"})
+		return
+	}
+	c.JSON(http.StatusOK, enrollmentResponse(enrollment))
+}
+
+// @Summary      Confirm MFA enrollment
+// @Description  Enables MFA with a code from the authenticator app and returns recovery codes. The recovery codes are only shown once.
+// @Tags         Profile
+// @Accept       json
+// @Produce      json
+// @Param        request body MFACodeRequest true "Authentication Code"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      401  {object}  map[string]interface{}
+// @Failure      409  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Security     BearerAuth
+// @Router       /profile/mfa/confirm [post]
+// ConfirmEnrollment handles POST requests to confirm an MFA enrollment
+func (h *MFAHandler) ConfirmEnrollment(c *gin.Context) {
+	var req MFACodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	userIDStr, _ := c.Get("userID")
+	userID, _ := uuid.Parse(userIDStr.(string))
+
+	codes, err := h.mfaService.ConfirmEnrollment(userID, req.Code)
+	if errors.Is(err, service.ErrInvalidMFACode) || errors.Is(err, service.ErrMFAEnrollmentNotStarted) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, service.ErrMFAAlreadyEnabled) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to confirm MFA enrollment"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"recovery_codes": codes})
+}
+
+// @Summary      Regenerate recovery codes
+// @Description  Replaces all recovery codes of the current user. Requires a current TOTP code. The new codes are only shown once.
+// @Tags         Profile
+// @Accept       json
+// @Produce      json
+// @Param        request body MFACodeRequest true "Authentication Code"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      401  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Security     BearerAuth
+// @Router       /profile/mfa/recovery-codes [post]
+// RegenerateRecoveryCodes handles POST requests to replace the recovery codes
+func (h *MFAHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	var req MFACodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	userIDStr, _ := c.Get("userID")
+	userID, _ := uuid.Parse(userIDStr.(string))
+
+	codes, err := h.mfaService.RegenerateRecoveryCodes(userID, req.Code)
+	if errors.Is(err, service.ErrInvalidMFACode) || errors.Is(err, service.ErrMFANotEnabled) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to regenerate recovery codes"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"recovery_codes": codes})
+}
+
+// @Summary      Disable MFA
+// @Description  Turns off MFA for the current user. Requires the password and a TOTP or recovery code. Not allowed for roles that require MFA.
+// @Tags         Profile
+// @Accept       json
+// @Produce      json
+// @Param        request body DisableMFARequest true "Password and Authentication Code"
+// @Success      204  {string}  string "No Content"
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      401  {object}  map[string]interface{}
+// @Failure      403  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Security     BearerAuth
+// @Router       /profile/mfa [delete]
+// DisableMFA handles DELETE requests to turn off MFA
+func (h *MFAHandler) DisableMFA(c *gin.Context) {
+	var req DisableMFARequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	userIDStr, _ := c.Get("userID")
+	userID, _ := uuid.Parse(userIDStr.(string))
+
+	err := h.mfaService.Disable(userID, req.Password, req.Code)
+	if errors.Is(err, service.ErrMFARequired) {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, service.ErrIncorrectPassword) || errors.Is(err, service.ErrInvalidMFACode) || errors.Is(err, service.ErrMFANotEnabled) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to disable MFA"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// @Summary      Reset a user's MFA
+// @Description  Removes the MFA enrollment and recovery codes of a user who lost their authenticator, and logs them out everywhere. They enroll again on their next login. Only accessible by admins.
+// @Tags         Users
+// @Produce      json
+// @Param        user_id path string true "User ID" format(uuid)
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      401  {object}  map[string]interface{}
+// @Failure      403  {object}  map[string]interface{}
+// @Failure      404  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Security     BearerAuth
+// @Router       /admin/users/{user_id}/mfa [delete]
+// ResetMFA handles DELETE requests to reset a user's MFA
+func (h *MFAHandler) ResetMFA(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user ID"})
+		return
+	}
+	user, err := h.mfaService.Reset(userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to reset MFA"})
+		return
+	}
+	c.JSON(http.StatusOK, userResponse(user))
+}
+
+// enrollmentResponse formats a TOTP enrollment for the response body
+func enrollmentResponse(enrollment *service.MFAEnrollment) gin.H {
+	return gin.H{
+		"secret":      enrollment.Secret,
+		"otpauth_uri": enrollment.ProvisioningURI,
+	}
+}
@@ -3,17 +3,20 @@ package api
 import (
 	"errors"
 	"net/http"
-	"os"
+	"slices"
 	"strings"
 
 	"github.com/RohanDSkaria/hospital-management-system/internal/auth"
 	"github.com/RohanDSkaria/hospital-management-system/internal/model"
+	"github.com/RohanDSkaria/hospital-management-system/internal/service"
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 )
 
-// AuthMiddleware creates a gin middleware for JWT authentication
-func AuthMiddleware() gin.HandlerFunc {
+// AuthMiddleware creates a gin middleware for JWT and API key authentication.
+// Tokens whose session has been revoked are rejected even if they have not expired yet.
+func AuthMiddleware(keyManager *auth.KeyManager, authService service.AuthService, apiKeyService service.APIKeyService) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
@@ -21,22 +24,21 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		// The header should be in the format "Bearer <token>"
+		// The header should be in the format "Bearer <token>" or "ApiKey <key>"
 		parts := strings.Split(authHeader, " ")
-		if len(parts) != 2 || parts[0] != "Bearer" {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "authorization header format must be Bearer {token}"})
+		if len(parts) != 2 || (parts[0] != "Bearer" && parts[0] != "ApiKey") {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "authorization header format must be Bearer {token} or ApiKey {key}"})
+			return
+		}
+		if parts[0] == "ApiKey" {
+			authenticateAPIKey(c, apiKeyService, parts[1])
 			return
 		}
 
 		tokenString := parts[1]
-		jwtSecret := []byte(os.Getenv("JWT_SECRET_KEY"))
-
-		// Parse and validate the token
-		claims := &auth.CustomClaims{}
-		token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-			return jwtSecret, nil
-		})
 
+		// Parse and validate the token against the key manager
+		claims, err := keyManager.ParseToken(tokenString)
 		if err != nil {
 			if errors.Is(err, jwt.ErrTokenExpired) {
 				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "token has expired"})
@@ -46,20 +48,97 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		if !token.Valid {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
+		// Make sure the session behind the token has not been logged out
+		if err := authService.ValidateSession(claims.SessionID, claims.UserID); err != nil {
+			if errors.Is(err, service.ErrSessionRevoked) || errors.Is(err, service.ErrAccountDeactivated) {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			} else {
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "failed to validate session"})
+			}
 			return
 		}
 
 		// If the token is valid, set the user info in the context for later use
 		c.Set("userID", claims.UserID.String())
 		c.Set("userRole", claims.Role)
+		c.Set("sessionID", claims.SessionID.String())
 
 		// Continue to the next handler
 		c.Next()
 	}
 }
 
+// authenticateAPIKey authenticates a request made with an API key. The
+// request acts as the key's owner, limited to the key's scopes.
+func authenticateAPIKey(c *gin.Context, apiKeyService service.APIKeyService, rawKey string) {
+	key, err := apiKeyService.Authenticate(rawKey, c.ClientIP())
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidAPIKey) || errors.Is(err, service.ErrAccountDeactivated) {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		} else {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "failed to validate API key"})
+		}
+		return
+	}
+
+	c.Set("userID", key.OwnerID.String())
+	c.Set("userRole", key.Owner.Role)
+	c.Set("apiKeyID", key.ID.String())
+	c.Set("apiKeyScopes", key.Scopes)
+	c.Next()
+}
+
+// RequireSession rejects requests authenticated with an API key. It guards
+// endpoints that act on a login session or manage accounts, which
+// integrations must never reach.
+func RequireSession() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, isAPIKey := c.Get("apiKeyID"); isAPIKey {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "this endpoint cannot be used with an API key"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequirePermission makes sure the caller's role grants every one of the
+// permissions. A request made with an API key also needs each permission among
+// the key's scopes, and the owner's current role must still grant it.
+func RequirePermission(permissionService service.PermissionService, permissions ...model.Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userRole, exists := c.Get("userRole")
+		if !exists {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "user role not found in token"})
+			return
+		}
+		role, _ := userRole.(model.Role)
+		value, isAPIKey := c.Get("apiKeyScopes")
+		scopes, _ := value.([]model.Permission)
+
+		for _, permission := range permissions {
+			if !permissionService.HasPermission(role, permission) {
+				c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "you are not authorized to perform this action"})
+				return
+			}
+			if isAPIKey && !slices.Contains(scopes, permission) {
+				c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "API key is missing the " + string(permission) + " scope"})
+				return
+			}
+		}
+		c.Next()
+	}
+}
+
+// Deprecated marks the responses of a route that is kept only for old
+// clients and points them to the route that replaces it
+func Deprecated(successor string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Deprecation", "true")
+		c.Header("Link", "<"+successor+">; rel=\"successor-version\"")
+		c.Next()
+	}
+}
+
 // RoleAuthMiddleware checks if the user role from the JWT matches the required role
 func RoleAuthMiddleware(requiredRole model.Role) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -80,3 +159,49 @@ func RoleAuthMiddleware(requiredRole model.Role) gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// actorFromContext returns the authenticated user set by AuthMiddleware
+func actorFromContext(c *gin.Context) service.Actor {
+	userIDStr, _ := c.Get("userID")
+	userID, _ := uuid.Parse(userIDStr.(string))
+	userRole, _ := c.Get("userRole")
+	role, _ := userRole.(model.Role)
+	return service.Actor{
+		UserID:    userID,
+		Role:      role,
+		RequestID: c.GetString("requestID"),
+		ClientIP:  c.ClientIP(),
+	}
+}
+
+// maxRequestIDLength is the longest X-Request-ID accepted from a client
+const maxRequestIDLength = 64
+
+// RequestID creates a gin middleware that gives every request an ID. A
+// well-formed X-Request-ID from the client is kept so calls can be traced
+// across services; otherwise a new one is generated. The ID is echoed back
+// in the response and recorded in the audit log.
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader("X-Request-ID")
+		if !validRequestID(requestID) {
+			requestID = uuid.NewString()
+		}
+		c.Set("requestID", requestID)
+		c.Header("X-Request-ID", requestID)
+		c.Next()
+	}
+}
+
+// validRequestID reports whether a client supplied request ID is safe to log
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range id {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' || r == '.') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,150 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/RohanDSkaria/hospital-management-system/internal/password"
+	"github.com/RohanDSkaria/hospital-management-system/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type PasswordHandler struct {
+	passwordService service.PasswordService
+}
+
+// NewPasswordHandler creates a new PasswordHandler
+func NewPasswordHandler(passwordService service.PasswordService) *PasswordHandler {
+	return &PasswordHandler{passwordService: passwordService}
+}
+
+// ChangePasswordRequest defines the structure for the change password request body
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required"`
+}
+
+// @Summary      Change password
+// @Description  Changes the password of the current user. The new password must follow the password policy and differ from recent ones. All other sessions of the user are logged out.
+// @Tags         Profile
+// @Accept       json
+// @Produce      json
+// @Param        password body ChangePasswordRequest true "Current and New Password"
+// @Success      204  {string}  string "No Content"
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      401  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Security     BearerAuth
+// @Router       /profile/password [put]
+// ChangePassword handles PUT requests to change the current user's password
+func (h *PasswordHandler) ChangePassword(c *gin.Context) {
+	var req ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userIDStr, _ := c.Get("userID")
+	userID, _ := uuid.Parse(userIDStr.(string))
+	sessionIDStr, _ := c.Get("sessionID")
+	sessionID, _ := uuid.Parse(sessionIDStr.(string))
+
+	err := h.passwordService.ChangePassword(userID, sessionID, req.CurrentPassword, req.NewPassword)
+	if respondPasswordRejected(c, err) {
+		return
+	}
+	if errors.Is(err, service.ErrIncorrectPassword) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to change password"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// ForgotPasswordRequest defines the structure for the forgot password request body
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// @Summary      Forgot password
+// @Description  Emails a single-use password reset link if an active account exists for the email. The response is the same either way.
+// @Tags         Authentication
+// @Accept       json
+// @Produce      json
+// @Param        request body ForgotPasswordRequest true "Account Email"
+// @Success      202  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Router       /password/forgot [post]
+// ForgotPassword handles POST requests to start a password reset
+func (h *PasswordHandler) ForgotPassword(c *gin.Context) {
+	var req ForgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.passwordService.RequestPasswordReset(req.Email); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to request password reset"})
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{"message": "if an account exists for this email, a reset link has been sent"})
+}
+
+// ResetPasswordRequest defines the structure for the reset password request body
+type ResetPasswordRequest struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required"`
+}
+
+// @Summary      Reset password
+// @Description  Sets a new password using a reset token. The new password must follow the password policy and differ from recent ones. The token can only be used once and all sessions of the user are logged out.
+// @Tags         Authentication
+// @Accept       json
+// @Produce      json
+// @Param        request body ResetPasswordRequest true "Reset Token and New Password"
+// @Success      204  {string}  string "No Content"
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Router       /password/reset [post]
+// ResetPassword handles POST requests to reset a password with a token
+func (h *PasswordHandler) ResetPassword(c *gin.Context) {
+	var req ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err := h.passwordService.ResetPassword(req.Token, req.NewPassword)
+	if respondPasswordRejected(c, err) {
+		return
+	}
+	if errors.Is(err, service.ErrInvalidResetToken) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to reset password"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// respondPasswordRejected writes a 400 response if err means the new password
+// was refused by the policy or was used recently, and reports whether it did
+func respondPasswordRejected(c *gin.Context, err error) bool {
+	var policyErr *password.PolicyError
+	if errors.As(err, &policyErr) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "password does not meet the policy", "problems": policyErr.Problems})
+		return true
+	}
+	if errors.Is(err, service.ErrPasswordReused) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return true
+	}
+	return false
+}
@@ -1,9 +1,15 @@
 package api
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/RohanDSkaria/hospital-management-system/internal/model"
 	"github.com/RohanDSkaria/hospital-management-system/internal/service"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -18,6 +24,9 @@ func NewPatientHandler(s service.PatientService) *PatientHandler {
 	return &PatientHandler{patientService: s}
 }
 
+// maxDeletionReasonLength is the longest reason accepted when a patient is deleted
+const maxDeletionReasonLength = 500
+
 type PatientRequest struct {
 	FullName       string    `json:"full_name" binding:"required"`
 	DateOfBirth    time.Time `json:"date_of_birth" binding:"required"`
@@ -27,17 +36,21 @@ type PatientRequest struct {
 }
 
 // @Summary      Create a new patient
-// @Description  Creates a new patient record in the system. Only accessible by receptionists.
+// @Description  Creates a new patient record in the system. Requires the patient:write permission. Only clinicians may set the medical history; a clinician who creates a patient joins their care team. If the patient looks like one already registered, nothing is created and the likely matches are returned with 409; doctors and nurses only get the IDs of matches outside their care teams, and every disclosed match is audited; repeat the request with allow_duplicate=true once you have checked it is a different person. The role-prefixed routes are deprecated aliases.
 // @Tags         Patients
 // @Accept       json
 // @Produce      json
-// @Param        patient body PatientRequest true "Patient Information"
+// @Param        patient          body   PatientRequest  true   "Patient Information"
+// @Param        allow_duplicate  query  bool            false  "Create the patient even if they look like a registered one"
 // @Success      201  {object}  map[string]interface{}
 // @Failure      400  {object}  map[string]interface{}
 // @Failure      401  {object}  map[string]interface{}
 // @Failure      403  {object}  map[string]interface{}
+// @Failure      409  {object}  map[string]interface{}
 // @Failure      500  {object}  map[string]interface{}
 // @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Router       /patients [post]
 // @Router       /receptionist/patients [post]
 // CreatePatient handles POST requests to create a patient
 func (h *PatientHandler) CreatePatient(c *gin.Context) {
@@ -47,53 +60,234 @@ func (h *PatientHandler) CreatePatient(c *gin.Context) {
 		return
 	}
 
-	// Get the user ID from the context (set by AuthMiddleware)
-	userIDStr, _ := c.Get("userID")
-	userID, _ := uuid.Parse(userIDStr.(string))
+	allowDuplicate := false
+	if value := c.Query("allow_duplicate"); value != "" {
+		var err error
+		allowDuplicate, err = strconv.ParseBool(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid allow_duplicate flag"})
+			return
+		}
+	}
 
-	patient, err := h.patientService.CreatePatient(req.FullName, req.Address, req.ContactNumber, req.DateOfBirth, req.MedicalHistory, userID)
+	patient, err := h.patientService.CreatePatient(actorFromContext(c), req.FullName, req.Address, req.ContactNumber, req.DateOfBirth, req.MedicalHistory, allowDuplicate)
+	var duplicates *service.PossibleDuplicatesError
+	if errors.As(err, &duplicates) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "candidates": duplicates.Candidates})
+		return
+	}
+	if respondPatientForbidden(c, err) {
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create patient"})
 		return
 	}
+	setPatientETag(c, patient)
 	c.JSON(http.StatusCreated, patient)
 }
 
-// @Summary      Get all patients
-// @Description  Retrieves a list of all patients in the system. Accessible by both receptionists and doctors.
+// @Summary      List patients
+// @Description  Returns a page of the patients the caller may see, in a stable order. Pass next_cursor from the response as cursor to get the following page, with the same sort and filters. Requires the patient:read permission. Doctors and nurses only get the patients whose care team they are on; other roles get every patient without the medical history.
+// @Tags         Patients
+// @Accept       json
+// @Produce      json
+// @Param        limit          query  int     false  "Page size (1-200, default 50)"
+// @Param        cursor         query  string  false  "next_cursor of the previous page"
+// @Param        sort           query  string  false  "name, created_at (default) or date_of_birth, prefixed with - for descending"
+// @Param        registered_by  query  string  false  "Only patients registered by this user" format(uuid)
+// @Param        created_from   query  string  false  "Registered at or after this time (RFC 3339)"
+// @Param        created_to     query  string  false  "Registered before this time (RFC 3339)"
+// @Param        min_age        query  int     false  "Minimum age in whole years"
+// @Param        max_age        query  int     false  "Maximum age in whole years"
+// @Param        include_total  query  bool    false  "Also count every matching patient"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      401  {object}  map[string]interface{}
+// @Failure      403  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Router       /patients [get]
+// GetAllPatients handles GET requests to list patients
+func (h *PatientHandler) GetAllPatients(c *gin.Context) {
+	query, err := parsePatientListQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	page, err := h.patientService.GetAllPatients(actorFromContext(c), query)
+	if errors.Is(err, service.ErrInvalidPatientQuery) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch patients"})
+		return
+	}
+	response := gin.H{"data": page.Patients, "limit": query.Limit, "next_cursor": nil}
+	if page.NextCursor != "" {
+		response["next_cursor"] = page.NextCursor
+	}
+	if page.Total != nil {
+		response["total"] = *page.Total
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// @Summary      List patients (deprecated)
+// @Description  Deprecated alias of GET /patients that keeps the old response shape: a bare array of patients, oldest first. Only one page is returned; if there are more, the Link header has a rel="next" URL and X-Next-Cursor holds the cursor for it. Requires the patient:read permission and the role of the route. Doctors and nurses only get the patients whose care team they are on; other roles get every patient without the medical history.
 // @Tags         Patients
 // @Accept       json
 // @Produce      json
+// @Param        limit   query  int     false  "Page size (1-200, default 200)"
+// @Param        cursor  query  string  false  "Cursor from the Link header of the previous page"
 // @Success      200  {array}   map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
 // @Failure      401  {object}  map[string]interface{}
 // @Failure      403  {object}  map[string]interface{}
 // @Failure      500  {object}  map[string]interface{}
 // @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Header       200  {string}  Link           "URL of the next page, with rel=\"next\""
+// @Header       200  {string}  X-Next-Cursor  "Cursor of the next page, if there is one"
 // @Router       /receptionist/patients [get]
 // @Router       /doctor/patients [get]
-// GetAllPatients handles GET requests to fetch all patients
-func (h *PatientHandler) GetAllPatients(c *gin.Context) {
-	patients, err := h.patientService.GetAllPatients()
+// GetAllPatientsLegacy handles GET requests to the deprecated role-prefixed
+// patient lists. It answers one page as a bare array, as older clients expect.
+func (h *PatientHandler) GetAllPatientsLegacy(c *gin.Context) {
+	query := service.PatientListQuery{Cursor: c.Query("cursor"), Limit: 200}
+	if c.Query("limit") != "" {
+		limit, err := parseLimit(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		query.Limit = limit
+	}
+	page, err := h.patientService.GetAllPatients(actorFromContext(c), query)
+	if errors.Is(err, service.ErrInvalidPatientQuery) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch patients"})
 		return
 	}
-	c.JSON(http.StatusOK, patients)
+	if page.NextCursor != "" {
+		next := url.Values{"cursor": {page.NextCursor}, "limit": {strconv.Itoa(query.Limit)}}
+		c.Writer.Header().Add("Link", "<"+c.Request.URL.Path+"?"+next.Encode()+">; rel=\"next\"")
+		c.Header("X-Next-Cursor", page.NextCursor)
+	}
+	c.JSON(http.StatusOK, page.Patients)
+}
+
+// parsePatientListQuery reads the paging, sort and filter parameters of a patient listing
+func parsePatientListQuery(c *gin.Context) (service.PatientListQuery, error) {
+	limit, err := parseLimit(c)
+	if err != nil {
+		return service.PatientListQuery{}, err
+	}
+	query := service.PatientListQuery{Sort: c.Query("sort"), Cursor: c.Query("cursor"), Limit: limit}
+	if value := c.Query("registered_by"); value != "" {
+		id, err := uuid.Parse(value)
+		if err != nil {
+			return query, errors.New("invalid registered_by user ID")
+		}
+		query.RegisteredByID = &id
+	}
+	for name, target := range map[string]**time.Time{"created_from": &query.CreatedFrom, "created_to": &query.CreatedTo} {
+		if value := c.Query(name); value != "" {
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				return query, fmt.Errorf("%s must be an RFC 3339 time", name)
+			}
+			*target = &t
+		}
+	}
+	for name, target := range map[string]**int{"min_age": &query.MinAge, "max_age": &query.MaxAge} {
+		if value := c.Query(name); value != "" {
+			age, err := strconv.Atoi(value)
+			if err != nil || age < 0 || age > 150 {
+				return query, fmt.Errorf("%s must be a whole number of years between 0 and 150", name)
+			}
+			*target = &age
+		}
+	}
+	if value := c.Query("include_total"); value != "" {
+		include, err := strconv.ParseBool(value)
+		if err != nil {
+			return query, errors.New("invalid include_total flag")
+		}
+		query.IncludeTotal = include
+	}
+	return query, nil
+}
+
+// @Summary      Search patients
+// @Description  Finds patients by part of their name, address or phone number, or by date of birth, best match first. Names also match despite typos or spelling that sounds alike. Highlights mark the matched text with <mark> tags. Requires the patient:read permission. Doctors and nurses only find the patients whose care team they are on.
+// @Tags         Patients
+// @Accept       json
+// @Produce      json
+// @Param        q      query  string  false  "Name, address, phone number or date of birth (YYYY-MM-DD)"
+// @Param        dob    query  string  false  "Date of birth (YYYY-MM-DD)"
+// @Param        limit  query  int     false  "Number of results (1-50, default 20)"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      401  {object}  map[string]interface{}
+// @Failure      403  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Router       /patients/search [get]
+// SearchPatients handles GET requests to search patients
+func (h *PatientHandler) SearchPatients(c *gin.Context) {
+	query := service.PatientSearchQuery{Text: c.Query("q"), Limit: 20}
+	if value := c.Query("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > 50 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 50"})
+			return
+		}
+		query.Limit = n
+	}
+	if value := c.Query("dob"); value != "" {
+		dob, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "dob must be a date as YYYY-MM-DD"})
+			return
+		}
+		query.DateOfBirth = &dob
+	}
+	results, err := h.patientService.SearchPatients(actorFromContext(c), query)
+	if errors.Is(err, service.ErrInvalidPatientQuery) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to search patients"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": results, "limit": query.Limit})
 }
 
 // @Summary      Get patient by ID
-// @Description  Retrieves a specific patient by their unique ID. Accessible by both receptionists and doctors.
+// @Description  Retrieves a specific patient by their unique ID. Requires the patient:read permission. Doctors and nurses must be on the patient's care team; other roles do not see the medical history. Doctors and nurses also get the active allergies (active_allergies) and the no known allergies assertion (no_known_allergies, null if not asserted). The ID of a patient merged into another redirects to that patient. The role-prefixed routes are deprecated aliases.
 // @Tags         Patients
 // @Accept       json
 // @Produce      json
 // @Param        patient_id path string true "Patient ID" format(uuid)
 // @Success      200  {object}  map[string]interface{}
+// @Success      301  {object}  map[string]interface{}
 // @Failure      400  {object}  map[string]interface{}
 // @Failure      401  {object}  map[string]interface{}
 // @Failure      403  {object}  map[string]interface{}
 // @Failure      404  {object}  map[string]interface{}
 // @Failure      500  {object}  map[string]interface{}
 // @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Header       200  {string}  ETag  "Current version of the patient, to send back in If-Match"
+// @Router       /patients/{patient_id} [get]
 // @Router       /receptionist/patients/{patient_id} [get]
 // @Router       /doctor/patients/{patient_id} [get]
 // GetPatientByID handles GET requests for a single patient
@@ -103,32 +297,111 @@ func (h *PatientHandler) GetPatientByID(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid patient ID"})
 		return
 	}
-	patient, err := h.patientService.GetPatientByID(patientID)
-	if err == gorm.ErrRecordNotFound {
+	patient, allergies, err := h.patientService.GetPatientByID(actorFromContext(c), patientID)
+	respondPatientLookup(c, patient, allergies, err, func(target uuid.UUID) string {
+		return strings.Replace(c.Request.URL.Path, c.Param("patient_id"), target.String(), 1)
+	})
+}
+
+// @Summary      Get patient by medical record number
+// @Description  Retrieves a patient by their medical record number (MRN). Case, spaces and hyphens are ignored; a number whose check digit does not match is refused with 400, as it was mistyped. Access rules are the same as for a lookup by ID, and the number of a merged patient redirects to the patient it was merged into. Requires the patient:read permission.
+// @Tags         Patients
+// @Accept       json
+// @Produce      json
+// @Param        mrn path string true "Medical record number"
+// @Success      200  {object}  map[string]interface{}
+// @Success      301  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      401  {object}  map[string]interface{}
+// @Failure      403  {object}  map[string]interface{}
+// @Failure      404  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Header       200  {string}  ETag  "Current version of the patient, to send back in If-Match"
+// @Router       /patients/by-mrn/{mrn} [get]
+// GetPatientByMRN handles GET requests for a patient by medical record number
+func (h *PatientHandler) GetPatientByMRN(c *gin.Context) {
+	patient, allergies, err := h.patientService.GetPatientByMRN(actorFromContext(c), c.Param("mrn"))
+	if errors.Is(err, service.ErrInvalidMRN) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	respondPatientLookup(c, patient, allergies, err, func(target uuid.UUID) string {
+		path := c.Request.URL.Path
+		return path[:strings.LastIndex(path, "/by-mrn/")] + "/" + target.String()
+	})
+}
+
+// respondPatientLookup writes the response to a lookup of a single patient,
+// with their allergies if the caller may see them. A patient that was merged
+// into another redirects to the URL movedTo returns for it.
+func respondPatientLookup(c *gin.Context, patient *model.Patient, allergies *service.AllergySummary, err error, movedTo func(target uuid.UUID) string) {
+	var merged *service.PatientMergedError
+	if errors.As(err, &merged) {
+		c.Header("Location", movedTo(merged.TargetID))
+		c.JSON(http.StatusMovedPermanently, gin.H{"error": err.Error(), "merged_into": merged.TargetID})
+		return
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "patient not found"})
 		return
 	}
+	if respondPatientForbidden(c, err) {
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch patient"})
 		return
 	}
-	c.JSON(http.StatusOK, patient)
+	setPatientETag(c, patient)
+	if allergies == nil {
+		c.JSON(http.StatusOK, patient)
+		return
+	}
+	c.JSON(http.StatusOK, patientWithAllergies(patient, allergies))
+}
+
+// patientAllergiesResponse is a patient with the allergies a prescriber must
+// be warned about
+type patientAllergiesResponse struct {
+	*model.Patient
+	ActiveAllergies  []gin.H `json:"active_allergies"`
+	NoKnownAllergies gin.H   `json:"no_known_allergies"`
+}
+
+// patientWithAllergies adds the allergy summary to a patient for the response body
+func patientWithAllergies(patient *model.Patient, allergies *service.AllergySummary) patientAllergiesResponse {
+	response := patientAllergiesResponse{Patient: patient, ActiveAllergies: make([]gin.H, 0, len(allergies.Active))}
+	for i := range allergies.Active {
+		response.ActiveAllergies = append(response.ActiveAllergies, allergyResponse(&allergies.Active[i]))
+	}
+	if allergies.NoKnownAllergies != nil {
+		response.NoKnownAllergies = noKnownAllergiesResponse(allergies.NoKnownAllergies)
+	}
+	return response
 }
 
 // @Summary      Update patient
-// @Description  Updates an existing patient's information. Accessible by both receptionists and doctors.
+// @Description  Replaces an existing patient's information. Only the fields the caller's role may change can differ from the stored values (see PATCH). The If-Match header must carry the ETag of the version being edited; the update is refused with 412 if the patient has changed since. Requires the patient:write permission. Doctors and nurses must be on the patient's care team; other roles must leave the medical history empty and it is kept unchanged. The role-prefixed routes are deprecated aliases.
 // @Tags         Patients
 // @Accept       json
 // @Produce      json
 // @Param        patient_id path string true "Patient ID" format(uuid)
+// @Param        If-Match header string true "ETag of the version being edited"
 // @Param        patient body PatientRequest true "Updated Patient Information"
 // @Success      200  {object}  map[string]interface{}
+// @Header       200  {string}  ETag  "New version of the patient"
 // @Failure      400  {object}  map[string]interface{}
 // @Failure      401  {object}  map[string]interface{}
 // @Failure      403  {object}  map[string]interface{}
 // @Failure      404  {object}  map[string]interface{}
+// @Failure      412  {object}  map[string]interface{}
+// @Failure      428  {object}  map[string]interface{}
 // @Failure      500  {object}  map[string]interface{}
 // @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Router       /patients/{patient_id} [put]
 // @Router       /receptionist/patients/{patient_id} [put]
 // @Router       /doctor/patients/{patient_id} [put]
 // UpdatePatient handles PUT requests to update a patient
@@ -138,35 +411,123 @@ func (h *PatientHandler) UpdatePatient(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid patient ID"})
 		return
 	}
+	expectedVersion, ok := requireIfMatch(c)
+	if !ok {
+		return
+	}
 	var req PatientRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	patient, err := h.patientService.UpdatePatient(patientID, req.FullName, req.Address, req.ContactNumber, req.DateOfBirth, req.MedicalHistory)
+	patient, err := h.patientService.UpdatePatient(actorFromContext(c), patientID, expectedVersion, req.FullName, req.Address, req.ContactNumber, req.DateOfBirth, req.MedicalHistory)
 	if err == gorm.ErrRecordNotFound {
 		c.JSON(http.StatusNotFound, gin.H{"error": "patient not found"})
 		return
 	}
+	if respondPatientForbidden(c, err) || respondVersionMismatch(c, err) {
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update patient"})
 		return
 	}
+	setPatientETag(c, patient)
 	c.JSON(http.StatusOK, patient)
 }
 
+// @Summary      Partially update patient
+// @Description  Changes only the fields named in the patch. Send an RFC 7396 merge patch as application/merge-patch+json (or application/json), or an RFC 6902 JSON Patch as application/json-patch+json. The patch is applied to full_name, date_of_birth, address, contact_number and, for doctors and nurses, medical_history. Each role may only change some fields: receptionists and admins the demographics, nurses the address, contact number and medical history, doctors everything. The If-Match header must carry the ETag of the version being edited. Requires the patient:write permission.
+// @Tags         Patients
+// @Accept       json
+// @Accept       application/merge-patch+json
+// @Accept       application/json-patch+json
+// @Produce      json
+// @Param        patient_id path   string true "Patient ID" format(uuid)
+// @Param        If-Match   header string true "ETag of the version being edited"
+// @Param        patch      body   object true "Merge patch object or JSON Patch array"
+// @Success      200  {object}  map[string]interface{}
+// @Header       200  {string}  ETag  "New version of the patient"
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      401  {object}  map[string]interface{}
+// @Failure      403  {object}  map[string]interface{}
+// @Failure      404  {object}  map[string]interface{}
+// @Failure      409  {object}  map[string]interface{}
+// @Failure      412  {object}  map[string]interface{}
+// @Failure      415  {object}  map[string]interface{}
+// @Failure      428  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Router       /patients/{patient_id} [patch]
+// PatchPatient handles PATCH requests to partially update a patient
+func (h *PatientHandler) PatchPatient(c *gin.Context) {
+	patientID, err := uuid.Parse(c.Param("patient_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid patient ID"})
+		return
+	}
+	var format string
+	switch c.ContentType() {
+	case "application/merge-patch+json", "application/json":
+		format = service.MergePatch
+	case "application/json-patch+json":
+		format = service.JSONPatch
+	default:
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "Content-Type must be application/merge-patch+json or application/json-patch+json"})
+		return
+	}
+	expectedVersion, ok := requireIfMatch(c)
+	if !ok {
+		return
+	}
+	document, err := c.GetRawData()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read the request body"})
+		return
+	}
+
+	patch := service.PatientPatch{Format: format, Document: document}
+	patient, err := h.patientService.PatchPatient(actorFromContext(c), patientID, expectedVersion, patch)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "patient not found"})
+		return
+	}
+	if respondPatientForbidden(c, err) || respondVersionMismatch(c, err) {
+		return
+	}
+	switch {
+	case errors.Is(err, service.ErrInvalidPatientPatch):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrPatientPatchTestFailed):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update patient"})
+	default:
+		setPatientETag(c, patient)
+		c.JSON(http.StatusOK, patient)
+	}
+}
+
 // @Summary      Delete patient
-// @Description  Deletes a patient from the system. Only accessible by receptionists.
+// @Description  Marks a patient as deleted. The If-Match header must carry the ETag of the current version. The record is kept, can be restored by an admin, and is purged for good once the retention period has passed. Requires the patient:delete permission. Doctors and nurses must be on the patient's care team. The role-prefixed routes are deprecated aliases.
 // @Tags         Patients
 // @Accept       json
 // @Produce      json
-// @Param        patient_id path string true "Patient ID" format(uuid)
+// @Param        patient_id path  string true  "Patient ID" format(uuid)
+// @Param        reason     query string false "Why the patient is deleted (at most 500 characters)"
+// @Param        If-Match   header string true "ETag of the current version"
 // @Success      204  {string}  string "No Content"
 // @Failure      400  {object}  map[string]interface{}
 // @Failure      401  {object}  map[string]interface{}
 // @Failure      403  {object}  map[string]interface{}
+// @Failure      404  {object}  map[string]interface{}
+// @Failure      412  {object}  map[string]interface{}
+// @Failure      428  {object}  map[string]interface{}
 // @Failure      500  {object}  map[string]interface{}
 // @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Router       /patients/{patient_id} [delete]
 // @Router       /receptionist/patients/{patient_id} [delete]
 // DeletePatient handles DELETE requests to remove a patient
 func (h *PatientHandler) DeletePatient(c *gin.Context) {
@@ -175,9 +536,80 @@ func (h *PatientHandler) DeletePatient(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid patient ID"})
 		return
 	}
-	if err := h.patientService.DeletePatient(patientID); err != nil {
+	reason := strings.TrimSpace(c.Query("reason"))
+	if len(reason) > maxDeletionReasonLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "reason must be at most 500 characters"})
+		return
+	}
+	expectedVersion, ok := requireIfMatch(c)
+	if !ok {
+		return
+	}
+	err = h.patientService.DeletePatient(actorFromContext(c), patientID, expectedVersion, reason)
+	if err == gorm.ErrRecordNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": "patient not found"})
+		return
+	}
+	if respondPatientForbidden(c, err) || respondVersionMismatch(c, err) {
+		return
+	}
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete patient"})
 		return
 	}
 	c.Status(http.StatusNoContent)
 }
+
+// respondPatientForbidden writes a 403 response if err means the caller may
+// not open the patient, change some of its fields or see its clinical data,
+// and reports whether it did
+func respondPatientForbidden(c *gin.Context, err error) bool {
+	if errors.Is(err, service.ErrNotOnCareTeam) || errors.Is(err, service.ErrMedicalHistoryForbidden) ||
+		errors.Is(err, service.ErrPatientFieldForbidden) || errors.Is(err, service.ErrClinicalDataForbidden) {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return true
+	}
+	return false
+}
+
+// setPatientETag sets the ETag header to the patient's version
+func setPatientETag(c *gin.Context, patient *model.Patient) {
+	c.Header("ETag", strconv.Quote(strconv.Itoa(patient.Version)))
+}
+
+// requireIfMatch reads the patient version a write is based on from the
+// If-Match header. Weak ETags (W/"3") are read like strong ones. It writes a
+// 428 response if the header is missing, a 412 for "*", which would
+// overwrite any version, or a 400 if it is not a patient ETag, and reports
+// whether the caller may go on.
+func requireIfMatch(c *gin.Context) (int, bool) {
+	header := strings.TrimSpace(c.GetHeader("If-Match"))
+	if header == "" {
+		c.JSON(http.StatusPreconditionRequired, gin.H{"error": "If-Match header is required, send the ETag from the patient's last response"})
+		return 0, false
+	}
+	if header == "*" {
+		c.JSON(http.StatusPreconditionFailed, gin.H{"error": "If-Match must name the version the change is based on, not *"})
+		return 0, false
+	}
+	value := strings.TrimPrefix(header, "W/")
+	if unquoted, err := strconv.Unquote(value); err == nil {
+		value = unquoted
+	}
+	version, err := strconv.Atoi(value)
+	if err != nil || version < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "If-Match must be the ETag from the patient's last response"})
+		return 0, false
+	}
+	return version, true
+}
+
+// respondVersionMismatch writes a 412 response if err means the patient has
+// changed since the version the write was based on, and reports whether it did
+func respondVersionMismatch(c *gin.Context, err error) bool {
+	if errors.Is(err, service.ErrPatientVersionMismatch) {
+		c.JSON(http.StatusPreconditionFailed, gin.H{"error": err.Error()})
+		return true
+	}
+	return false
+}
@@ -0,0 +1,256 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/RohanDSkaria/hospital-management-system/internal/model"
+	"github.com/RohanDSkaria/hospital-management-system/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type PatientHistoryHandler struct {
+	historyService service.PatientHistoryService
+}
+
+// NewPatientHistoryHandler creates a new PatientHistoryHandler
+func NewPatientHistoryHandler(historyService service.PatientHistoryService) *PatientHistoryHandler {
+	return &PatientHistoryHandler{historyService: historyService}
+}
+
+// @Summary      List patient versions
+// @Description  Lists the versions of a patient record, newest first. Every create, update and restore writes a version. Requires the patient:read permission. Doctors and nurses must be on the patient's care team; other roles do not see the medical history.
+// @Tags         Patient History
+// @Produce      json
+// @Param        patient_id path string true "Patient ID" format(uuid)
+// @Param        limit   query  int  false  "Page size (1-200, default 50)"
+// @Param        offset  query  int  false  "Number of versions to skip"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      401  {object}  map[string]interface{}
+// @Failure      403  {object}  map[string]interface{}
+// @Failure      404  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Router       /patients/{patient_id}/versions [get]
+// ListPatientVersions handles GET requests for a patient's version history
+func (h *PatientHistoryHandler) ListPatientVersions(c *gin.Context) {
+	patientID, err := uuid.Parse(c.Param("patient_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid patient ID"})
+		return
+	}
+	limit, offset, err := parseLimitOffset(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	versions, total, err := h.historyService.ListVersions(actorFromContext(c), patientID, limit, offset)
+	if respondPatientHistoryError(c, err, "failed to fetch patient versions") {
+		return
+	}
+	data := make([]gin.H, 0, len(versions))
+	for i := range versions {
+		data = append(data, patientVersionResponse(&versions[i]))
+	}
+	c.JSON(http.StatusOK, gin.H{"data": data, "total": total, "limit": limit, "offset": offset})
+}
+
+// @Summary      Get a patient version
+// @Description  Returns one version of a patient record. Requires the patient:read permission.
+// @Tags         Patient History
+// @Produce      json
+// @Param        patient_id path string true "Patient ID" format(uuid)
+// @Param        version    path int    true "Version number"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      401  {object}  map[string]interface{}
+// @Failure      403  {object}  map[string]interface{}
+// @Failure      404  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Router       /patients/{patient_id}/versions/{version} [get]
+// GetPatientVersion handles GET requests for a single version of a patient record
+func (h *PatientHistoryHandler) GetPatientVersion(c *gin.Context) {
+	patientID, err := uuid.Parse(c.Param("patient_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid patient ID"})
+		return
+	}
+	version, err := parseVersion(c.Param("version"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	patientVersion, err := h.historyService.GetVersion(actorFromContext(c), patientID, version)
+	if respondPatientHistoryError(c, err, "failed to fetch patient version") {
+		return
+	}
+	c.JSON(http.StatusOK, patientVersionResponse(patientVersion))
+}
+
+// @Summary      Get a patient as of a time
+// @Description  Returns the patient record as it was at the given time, as the version that was current then. Requires the patient:read permission.
+// @Tags         Patient History
+// @Produce      json
+// @Param        patient_id path  string true "Patient ID" format(uuid)
+// @Param        at         query string true "Point in time (RFC 3339)"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      401  {object}  map[string]interface{}
+// @Failure      403  {object}  map[string]interface{}
+// @Failure      404  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Router       /patients/{patient_id}/as-of [get]
+// GetPatientAsOf handles GET requests for a patient record at a point in time
+func (h *PatientHistoryHandler) GetPatientAsOf(c *gin.Context) {
+	patientID, err := uuid.Parse(c.Param("patient_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid patient ID"})
+		return
+	}
+	at, err := time.Parse(time.RFC3339, c.Query("at"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "at must be an RFC 3339 time"})
+		return
+	}
+
+	patientVersion, err := h.historyService.GetAsOf(actorFromContext(c), patientID, at)
+	if respondPatientHistoryError(c, err, "failed to fetch patient version") {
+		return
+	}
+	c.JSON(http.StatusOK, patientVersionResponse(patientVersion))
+}
+
+// @Summary      Diff two patient versions
+// @Description  Lists the fields that differ between two versions of a patient record with their values in each. For roles other than doctors and nurses, a changed medical history is marked as redacted. Requires the patient:read permission.
+// @Tags         Patient History
+// @Produce      json
+// @Param        patient_id path  string true "Patient ID" format(uuid)
+// @Param        from       query int    true "Older version"
+// @Param        to         query int    true "Newer version"
+// @Success      200  {object}  service.PatientDiff
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      401  {object}  map[string]interface{}
+// @Failure      403  {object}  map[string]interface{}
+// @Failure      404  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Router       /patients/{patient_id}/versions/diff [get]
+// DiffPatientVersions handles GET requests to compare two versions of a patient record
+func (h *PatientHistoryHandler) DiffPatientVersions(c *gin.Context) {
+	patientID, err := uuid.Parse(c.Param("patient_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid patient ID"})
+		return
+	}
+	from, err := parseVersion(c.Query("from"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from: " + err.Error()})
+		return
+	}
+	to, err := parseVersion(c.Query("to"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "to: " + err.Error()})
+		return
+	}
+
+	diff, err := h.historyService.Diff(actorFromContext(c), patientID, from, to)
+	if respondPatientHistoryError(c, err, "failed to compare patient versions") {
+		return
+	}
+	c.JSON(http.StatusOK, diff)
+}
+
+// @Summary      Restore a patient version
+// @Description  Brings a patient record back to an old version. The caller's role must be allowed to change every field the restore changes. The restore is saved as a new version, so no history is lost, and is refused with 409 if the patient changes while it runs. Requires the patient:write permission. For roles other than doctors and nurses, the medical history is kept as it is.
+// @Tags         Patient History
+// @Produce      json
+// @Param        patient_id path string true "Patient ID" format(uuid)
+// @Param        version    path int    true "Version to restore"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      401  {object}  map[string]interface{}
+// @Failure      403  {object}  map[string]interface{}
+// @Failure      404  {object}  map[string]interface{}
+// @Failure      409  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Router       /patients/{patient_id}/versions/{version}/restore [post]
+// RestorePatientVersion handles POST requests to restore an old version of a patient record
+func (h *PatientHistoryHandler) RestorePatientVersion(c *gin.Context) {
+	patientID, err := uuid.Parse(c.Param("patient_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid patient ID"})
+		return
+	}
+	version, err := parseVersion(c.Param("version"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	patient, err := h.historyService.Restore(actorFromContext(c), patientID, version)
+	if respondPatientHistoryError(c, err, "failed to restore patient version") {
+		return
+	}
+	setPatientETag(c, patient)
+	c.JSON(http.StatusOK, patient)
+}
+
+// respondPatientHistoryError writes the response for a failed history call.
+// It returns false if there was no error.
+func respondPatientHistoryError(c *gin.Context, err error, message string) bool {
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "patient not found"})
+	case errors.Is(err, service.ErrPatientVersionNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrPatientVersionMismatch):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case respondPatientForbidden(c, err):
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+	return true
+}
+
+// parseVersion parses a patient version number
+func parseVersion(value string) (int, error) {
+	version, err := strconv.Atoi(value)
+	if err != nil || version < 1 {
+		return 0, errors.New("version must be a positive integer")
+	}
+	return version, nil
+}
+
+// patientVersionResponse formats a patient version for the response body
+func patientVersionResponse(version *model.PatientVersion) gin.H {
+	return gin.H{
+		"patient_id":      version.PatientID,
+		"version":         version.Version,
+		"full_name":       version.FullName,
+		"date_of_birth":   version.DateOfBirth,
+		"address":         version.Address,
+		"contact_number":  version.ContactNumber,
+		"medical_history": version.MedicalHistory,
+		"change_type":     version.ChangeType,
+		"restored_from":   version.RestoredFrom,
+		"changed_by_id":   version.ChangedByID,
+		"created_at":      version.CreatedAt,
+	}
+}
@@ -0,0 +1,131 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/RohanDSkaria/hospital-management-system/internal/model"
+	"github.com/RohanDSkaria/hospital-management-system/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// maxMergeReasonLength is the longest reason accepted when patients are merged
+const maxMergeReasonLength = 500
+
+type PatientMergeHandler struct {
+	mergeService service.PatientMergeService
+}
+
+// NewPatientMergeHandler creates a new PatientMergeHandler
+func NewPatientMergeHandler(mergeService service.PatientMergeService) *PatientMergeHandler {
+	return &PatientMergeHandler{mergeService: mergeService}
+}
+
+type MergePatientsRequest struct {
+	SourceID uuid.UUID `json:"source_id" binding:"required"`
+	TargetID uuid.UUID `json:"target_id" binding:"required"`
+	Reason   string    `json:"reason" binding:"required"`
+}
+
+// @Summary      Merge duplicate patients
+// @Description  Merges the source patient, a duplicate record of the same person, into the target patient. The target keeps its name and date of birth, gains the source's missing address and contact number and its medical history, care team, break-glass grants, allergies, clinical notes and vital signs, and is saved as a new version. The source is deleted and its ID redirects to the target from then on. Only accessible by admins.
+// @Tags         Patient Merges
+// @Accept       json
+// @Produce      json
+// @Param        merge body MergePatientsRequest true "Patients to merge"
+// @Success      201  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      401  {object}  map[string]interface{}
+// @Failure      403  {object}  map[string]interface{}
+// @Failure      404  {object}  map[string]interface{}
+// @Failure      409  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Security     BearerAuth
+// @Router       /admin/patient-merges [post]
+// MergePatients handles POST requests to merge a duplicate patient into another
+func (h *PatientMergeHandler) MergePatients(c *gin.Context) {
+	var req MergePatientsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	req.Reason = strings.TrimSpace(req.Reason)
+	if req.Reason == "" || len(req.Reason) > maxMergeReasonLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "reason is required and must be at most 500 characters"})
+		return
+	}
+
+	merge, patient, err := h.mergeService.Merge(actorFromContext(c), req.SourceID, req.TargetID, req.Reason)
+	if errors.Is(err, service.ErrSelfMerge) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "patient not found"})
+		return
+	}
+	if errors.Is(err, service.ErrPatientVersionMismatch) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to merge patients"})
+		return
+	}
+	response := patientMergeResponse(merge)
+	response["patient"] = patient
+	c.JSON(http.StatusCreated, response)
+}
+
+// @Summary      List patient merges
+// @Description  Lists past merges of duplicate patients, newest first. Only accessible by admins.
+// @Tags         Patient Merges
+// @Produce      json
+// @Param        limit   query  int  false  "Page size (1-200, default 50)"
+// @Param        offset  query  int  false  "Number of merges to skip"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      401  {object}  map[string]interface{}
+// @Failure      403  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Security     BearerAuth
+// @Router       /admin/patient-merges [get]
+// ListPatientMerges handles GET requests for patient merges
+func (h *PatientMergeHandler) ListPatientMerges(c *gin.Context) {
+	limit, offset, err := parseLimitOffset(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	merges, total, err := h.mergeService.ListMerges(limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch patient merges"})
+		return
+	}
+	data := make([]gin.H, 0, len(merges))
+	for i := range merges {
+		data = append(data, patientMergeResponse(&merges[i]))
+	}
+	c.JSON(http.StatusOK, gin.H{"data": data, "total": total, "limit": limit, "offset": offset})
+}
+
+// patientMergeResponse formats a patient merge for the response body
+func patientMergeResponse(merge *model.PatientMerge) gin.H {
+	return gin.H{
+		"id":                         merge.ID,
+		"source_patient_id":          merge.SourcePatientID,
+		"target_patient_id":          merge.TargetPatientID,
+		"target_version":             merge.TargetVersion,
+		"merged_by_id":               merge.MergedByID,
+		"reason":                     merge.Reason,
+		"moved_care_team_members":    merge.MovedCareTeamMembers,
+		"moved_break_glass_accesses": merge.MovedBreakGlassAccesses,
+		"moved_allergies":            merge.MovedAllergies,
+		"moved_clinical_notes":       merge.MovedClinicalNotes,
+		"moved_vital_signs":          merge.MovedVitalSigns,
+		"created_at":                 merge.CreatedAt,
+	}
+}
@@ -0,0 +1,230 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/RohanDSkaria/hospital-management-system/internal/model"
+	"github.com/RohanDSkaria/hospital-management-system/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type PatientRetentionHandler struct {
+	retentionService service.PatientRetentionService
+}
+
+// NewPatientRetentionHandler creates a new PatientRetentionHandler
+func NewPatientRetentionHandler(retentionService service.PatientRetentionService) *PatientRetentionHandler {
+	return &PatientRetentionHandler{retentionService: retentionService}
+}
+
+// @Summary      List deleted patients
+// @Description  Lists deleted patients that have not been purged yet, most recently deleted first, with the date each becomes eligible for purge. Patients deleted by a merge are listed with the merges instead. Only accessible by admins.
+// @Tags         Patient Retention
+// @Produce      json
+// @Param        limit   query  int  false  "Page size (1-200, default 50)"
+// @Param        offset  query  int  false  "Number of patients to skip"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      401  {object}  map[string]interface{}
+// @Failure      403  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Security     BearerAuth
+// @Router       /admin/patients/deleted [get]
+// ListDeletedPatients handles GET requests for deleted patients
+func (h *PatientRetentionHandler) ListDeletedPatients(c *gin.Context) {
+	limit, offset, err := parseLimitOffset(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	patients, total, err := h.retentionService.ListDeleted(actorFromContext(c), limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch deleted patients"})
+		return
+	}
+	retentionDays := h.retentionService.RetentionDays()
+	data := make([]gin.H, 0, len(patients))
+	for i := range patients {
+		patient := &patients[i]
+		data = append(data, gin.H{
+			"id":              patient.ID,
+			"full_name":       patient.FullName,
+			"date_of_birth":   patient.DateOfBirth,
+			"deleted_at":      patient.DeletedAt.Time,
+			"deleted_by_id":   patient.DeletedByID,
+			"deletion_reason": patient.DeletionReason,
+			"purge_after":     patient.DeletedAt.Time.AddDate(0, 0, retentionDays),
+		})
+	}
+	c.JSON(http.StatusOK, gin.H{"data": data, "total": total, "limit": limit, "offset": offset})
+}
+
+// @Summary      Restore a deleted patient
+// @Description  Brings back a deleted patient that has not been purged yet. Patients deleted by a merge cannot be restored. Only accessible by admins.
+// @Tags         Patient Retention
+// @Produce      json
+// @Param        patient_id path string true "Patient ID" format(uuid)
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      401  {object}  map[string]interface{}
+// @Failure      403  {object}  map[string]interface{}
+// @Failure      404  {object}  map[string]interface{}
+// @Failure      409  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Security     BearerAuth
+// @Router       /admin/patients/{patient_id}/restore [post]
+// RestoreDeletedPatient handles POST requests to undo the deletion of a patient
+func (h *PatientRetentionHandler) RestoreDeletedPatient(c *gin.Context) {
+	patientID, err := uuid.Parse(c.Param("patient_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid patient ID"})
+		return
+	}
+	patient, err := h.retentionService.Undelete(actorFromContext(c), patientID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "deleted patient not found"})
+		return
+	}
+	if errors.Is(err, service.ErrPatientMerged) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to restore patient"})
+		return
+	}
+	c.JSON(http.StatusOK, patient)
+}
+
+// @Summary      Purge deleted patients
+// @Description  Permanently removes every patient deleted longer ago than the retention period, except patients deleted by a merge, with their versions, care team and break-glass records, and returns the purge report. With dry_run nothing is removed and the report is not saved. Only accessible by admins.
+// @Tags         Patient Retention
+// @Produce      json
+// @Param        dry_run query bool false "Only report what would be purged"
+// @Success      200  {object}  map[string]interface{}
+// @Success      201  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      401  {object}  map[string]interface{}
+// @Failure      403  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Security     BearerAuth
+// @Router       /admin/patient-purges [post]
+// PurgePatients handles POST requests to run the retention purge
+func (h *PatientRetentionHandler) PurgePatients(c *gin.Context) {
+	dryRun := false
+	if value := c.Query("dry_run"); value != "" {
+		var err error
+		dryRun, err = strconv.ParseBool(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid dry_run flag"})
+			return
+		}
+	}
+	purge, err := h.retentionService.Purge(actorFromContext(c), dryRun)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to purge patients"})
+		return
+	}
+	if dryRun {
+		c.JSON(http.StatusOK, patientPurgeDetailResponse(purge))
+		return
+	}
+	c.JSON(http.StatusCreated, patientPurgeDetailResponse(purge))
+}
+
+// @Summary      List purge reports
+// @Description  Lists the reports of past retention purges, newest first. Only accessible by admins.
+// @Tags         Patient Retention
+// @Produce      json
+// @Param        limit   query  int  false  "Page size (1-200, default 50)"
+// @Param        offset  query  int  false  "Number of reports to skip"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      401  {object}  map[string]interface{}
+// @Failure      403  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Security     BearerAuth
+// @Router       /admin/patient-purges [get]
+// ListPatientPurges handles GET requests for purge reports
+func (h *PatientRetentionHandler) ListPatientPurges(c *gin.Context) {
+	limit, offset, err := parseLimitOffset(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	purges, total, err := h.retentionService.ListPurges(limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch purge reports"})
+		return
+	}
+	data := make([]gin.H, 0, len(purges))
+	for i := range purges {
+		data = append(data, patientPurgeResponse(&purges[i]))
+	}
+	c.JSON(http.StatusOK, gin.H{"data": data, "total": total, "limit": limit, "offset": offset})
+}
+
+// @Summary      Get a purge report
+// @Description  Returns a purge report with the IDs of the patients it removed and when and why each was deleted. Only accessible by admins.
+// @Tags         Patient Retention
+// @Produce      json
+// @Param        purge_id path string true "Purge ID" format(uuid)
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      401  {object}  map[string]interface{}
+// @Failure      403  {object}  map[string]interface{}
+// @Failure      404  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Security     BearerAuth
+// @Router       /admin/patient-purges/{purge_id} [get]
+// GetPatientPurge handles GET requests for a single purge report
+func (h *PatientRetentionHandler) GetPatientPurge(c *gin.Context) {
+	purgeID, err := uuid.Parse(c.Param("purge_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid purge ID"})
+		return
+	}
+	purge, err := h.retentionService.GetPurge(purgeID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "purge report not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch purge report"})
+		return
+	}
+	c.JSON(http.StatusOK, patientPurgeDetailResponse(purge))
+}
+
+// patientPurgeResponse formats a purge report for the response body
+func patientPurgeResponse(purge *model.PatientPurge) gin.H {
+	return gin.H{
+		"id":              purge.ID,
+		"cutoff":          purge.Cutoff,
+		"retention_days":  purge.RetentionDays,
+		"triggered_by_id": purge.TriggeredByID,
+		"purged_count":    purge.PurgedCount,
+		"created_at":      purge.CreatedAt,
+	}
+}
+
+// patientPurgeDetailResponse formats a purge report with the patients it removed
+func patientPurgeDetailResponse(purge *model.PatientPurge) gin.H {
+	response := patientPurgeResponse(purge)
+	patients := make([]gin.H, 0, len(purge.Patients))
+	for _, patient := range purge.Patients {
+		patients = append(patients, gin.H{
+			"patient_id":      patient.PatientID,
+			"registered_at":   patient.RegisteredAt,
+			"deleted_at":      patient.DeletedAt,
+			"deleted_by_id":   patient.DeletedByID,
+			"deletion_reason": patient.DeletionReason,
+		})
+	}
+	response["patients"] = patients
+	return response
+}
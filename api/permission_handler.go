@@ -0,0 +1,82 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/RohanDSkaria/hospital-management-system/internal/model"
+	"github.com/RohanDSkaria/hospital-management-system/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+type PermissionHandler struct {
+	permissionService service.PermissionService
+}
+
+// NewPermissionHandler creates a new PermissionHandler
+func NewPermissionHandler(permissionService service.PermissionService) *PermissionHandler {
+	return &PermissionHandler{permissionService: permissionService}
+}
+
+// @Summary      List role permissions
+// @Description  Lists every known permission and the permissions granted to each role. Only accessible by admins.
+// @Tags         Permissions
+// @Produce      json
+// @Success      200  {object}  map[string]interface{}
+// @Failure      401  {object}  map[string]interface{}
+// @Failure      403  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Security     BearerAuth
+// @Router       /admin/permissions [get]
+// ListPermissions handles GET requests to list the role permissions
+func (h *PermissionHandler) ListPermissions(c *gin.Context) {
+	roles, err := h.permissionService.RolePermissions()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch permissions"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"permissions": model.AllPermissions,
+		"roles":       roles,
+	})
+}
+
+// SetRolePermissionsRequest defines the structure for the set role permissions request body
+type SetRolePermissionsRequest struct {
+	Permissions []model.Permission `json:"permissions" binding:"required"`
+}
+
+// @Summary      Set the permissions of a role
+// @Description  Replaces the permissions granted to a role. Users with the role, and API keys they own, are affected immediately. Only accessible by admins.
+// @Tags         Permissions
+// @Accept       json
+// @Produce      json
+// @Param        role path string true "Role" Enums(receptionist, doctor, nurse, admin)
+// @Param        permissions body SetRolePermissionsRequest true "Permissions"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      401  {object}  map[string]interface{}
+// @Failure      403  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Security     BearerAuth
+// @Router       /admin/roles/{role}/permissions [put]
+// SetRolePermissions handles PUT requests to replace the permissions of a role
+func (h *PermissionHandler) SetRolePermissions(c *gin.Context) {
+	var req SetRolePermissionsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	role := model.Role(c.Param("role"))
+	permissions, err := h.permissionService.SetRolePermissions(role, req.Permissions)
+	if errors.Is(err, service.ErrInvalidRole) || errors.Is(err, service.ErrInvalidPermission) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update permissions"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"role": role, "permissions": permissions})
+}
@@ -0,0 +1,387 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/RohanDSkaria/hospital-management-system/internal/model"
+	"github.com/RohanDSkaria/hospital-management-system/internal/repository"
+	"github.com/RohanDSkaria/hospital-management-system/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type UserHandler struct {
+	userService service.UserService
+}
+
+// NewUserHandler creates a new UserHandler
+func NewUserHandler(userService service.UserService) *UserHandler {
+	return &UserHandler{userService: userService}
+}
+
+// CreateUserRequest defines the structure for the admin create user request body
+type CreateUserRequest struct {
+	FullName string     `json:"full_name" binding:"required"`
+	Email    string     `json:"email" binding:"required,email"`
+	Password string     `json:"password" binding:"required"`
+	Role     model.Role `json:"role" binding:"required"`
+}
+
+// @Summary      Create a staff account
+// @Description  Creates a new user account with the given role. The password must follow the password policy. Only accessible by admins.
+// @Tags         Users
+// @Accept       json
+// @Produce      json
+// @Param        user body CreateUserRequest true "User Information"
+// @Success      201  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      401  {object}  map[string]interface{}
+// @Failure      403  {object}  map[string]interface{}
+// @Failure      409  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Security     BearerAuth
+// @Router       /admin/users [post]
+// CreateUser handles POST requests to create a staff account
+func (h *UserHandler) CreateUser(c *gin.Context) {
+	var req CreateUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := h.userService.CreateUser(req.FullName, req.Email, req.Password, req.Role)
+	if err != nil {
+		if respondPasswordRejected(c, err) {
+			return
+		}
+		if errors.Is(err, service.ErrInvalidRole) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, service.ErrUserExists) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create user"})
+		return
+	}
+	c.JSON(http.StatusCreated, userResponse(user))
+}
+
+// InviteRequest defines the structure for the invitation request body
+type InviteRequest struct {
+	Email string     `json:"email" binding:"required,email"`
+	Role  model.Role `json:"role" binding:"required"`
+}
+
+// @Summary      Invite a staff member
+// @Description  Creates a single-use invite token that lets the recipient register with the given email and role. Only accessible by admins.
+// @Tags         Users
+// @Accept       json
+// @Produce      json
+// @Param        invitation body InviteRequest true "Invitation Information"
+// @Success      201  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      401  {object}  map[string]interface{}
+// @Failure      403  {object}  map[string]interface{}
+// @Failure      409  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Security     BearerAuth
+// @Router       /admin/invitations [post]
+// InviteUser handles POST requests to invite a staff member
+func (h *UserHandler) InviteUser(c *gin.Context) {
+	var req InviteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userIDStr, _ := c.Get("userID")
+	userID, _ := uuid.Parse(userIDStr.(string))
+
+	invitation, token, err := h.userService.InviteUser(req.Email, req.Role, userID)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidRole) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, service.ErrUserExists) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create invitation"})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{
+		"id":           invitation.ID,
+		"email":        invitation.Email,
+		"role":         invitation.Role,
+		"expires_at":   invitation.ExpiresAt,
+		"invite_token": token,
+	})
+}
+
+// ChangeRoleRequest defines the structure for the change role request body
+type ChangeRoleRequest struct {
+	Role model.Role `json:"role" binding:"required"`
+}
+
+// @Summary      Assign a role
+// @Description  Changes the role of a user and logs them out of all devices. Only accessible by admins.
+// @Tags         Users
+// @Accept       json
+// @Produce      json
+// @Param        user_id path string true "User ID" format(uuid)
+// @Param        role body ChangeRoleRequest true "New Role"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      401  {object}  map[string]interface{}
+// @Failure      403  {object}  map[string]interface{}
+// @Failure      404  {object}  map[string]interface{}
+// @Failure      409  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Security     BearerAuth
+// @Router       /admin/users/{user_id}/role [put]
+// ChangeRole handles PUT requests to change a user's role
+func (h *UserHandler) ChangeRole(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user ID"})
+		return
+	}
+	var req ChangeRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := h.userService.ChangeRole(userID, req.Role)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		return
+	}
+	if errors.Is(err, service.ErrInvalidRole) || errors.Is(err, service.ErrInvalidServiceAccountRole) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, service.ErrLastAdmin) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to change role"})
+		return
+	}
+	c.JSON(http.StatusOK, userResponse(user))
+}
+
+// @Summary      List users
+// @Description  Lists and searches staff accounts. Only accessible by admins.
+// @Tags         Users
+// @Produce      json
+// @Param        q       query  string  false  "Search by name or email"
+// @Param        role    query  string  false  "Filter by role"
+// @Param        active  query  bool    false  "Filter by active status"
+// @Param        service_account  query  bool  false  "Only service accounts (true) or only people (false)"
+// @Param        limit   query  int     false  "Page size (default 50, max 200)"
+// @Param        offset  query  int     false  "Number of users to skip"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      401  {object}  map[string]interface{}
+// @Failure      403  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Security     BearerAuth
+// @Router       /admin/users [get]
+// ListUsers handles GET requests to list users
+func (h *UserHandler) ListUsers(c *gin.Context) {
+	limit, offset, err := parseLimitOffset(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	filter := repository.UserFilter{
+		Query:  c.Query("q"),
+		Role:   model.Role(c.Query("role")),
+		Limit:  limit,
+		Offset: offset,
+	}
+	if active := c.Query("active"); active != "" {
+		value, err := strconv.ParseBool(active)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid active filter"})
+			return
+		}
+		filter.Active = &value
+	}
+	if serviceAccount := c.Query("service_account"); serviceAccount != "" {
+		value, err := strconv.ParseBool(serviceAccount)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid service_account filter"})
+			return
+		}
+		filter.ServiceAccount = &value
+	}
+
+	users, total, err := h.userService.ListUsers(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch users"})
+		return
+	}
+	data := make([]gin.H, 0, len(users))
+	for i := range users {
+		data = append(data, userResponse(&users[i]))
+	}
+	c.JSON(http.StatusOK, gin.H{"data": data, "total": total, "limit": limit, "offset": offset})
+}
+
+// @Summary      Get user by ID
+// @Description  Retrieves a single staff account. Only accessible by admins.
+// @Tags         Users
+// @Produce      json
+// @Param        user_id path string true "User ID" format(uuid)
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      401  {object}  map[string]interface{}
+// @Failure      403  {object}  map[string]interface{}
+// @Failure      404  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Security     BearerAuth
+// @Router       /admin/users/{user_id} [get]
+// GetUser handles GET requests for a single user
+func (h *UserHandler) GetUser(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user ID"})
+		return
+	}
+	user, err := h.userService.GetUser(userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch user"})
+		return
+	}
+	c.JSON(http.StatusOK, userResponse(user))
+}
+
+// @Summary      Deactivate user
+// @Description  Deactivates an account. The user is logged out everywhere immediately and can no longer log in. Only accessible by admins.
+// @Tags         Users
+// @Produce      json
+// @Param        user_id path string true "User ID" format(uuid)
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      401  {object}  map[string]interface{}
+// @Failure      403  {object}  map[string]interface{}
+// @Failure      404  {object}  map[string]interface{}
+// @Failure      409  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Security     BearerAuth
+// @Router       /admin/users/{user_id}/deactivate [post]
+// DeactivateUser handles POST requests to deactivate a user
+func (h *UserHandler) DeactivateUser(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user ID"})
+		return
+	}
+	actorIDStr, _ := c.Get("userID")
+	actorID, _ := uuid.Parse(actorIDStr.(string))
+
+	user, err := h.userService.DeactivateUser(userID, actorID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		return
+	}
+	if errors.Is(err, service.ErrDeactivateSelf) || errors.Is(err, service.ErrLastAdmin) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to deactivate user"})
+		return
+	}
+	c.JSON(http.StatusOK, userResponse(user))
+}
+
+// @Summary      Reactivate user
+// @Description  Allows a deactivated account to log in again. Only accessible by admins.
+// @Tags         Users
+// @Produce      json
+// @Param        user_id path string true "User ID" format(uuid)
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      401  {object}  map[string]interface{}
+// @Failure      403  {object}  map[string]interface{}
+// @Failure      404  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Security     BearerAuth
+// @Router       /admin/users/{user_id}/reactivate [post]
+// ReactivateUser handles POST requests to reactivate a user
+func (h *UserHandler) ReactivateUser(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user ID"})
+		return
+	}
+	user, err := h.userService.ReactivateUser(userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to reactivate user"})
+		return
+	}
+	c.JSON(http.StatusOK, userResponse(user))
+}
+
+// userResponse formats a user for the response body without sensitive fields
+func userResponse(user *model.User) gin.H {
+	return gin.H{
+		"id":              user.ID,
+		"full_name":       user.FullName,
+		"email":           user.Email,
+		"role":            user.Role,
+		"active":          user.Active,
+		"mfa_enabled":     user.MFAEnabled,
+		"service_account": user.ServiceAccount,
+		"deactivated_at":  user.DeactivatedAt,
+		"created_at":      user.CreatedAt,
+	}
+}
+
+// parseLimitOffset reads the limit and offset query parameters
+func parseLimitOffset(c *gin.Context) (int, int, error) {
+	limit, err := parseLimit(c)
+	if err != nil {
+		return 0, 0, err
+	}
+	offset := 0
+	if value := c.Query("offset"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return 0, 0, errors.New("offset must be a non-negative integer")
+		}
+		offset = n
+	}
+	return limit, offset, nil
+}
+
+// parseLimit reads the page size query parameter
+func parseLimit(c *gin.Context) (int, error) {
+	limit := 50
+	if value := c.Query("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > 200 {
+			return 0, errors.New("limit must be between 1 and 200")
+		}
+		limit = n
+	}
+	return limit, nil
+}
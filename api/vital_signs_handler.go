@@ -0,0 +1,314 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/RohanDSkaria/hospital-management-system/internal/service"
+	"github.com/RohanDSkaria/hospital-management-system/internal/vitals"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type VitalSignsHandler struct {
+	vitalsService service.VitalSignsService
+}
+
+// NewVitalSignsHandler creates a new VitalSignsHandler
+func NewVitalSignsHandler(vitalsService service.VitalSignsService) *VitalSignsHandler {
+	return &VitalSignsHandler{vitalsService: vitalsService}
+}
+
+// VitalsRequest defines the structure of one set of vital signs in a record vital signs request body
+type VitalsRequest struct {
+	TakenAt         *time.Time `json:"taken_at" example:"2024-05-01T08:00:00Z"`
+	SystolicBP      *float64   `json:"systolic_bp" example:"128"`
+	DiastolicBP     *float64   `json:"diastolic_bp" example:"82"`
+	HeartRate       *float64   `json:"heart_rate" example:"72"`
+	RespiratoryRate *float64   `json:"respiratory_rate" example:"16"`
+	Temperature     *float64   `json:"temperature" example:"37.1"`
+	SpO2            *float64   `json:"spo2" example:"97"`
+	Weight          *float64   `json:"weight" example:"70.5"`
+	Height          *float64   `json:"height" example:"175"`
+	Note            string     `json:"note" example:"Taken sitting, left arm"`
+}
+
+// input converts one set of vital signs for the service
+func (req VitalsRequest) input(patientID uuid.UUID) service.VitalsInput {
+	input := service.VitalsInput{
+		PatientID:       patientID,
+		SystolicBP:      req.SystolicBP,
+		DiastolicBP:     req.DiastolicBP,
+		HeartRate:       req.HeartRate,
+		RespiratoryRate: req.RespiratoryRate,
+		Temperature:     req.Temperature,
+		SpO2:            req.SpO2,
+		Weight:          req.Weight,
+		Height:          req.Height,
+		Note:            req.Note,
+	}
+	if req.TakenAt != nil {
+		input.TakenAt = *req.TakenAt
+	}
+	return input
+}
+
+// RecordVitalsRequest defines the structure for the record vital signs request body
+type RecordVitalsRequest struct {
+	Observations []VitalsRequest `json:"observations" binding:"required,min=1"`
+}
+
+// BatchVitalsRequest defines one set of vital signs in a batch request body
+type BatchVitalsRequest struct {
+	PatientID uuid.UUID `json:"patient_id" binding:"required" example:"7b1e2f8a-3c4d-4e5f-8a9b-0c1d2e3f4a5b"`
+	VitalsRequest
+}
+
+// RecordVitalsBatchRequest defines the structure for the record vital signs batch request body
+type RecordVitalsBatchRequest struct {
+	Observations []BatchVitalsRequest `json:"observations" binding:"required,min=1,dive"`
+}
+
+// @Summary      Record a patient's vital signs
+// @Description  Records one or more sets of vital signs of a patient, all or none of them. Each set needs at least one measurement: blood pressure in mmHg, heart and respiratory rate per minute, temperature in °C, SpO2 in %, weight in kg and height in cm. Implausible values, such as a temperature in Fahrenheit, are refused. taken_at defaults to now and cannot be in the future. The BMI is computed from the weight and height; adults weighed without being measured get it from their latest height. Values outside the reference ranges for the patient's age are flagged low or high. Only doctors and nurses on the patient's care team can record vital signs. Requires the patient:write permission.
+// @Tags         Vital Signs
+// @Accept       json
+// @Produce      json
+// @Param        patient_id path string true "Patient ID" format(uuid)
+// @Param        vitals body RecordVitalsRequest true "Vital Signs"
+// @Success      201  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      401  {object}  map[string]interface{}
+// @Failure      403  {object}  map[string]interface{}
+// @Failure      404  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Router       /patients/{patient_id}/vitals [post]
+// RecordVitals handles POST requests to record a patient's vital signs
+func (h *VitalSignsHandler) RecordVitals(c *gin.Context) {
+	patientID, err := uuid.Parse(c.Param("patient_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid patient ID"})
+		return
+	}
+	var req RecordVitalsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	inputs := make([]service.VitalsInput, 0, len(req.Observations))
+	for _, observation := range req.Observations {
+		inputs = append(inputs, observation.input(patientID))
+	}
+
+	observations, err := h.vitalsService.RecordVitals(actorFromContext(c), inputs)
+	if respondVitalsError(c, err, "failed to record vital signs") {
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"data": vitalsResponses(observations)})
+}
+
+// @Summary      Record vital signs of several patients
+// @Description  Records sets of vital signs of several patients at once, for example during a ward round, all or none of them. Each set names its patient and follows the rules of recording a single patient's vital signs. The whole batch is refused if any patient is not found or not on the caller's care team. Requires the patient:write permission.
+// @Tags         Vital Signs
+// @Accept       json
+// @Produce      json
+// @Param        vitals body RecordVitalsBatchRequest true "Vital Signs"
+// @Success      201  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      401  {object}  map[string]interface{}
+// @Failure      403  {object}  map[string]interface{}
+// @Failure      404  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Router       /vitals/batch [post]
+// RecordVitalsBatch handles POST requests to record the vital signs of several patients
+func (h *VitalSignsHandler) RecordVitalsBatch(c *gin.Context) {
+	var req RecordVitalsBatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	inputs := make([]service.VitalsInput, 0, len(req.Observations))
+	for _, observation := range req.Observations {
+		inputs = append(inputs, observation.input(observation.PatientID))
+	}
+
+	observations, err := h.vitalsService.RecordVitals(actorFromContext(c), inputs)
+	if respondVitalsError(c, err, "failed to record vital signs") {
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"data": vitalsResponses(observations)})
+}
+
+// @Summary      List a patient's vital signs
+// @Description  Lists a patient's vital signs, most recently taken first, with the values outside the reference ranges for the patient's age flagged low or high. from and to limit the times taken. Only doctors and nurses on the patient's care team can see vital signs. Requires the patient:read permission.
+// @Tags         Vital Signs
+// @Produce      json
+// @Param        patient_id path string true "Patient ID" format(uuid)
+// @Param        from query string false "Taken at or after this RFC 3339 time"
+// @Param        to query string false "Taken before this RFC 3339 time"
+// @Param        limit query int false "Page size (1-200, default 50)"
+// @Param        offset query int false "Number of observations to skip"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      401  {object}  map[string]interface{}
+// @Failure      403  {object}  map[string]interface{}
+// @Failure      404  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Router       /patients/{patient_id}/vitals [get]
+// ListVitals handles GET requests to list a patient's vital signs
+func (h *VitalSignsHandler) ListVitals(c *gin.Context) {
+	patientID, err := uuid.Parse(c.Param("patient_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid patient ID"})
+		return
+	}
+	limit, offset, err := parseLimitOffset(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	from, to, ok := parseTimeRange(c)
+	if !ok {
+		return
+	}
+
+	query := service.VitalsQuery{From: from, To: to, Limit: limit, Offset: offset}
+	observations, total, err := h.vitalsService.ListVitals(actorFromContext(c), patientID, query)
+	if respondVitalsError(c, err, "failed to fetch vital signs") {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": vitalsResponses(observations), "total": total, "limit": limit, "offset": offset})
+}
+
+// @Summary      Trend of a vital sign
+// @Description  Returns the values of one vital sign of a patient, oldest first, each flagged against the reference range for the patient's age when it was taken, with the unit and the reference range for their current age. from and to limit the times taken. At most 1000 values are returned; truncated is true when older values were left out. Only doctors and nurses on the patient's care team can see vital signs. Requires the patient:read permission.
+// @Tags         Vital Signs
+// @Produce      json
+// @Param        patient_id path string true "Patient ID" format(uuid)
+// @Param        vital query string true "Vital sign" Enums(systolic_bp, diastolic_bp, heart_rate, respiratory_rate, temperature, spo2, weight, height, bmi)
+// @Param        from query string false "Taken at or after this RFC 3339 time"
+// @Param        to query string false "Taken before this RFC 3339 time"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      401  {object}  map[string]interface{}
+// @Failure      403  {object}  map[string]interface{}
+// @Failure      404  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Router       /patients/{patient_id}/vitals/trend [get]
+// GetTrend handles GET requests for the trend of one of a patient's vital signs
+func (h *VitalSignsHandler) GetTrend(c *gin.Context) {
+	patientID, err := uuid.Parse(c.Param("patient_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid patient ID"})
+		return
+	}
+	from, to, ok := parseTimeRange(c)
+	if !ok {
+		return
+	}
+
+	query := service.TrendQuery{Vital: c.Query("vital"), From: from, To: to}
+	trend, err := h.vitalsService.Trend(actorFromContext(c), patientID, query)
+	if respondVitalsError(c, err, "failed to fetch trend") {
+		return
+	}
+	points := make([]gin.H, 0, len(trend.Points))
+	for _, point := range trend.Points {
+		points = append(points, gin.H{"taken_at": point.TakenAt, "value": point.Value, "flag": point.Flag})
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"vital":     trend.Vital,
+		"unit":      trend.Unit,
+		"range":     trend.Range,
+		"points":    points,
+		"truncated": trend.Truncated,
+	})
+}
+
+// parseTimeRange reads the optional from and to query parameters as RFC
+// 3339 times. It writes a 400 response if either is malformed and reports
+// whether the caller may go on.
+func parseTimeRange(c *gin.Context) (*time.Time, *time.Time, bool) {
+	var from, to *time.Time
+	if value := c.Query("from"); value != "" {
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "from must be an RFC 3339 time"})
+			return nil, nil, false
+		}
+		from = &t
+	}
+	if value := c.Query("to"); value != "" {
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "to must be an RFC 3339 time"})
+			return nil, nil, false
+		}
+		to = &t
+	}
+	return from, to, true
+}
+
+// respondVitalsError writes the error response for a failed vital signs
+// call, with message for unexpected errors, and reports whether there was an
+// error
+func respondVitalsError(c *gin.Context, err error, message string) bool {
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "patient not found"})
+	case errors.Is(err, service.ErrInvalidVitals):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case respondPatientForbidden(c, err):
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+	return true
+}
+
+// vitalsResponses formats sets of vital signs for the response body
+func vitalsResponses(observations []service.VitalsObservation) []gin.H {
+	data := make([]gin.H, 0, len(observations))
+	for i := range observations {
+		data = append(data, vitalsResponse(&observations[i]))
+	}
+	return data
+}
+
+// vitalsResponse formats a set of vital signs and its flags for the response
+// body. Flags only lists the values outside their reference range.
+func vitalsResponse(observation *service.VitalsObservation) gin.H {
+	flags := observation.Flags
+	if flags == nil {
+		flags = map[string]vitals.Flag{}
+	}
+	return gin.H{
+		"id":               observation.ID,
+		"patient_id":       observation.PatientID,
+		"recorded_by_id":   observation.RecordedByID,
+		"taken_at":         observation.TakenAt,
+		"systolic_bp":      observation.SystolicBP,
+		"diastolic_bp":     observation.DiastolicBP,
+		"heart_rate":       observation.HeartRate,
+		"respiratory_rate": observation.RespiratoryRate,
+		"temperature":      observation.Temperature,
+		"spo2":             observation.SpO2,
+		"weight":           observation.Weight,
+		"height":           observation.Height,
+		"bmi":              observation.BMI,
+		"note":             observation.Note,
+		"flags":            flags,
+		"created_at":       observation.CreatedAt,
+	}
+}
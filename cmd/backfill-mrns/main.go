@@ -0,0 +1,64 @@
+// Command backfill-mrns gives every patient registered before medical record
+// numbers existed an MRN, in the configured format (MRN_PREFIX, MRN_DIGITS,
+// MRN_CHECK_DIGIT), oldest patient first.
+//
+// Usage:
+//
+//	go run ./cmd/backfill-mrns [-batch-size 500] [-dry-run]
+//
+// Patients are numbered in batches, each in its own transaction, from the
+// same sequence as new patients, so the server can keep running meanwhile
+// and an interrupted run can simply be started again.
+package main
+
+import (
+	"flag"
+	"log"
+
+	"github.com/RohanDSkaria/hospital-management-system/internal/config"
+	"github.com/RohanDSkaria/hospital-management-system/internal/database"
+	"github.com/RohanDSkaria/hospital-management-system/internal/repository"
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	batchSize := flag.Int("batch-size", 500, "patients numbered per transaction")
+	dryRun := flag.Bool("dry-run", false, "only count the patients without an MRN")
+	flag.Parse()
+	if *batchSize < 1 {
+		log.Fatal("-batch-size must be positive")
+	}
+
+	if err := godotenv.Load(); err != nil {
+		log.Println("No .env file found")
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Invalid configuration:\n%v", err)
+	}
+	database.Connect(cfg.DatabaseDSN)
+	patientRepo := repository.NewPatientRepository(database.DB, cfg.MRNFormat())
+
+	missing, err := patientRepo.CountMissingMRNs()
+	if err != nil {
+		log.Fatalf("Failed to count patients without an MRN: %v", err)
+	}
+	if *dryRun {
+		log.Printf("Dry run: %d patients have no MRN", missing)
+		return
+	}
+
+	total := 0
+	for {
+		assigned, err := patientRepo.AssignMissingMRNs(*batchSize)
+		if err != nil {
+			log.Fatalf("Failed to assign MRNs after numbering %d patients: %v", total, err)
+		}
+		if assigned == 0 {
+			break
+		}
+		total += assigned
+		log.Printf("Numbered %d of %d patients", total, missing)
+	}
+	log.Printf("Done: %d patients were given an MRN", total)
+}
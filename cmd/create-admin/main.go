@@ -0,0 +1,80 @@
+// Command create-admin bootstraps the first admin account.
+//
+// Usage:
+//
+//	go run ./cmd/create-admin -name "Jane Doe" -email admin@example.com
+//
+// The password is read from the ADMIN_PASSWORD environment variable, or from
+// standard input if it is not set. The command refuses to run once an admin
+// exists; further admins are created through the admin API.
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/RohanDSkaria/hospital-management-system/internal/config"
+	"github.com/RohanDSkaria/hospital-management-system/internal/database"
+	"github.com/RohanDSkaria/hospital-management-system/internal/model"
+	"github.com/RohanDSkaria/hospital-management-system/internal/repository"
+	"github.com/RohanDSkaria/hospital-management-system/internal/service"
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	name := flag.String("name", "", "full name of the admin")
+	email := flag.String("email", "", "email address of the admin")
+	flag.Parse()
+
+	if *name == "" || *email == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err := godotenv.Load(); err != nil {
+		log.Println("No .env file found")
+	}
+	// The same configuration as the server, so the password policy and hash
+	// settings match
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Invalid configuration:\n%v", err)
+	}
+	hasher, err := cfg.NewPasswordHasher()
+	if err != nil {
+		log.Fatalf("Failed to create password hasher: %v", err)
+	}
+	database.Connect(cfg.DatabaseDSN)
+	db := database.DB
+
+	userRepo := repository.NewUserRepository(db)
+	admins, err := userRepo.CountByRole(model.Admin)
+	if err != nil {
+		log.Fatalf("Failed to count admins: %v", err)
+	}
+	if admins > 0 {
+		log.Fatal("An admin already exists, use the admin API to create more")
+	}
+
+	password := os.Getenv("ADMIN_PASSWORD")
+	if password == "" {
+		fmt.Print("Password: ")
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil {
+			log.Fatalf("Failed to read password: %v", err)
+		}
+		password = strings.TrimRight(line, "\r\n")
+	}
+
+	passwords := service.NewPasswords(hasher, cfg.PasswordPolicy(), repository.NewPasswordHistoryRepository(db))
+	userService := service.NewUserService(userRepo, repository.NewInvitationRepository(db), repository.NewSessionRepository(db), passwords)
+	user, err := userService.CreateUser(*name, *email, password, model.Admin)
+	if err != nil {
+		log.Fatalf("Failed to create admin: %v", err)
+	}
+	log.Printf("Created admin %s (%s)", user.Email, user.ID)
+}
@@ -0,0 +1,59 @@
+// Command purge-patients permanently removes patients that were deleted
+// longer ago than the retention period (PATIENT_RETENTION_DAYS).
+//
+// Usage:
+//
+//	go run ./cmd/purge-patients [-dry-run]
+//
+// It is meant to run once a day from cron or a scheduled job. Each run saves
+// a purge report that admins can read through the admin API; -dry-run only
+// prints what would be purged.
+package main
+
+import (
+	"flag"
+	"log"
+
+	"github.com/RohanDSkaria/hospital-management-system/internal/config"
+	"github.com/RohanDSkaria/hospital-management-system/internal/database"
+	"github.com/RohanDSkaria/hospital-management-system/internal/repository"
+	"github.com/RohanDSkaria/hospital-management-system/internal/service"
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	dryRun := flag.Bool("dry-run", false, "only report what would be purged")
+	flag.Parse()
+
+	if err := godotenv.Load(); err != nil {
+		log.Println("No .env file found")
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Invalid configuration:\n%v", err)
+	}
+	database.Connect(cfg.DatabaseDSN)
+	db := database.DB
+
+	retentionService := service.NewPatientRetentionService(
+		repository.NewPatientRepository(db, cfg.MRNFormat()),
+		repository.NewPatientPurgeRepository(db),
+		repository.NewPatientMergeRepository(db),
+		repository.NewTransactor(db, cfg.MRNFormat()),
+		service.NewAuditService(repository.NewAuditRepository(db)),
+		cfg.PatientRetentionDays,
+	)
+	purge, err := retentionService.Purge(service.SystemActor, *dryRun)
+	if err != nil {
+		log.Fatalf("Failed to purge patients: %v", err)
+	}
+
+	for _, patient := range purge.Patients {
+		log.Printf("Patient %s deleted %s", patient.PatientID, patient.DeletedAt.Format("2006-01-02"))
+	}
+	if *dryRun {
+		log.Printf("Dry run: %d patients deleted before %s would be purged", purge.PurgedCount, purge.Cutoff.Format("2006-01-02"))
+		return
+	}
+	log.Printf("Purged %d patients deleted before %s (report %s)", purge.PurgedCount, purge.Cutoff.Format("2006-01-02"), purge.ID)
+}
@@ -3,10 +3,14 @@ package main
 import (
 	"log"
 	"net/http"
+	"time"
 
 	"github.com/RohanDSkaria/hospital-management-system/api"
 	_ "github.com/RohanDSkaria/hospital-management-system/docs"
+	"github.com/RohanDSkaria/hospital-management-system/internal/auth"
+	"github.com/RohanDSkaria/hospital-management-system/internal/config"
 	"github.com/RohanDSkaria/hospital-management-system/internal/database"
+	"github.com/RohanDSkaria/hospital-management-system/internal/mailer"
 	"github.com/RohanDSkaria/hospital-management-system/internal/model"
 	"github.com/RohanDSkaria/hospital-management-system/internal/repository"
 	"github.com/RohanDSkaria/hospital-management-system/internal/service"
@@ -35,28 +39,130 @@ import (
 // @in header
 // @name Authorization
 
+// @securityDefinitions.apikey ApiKeyAuth
+// @in header
+// @name Authorization
+// @description Send "ApiKey <key>" to authenticate with an API key
+
 func main() {
 
 	if err := godotenv.Load(); err != nil {
 		log.Println("No .env file found")
 	}
-	database.Connect()
+
+	// --- Configuration ---
+	// Refuse to start with a missing or weak configuration rather than
+	// running with authentication silently broken.
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Invalid configuration:\n%v", err)
+	}
+
+	// --- Signing keys ---
+	keyManager, err := auth.LoadKeyManager(cfg.KeyOptions())
+	if err != nil {
+		log.Fatalf("Failed to load signing keys: %v", err)
+	}
+
+	// --- TOTP secret encryption ---
+	totpBox, err := auth.NewSecretBox(cfg.MFAKey(), "totp-secret")
+	if err != nil {
+		log.Fatalf("Failed to create TOTP secret encryption: %v", err)
+	}
+
+	// --- Password hashing ---
+	hasher, err := cfg.NewPasswordHasher()
+	if err != nil {
+		log.Fatalf("Failed to create password hasher: %v", err)
+	}
+
+	// --- Mailer ---
+	mail, err := mailer.New(cfg.Mailer, cfg.MailFrom, cfg.MailerDir)
+	if err != nil {
+		log.Fatalf("Failed to create mailer: %v", err)
+	}
+
+	database.Connect(cfg.DatabaseDSN)
 	db := database.DB
 
 	// --- Repositories ---
 	userRepo := repository.NewUserRepository(db)
-	patientRepo := repository.NewPatientRepository(db)
+	patientRepo := repository.NewPatientRepository(db, cfg.MRNFormat())
+	sessionRepo := repository.NewSessionRepository(db)
+	invitationRepo := repository.NewInvitationRepository(db)
+	passwordResetRepo := repository.NewPasswordResetRepository(db)
+	loginAttemptRepo := repository.NewLoginAttemptRepository(db)
+	mfaRepo := repository.NewMFARepository(db)
+	passwordHistoryRepo := repository.NewPasswordHistoryRepository(db)
+	apiKeyRepo := repository.NewAPIKeyRepository(db)
+	rolePermissionRepo := repository.NewRolePermissionRepository(db)
+	careTeamRepo := repository.NewCareTeamRepository(db)
+	breakGlassRepo := repository.NewBreakGlassRepository(db)
+	auditRepo := repository.NewAuditRepository(db)
+	patientVersionRepo := repository.NewPatientVersionRepository(db)
+	patientPurgeRepo := repository.NewPatientPurgeRepository(db)
+	patientMergeRepo := repository.NewPatientMergeRepository(db)
+	allergyRepo := repository.NewAllergyRepository(db)
+	clinicalNoteRepo := repository.NewClinicalNoteRepository(db)
+	vitalSignsRepo := repository.NewVitalSignsRepository(db)
+	transactor := repository.NewTransactor(db, cfg.MRNFormat())
 
 	// --- Services ---
-	authService := service.NewAuthService(userRepo)
-	patientService := service.NewPatientService(patientRepo)
+	permissionService, err := service.NewPermissionService(rolePermissionRepo)
+	if err != nil {
+		log.Fatalf("Failed to load role permissions: %v", err)
+	}
+	passwords := service.NewPasswords(hasher, cfg.PasswordPolicy(), passwordHistoryRepo)
+	lockoutPolicy := service.DefaultLockoutPolicy()
+	lockoutPolicy.BackoffAfter = cfg.LoginBackoffAfter
+	lockoutPolicy.AccountLockoutAfter = cfg.LoginLockoutAfter
+	lockoutPolicy.IPLockoutAfter = cfg.LoginIPLockoutAfter
+	lockoutPolicy.LockoutDuration = cfg.LoginLockoutDuration
+	lockoutService := service.NewLockoutService(loginAttemptRepo, lockoutPolicy)
+	go pruneLoginThrottles(lockoutService, lockoutPolicy.FailureWindow)
+	mfaService := service.NewMFAService(userRepo, mfaRepo, sessionRepo, passwords, totpBox, cfg.MFAIssuer, cfg.MFARequiredRoles)
+	authService := service.NewAuthService(userRepo, sessionRepo, invitationRepo, mfaRepo, passwords, lockoutService, mfaService, keyManager)
+	userService := service.NewUserService(userRepo, invitationRepo, sessionRepo, passwords)
+	passwordService := service.NewPasswordService(userRepo, sessionRepo, passwordResetRepo, passwords, mail, cfg.PasswordResetURL)
+	auditService := service.NewAuditService(auditRepo)
+	patientAccess := service.NewPatientAccess(careTeamRepo, breakGlassRepo)
+	patientService := service.NewPatientService(patientRepo, careTeamRepo, patientMergeRepo, allergyRepo, transactor, patientAccess, auditService, cfg.MRNFormat())
+	patientHistoryService := service.NewPatientHistoryService(patientRepo, patientVersionRepo, transactor, patientAccess, auditService)
+	patientRetentionService := service.NewPatientRetentionService(patientRepo, patientPurgeRepo, patientMergeRepo, transactor, auditService, cfg.PatientRetentionDays)
+	patientMergeService := service.NewPatientMergeService(patientRepo, patientMergeRepo, transactor, auditService)
+	allergyService := service.NewAllergyService(allergyRepo, patientRepo, transactor, patientAccess, auditService)
+	clinicalNoteService := service.NewClinicalNoteService(clinicalNoteRepo, patientRepo, transactor, patientAccess, auditService)
+	vitalSignsService := service.NewVitalSignsService(vitalSignsRepo, patientRepo, transactor, patientAccess, auditService, cfg.VitalRanges)
+	careTeamService := service.NewCareTeamService(careTeamRepo, patientRepo, userRepo, transactor, patientAccess, auditService)
+	breakGlassService := service.NewBreakGlassService(breakGlassRepo, careTeamRepo, patientRepo, transactor, auditService, cfg.BreakGlassDuration)
+	apiKeyService := service.NewAPIKeyService(apiKeyRepo, userRepo, permissionService)
 
 	// --- Handlers ---
 	authHandler := api.NewAuthHandler(authService)
 	patientHandler := api.NewPatientHandler(patientService)
+	patientHistoryHandler := api.NewPatientHistoryHandler(patientHistoryService)
+	patientRetentionHandler := api.NewPatientRetentionHandler(patientRetentionService)
+	patientMergeHandler := api.NewPatientMergeHandler(patientMergeService)
+	userHandler := api.NewUserHandler(userService)
+	passwordHandler := api.NewPasswordHandler(passwordService)
+	lockoutHandler := api.NewLockoutHandler(lockoutService)
+	mfaHandler := api.NewMFAHandler(mfaService)
+	apiKeyHandler := api.NewAPIKeyHandler(apiKeyService)
+	permissionHandler := api.NewPermissionHandler(permissionService)
+	careTeamHandler := api.NewCareTeamHandler(careTeamService)
+	allergyHandler := api.NewAllergyHandler(allergyService)
+	clinicalNoteHandler := api.NewClinicalNoteHandler(clinicalNoteService)
+	vitalSignsHandler := api.NewVitalSignsHandler(vitalSignsService)
+	breakGlassHandler := api.NewBreakGlassHandler(breakGlassService)
+	auditHandler := api.NewAuditHandler(auditService)
 
 	// --- Router ---
 	router := gin.Default()
+	// Only trusted proxies may set the client IP the login lockout counts against
+	if err := router.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		log.Fatalf("Failed to set trusted proxies: %v", err)
+	}
+	router.Use(api.RequestID())
 
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
@@ -65,12 +171,30 @@ func main() {
 	{
 		v1Public.POST("/register", authHandler.RegisterHandler)
 		v1Public.POST("/login", authHandler.LoginHandler)
+		v1Public.POST("/login/mfa", authHandler.MFALoginHandler)
+		v1Public.POST("/login/mfa/enroll", authHandler.MFAEnrollHandler)
+		v1Public.POST("/token/refresh", authHandler.RefreshHandler)
+		v1Public.POST("/password/forgot", passwordHandler.ForgotPassword)
+		v1Public.POST("/password/reset", passwordHandler.ResetPassword)
 	}
 
 	// Protected routes group
 	v1Protected := router.Group("/api/v1")
-	v1Protected.Use(api.AuthMiddleware())
+	v1Protected.Use(api.AuthMiddleware(keyManager, authService, apiKeyService))
 	{
+		// Routes that only make sense for a logged in person, not an API key
+		sessionRoutes := v1Protected.Group("")
+		sessionRoutes.Use(api.RequireSession())
+		{
+			sessionRoutes.POST("/logout", authHandler.LogoutHandler)
+			sessionRoutes.POST("/logout/all", authHandler.LogoutAllHandler)
+			sessionRoutes.PUT("/profile/password", passwordHandler.ChangePassword)
+			sessionRoutes.POST("/profile/mfa/enroll", mfaHandler.BeginEnrollment)
+			sessionRoutes.POST("/profile/mfa/confirm", mfaHandler.ConfirmEnrollment)
+			sessionRoutes.POST("/profile/mfa/recovery-codes", mfaHandler.RegenerateRecoveryCodes)
+			sessionRoutes.DELETE("/profile/mfa", mfaHandler.DisableMFA)
+		}
+
 		// This is a sample protected route for testing
 		// @Summary      Get user profile
 		// @Description  Returns the current user's profile information from the JWT token.
@@ -92,27 +216,119 @@ func main() {
 			})
 		})
 
-		// --- Receptionist Routes ---
+		// --- Patient Routes ---
+		canRead := api.RequirePermission(permissionService, model.PermissionPatientRead)
+		canWrite := api.RequirePermission(permissionService, model.PermissionPatientWrite)
+		canDelete := api.RequirePermission(permissionService, model.PermissionPatientDelete)
+		canManageCareTeam := api.RequirePermission(permissionService, model.PermissionCareTeamManage)
+
+		patientRoutes := v1Protected.Group("/patients")
+		{
+			patientRoutes.POST("", canWrite, patientHandler.CreatePatient)
+			patientRoutes.GET("", canRead, patientHandler.GetAllPatients)
+			patientRoutes.GET("/search", canRead, patientHandler.SearchPatients)
+			patientRoutes.GET("/by-mrn/:mrn", canRead, patientHandler.GetPatientByMRN)
+			patientRoutes.GET("/:patient_id", canRead, patientHandler.GetPatientByID)
+			patientRoutes.PUT("/:patient_id", canWrite, patientHandler.UpdatePatient)
+			patientRoutes.PATCH("/:patient_id", canWrite, patientHandler.PatchPatient)
+			patientRoutes.DELETE("/:patient_id", canDelete, patientHandler.DeletePatient)
+			patientRoutes.GET("/:patient_id/versions", canRead, patientHistoryHandler.ListPatientVersions)
+			patientRoutes.GET("/:patient_id/versions/diff", canRead, patientHistoryHandler.DiffPatientVersions)
+			patientRoutes.GET("/:patient_id/versions/:version", canRead, patientHistoryHandler.GetPatientVersion)
+			patientRoutes.POST("/:patient_id/versions/:version/restore", canWrite, patientHistoryHandler.RestorePatientVersion)
+			patientRoutes.GET("/:patient_id/as-of", canRead, patientHistoryHandler.GetPatientAsOf)
+			patientRoutes.GET("/:patient_id/care-team", canRead, careTeamHandler.ListCareTeam)
+			patientRoutes.POST("/:patient_id/care-team", canManageCareTeam, careTeamHandler.AssignCareTeamMember)
+			patientRoutes.DELETE("/:patient_id/care-team/:user_id", canManageCareTeam, careTeamHandler.UnassignCareTeamMember)
+			patientRoutes.GET("/:patient_id/allergies", canRead, allergyHandler.ListAllergies)
+			patientRoutes.POST("/:patient_id/allergies", canWrite, allergyHandler.CreateAllergy)
+			patientRoutes.PUT("/:patient_id/allergies/none", canWrite, allergyHandler.AssertNoKnownAllergies)
+			patientRoutes.DELETE("/:patient_id/allergies/none", canWrite, allergyHandler.WithdrawNoKnownAllergies)
+			patientRoutes.GET("/:patient_id/allergies/:allergy_id", canRead, allergyHandler.GetAllergy)
+			patientRoutes.PUT("/:patient_id/allergies/:allergy_id", canWrite, allergyHandler.UpdateAllergy)
+			patientRoutes.DELETE("/:patient_id/allergies/:allergy_id", canWrite, allergyHandler.DeleteAllergy)
+			patientRoutes.GET("/:patient_id/notes", canRead, clinicalNoteHandler.ListNotes)
+			patientRoutes.POST("/:patient_id/notes", canWrite, clinicalNoteHandler.CreateNote)
+			patientRoutes.GET("/:patient_id/notes/:note_id", canRead, clinicalNoteHandler.GetNote)
+			patientRoutes.PUT("/:patient_id/notes/:note_id", canWrite, clinicalNoteHandler.UpdateNote)
+			patientRoutes.DELETE("/:patient_id/notes/:note_id", canWrite, clinicalNoteHandler.DiscardNote)
+			patientRoutes.POST("/:patient_id/notes/:note_id/sign", canWrite, clinicalNoteHandler.SignNote)
+			patientRoutes.POST("/:patient_id/notes/:note_id/addenda", canWrite, clinicalNoteHandler.AddAddendum)
+			patientRoutes.GET("/:patient_id/timeline", canRead, clinicalNoteHandler.GetTimeline)
+			patientRoutes.GET("/:patient_id/vitals", canRead, vitalSignsHandler.ListVitals)
+			patientRoutes.POST("/:patient_id/vitals", canWrite, vitalSignsHandler.RecordVitals)
+			patientRoutes.GET("/:patient_id/vitals/trend", canRead, vitalSignsHandler.GetTrend)
+			patientRoutes.POST("/:patient_id/break-glass", api.RequireSession(), canRead, breakGlassHandler.RequestBreakGlass)
+		}
+
+		v1Protected.POST("/vitals/batch", canWrite, vitalSignsHandler.RecordVitalsBatch)
+
+		// --- Deprecated role-prefixed aliases of the patient routes ---
 		receptionistRoutes := v1Protected.Group("/receptionist")
-		receptionistRoutes.Use(api.RoleAuthMiddleware(model.Receptionist))
+		receptionistRoutes.Use(api.RoleAuthMiddleware(model.Receptionist), api.Deprecated("/api/v1/patients"))
 		{
-			receptionistRoutes.POST("/patients", patientHandler.CreatePatient)
-			receptionistRoutes.GET("/patients", patientHandler.GetAllPatients)
-			receptionistRoutes.GET("/patients/:patient_id", patientHandler.GetPatientByID)
-			receptionistRoutes.PUT("/patients/:patient_id", patientHandler.UpdatePatient)
-			receptionistRoutes.DELETE("/patients/:patient_id", patientHandler.DeletePatient)
+			receptionistRoutes.POST("/patients", canWrite, patientHandler.CreatePatient)
+			receptionistRoutes.GET("/patients", canRead, patientHandler.GetAllPatientsLegacy)
+			receptionistRoutes.GET("/patients/:patient_id", canRead, patientHandler.GetPatientByID)
+			receptionistRoutes.PUT("/patients/:patient_id", canWrite, patientHandler.UpdatePatient)
+			receptionistRoutes.DELETE("/patients/:patient_id", canDelete, patientHandler.DeletePatient)
 		}
 
-		// --- Doctor Routes ---
 		doctorRoutes := v1Protected.Group("/doctor")
-		doctorRoutes.Use(api.RoleAuthMiddleware(model.Doctor))
+		doctorRoutes.Use(api.RoleAuthMiddleware(model.Doctor), api.Deprecated("/api/v1/patients"))
+		{
+			doctorRoutes.GET("/patients", canRead, patientHandler.GetAllPatientsLegacy)
+			doctorRoutes.GET("/patients/:patient_id", canRead, patientHandler.GetPatientByID)
+			doctorRoutes.PUT("/patients/:patient_id", canWrite, patientHandler.UpdatePatient)
+		}
+
+		// --- Admin Routes ---
+		adminRoutes := v1Protected.Group("/admin")
+		adminRoutes.Use(api.RequireSession(), api.RoleAuthMiddleware(model.Admin))
 		{
-			doctorRoutes.GET("/patients", patientHandler.GetAllPatients)
-			doctorRoutes.GET("/patients/:patient_id", patientHandler.GetPatientByID)
-			doctorRoutes.PUT("/patients/:patient_id", patientHandler.UpdatePatient)
+			adminRoutes.POST("/invitations", userHandler.InviteUser)
+			adminRoutes.POST("/users", userHandler.CreateUser)
+			adminRoutes.GET("/users", userHandler.ListUsers)
+			adminRoutes.GET("/users/:user_id", userHandler.GetUser)
+			adminRoutes.POST("/users/:user_id/deactivate", userHandler.DeactivateUser)
+			adminRoutes.POST("/users/:user_id/reactivate", userHandler.ReactivateUser)
+			adminRoutes.PUT("/users/:user_id/role", userHandler.ChangeRole)
+			adminRoutes.DELETE("/users/:user_id/mfa", mfaHandler.ResetMFA)
+			adminRoutes.GET("/lockouts", lockoutHandler.ListLockouts)
+			adminRoutes.DELETE("/lockouts/:lockout_id", lockoutHandler.ClearLockout)
+			adminRoutes.GET("/login-attempts", lockoutHandler.ListLoginAttempts)
+			adminRoutes.POST("/service-accounts", apiKeyHandler.CreateServiceAccount)
+			adminRoutes.POST("/api-keys", apiKeyHandler.CreateAPIKey)
+			adminRoutes.GET("/api-keys", apiKeyHandler.ListAPIKeys)
+			adminRoutes.DELETE("/api-keys/:key_id", apiKeyHandler.RevokeAPIKey)
+			adminRoutes.GET("/permissions", permissionHandler.ListPermissions)
+			adminRoutes.PUT("/roles/:role/permissions", permissionHandler.SetRolePermissions)
+			adminRoutes.GET("/break-glass", breakGlassHandler.ListBreakGlass)
+			adminRoutes.GET("/break-glass/:access_id", breakGlassHandler.GetBreakGlass)
+			adminRoutes.POST("/break-glass/:access_id/review", breakGlassHandler.ReviewBreakGlass)
+			adminRoutes.GET("/patients/deleted", patientRetentionHandler.ListDeletedPatients)
+			adminRoutes.POST("/patients/:patient_id/restore", patientRetentionHandler.RestoreDeletedPatient)
+			adminRoutes.POST("/patient-purges", patientRetentionHandler.PurgePatients)
+			adminRoutes.GET("/patient-purges", patientRetentionHandler.ListPatientPurges)
+			adminRoutes.GET("/patient-purges/:purge_id", patientRetentionHandler.GetPatientPurge)
+			adminRoutes.POST("/patient-merges", patientMergeHandler.MergePatients)
+			adminRoutes.GET("/patient-merges", patientMergeHandler.ListPatientMerges)
+			adminRoutes.GET("/audit", auditHandler.ListAuditEntries)
+			adminRoutes.GET("/audit/verify", auditHandler.VerifyAuditLog)
 		}
 	}
 
+	// @Summary      JSON Web Key Set
+	// @Description  Publishes the public keys used to sign access tokens so other services can verify them offline.
+	// @Tags         Authentication
+	// @Produce      json
+	// @Success      200  {object}  auth.JWKSet
+	// @Router       /.well-known/jwks.json [get]
+	router.GET("/.well-known/jwks.json", func(c *gin.Context) {
+		c.Header("Cache-Control", "public, max-age=300")
+		c.JSON(http.StatusOK, keyManager.JWKS())
+	})
+
 	// @Summary      Health check
 	// @Description  Simple health check endpoint to verify the server is running.
 	// @Tags         Health
@@ -131,3 +347,20 @@ func main() {
 		log.Fatalf("Failed to start server: %v", err)
 	}
 }
+
+// pruneLoginThrottles deletes stale login throttles once per interval for as
+// long as the server runs
+func pruneLoginThrottles(lockout service.LockoutService, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		pruned, err := lockout.PruneThrottles()
+		if err != nil {
+			log.Printf("Failed to prune login throttles: %v", err)
+			continue
+		}
+		if pruned > 0 {
+			log.Printf("Pruned %d stale login throttles", pruned)
+		}
+	}
+}
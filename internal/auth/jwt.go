@@ -1,7 +1,7 @@
 package auth
 
 import (
-	"os"
+	"errors"
 	"time"
 
 	"github.com/RohanDSkaria/hospital-management-system/internal/model"
@@ -9,32 +9,57 @@ import (
 	"github.com/google/uuid"
 )
 
+const (
+	// AccessTokenTTL is the lifetime of an access token
+	AccessTokenTTL = 15 * time.Minute
+	// RefreshTokenTTL is the lifetime of a single refresh token
+	RefreshTokenTTL = 7 * 24 * time.Hour
+	// SessionTTL is the absolute lifetime of a session, regardless of refreshes
+	SessionTTL = 30 * 24 * time.Hour
+	// Issuer is the iss claim of every token we sign, checked by downstream services
+	Issuer = "hospital-management-system"
+)
+
 // CustomClaims defines the structure of the JWT claims
 type CustomClaims struct {
-	UserID uuid.UUID  `json:"user_id"`
-	Role   model.Role `json:"role"`
+	UserID    uuid.UUID  `json:"user_id"`
+	Role      model.Role `json:"role"`
+	SessionID uuid.UUID  `json:"sid"`
 	jwt.RegisteredClaims
 }
 
-// GenerateToken creates a new JWT for a given user
-func GenerateToken(userID uuid.UUID, role model.Role) (string, error) {
-	// Get the secret key from environment variables
-	jwtSecret := []byte(os.Getenv("JWT_SECRET_KEY"))
-
+// GenerateToken creates a new JWT for a given user and session, signed with the active key
+func (m *KeyManager) GenerateToken(userID uuid.UUID, role model.Role, sessionID uuid.UUID) (string, error) {
 	// Create the claims
 	claims := CustomClaims{
-		UserID: userID,
-		Role:   role,
+		UserID:    userID,
+		Role:      role,
+		SessionID: sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour * 24)), // Token expires in 24 hours
+			Issuer:    Issuer,
+			Subject:   userID.String(),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(AccessTokenTTL)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			NotBefore: jwt.NewNumericDate(time.Now()),
 		},
 	}
 
-	// Create token with claims
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	// Sign the token and return it as a string
+	return m.Sign(claims)
+}
 
-	// Generate encoded token and return it as a string
-	return token.SignedString(jwtSecret)
+// ParseToken verifies a JWT against the known keys and returns its claims
+func (m *KeyManager) ParseToken(tokenString string) (*CustomClaims, error) {
+	claims := &CustomClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, m.Keyfunc,
+		jwt.WithValidMethods(m.ValidMethods()),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	if !token.Valid {
+		return nil, errors.New("invalid token")
+	}
+	return claims, nil
 }
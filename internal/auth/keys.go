@@ -0,0 +1,362 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// KeyStatus describes what a key in the KeyManager may be used for
+type KeyStatus string
+
+const (
+	// KeyActive is the single key used to sign new tokens
+	KeyActive KeyStatus = "active"
+	// KeyVerifyOnly keys no longer sign tokens but still verify the ones they issued
+	KeyVerifyOnly KeyStatus = "verify-only"
+	// KeyRetired keys are kept only to give a clear error; tokens signed by them are rejected
+	KeyRetired KeyStatus = "retired"
+)
+
+// LegacyKeyID is the key ID given to the shared HS256 secret from JWT_SECRET_KEY.
+// Tokens issued before key rotation was introduced carry no kid header and are
+// verified against this key until they expire.
+const LegacyKeyID = "legacy-hs256"
+
+// MinSecretLength is the minimum length in bytes of an HMAC secret (256 bits)
+const MinSecretLength = 32
+
+var (
+	// ErrUnknownKey is returned when a token references a key the manager does not hold
+	ErrUnknownKey = errors.New("unknown signing key")
+	// ErrKeyRetired is returned when a token was signed with a retired key
+	ErrKeyRetired = errors.New("signing key has been retired")
+	// ErrNoActiveKey is returned when signing is attempted without an active key
+	ErrNoActiveKey = errors.New("no active signing key")
+	// ErrWeakSecret is returned when an HMAC secret is shorter than MinSecretLength
+	ErrWeakSecret = errors.New("secret must be at least 32 bytes long")
+)
+
+// Key is a single signing or verification key identified by its kid
+type Key struct {
+	ID     string
+	Method jwt.SigningMethod
+	Status KeyStatus
+
+	signingKey interface{}
+	verifyKey  interface{}
+}
+
+// IsAsymmetric reports whether the key is an RSA or Ed25519 key that can be published
+func (k *Key) IsAsymmetric() bool {
+	return k.Method != jwt.SigningMethodHS256
+}
+
+// KeyManager holds every key the service knows about. It signs new tokens
+// with the active asymmetric key and verifies tokens against any key that is
+// not retired, selected by the kid header.
+type KeyManager struct {
+	mu       sync.RWMutex
+	keys     map[string]*Key
+	activeID string
+}
+
+// NewKeyManager creates an empty key manager
+func NewKeyManager() *KeyManager {
+	return &KeyManager{keys: make(map[string]*Key)}
+}
+
+// AddPrivateKey registers an RSA or Ed25519 private key. The key starts as verify-only.
+func (m *KeyManager) AddPrivateKey(id string, key crypto.PrivateKey) error {
+	var k *Key
+	switch priv := key.(type) {
+	case *rsa.PrivateKey:
+		k = &Key{ID: id, Method: jwt.SigningMethodRS256, signingKey: priv, verifyKey: &priv.PublicKey}
+	case ed25519.PrivateKey:
+		k = &Key{ID: id, Method: jwt.SigningMethodEdDSA, signingKey: priv, verifyKey: priv.Public()}
+	default:
+		return fmt.Errorf("key %q: unsupported private key type %T", id, key)
+	}
+	return m.add(k)
+}
+
+// AddPublicKey registers an RSA or Ed25519 public key that can only verify tokens.
+// This is used for old keys whose private half has already been destroyed.
+func (m *KeyManager) AddPublicKey(id string, key crypto.PublicKey) error {
+	var k *Key
+	switch pub := key.(type) {
+	case *rsa.PublicKey:
+		k = &Key{ID: id, Method: jwt.SigningMethodRS256, verifyKey: pub}
+	case ed25519.PublicKey:
+		k = &Key{ID: id, Method: jwt.SigningMethodEdDSA, verifyKey: pub}
+	default:
+		return fmt.Errorf("key %q: unsupported public key type %T", id, key)
+	}
+	return m.add(k)
+}
+
+// AddLegacySecret registers the shared HS256 secret as a verify-only key.
+// Short secrets are refused so an empty or placeholder value can never verify a token.
+func (m *KeyManager) AddLegacySecret(secret []byte) error {
+	if len(secret) < MinSecretLength {
+		return ErrWeakSecret
+	}
+	return m.add(&Key{ID: LegacyKeyID, Method: jwt.SigningMethodHS256, verifyKey: secret})
+}
+
+// SetActive makes the given key the one used to sign new tokens.
+// The previously active key stays available for verification.
+func (m *KeyManager) SetActive(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	k, ok := m.keys[id]
+	if !ok {
+		return fmt.Errorf("key %q: %w", id, ErrUnknownKey)
+	}
+	if k.signingKey == nil || !k.IsAsymmetric() {
+		return fmt.Errorf("key %q cannot be used for signing", id)
+	}
+	if k.Status == KeyRetired {
+		return fmt.Errorf("key %q: %w", id, ErrKeyRetired)
+	}
+	if current, ok := m.keys[m.activeID]; ok {
+		current.Status = KeyVerifyOnly
+	}
+	k.Status = KeyActive
+	m.activeID = id
+	return nil
+}
+
+// Retire stops a key from verifying any more tokens and removes it from the JWKS
+func (m *KeyManager) Retire(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	k, ok := m.keys[id]
+	if !ok {
+		return fmt.Errorf("key %q: %w", id, ErrUnknownKey)
+	}
+	if id == m.activeID {
+		return fmt.Errorf("key %q is the active key and cannot be retired", id)
+	}
+	k.Status = KeyRetired
+	return nil
+}
+
+// ActiveKeyID returns the kid of the active signing key
+func (m *KeyManager) ActiveKeyID() string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.activeID
+}
+
+// Sign signs the claims with the active key and sets the kid header
+func (m *KeyManager) Sign(claims jwt.Claims) (string, error) {
+	m.mu.RLock()
+	k, ok := m.keys[m.activeID]
+	m.mu.RUnlock()
+	if !ok {
+		return "", ErrNoActiveKey
+	}
+
+	token := jwt.NewWithClaims(k.Method, claims)
+	token.Header["kid"] = k.ID
+	return token.SignedString(k.signingKey)
+}
+
+// Keyfunc resolves the verification key for a token from its kid header.
+// It is meant to be passed to jwt.Parse.
+func (m *KeyManager) Keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		kid = LegacyKeyID
+	}
+
+	m.mu.RLock()
+	k, ok := m.keys[kid]
+	m.mu.RUnlock()
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+	if k.Status == KeyRetired {
+		return nil, ErrKeyRetired
+	}
+	// The algorithm is chosen by whoever made the token, so never trust it:
+	// it must match the algorithm the key was registered with. Otherwise an
+	// attacker could, for example, HMAC-sign a token with our public RSA key.
+	if !sameMethod(token.Method, k.Method) {
+		return nil, fmt.Errorf("unexpected signing method %q for key %q", token.Method.Alg(), k.ID)
+	}
+	return k.verifyKey, nil
+}
+
+// ValidMethods returns the algorithms of every key that may verify tokens
+func (m *KeyManager) ValidMethods() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	seen := make(map[string]bool)
+	var methods []string
+	for _, k := range m.keys {
+		if k.Status == KeyRetired || seen[k.Method.Alg()] {
+			continue
+		}
+		seen[k.Method.Alg()] = true
+		methods = append(methods, k.Method.Alg())
+	}
+	return methods
+}
+
+// sameMethod reports whether a token's signing method is exactly the expected one
+func sameMethod(got, want jwt.SigningMethod) bool {
+	if got == nil || got.Alg() != want.Alg() {
+		return false
+	}
+	switch want.(type) {
+	case *jwt.SigningMethodHMAC:
+		_, ok := got.(*jwt.SigningMethodHMAC)
+		return ok
+	case *jwt.SigningMethodRSA:
+		_, ok := got.(*jwt.SigningMethodRSA)
+		return ok
+	case *jwt.SigningMethodEd25519:
+		_, ok := got.(*jwt.SigningMethodEd25519)
+		return ok
+	}
+	return false
+}
+
+func (m *KeyManager) add(k *Key) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if k.ID == "" {
+		return errors.New("key ID must not be empty")
+	}
+	if _, exists := m.keys[k.ID]; exists {
+		return fmt.Errorf("key %q is already registered", k.ID)
+	}
+	k.Status = KeyVerifyOnly
+	m.keys[k.ID] = k
+	return nil
+}
+
+// LoadKeysFromDir registers every PEM file in dir as a key. The file name
+// without its extension becomes the kid. Private keys may be PKCS#8 or PKCS#1
+// encoded; files containing only a public key become verify-only keys.
+func (m *KeyManager) LoadKeysFromDir(dir string) error {
+	files, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		block, _ := pem.Decode(data)
+		if block == nil {
+			return fmt.Errorf("%s: no PEM data found", file)
+		}
+		id := strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+
+		switch block.Type {
+		case "PRIVATE KEY":
+			key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+			if err != nil {
+				return fmt.Errorf("%s: %w", file, err)
+			}
+			if err := m.AddPrivateKey(id, key); err != nil {
+				return err
+			}
+		case "RSA PRIVATE KEY":
+			key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+			if err != nil {
+				return fmt.Errorf("%s: %w", file, err)
+			}
+			if err := m.AddPrivateKey(id, key); err != nil {
+				return err
+			}
+		case "PUBLIC KEY":
+			key, err := x509.ParsePKIXPublicKey(block.Bytes)
+			if err != nil {
+				return fmt.Errorf("%s: %w", file, err)
+			}
+			if err := m.AddPublicKey(id, key); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("%s: unsupported PEM block type %q", file, block.Type)
+		}
+	}
+	return nil
+}
+
+// KeyOptions describes where LoadKeyManager gets its keys from
+type KeyOptions struct {
+	// Dir is a directory of PEM encoded keys, one per file
+	Dir string
+	// ActiveID is the kid of the key used for signing
+	ActiveID string
+	// RetiredIDs are kids whose tokens must no longer be accepted
+	RetiredIDs []string
+	// LegacySecret is the HS256 secret accepted for tokens without a kid
+	LegacySecret []byte
+}
+
+// LoadKeyManager builds a key manager from the given options. When no key
+// directory is configured an ephemeral Ed25519 key is generated, which means
+// all tokens become invalid when the server restarts.
+func LoadKeyManager(opts KeyOptions) (*KeyManager, error) {
+	m := NewKeyManager()
+
+	if len(opts.LegacySecret) > 0 {
+		if err := m.AddLegacySecret(opts.LegacySecret); err != nil {
+			return nil, err
+		}
+	}
+
+	activeID := opts.ActiveID
+	if opts.Dir != "" {
+		if err := m.LoadKeysFromDir(opts.Dir); err != nil {
+			return nil, err
+		}
+	} else {
+		log.Println("JWT_KEYS_DIR not set, generating an ephemeral signing key; tokens will not survive a restart")
+		_, priv, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		activeID = "ephemeral"
+		if err := m.AddPrivateKey(activeID, priv); err != nil {
+			return nil, err
+		}
+	}
+
+	if activeID == "" {
+		return nil, errors.New("no active signing key configured")
+	}
+	if err := m.SetActive(activeID); err != nil {
+		return nil, err
+	}
+
+	for _, id := range opts.RetiredIDs {
+		if err := m.Retire(id); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
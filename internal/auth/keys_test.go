@@ -0,0 +1,216 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/RohanDSkaria/hospital-management-system/internal/model"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+var testSecret = []byte("0123456789abcdefghijklmnopqrstuvwxyzABCD")
+
+// newTestKeyManager returns a manager holding an active Ed25519 key, a
+// verify-only RSA key and the legacy HS256 secret
+func newTestKeyManager(t *testing.T) (*KeyManager, *rsa.PrivateKey) {
+	t.Helper()
+
+	m := NewKeyManager()
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.AddPrivateKey("ed", edKey); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.AddPrivateKey("rsa", rsaKey); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.AddLegacySecret(testSecret); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.SetActive("ed"); err != nil {
+		t.Fatal(err)
+	}
+	return m, rsaKey
+}
+
+func testClaims() CustomClaims {
+	return CustomClaims{
+		UserID:    uuid.New(),
+		Role:      model.Doctor,
+		SessionID: uuid.New(),
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+	}
+}
+
+// forge signs claims with an arbitrary method, key and kid header
+func forge(t *testing.T, method jwt.SigningMethod, key interface{}, kid string) string {
+	t.Helper()
+
+	token := jwt.NewWithClaims(method, testClaims())
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func TestParseTokenAcceptsValidTokens(t *testing.T) {
+	m, rsaKey := newTestKeyManager(t)
+
+	tokens := map[string]string{
+		"active key":      mustGenerate(t, m),
+		"verify-only key": forge(t, jwt.SigningMethodRS256, rsaKey, "rsa"),
+		"legacy secret":   forge(t, jwt.SigningMethodHS256, testSecret, ""),
+	}
+	for name, token := range tokens {
+		if _, err := m.ParseToken(token); err != nil {
+			t.Errorf("%s: expected token to be accepted, got %v", name, err)
+		}
+	}
+}
+
+func TestParseTokenRejectsNoneAlgorithm(t *testing.T) {
+	m, _ := newTestKeyManager(t)
+
+	for _, kid := range []string{"", "ed", "rsa", LegacyKeyID} {
+		token := forge(t, jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, kid)
+		if _, err := m.ParseToken(token); err == nil {
+			t.Errorf("kid %q: token with alg none was accepted", kid)
+		}
+	}
+}
+
+func TestParseTokenRejectsHandCraftedNoneToken(t *testing.T) {
+	m, _ := newTestKeyManager(t)
+
+	// Some attackers vary the case of "none" or leave the signature empty
+	for _, alg := range []string{"none", "None", "NONE", "nOnE"} {
+		valid := mustGenerate(t, m)
+		parts := strings.Split(valid, ".")
+		forged := encodeSegment(`{"alg":"`+alg+`","typ":"JWT"}`) + "." + parts[1] + "."
+		if _, err := m.ParseToken(forged); err == nil {
+			t.Errorf("alg %q: unsigned token was accepted", alg)
+		}
+	}
+}
+
+func TestParseTokenRejectsAlgorithmConfusion(t *testing.T) {
+	m, rsaKey := newTestKeyManager(t)
+
+	// Classic attack: HMAC-sign with the RSA public key, which is public knowledge
+	pubDER, err := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pubPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDER})
+
+	tokens := map[string]string{
+		"HS256 with RSA public key PEM":     forge(t, jwt.SigningMethodHS256, pubPEM, "rsa"),
+		"HS256 with RSA public key DER":     forge(t, jwt.SigningMethodHS256, pubDER, "rsa"),
+		"HS256 with legacy secret, RSA kid": forge(t, jwt.SigningMethodHS256, testSecret, "rsa"),
+		"RS256 for Ed25519 key":             forge(t, jwt.SigningMethodRS256, rsaKey, "ed"),
+		"RS512 for RS256 key":               forge(t, jwt.SigningMethodRS512, rsaKey, "rsa"),
+		"PS256 for RS256 key":               forge(t, jwt.SigningMethodPS256, rsaKey, "rsa"),
+		"RS256 without kid":                 forge(t, jwt.SigningMethodRS256, rsaKey, ""),
+		"HS512 with legacy secret":          forge(t, jwt.SigningMethodHS512, testSecret, ""),
+	}
+	for name, token := range tokens {
+		if _, err := m.ParseToken(token); err == nil {
+			t.Errorf("%s: forged token was accepted", name)
+		}
+	}
+}
+
+func TestParseTokenRejectsEmptySecret(t *testing.T) {
+	// A manager without a legacy secret, as when JWT_SECRET_KEY was unset
+	m := NewKeyManager()
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+	if err := m.AddPrivateKey("ed", edKey); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.SetActive("ed"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, kid := range []string{"", LegacyKeyID, "ed"} {
+		token := forge(t, jwt.SigningMethodHS256, []byte(""), kid)
+		if _, err := m.ParseToken(token); err == nil {
+			t.Errorf("kid %q: token signed with an empty secret was accepted", kid)
+		}
+	}
+}
+
+func TestParseTokenRejectsUnknownAndRetiredKeys(t *testing.T) {
+	m, rsaKey := newTestKeyManager(t)
+
+	if _, err := m.ParseToken(forge(t, jwt.SigningMethodRS256, rsaKey, "nope")); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("unknown kid: expected ErrUnknownKey, got %v", err)
+	}
+
+	if err := m.Retire("rsa"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.ParseToken(forge(t, jwt.SigningMethodRS256, rsaKey, "rsa")); err == nil {
+		t.Error("retired kid: token was accepted")
+	}
+	if _, err := m.Keyfunc(&jwt.Token{Method: jwt.SigningMethodRS256, Header: map[string]interface{}{"kid": "rsa"}}); !errors.Is(err, ErrKeyRetired) {
+		t.Errorf("retired kid: expected ErrKeyRetired, got %v", err)
+	}
+}
+
+func TestParseTokenRequiresExpiry(t *testing.T) {
+	m, _ := newTestKeyManager(t)
+
+	claims := testClaims()
+	claims.ExpiresAt = nil
+	token, err := m.Sign(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.ParseToken(token); err == nil {
+		t.Error("token without exp was accepted")
+	}
+}
+
+func TestAddLegacySecretRejectsWeakSecrets(t *testing.T) {
+	for _, secret := range []string{"", "short", strings.Repeat("a", MinSecretLength-1)} {
+		if err := NewKeyManager().AddLegacySecret([]byte(secret)); !errors.Is(err, ErrWeakSecret) {
+			t.Errorf("secret of length %d: expected ErrWeakSecret, got %v", len(secret), err)
+		}
+	}
+}
+
+func mustGenerate(t *testing.T, m *KeyManager) string {
+	t.Helper()
+
+	token, err := m.GenerateToken(uuid.New(), model.Doctor, uuid.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func encodeSegment(s string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(s))
+}
@@ -0,0 +1,82 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfcSecret is the SHA-1 seed of the RFC 6238 test vectors, base32 encoded
+var rfcSecret = totpEncoding.EncodeToString([]byte("12345678901234567890"))
+
+func TestTOTPCodeMatchesRFC6238(t *testing.T) {
+	// The RFC lists 8 digit codes; a 6 digit code is their last 6 digits
+	vectors := map[int64]string{
+		59:          "287082",
+		1111111109:  "081804",
+		1111111111:  "050471",
+		1234567890:  "005924",
+		2000000000:  "279037",
+		20000000000: "353130",
+	}
+	for unix, want := range vectors {
+		got, err := TOTPCode(rfcSecret, TOTPStep(time.Unix(unix, 0)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("t=%d: expected %s, got %s", unix, want, got)
+		}
+	}
+}
+
+func TestValidateTOTPAcceptsOnlyNearbySteps(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	code, err := TOTPCode(rfcSecret, TOTPStep(now))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if step, ok := ValidateTOTP(rfcSecret, code, now.Add(TOTPPeriod)); !ok || step != TOTPStep(now) {
+		t.Errorf("expected code from the previous step to be accepted")
+	}
+	if _, ok := ValidateTOTP(rfcSecret, code, now.Add(3*TOTPPeriod)); ok {
+		t.Errorf("expected code from three steps ago to be rejected")
+	}
+	if _, ok := ValidateTOTP(rfcSecret, "", now); ok {
+		t.Errorf("expected empty code to be rejected")
+	}
+}
+
+func TestProvisioningURI(t *testing.T) {
+	uri := TOTPProvisioningURI("Hospital", "doc@example.com", rfcSecret)
+	if !strings.HasPrefix(uri, "otpauth://totp/Hospital:doc@example.com?") {
+		t.Errorf("unexpected URI %s", uri)
+	}
+	if !strings.Contains(uri, "secret="+rfcSecret) {
+		t.Errorf("URI does not contain the secret: %s", uri)
+	}
+}
+
+func TestSecretBoxRoundTrip(t *testing.T) {
+	box, err := NewSecretBox(testSecret, "totp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed, err := box.Seal(rfcSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opened, err := box.Open(sealed)
+	if err != nil || opened != rfcSecret {
+		t.Fatalf("expected %s, got %s (%v)", rfcSecret, opened, err)
+	}
+
+	other, err := NewSecretBox(testSecret, "other")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := other.Open(sealed); err == nil {
+		t.Errorf("expected a key derived for another label to fail")
+	}
+}
@@ -0,0 +1,326 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/RohanDSkaria/hospital-management-system/internal/auth"
+	"github.com/RohanDSkaria/hospital-management-system/internal/model"
+	"github.com/RohanDSkaria/hospital-management-system/internal/mrn"
+	"github.com/RohanDSkaria/hospital-management-system/internal/password"
+	"github.com/RohanDSkaria/hospital-management-system/internal/vitals"
+	"github.com/RohanDSkaria/hospital-management-system/pkg/utils"
+)
+
+// Config holds the settings the server needs at startup
+type Config struct {
+	Environment    string
+	DatabaseDSN    string
+	JWTSecret      []byte
+	JWTKeysDir     string
+	JWTActiveKID   string
+	JWTRetiredKIDs []string
+
+	Mailer           string
+	MailerDir        string
+	MailFrom         string
+	PasswordResetURL string
+
+	LoginBackoffAfter    int
+	LoginLockoutAfter    int
+	LoginIPLockoutAfter  int
+	LoginLockoutDuration time.Duration
+
+	// TrustedProxies are the proxy IPs or CIDR ranges whose X-Forwarded-For
+	// header is believed when working out the client IP. None by default.
+	TrustedProxies []string
+
+	PasswordHasher      string
+	BcryptCost          int
+	PasswordMinLength   int
+	PasswordMinClasses  int
+	PasswordHistorySize int
+
+	MFARequiredRoles []model.Role
+	MFAIssuer        string
+	MFAEncryptionKey []byte
+
+	BreakGlassDuration time.Duration
+
+	PatientRetentionDays int
+
+	MRNPrefix     string
+	MRNDigits     int
+	MRNCheckDigit string
+
+	VitalRangesFile string
+	VitalRanges     vitals.Ranges
+
+	// parseErrs collects malformed values found by Load so Validate can report them
+	parseErrs []error
+}
+
+// weakSecrets are well-known placeholder values that must never be used as a secret
+var weakSecrets = []string{
+	"secret",
+	"changeme",
+	"change-me",
+	"your-secret-key",
+	"your_jwt_secret_key",
+	"jwt_secret_key",
+	"supersecret",
+	"password",
+}
+
+// Load reads the configuration from the environment and validates it.
+// The server must not start if this returns an error.
+func Load() (*Config, error) {
+	cfg := &Config{
+		Environment:  getEnv("APP_ENV", "development"),
+		DatabaseDSN:  os.Getenv("DB_DSN"),
+		JWTSecret:    []byte(os.Getenv("JWT_SECRET_KEY")),
+		JWTKeysDir:   os.Getenv("JWT_KEYS_DIR"),
+		JWTActiveKID: os.Getenv("JWT_ACTIVE_KID"),
+
+		Mailer:           getEnv("MAILER", "log"),
+		MailerDir:        getEnv("MAILER_DIR", "mail"),
+		MailFrom:         getEnv("MAIL_FROM", "no-reply@hospital.local"),
+		PasswordResetURL: getEnv("PASSWORD_RESET_URL", "http://localhost:8080/reset-password"),
+	}
+	cfg.LoginBackoffAfter = cfg.getEnvInt("LOGIN_BACKOFF_AFTER", 3)
+	cfg.LoginLockoutAfter = cfg.getEnvInt("LOGIN_LOCKOUT_AFTER", 10)
+	cfg.LoginIPLockoutAfter = cfg.getEnvInt("LOGIN_IP_LOCKOUT_AFTER", 50)
+	cfg.LoginLockoutDuration = cfg.getEnvDuration("LOGIN_LOCKOUT_DURATION", 15*time.Minute)
+	for _, proxy := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			cfg.TrustedProxies = append(cfg.TrustedProxies, proxy)
+		}
+	}
+
+	defaults := password.DefaultPolicy()
+	cfg.PasswordHasher = getEnv("PASSWORD_HASHER", utils.HashBcrypt)
+	cfg.BcryptCost = cfg.getEnvInt("BCRYPT_COST", utils.DefaultBcryptCost)
+	cfg.PasswordMinLength = cfg.getEnvInt("PASSWORD_MIN_LENGTH", defaults.MinLength)
+	cfg.PasswordMinClasses = cfg.getEnvInt("PASSWORD_MIN_CLASSES", defaults.MinClasses)
+	cfg.PasswordHistorySize = cfg.getEnvInt("PASSWORD_HISTORY", defaults.HistorySize)
+
+	cfg.MFAIssuer = getEnv("MFA_ISSUER", "Hospital Management System")
+	cfg.MFAEncryptionKey = []byte(os.Getenv("MFA_ENCRYPTION_KEY"))
+	for _, role := range strings.Split(getEnv("MFA_REQUIRED_ROLES", "doctor,admin"), ",") {
+		if role = strings.TrimSpace(role); role != "" && role != "none" {
+			cfg.MFARequiredRoles = append(cfg.MFARequiredRoles, model.Role(role))
+		}
+	}
+	cfg.BreakGlassDuration = cfg.getEnvDuration("BREAK_GLASS_DURATION", time.Hour)
+	cfg.PatientRetentionDays = cfg.getEnvInt("PATIENT_RETENTION_DAYS", 3650)
+	cfg.MRNPrefix = getEnv("MRN_PREFIX", "HMS")
+	cfg.MRNDigits = cfg.getEnvInt("MRN_DIGITS", 7)
+	cfg.MRNCheckDigit = getEnv("MRN_CHECK_DIGIT", mrn.Luhn)
+	cfg.VitalRangesFile = os.Getenv("VITAL_RANGES_FILE")
+	cfg.VitalRanges = vitals.DefaultRanges()
+	if cfg.VitalRangesFile != "" {
+		ranges, err := vitals.LoadRanges(cfg.VitalRangesFile)
+		if err != nil {
+			cfg.parseErrs = append(cfg.parseErrs, fmt.Errorf("VITAL_RANGES_FILE: %w", err))
+		} else {
+			cfg.VitalRanges = ranges
+		}
+	}
+
+	for _, id := range strings.Split(os.Getenv("JWT_RETIRED_KIDS"), ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			cfg.JWTRetiredKIDs = append(cfg.JWTRetiredKIDs, id)
+		}
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// Validate checks that the configuration is safe to run with.
+// All problems are reported at once so a deploy can be fixed in one go.
+func (c *Config) Validate() error {
+	errs := append([]error(nil), c.parseErrs...)
+
+	if c.DatabaseDSN == "" {
+		errs = append(errs, errors.New("DB_DSN environment variable not set"))
+	}
+	if err := validateSecret(c.JWTSecret); err != nil {
+		errs = append(errs, fmt.Errorf("JWT_SECRET_KEY: %w", err))
+	}
+	if c.JWTKeysDir != "" && c.JWTActiveKID == "" {
+		errs = append(errs, errors.New("JWT_ACTIVE_KID must be set when JWT_KEYS_DIR is set"))
+	}
+	if c.Mailer != "log" && c.Mailer != "file" {
+		errs = append(errs, fmt.Errorf("MAILER must be \"log\" or \"file\", got %q", c.Mailer))
+	}
+	if c.LoginBackoffAfter < 1 || c.LoginLockoutAfter < 1 || c.LoginIPLockoutAfter < 1 {
+		errs = append(errs, errors.New("LOGIN_BACKOFF_AFTER, LOGIN_LOCKOUT_AFTER and LOGIN_IP_LOCKOUT_AFTER must be positive"))
+	}
+	if c.LoginLockoutDuration <= 0 {
+		errs = append(errs, errors.New("LOGIN_LOCKOUT_DURATION must be positive"))
+	}
+	for _, proxy := range c.TrustedProxies {
+		if !isIPOrCIDR(proxy) {
+			errs = append(errs, fmt.Errorf("TRUSTED_PROXIES: %q is not an IP address or CIDR range", proxy))
+		}
+	}
+	if _, err := c.NewPasswordHasher(); err != nil {
+		errs = append(errs, fmt.Errorf("PASSWORD_HASHER/BCRYPT_COST: %w", err))
+	}
+	if c.PasswordMinLength < 8 {
+		errs = append(errs, errors.New("PASSWORD_MIN_LENGTH must be at least 8"))
+	}
+	if c.PasswordMinClasses < 0 || c.PasswordMinClasses > 4 {
+		errs = append(errs, errors.New("PASSWORD_MIN_CLASSES must be between 0 and 4"))
+	}
+	if c.PasswordHistorySize < 0 {
+		errs = append(errs, errors.New("PASSWORD_HISTORY must not be negative"))
+	}
+	for _, role := range c.MFARequiredRoles {
+		if !role.IsValid() {
+			errs = append(errs, fmt.Errorf("MFA_REQUIRED_ROLES: unknown role %q", role))
+		}
+	}
+	if len(c.MFAEncryptionKey) > 0 {
+		if err := validateSecret(c.MFAEncryptionKey); err != nil {
+			errs = append(errs, fmt.Errorf("MFA_ENCRYPTION_KEY: %w", err))
+		}
+	}
+	if c.BreakGlassDuration < 5*time.Minute || c.BreakGlassDuration > 24*time.Hour {
+		errs = append(errs, errors.New("BREAK_GLASS_DURATION must be between 5m and 24h"))
+	}
+	if c.PatientRetentionDays < 30 {
+		errs = append(errs, errors.New("PATIENT_RETENTION_DAYS must be at least 30"))
+	}
+	if err := c.MRNFormat().Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("MRN_PREFIX/MRN_DIGITS/MRN_CHECK_DIGIT: %w", err))
+	}
+	if err := c.VitalRanges.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("VITAL_RANGES_FILE: %w", err))
+	}
+	if c.IsProduction() && c.JWTKeysDir == "" {
+		errs = append(errs, errors.New("JWT_KEYS_DIR must be set in production, ephemeral signing keys are not allowed"))
+	}
+
+	return errors.Join(errs...)
+}
+
+// IsProduction reports whether the server runs in production
+func (c *Config) IsProduction() bool {
+	return c.Environment == "production"
+}
+
+// KeyOptions returns the options used to load the signing keys
+func (c *Config) KeyOptions() auth.KeyOptions {
+	return auth.KeyOptions{
+		Dir:          c.JWTKeysDir,
+		ActiveID:     c.JWTActiveKID,
+		RetiredIDs:   c.JWTRetiredKIDs,
+		LegacySecret: c.JWTSecret,
+	}
+}
+
+// NewPasswordHasher returns the hasher for new passwords
+func (c *Config) NewPasswordHasher() (*utils.PasswordHasher, error) {
+	return utils.NewPasswordHasher(c.PasswordHasher, c.BcryptCost)
+}
+
+// PasswordPolicy returns the rules new passwords must follow
+func (c *Config) PasswordPolicy() password.Policy {
+	policy := password.DefaultPolicy()
+	policy.MinLength = c.PasswordMinLength
+	policy.MinClasses = c.PasswordMinClasses
+	policy.HistorySize = c.PasswordHistorySize
+	// bcrypt ignores everything after 72 bytes, argon2id has no such limit
+	if c.PasswordHasher == utils.HashArgon2id {
+		policy.MaxLength = 256
+	}
+	return policy
+}
+
+// MRNFormat returns how medical record numbers are written
+func (c *Config) MRNFormat() mrn.Format {
+	return mrn.Format{Prefix: c.MRNPrefix, Digits: c.MRNDigits, CheckDigit: c.MRNCheckDigit}
+}
+
+// MFAKey returns the key that encrypts TOTP secrets at rest. Without a
+// dedicated MFA_ENCRYPTION_KEY it falls back to JWT_SECRET_KEY.
+func (c *Config) MFAKey() []byte {
+	if len(c.MFAEncryptionKey) > 0 {
+		return c.MFAEncryptionKey
+	}
+	return c.JWTSecret
+}
+
+// validateSecret rejects missing, short and placeholder secrets
+func validateSecret(secret []byte) error {
+	if len(secret) == 0 {
+		return errors.New("not set")
+	}
+	if len(secret) < auth.MinSecretLength {
+		return fmt.Errorf("must be at least %d bytes long, got %d", auth.MinSecretLength, len(secret))
+	}
+	lower := strings.ToLower(string(secret))
+	for _, weak := range weakSecrets {
+		if strings.Contains(lower, weak) {
+			return errors.New("looks like a placeholder value, generate a random secret instead")
+		}
+	}
+	if strings.Count(string(secret), string(secret[0])) == len(secret) {
+		return errors.New("must not repeat a single character")
+	}
+	return nil
+}
+
+// isIPOrCIDR reports whether value is an IP address or a CIDR range
+func isIPOrCIDR(value string) bool {
+	if net.ParseIP(value) != nil {
+		return true
+	}
+	_, _, err := net.ParseCIDR(value)
+	return err == nil
+}
+
+// getEnvInt reads an integer environment variable, recording an error if it is malformed
+func (c *Config) getEnvInt(key string, fallback int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		c.parseErrs = append(c.parseErrs, fmt.Errorf("%s must be a whole number, got %q", key, value))
+		return fallback
+	}
+	return n
+}
+
+// getEnvDuration reads a duration such as "15m", recording an error if it is malformed
+func (c *Config) getEnvDuration(key string, fallback time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		c.parseErrs = append(c.parseErrs, fmt.Errorf("%s must be a duration like \"15m\", got %q", key, value))
+		return fallback
+	}
+	return d
+}
+
+// getEnv returns the value of an environment variable or a fallback if it is not set
+func getEnv(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
@@ -0,0 +1,148 @@
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/RohanDSkaria/hospital-management-system/internal/model"
+	"github.com/RohanDSkaria/hospital-management-system/internal/vitals"
+)
+
+func validConfig() *Config {
+	return &Config{
+		Environment: "development",
+		DatabaseDSN: "host=localhost",
+		JWTSecret:   []byte("kq7Xv2Lr9TzB4mWc8NpY1sHd6FgJ3aQe"),
+		Mailer:      "log",
+
+		LoginBackoffAfter:    3,
+		LoginLockoutAfter:    10,
+		LoginIPLockoutAfter:  50,
+		LoginLockoutDuration: 15 * time.Minute,
+
+		PasswordHasher:      "bcrypt",
+		BcryptCost:          12,
+		PasswordMinLength:   12,
+		PasswordMinClasses:  3,
+		PasswordHistorySize: 5,
+
+		MFARequiredRoles: []model.Role{model.Doctor, model.Admin},
+
+		BreakGlassDuration: time.Hour,
+
+		PatientRetentionDays: 3650,
+
+		MRNPrefix:     "HMS",
+		MRNDigits:     7,
+		MRNCheckDigit: "luhn",
+
+		VitalRanges: vitals.DefaultRanges(),
+	}
+}
+
+func TestValidateAcceptsStrongSecret(t *testing.T) {
+	if err := validConfig().Validate(); err != nil {
+		t.Fatalf("expected valid config, got %v", err)
+	}
+}
+
+func TestValidateRejectsMissingOrWeakSecret(t *testing.T) {
+	secrets := map[string]string{
+		"missing":     "",
+		"short":       "tooshort",
+		"31 bytes":    strings.Repeat("x", 15) + strings.Repeat("y", 16),
+		"placeholder": "your-secret-key-please-change-it-now",
+		"repeated":    strings.Repeat("a", 64),
+	}
+	for name, secret := range secrets {
+		cfg := validConfig()
+		cfg.JWTSecret = []byte(secret)
+		if err := cfg.Validate(); err == nil {
+			t.Errorf("%s: expected secret to be rejected", name)
+		}
+	}
+}
+
+func TestValidateRequiresKeysDirInProduction(t *testing.T) {
+	cfg := validConfig()
+	cfg.Environment = "production"
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected production config without JWT_KEYS_DIR to be rejected")
+	}
+
+	cfg.JWTKeysDir = "/etc/hms/keys"
+	cfg.JWTActiveKID = "2025-01"
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("expected production config with keys to be valid, got %v", err)
+	}
+}
+
+func TestValidateRejectsUnknownMFARole(t *testing.T) {
+	cfg := validConfig()
+	cfg.MFARequiredRoles = []model.Role{model.Doctor, "surgeon"}
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected unknown role in MFA_REQUIRED_ROLES to be rejected")
+	}
+}
+
+func TestValidateRejectsUnknownHasherAndLowBcryptCost(t *testing.T) {
+	cfg := validConfig()
+	cfg.PasswordHasher = "md5"
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected unknown PASSWORD_HASHER to be rejected")
+	}
+
+	cfg = validConfig()
+	cfg.BcryptCost = 8
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected BCRYPT_COST below the minimum to be rejected")
+	}
+
+	cfg = validConfig()
+	cfg.PasswordHasher = "argon2id"
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("expected argon2id to be accepted, got %v", err)
+	}
+}
+
+func TestValidateRejectsShortPatientRetention(t *testing.T) {
+	cfg := validConfig()
+	cfg.PatientRetentionDays = 7
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected PATIENT_RETENTION_DAYS below the minimum to be rejected")
+	}
+}
+
+func TestValidateRejectsBadMRNFormat(t *testing.T) {
+	cfg := validConfig()
+	cfg.MRNCheckDigit = "crc"
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected an unknown MRN_CHECK_DIGIT to be rejected")
+	}
+	cfg = validConfig()
+	cfg.MRNDigits = 2
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected MRN_DIGITS below the minimum to be rejected")
+	}
+}
+
+func TestValidateRejectsBadVitalRanges(t *testing.T) {
+	cfg := validConfig()
+	cfg.VitalRanges.AdultAge = 0
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected an adult age of 0 to be rejected")
+	}
+}
+
+func TestValidateRejectsMalformedTrustedProxy(t *testing.T) {
+	cfg := validConfig()
+	cfg.TrustedProxies = []string{"10.0.0.1", "172.16.0.0/12", "::1"}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("expected IPs and CIDR ranges to be accepted, got %v", err)
+	}
+	cfg.TrustedProxies = []string{"proxy.internal"}
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected a host name in TRUSTED_PROXIES to be rejected")
+	}
+}
@@ -3,7 +3,6 @@ package database
 import (
 	"fmt"
 	"log"
-	"os"
 
 	"github.com/RohanDSkaria/hospital-management-system/internal/model"
 	"gorm.io/driver/postgres"
@@ -14,14 +13,11 @@ import (
 var DB *gorm.DB
 
 // Connect initializes the database connection and runs migrations
-func Connect() {
-	dsn := os.Getenv("DB_DSN")
-	if dsn == "" {
-		log.Fatal("DB_DSN environment variable not set")
-	}
-
+func Connect(dsn string) {
 	var err error
-	DB, err = gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	// TranslateError turns constraint violations into gorm errors such as
+	// gorm.ErrDuplicatedKey, so services can tell them from other failures
+	DB, err = gorm.Open(postgres.Open(dsn), &gorm.Config{TranslateError: true})
 	if err != nil {
 		log.Fatalf("Failed to connect to database: %v", err)
 	}
@@ -29,9 +25,169 @@ func Connect() {
 	fmt.Println("Successfully connected to the database!")
 
 	fmt.Println("Staring automigration...")
-	err = DB.AutoMigrate(&model.User{}, &model.Patient{})
+	err = DB.AutoMigrate(&model.User{}, &model.Patient{}, &model.Session{}, &model.RefreshToken{}, &model.Invitation{}, &model.PasswordResetToken{}, &model.LoginAttempt{}, &model.LoginThrottle{}, &model.RecoveryCode{}, &model.MFAChallenge{}, &model.PasswordHistory{}, &model.APIKey{}, &model.RolePermission{}, &model.CareTeamMember{}, &model.BreakGlassAccess{}, &model.BreakGlassEvent{}, &model.AuditEntry{}, &model.PatientVersion{}, &model.PatientPurge{}, &model.PurgedPatient{}, &model.PatientMerge{}, &model.Allergy{}, &model.NoKnownAllergies{}, &model.ClinicalNote{}, &model.NoteAddendum{}, &model.VitalSigns{})
 	if err != nil {
 		log.Fatalf("Failed to auto-migrate database: %v", err)
 	}
+	if err := protectAuditLog(DB); err != nil {
+		log.Fatalf("Failed to protect the audit log: %v", err)
+	}
+	if err := protectClinicalNotes(DB); err != nil {
+		log.Fatalf("Failed to protect signed clinical notes: %v", err)
+	}
+	if err := DB.Exec(`CREATE SEQUENCE IF NOT EXISTS patient_mrn_seq`).Error; err != nil {
+		log.Fatalf("Failed to create the medical record number sequence: %v", err)
+	}
+	if err := backfillPatientVersions(DB); err != nil {
+		log.Fatalf("Failed to backfill patient versions: %v", err)
+	}
+	if err := indexPatientSearch(DB); err != nil {
+		log.Fatalf("Failed to set up patient search: %v", err)
+	}
 	fmt.Println("Database migration successful!")
 }
+
+// auditAppendOnlySQL makes the database itself refuse to change or remove
+// audit entries, whoever is connected
+var auditAppendOnlySQL = []string{
+	`CREATE OR REPLACE FUNCTION audit_entries_append_only() RETURNS trigger AS $$
+BEGIN
+	RAISE EXCEPTION 'audit_entries is append-only';
+END;
+$$ LANGUAGE plpgsql`,
+	`DROP TRIGGER IF EXISTS audit_entries_no_update_delete ON audit_entries`,
+	`CREATE TRIGGER audit_entries_no_update_delete BEFORE UPDATE OR DELETE ON audit_entries
+	FOR EACH ROW EXECUTE FUNCTION audit_entries_append_only()`,
+	`DROP TRIGGER IF EXISTS audit_entries_no_truncate ON audit_entries`,
+	`CREATE TRIGGER audit_entries_no_truncate BEFORE TRUNCATE ON audit_entries
+	FOR EACH STATEMENT EXECUTE FUNCTION audit_entries_append_only()`,
+}
+
+// protectAuditLog installs the triggers that keep the audit log append-only
+func protectAuditLog(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, statement := range auditAppendOnlySQL {
+			if err := tx.Exec(statement).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// signedNotesSQL makes the database itself refuse to change a signed
+// clinical note or an addendum. The only status change allowed is from signed
+// to amended, which with every further addendum is also the only time the
+// update time may change. A note may only move away from a deleted patient,
+// as it does when the patient is merged into another. Signed notes and
+// addenda can only be deleted along with their patient or note, which the
+// cascade has already removed when the trigger runs.
+var signedNotesSQL = []string{
+	`CREATE OR REPLACE FUNCTION clinical_notes_signed_immutable() RETURNS trigger AS $$
+BEGIN
+	IF TG_OP = 'DELETE' THEN
+		IF OLD.status <> 'draft' AND EXISTS (SELECT 1 FROM patients WHERE id = OLD.patient_id) THEN
+			RAISE EXCEPTION 'signed clinical notes cannot be deleted';
+		END IF;
+		RETURN OLD;
+	END IF;
+	IF OLD.status = 'draft' THEN
+		RETURN NEW;
+	END IF;
+	IF NEW.status IS DISTINCT FROM OLD.status AND NOT (OLD.status = 'signed' AND NEW.status = 'amended') OR
+		NEW.updated_at IS DISTINCT FROM OLD.updated_at AND NEW.status <> 'amended' OR
+		(NEW.id, NEW.author_id, NEW.encounter_type, NEW.encounter_at, NEW.subjective, NEW.objective, NEW.assessment, NEW.plan, NEW.signed_at, NEW.created_at)
+		IS DISTINCT FROM
+		(OLD.id, OLD.author_id, OLD.encounter_type, OLD.encounter_at, OLD.subjective, OLD.objective, OLD.assessment, OLD.plan, OLD.signed_at, OLD.created_at)
+	THEN
+		RAISE EXCEPTION 'signed clinical notes cannot be changed, add an addendum instead';
+	END IF;
+	IF NEW.patient_id <> OLD.patient_id AND
+		NOT EXISTS (SELECT 1 FROM patients WHERE id = OLD.patient_id AND deleted_at IS NOT NULL) THEN
+		RAISE EXCEPTION 'signed clinical notes can only be moved away from a merged patient';
+	END IF;
+	RETURN NEW;
+END;
+$$ LANGUAGE plpgsql`,
+	`DROP TRIGGER IF EXISTS clinical_notes_signed_immutable ON clinical_notes`,
+	`CREATE TRIGGER clinical_notes_signed_immutable BEFORE UPDATE OR DELETE ON clinical_notes
+	FOR EACH ROW EXECUTE FUNCTION clinical_notes_signed_immutable()`,
+	`CREATE OR REPLACE FUNCTION note_addenda_immutable() RETURNS trigger AS $$
+BEGIN
+	IF TG_OP = 'DELETE' AND NOT EXISTS (SELECT 1 FROM clinical_notes WHERE id = OLD.note_id) THEN
+		RETURN OLD;
+	END IF;
+	RAISE EXCEPTION 'note addenda cannot be changed or deleted';
+END;
+$$ LANGUAGE plpgsql`,
+	`DROP TRIGGER IF EXISTS note_addenda_immutable ON note_addenda`,
+	`CREATE TRIGGER note_addenda_immutable BEFORE UPDATE OR DELETE ON note_addenda
+	FOR EACH ROW EXECUTE FUNCTION note_addenda_immutable()`,
+}
+
+// protectClinicalNotes installs the triggers that keep signed clinical notes
+// and their addenda from changing
+func protectClinicalNotes(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, statement := range signedNotesSQL {
+			if err := tx.Exec(statement).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// backfillPatientVersions gives every patient created before versions were
+// kept a first version holding their current details, and makes each
+// patient's version number match their latest version
+func backfillPatientVersions(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Exec(`INSERT INTO patient_versions
+			(id, patient_id, version, full_name, date_of_birth, address, contact_number, medical_history, change_type, created_at)
+		SELECT gen_random_uuid(), p.id, 1, p.full_name, p.date_of_birth, p.address, p.contact_number, p.medical_history, ?, p.updated_at
+		FROM patients p
+		WHERE NOT EXISTS (SELECT 1 FROM patient_versions v WHERE v.patient_id = p.id)`, model.PatientImported).Error
+		if err != nil {
+			return err
+		}
+		return tx.Exec(`UPDATE patients p SET version = v.latest
+		FROM (SELECT patient_id, MAX(version) AS latest FROM patient_versions GROUP BY patient_id) v
+		WHERE v.patient_id = p.id AND p.version <> v.latest`).Error
+	})
+}
+
+// patientSearchSQL sets up the indexes behind patient search. The indexed
+// expressions must stay the same as the ones the patient repository searches.
+var patientSearchSQL = []string{
+	`CREATE EXTENSION IF NOT EXISTS pg_trgm`,
+	`CREATE EXTENSION IF NOT EXISTS fuzzystrmatch`,
+	// The Double Metaphone codes of every word of a name, so names can be
+	// matched by how they sound
+	`CREATE OR REPLACE FUNCTION patient_name_phonetic(name text) RETURNS text AS $$
+	SELECT string_agg(code, ' ') FROM (
+		SELECT dmetaphone(word) AS code FROM regexp_split_to_table(lower(name), '[^[:alnum:]]+') AS word
+	) codes WHERE code <> ''
+$$ LANGUAGE sql IMMUTABLE STRICT PARALLEL SAFE`,
+	`CREATE INDEX IF NOT EXISTS idx_patients_search_document ON patients
+	USING GIN (to_tsvector('simple', coalesce(full_name, '') || ' ' || coalesce(address, '')))`,
+	`CREATE INDEX IF NOT EXISTS idx_patients_name_trgm ON patients USING GIN (full_name gin_trgm_ops)`,
+	`CREATE INDEX IF NOT EXISTS idx_patients_address_trgm ON patients USING GIN (address gin_trgm_ops)`,
+	`CREATE INDEX IF NOT EXISTS idx_patients_contact_digits ON patients
+	USING GIN (regexp_replace(contact_number, '[^0-9]', '', 'g') gin_trgm_ops)`,
+	`CREATE INDEX IF NOT EXISTS idx_patients_name_phonetic ON patients
+	USING GIN (to_tsvector('simple', patient_name_phonetic(full_name)))`,
+}
+
+// indexPatientSearch installs the extensions, function and indexes patient
+// search relies on
+func indexPatientSearch(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, statement := range patientSearchSQL {
+			if err := tx.Exec(statement).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
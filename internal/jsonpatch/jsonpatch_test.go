@@ -0,0 +1,141 @@
+package jsonpatch
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func mustDecode(t *testing.T, data string) interface{} {
+	t.Helper()
+	var value interface{}
+	if err := json.Unmarshal([]byte(data), &value); err != nil {
+		t.Fatalf("bad JSON %s: %v", data, err)
+	}
+	return value
+}
+
+// Examples from RFC 7396 appendix A
+func TestMergePatchFollowsRFC7396Examples(t *testing.T) {
+	cases := []struct{ doc, patch, want string }{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`["a","b"]`, `["c","d"]`, `["c","d"]`},
+		{`{"a":"b"}`, `["c"]`, `["c"]`},
+		{`{"a":"foo"}`, `null`, `null`},
+		{`{"a":"foo"}`, `"bar"`, `"bar"`},
+		{`{"e":null}`, `{"a":1}`, `{"e":null,"a":1}`},
+		{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+	}
+	for _, tc := range cases {
+		got, err := MergePatch(mustDecode(t, tc.doc), []byte(tc.patch))
+		if err != nil {
+			t.Errorf("%s + %s: unexpected error %v", tc.doc, tc.patch, err)
+			continue
+		}
+		if want := mustDecode(t, tc.want); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s + %s = %v, want %v", tc.doc, tc.patch, got, want)
+		}
+	}
+}
+
+func TestMergePatchLeavesDocumentUnchanged(t *testing.T) {
+	doc := mustDecode(t, `{"a":{"b":"c"}}`)
+	if _, err := MergePatch(doc, []byte(`{"a":{"b":null}}`)); err != nil {
+		t.Fatal(err)
+	}
+	if want := mustDecode(t, `{"a":{"b":"c"}}`); !reflect.DeepEqual(doc, want) {
+		t.Errorf("document was modified: %v", doc)
+	}
+}
+
+func TestMergePatchRejectsMalformedJSON(t *testing.T) {
+	for _, patch := range []string{`{"a":`, `{"a":1} {"b":2}`, ``} {
+		if _, err := MergePatch(map[string]interface{}{}, []byte(patch)); !errors.Is(err, ErrInvalidPatch) {
+			t.Errorf("%q: expected ErrInvalidPatch, got %v", patch, err)
+		}
+	}
+}
+
+// Examples from RFC 6902 appendix A
+func TestApplyFollowsRFC6902Examples(t *testing.T) {
+	cases := []struct{ doc, patch, want string }{
+		{`{"foo":"bar"}`, `[{"op":"add","path":"/baz","value":"qux"}]`, `{"baz":"qux","foo":"bar"}`},
+		{`{"foo":["bar","baz"]}`, `[{"op":"add","path":"/foo/1","value":"qux"}]`, `{"foo":["bar","qux","baz"]}`},
+		{`{"baz":"qux","foo":"bar"}`, `[{"op":"remove","path":"/baz"}]`, `{"foo":"bar"}`},
+		{`{"foo":["bar","qux","baz"]}`, `[{"op":"remove","path":"/foo/1"}]`, `{"foo":["bar","baz"]}`},
+		{`{"baz":"qux","foo":"bar"}`, `[{"op":"replace","path":"/baz","value":"boo"}]`, `{"baz":"boo","foo":"bar"}`},
+		{`{"foo":{"bar":"baz","waldo":"fred"},"qux":{"corge":"grault"}}`, `[{"op":"move","from":"/foo/waldo","path":"/qux/thud"}]`, `{"foo":{"bar":"baz"},"qux":{"corge":"grault","thud":"fred"}}`},
+		{`{"foo":["all","grass","cows","eat"]}`, `[{"op":"move","from":"/foo/1","path":"/foo/3"}]`, `{"foo":["all","cows","eat","grass"]}`},
+		{`{"baz":"qux","foo":["a",2,"c"]}`, `[{"op":"test","path":"/baz","value":"qux"},{"op":"test","path":"/foo/1","value":2}]`, `{"baz":"qux","foo":["a",2,"c"]}`},
+		{`{"foo":"bar"}`, `[{"op":"add","path":"/child","value":{"grandchild":{}}}]`, `{"foo":"bar","child":{"grandchild":{}}}`},
+		{`{"foo":["bar"]}`, `[{"op":"add","path":"/foo/-","value":["abc","def"]}]`, `{"foo":["bar",["abc","def"]]}`},
+		{`{"foo":null}`, `[{"op":"test","path":"/foo","value":null}]`, `{"foo":null}`},
+		{`{"/":9,"~1":10}`, `[{"op":"test","path":"/~01","value":10}]`, `{"/":9,"~1":10}`},
+		{`{"foo":"bar"}`, `[{"op":"copy","from":"/foo","path":"/baz"}]`, `{"foo":"bar","baz":"bar"}`},
+	}
+	for _, tc := range cases {
+		got, err := Apply(mustDecode(t, tc.doc), []byte(tc.patch))
+		if err != nil {
+			t.Errorf("%s + %s: unexpected error %v", tc.doc, tc.patch, err)
+			continue
+		}
+		if want := mustDecode(t, tc.want); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s + %s = %v, want %v", tc.doc, tc.patch, got, want)
+		}
+	}
+}
+
+func TestApplyReportsFailedTest(t *testing.T) {
+	cases := []string{
+		`[{"op":"test","path":"/baz","value":"bar"}]`,
+		`[{"op":"test","path":"/missing","value":"qux"}]`,
+		`[{"op":"test","path":"/foo","value":"1"}]`,
+	}
+	for _, patch := range cases {
+		_, err := Apply(mustDecode(t, `{"baz":"qux","foo":1}`), []byte(patch))
+		if !errors.Is(err, ErrTestFailed) {
+			t.Errorf("%s: expected ErrTestFailed, got %v", patch, err)
+		}
+	}
+}
+
+func TestApplyRejectsInvalidOperations(t *testing.T) {
+	cases := []string{
+		`{"op":"add","path":"/a","value":1}`,
+		`[{"op":"frobnicate","path":"/a"}]`,
+		`[{"op":"add","path":"a","value":1}]`,
+		`[{"op":"add","path":"/a"}]`,
+		`[{"op":"remove","path":"/missing"}]`,
+		`[{"op":"replace","path":"/missing","value":1}]`,
+		`[{"op":"add","path":"/missing/child","value":1}]`,
+		`[{"op":"add","path":"/list/5","value":1}]`,
+		`[{"op":"add","path":"/list/01","value":1}]`,
+		`[{"op":"move","from":"/obj","path":"/obj/child"}]`,
+	}
+	for _, patch := range cases {
+		_, err := Apply(mustDecode(t, `{"list":[1,2],"obj":{}}`), []byte(patch))
+		if !errors.Is(err, ErrInvalidPatch) {
+			t.Errorf("%s: expected ErrInvalidPatch, got %v", patch, err)
+		}
+	}
+}
+
+func TestApplyIsAllOrNothing(t *testing.T) {
+	doc := mustDecode(t, `{"a":"b","list":[1,2]}`)
+	patch := `[{"op":"replace","path":"/a","value":"c"},{"op":"add","path":"/list/-","value":3},{"op":"test","path":"/a","value":"b"}]`
+	if _, err := Apply(doc, []byte(patch)); !errors.Is(err, ErrTestFailed) {
+		t.Fatalf("expected ErrTestFailed, got %v", err)
+	}
+	if want := mustDecode(t, `{"a":"b","list":[1,2]}`); !reflect.DeepEqual(doc, want) {
+		t.Errorf("document was modified: %v", doc)
+	}
+}
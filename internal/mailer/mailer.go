@@ -0,0 +1,73 @@
+package mailer
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Message is a plain text email
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers email messages. Implementations must be safe for concurrent use.
+type Mailer interface {
+	Send(msg Message) error
+}
+
+// New returns the mailer selected by kind: "log" writes messages to the
+// application log and "file" writes each message to its own file in dir
+func New(kind, from, dir string) (Mailer, error) {
+	switch kind {
+	case "", "log":
+		return &LogMailer{From: from}, nil
+	case "file":
+		if err := os.MkdirAll(dir, 0o700); err != nil {
+			return nil, err
+		}
+		return &FileMailer{From: from, Dir: dir}, nil
+	default:
+		return nil, fmt.Errorf("unknown mailer %q", kind)
+	}
+}
+
+// LogMailer prints messages to the application log. Intended for local development.
+type LogMailer struct {
+	From string
+}
+
+// Send writes the message to the log
+func (m *LogMailer) Send(msg Message) error {
+	log.Printf("mail from=%s to=%s subject=%q\n%s", m.From, msg.To, msg.Subject, msg.Body)
+	return nil
+}
+
+// FileMailer writes every message to a separate .eml file in Dir
+type FileMailer struct {
+	From string
+	Dir  string
+}
+
+// Send writes the message to a new file
+func (m *FileMailer) Send(msg Message) error {
+	now := time.Now().UTC()
+	name := fmt.Sprintf("%s-%s.eml", now.Format("20060102T150405"), uuid.NewString())
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", m.From)
+	fmt.Fprintf(&b, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", msg.Subject)
+	fmt.Fprintf(&b, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	b.WriteString(msg.Body)
+
+	return os.WriteFile(filepath.Join(m.Dir, name), []byte(b.String()), 0o600)
+}
@@ -0,0 +1,106 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// AllergyCategory is the kind of substance a patient reacts to
+type AllergyCategory string
+
+const (
+	AllergyFood        AllergyCategory = "food"
+	AllergyMedication  AllergyCategory = "medication"
+	AllergyEnvironment AllergyCategory = "environment"
+	AllergyBiologic    AllergyCategory = "biologic"
+)
+
+// IsValid reports whether the category is one of the known categories
+func (c AllergyCategory) IsValid() bool {
+	switch c {
+	case AllergyFood, AllergyMedication, AllergyEnvironment, AllergyBiologic:
+		return true
+	}
+	return false
+}
+
+// AllergySeverity is how bad the reaction is
+type AllergySeverity string
+
+const (
+	AllergyMild     AllergySeverity = "mild"
+	AllergyModerate AllergySeverity = "moderate"
+	AllergySevere   AllergySeverity = "severe"
+)
+
+// IsValid reports whether the severity is one of the known severities
+func (s AllergySeverity) IsValid() bool {
+	switch s {
+	case AllergyMild, AllergyModerate, AllergySevere:
+		return true
+	}
+	return false
+}
+
+// AllergyStatus is whether an allergy still applies
+type AllergyStatus string
+
+const (
+	AllergyActive   AllergyStatus = "active"
+	AllergyInactive AllergyStatus = "inactive"
+	AllergyResolved AllergyStatus = "resolved"
+	// AllergyEnteredInError marks an allergy that was recorded by mistake.
+	// It is kept for the record but no longer shown as an allergy.
+	AllergyEnteredInError AllergyStatus = "entered_in_error"
+)
+
+// IsValid reports whether the status is one of the known statuses
+func (s AllergyStatus) IsValid() bool {
+	switch s {
+	case AllergyActive, AllergyInactive, AllergyResolved, AllergyEnteredInError:
+		return true
+	}
+	return false
+}
+
+// Allergy is an allergy or other adverse reaction of a patient to a
+// substance, recorded so prescribers can be warned
+type Allergy struct {
+	ID           uuid.UUID       `gorm:"type:uuid;primary_key;"`
+	PatientID    uuid.UUID       `gorm:"type:uuid;not null;index"`
+	Patient      Patient         `gorm:"foreignKey:PatientID;constraint:OnDelete:CASCADE" json:"-"`
+	Substance    string          `gorm:"size:255;not null"`
+	Category     AllergyCategory `gorm:"type:varchar(20);not null"`
+	Reaction     string          `gorm:"type:text"`
+	Severity     AllergySeverity `gorm:"type:varchar(20);not null"`
+	Status       AllergyStatus   `gorm:"type:varchar(20);not null;index"`
+	RecordedByID uuid.UUID       `gorm:"type:uuid;not null"`
+	RecordedAt   time.Time       `gorm:"not null"`
+	UpdatedByID  *uuid.UUID      `gorm:"type:uuid"`
+	UpdatedAt    time.Time
+	// Version counts the changes to the allergy, starting at 1
+	Version int `gorm:"not null;default:1"`
+}
+
+// BeforeCreate is a GORM hook for the Allergy model
+func (allergy *Allergy) BeforeCreate(tx *gorm.DB) (err error) {
+	allergy.ID = uuid.New()
+	return
+}
+
+// NoKnownAllergies records that a clinician checked with a patient and found
+// no allergies. It is withdrawn when an active allergy is recorded, so a
+// patient never has both.
+type NoKnownAllergies struct {
+	PatientID    uuid.UUID `gorm:"type:uuid;primary_key;"`
+	Patient      Patient   `gorm:"foreignKey:PatientID;constraint:OnDelete:CASCADE" json:"-"`
+	AssertedByID uuid.UUID `gorm:"type:uuid;not null"`
+	AssertedAt   time.Time `gorm:"not null"`
+}
+
+// TableName keeps GORM from pluralizing the table name a second time
+func (NoKnownAllergies) TableName() string {
+	return "no_known_allergies"
+}
@@ -0,0 +1,49 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// APIKey lets an integration authenticate as its owner without a password.
+// The key is only shown once at creation; the database keeps its prefix, used
+// to look it up, and the SHA-256 hash of its secret.
+type APIKey struct {
+	ID         uuid.UUID    `gorm:"type:uuid;primary_key;"`
+	Name       string       `gorm:"size:100;not null"`
+	Prefix     string       `gorm:"size:16;not null;unique"`
+	SecretHash string       `gorm:"size:64;not null" json:"-"`
+	Scopes     []Permission `gorm:"type:text;serializer:json;not null"`
+	// OwnerID is the user, human or service account, the key acts as
+	OwnerID     uuid.UUID `gorm:"type:uuid;not null;index"`
+	Owner       User      `gorm:"foreignKey:OwnerID" json:"-"`
+	CreatedByID uuid.UUID `gorm:"type:uuid;not null"`
+	ExpiresAt   *time.Time
+	LastUsedAt  *time.Time
+	LastUsedIP  string `gorm:"size:45"`
+	RevokedAt   *time.Time
+	CreatedAt   time.Time
+}
+
+// BeforeCreate is a GORM hook for the APIKey model
+func (key *APIKey) BeforeCreate(tx *gorm.DB) (err error) {
+	key.ID = uuid.New()
+	return
+}
+
+// IsActive reports whether the key can be used at the given time
+func (key *APIKey) IsActive(now time.Time) bool {
+	return key.RevokedAt == nil && (key.ExpiresAt == nil || now.Before(*key.ExpiresAt))
+}
+
+// HasScope reports whether the key was granted the permission
+func (key *APIKey) HasScope(p Permission) bool {
+	for _, scope := range key.Scopes {
+		if scope == p {
+			return true
+		}
+	}
+	return false
+}
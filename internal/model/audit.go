@@ -0,0 +1,122 @@
+package model
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// AuditGenesisHash is the previous hash of the first entry in the audit log
+var AuditGenesisHash = strings.Repeat("0", 64)
+
+// Audit outcomes
+const (
+	AuditSuccess = "success"
+	AuditDenied  = "denied"
+)
+
+// FieldChange is the before and after value of one field in an audited
+// change. Values of clinical fields are not copied into the audit log; only
+// the fact that they changed is.
+type FieldChange struct {
+	Old      interface{} `json:"old,omitempty"`
+	New      interface{} `json:"new,omitempty"`
+	Redacted bool        `json:"redacted,omitempty"`
+}
+
+// AuditEntry records one access to or change of patient data. Entries are
+// append-only and chained: each one's Hash covers its content and the Hash of
+// the entry before it, so editing or removing an entry breaks the chain.
+type AuditEntry struct {
+	ID                 uuid.UUID  `gorm:"type:uuid;primary_key;"`
+	Sequence           int64      `gorm:"not null;uniqueIndex"`
+	ActorID            uuid.UUID  `gorm:"type:uuid;not null;index"`
+	ActorRole          Role       `gorm:"type:varchar(20);not null"`
+	Action             string     `gorm:"size:50;not null;index"`
+	Outcome            string     `gorm:"size:20;not null"`
+	PatientID          *uuid.UUID `gorm:"type:uuid;index"`
+	BreakGlassAccessID *uuid.UUID `gorm:"type:uuid"`
+	RequestID          string     `gorm:"size:64"`
+	ClientIP           string     `gorm:"size:45"`
+	// Changes is the field-level diff as JSON text. It is kept exactly as it
+	// was hashed.
+	Changes   string    `gorm:"type:text"`
+	PrevHash  string    `gorm:"size:64;not null"`
+	Hash      string    `gorm:"size:64;not null;unique"`
+	CreatedAt time.Time `gorm:"not null;index"`
+}
+
+// BeforeCreate is a GORM hook for the AuditEntry model. The ID is part of
+// the hash, so one that was set before hashing is kept.
+func (entry *AuditEntry) BeforeCreate(tx *gorm.DB) (err error) {
+	if entry.ID == uuid.Nil {
+		entry.ID = uuid.New()
+	}
+	return
+}
+
+// ComputeHash returns the SHA-256 over the entry's content and PrevHash.
+// Every field is length-prefixed so no two entries hash the same input.
+func (entry *AuditEntry) ComputeHash() string {
+	fields := []string{
+		entry.PrevHash,
+		fmt.Sprint(entry.Sequence),
+		entry.ID.String(),
+		entry.CreatedAt.UTC().Format(time.RFC3339Nano),
+		entry.ActorID.String(),
+		string(entry.ActorRole),
+		entry.Action,
+		entry.Outcome,
+		optionalUUID(entry.PatientID),
+		optionalUUID(entry.BreakGlassAccessID),
+		entry.RequestID,
+		entry.ClientIP,
+		entry.Changes,
+	}
+	h := sha256.New()
+	for _, field := range fields {
+		fmt.Fprintf(h, "%d:%s;", len(field), field)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// optionalUUID formats an optional ID, empty when it is not set
+func optionalUUID(id *uuid.UUID) string {
+	if id == nil {
+		return ""
+	}
+	return id.String()
+}
+
+// AuditChain checks audit entries one at a time in sequence order
+type AuditChain struct {
+	lastHash     string
+	lastSequence int64
+}
+
+// NewAuditChain starts checking from the first entry of the audit log
+func NewAuditChain() *AuditChain {
+	return &AuditChain{lastHash: AuditGenesisHash}
+}
+
+// Next checks that the entry directly follows the previous one and has not
+// been altered since it was written
+func (c *AuditChain) Next(entry *AuditEntry) error {
+	if entry.Sequence != c.lastSequence+1 {
+		return fmt.Errorf("entry %d follows entry %d, entries are missing", entry.Sequence, c.lastSequence)
+	}
+	if entry.PrevHash != c.lastHash {
+		return fmt.Errorf("entry %d does not link to the entry before it", entry.Sequence)
+	}
+	if entry.ComputeHash() != entry.Hash {
+		return fmt.Errorf("entry %d has been altered", entry.Sequence)
+	}
+	c.lastHash = entry.Hash
+	c.lastSequence = entry.Sequence
+	return nil
+}
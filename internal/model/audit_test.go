@@ -0,0 +1,84 @@
+package model
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// buildChain returns n entries chained the way the audit repository writes them
+func buildChain(n int) []AuditEntry {
+	entries := make([]AuditEntry, n)
+	prev := AuditGenesisHash
+	for i := range entries {
+		patientID := uuid.New()
+		entries[i] = AuditEntry{
+			ID:        uuid.New(),
+			Sequence:  int64(i + 1),
+			ActorID:   uuid.New(),
+			ActorRole: Doctor,
+			Action:    "patient.view",
+			Outcome:   AuditSuccess,
+			PatientID: &patientID,
+			RequestID: "req-1",
+			ClientIP:  "10.0.0.1",
+			PrevHash:  prev,
+			CreatedAt: time.Date(2024, 1, 1, 12, 0, i, 0, time.UTC),
+		}
+		entries[i].Hash = entries[i].ComputeHash()
+		prev = entries[i].Hash
+	}
+	return entries
+}
+
+// verify runs entries through a fresh chain and returns the first error
+func verify(entries []AuditEntry) error {
+	chain := NewAuditChain()
+	for i := range entries {
+		if err := chain.Next(&entries[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestAuditChainAcceptsIntactChain(t *testing.T) {
+	if err := verify(buildChain(5)); err != nil {
+		t.Fatalf("expected intact chain to verify, got %v", err)
+	}
+}
+
+func TestAuditChainDetectsAlteredEntry(t *testing.T) {
+	entries := buildChain(5)
+	entries[2].Outcome = AuditDenied
+	if err := verify(entries); err == nil {
+		t.Fatal("expected altered entry to be detected")
+	}
+}
+
+func TestAuditChainDetectsRemovedEntry(t *testing.T) {
+	entries := buildChain(5)
+	entries = append(entries[:2], entries[3:]...)
+	if err := verify(entries); err == nil {
+		t.Fatal("expected removed entry to be detected")
+	}
+}
+
+func TestAuditChainDetectsRewrittenHistory(t *testing.T) {
+	entries := buildChain(5)
+	// Re-hashing an altered entry still breaks the link to the next one
+	entries[1].Action = "patient.list"
+	entries[1].Hash = entries[1].ComputeHash()
+	if err := verify(entries); err == nil {
+		t.Fatal("expected rewritten entry to be detected")
+	}
+}
+
+func TestAuditHashSurvivesTimeZone(t *testing.T) {
+	entry := buildChain(1)[0]
+	entry.CreatedAt = entry.CreatedAt.In(time.FixedZone("IST", 5*3600+1800))
+	if entry.ComputeHash() != entry.Hash {
+		t.Fatal("expected hash to ignore the time zone the timestamp is read in")
+	}
+}
@@ -0,0 +1,67 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// BreakGlassOutcome is an admin's verdict on a break-glass access
+type BreakGlassOutcome string
+
+const (
+	BreakGlassJustified   BreakGlassOutcome = "justified"
+	BreakGlassUnjustified BreakGlassOutcome = "unjustified"
+)
+
+// IsValid reports whether the outcome is one of the known outcomes
+func (o BreakGlassOutcome) IsValid() bool {
+	return o == BreakGlassJustified || o == BreakGlassUnjustified
+}
+
+// BreakGlassAccess is an emergency grant that lets a clinician open the chart
+// of a patient whose care team they are not on. It is time-boxed, needs a
+// reason, and waits in the review queue until an admin signs it off.
+type BreakGlassAccess struct {
+	ID           uuid.UUID         `gorm:"type:uuid;primary_key;"`
+	PatientID    uuid.UUID         `gorm:"type:uuid;not null;index"`
+	Patient      Patient           `gorm:"foreignKey:PatientID;constraint:OnDelete:CASCADE" json:"-"`
+	UserID       uuid.UUID         `gorm:"type:uuid;not null;index"`
+	User         User              `gorm:"foreignKey:UserID" json:"-"`
+	Reason       string            `gorm:"type:text;not null"`
+	ClientIP     string            `gorm:"size:45"`
+	ExpiresAt    time.Time         `gorm:"not null"`
+	ReviewedAt   *time.Time        `gorm:"index"`
+	ReviewedByID *uuid.UUID        `gorm:"type:uuid"`
+	Outcome      BreakGlassOutcome `gorm:"type:varchar(20)"`
+	ReviewNote   string            `gorm:"type:text"`
+	Events       []BreakGlassEvent `gorm:"foreignKey:AccessID;constraint:OnDelete:CASCADE" json:"-"`
+	CreatedAt    time.Time
+}
+
+// BeforeCreate is a GORM hook for the BreakGlassAccess model
+func (access *BreakGlassAccess) BeforeCreate(tx *gorm.DB) (err error) {
+	access.ID = uuid.New()
+	return
+}
+
+// IsActive reports whether the grant still opens the chart at the given time
+func (access *BreakGlassAccess) IsActive(now time.Time) bool {
+	return now.Before(access.ExpiresAt)
+}
+
+// BreakGlassEvent records one use of a break-glass grant, so a reviewer can
+// see what was done with it
+type BreakGlassEvent struct {
+	ID        uuid.UUID `gorm:"type:uuid;primary_key;"`
+	AccessID  uuid.UUID `gorm:"type:uuid;not null;index"`
+	Action    string    `gorm:"size:50;not null"`
+	CreatedAt time.Time
+}
+
+// BeforeCreate is a GORM hook for the BreakGlassEvent model
+func (event *BreakGlassEvent) BeforeCreate(tx *gorm.DB) (err error) {
+	event.ID = uuid.New()
+	return
+}
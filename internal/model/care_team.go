@@ -0,0 +1,54 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// CareTeamRole is the part a staff member plays in a patient's care
+type CareTeamRole string
+
+const (
+	CareTeamAttending  CareTeamRole = "attending"
+	CareTeamConsulting CareTeamRole = "consulting"
+	CareTeamNurse      CareTeamRole = "nurse"
+)
+
+// IsValid reports whether the care team role is one of the known roles
+func (r CareTeamRole) IsValid() bool {
+	switch r {
+	case CareTeamAttending, CareTeamConsulting, CareTeamNurse:
+		return true
+	}
+	return false
+}
+
+// AllowsRole reports whether a user with the given role may fill the care
+// team role: doctors attend and consult, nurses nurse
+func (r CareTeamRole) AllowsRole(role Role) bool {
+	if r == CareTeamNurse {
+		return role == Nurse
+	}
+	return role == Doctor
+}
+
+// CareTeamMember assigns a clinician to a patient. Clinicians can only see
+// and change the patients they are assigned to.
+type CareTeamMember struct {
+	ID           uuid.UUID    `gorm:"type:uuid;primary_key;"`
+	PatientID    uuid.UUID    `gorm:"type:uuid;not null;uniqueIndex:idx_care_team_member"`
+	Patient      Patient      `gorm:"foreignKey:PatientID;constraint:OnDelete:CASCADE" json:"-"`
+	UserID       uuid.UUID    `gorm:"type:uuid;not null;uniqueIndex:idx_care_team_member;index"`
+	User         User         `gorm:"foreignKey:UserID" json:"-"`
+	Role         CareTeamRole `gorm:"type:varchar(20);not null"`
+	AssignedByID uuid.UUID    `gorm:"type:uuid;not null"`
+	CreatedAt    time.Time
+}
+
+// BeforeCreate is a GORM hook for the CareTeamMember model
+func (member *CareTeamMember) BeforeCreate(tx *gorm.DB) (err error) {
+	member.ID = uuid.New()
+	return
+}
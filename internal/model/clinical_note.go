@@ -0,0 +1,106 @@
+package model
+
+import (
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// EncounterType is the kind of visit a clinical note documents
+type EncounterType string
+
+const (
+	EncounterOutpatient EncounterType = "outpatient"
+	EncounterInpatient  EncounterType = "inpatient"
+	EncounterEmergency  EncounterType = "emergency"
+	EncounterTelehealth EncounterType = "telehealth"
+)
+
+// IsValid reports whether the encounter type is one of the known types
+func (t EncounterType) IsValid() bool {
+	switch t {
+	case EncounterOutpatient, EncounterInpatient, EncounterEmergency, EncounterTelehealth:
+		return true
+	}
+	return false
+}
+
+// NoteStatus is where a clinical note is in its lifecycle
+type NoteStatus string
+
+const (
+	// NoteDraft notes can still be edited or discarded by their author
+	NoteDraft NoteStatus = "draft"
+	// NoteSigned notes are part of the legal record and can no longer change
+	NoteSigned NoteStatus = "signed"
+	// NoteAmended notes are signed notes that have been corrected or
+	// extended with addenda
+	NoteAmended NoteStatus = "amended"
+)
+
+// IsValid reports whether the status is one of the known statuses
+func (s NoteStatus) IsValid() bool {
+	switch s {
+	case NoteDraft, NoteSigned, NoteAmended:
+		return true
+	}
+	return false
+}
+
+// ClinicalNote documents one encounter with a patient in SOAP form:
+// Subjective (what the patient reports), Objective (what was observed and
+// measured), Assessment (the clinician's conclusions) and Plan. Once signed
+// the note cannot change; corrections are added as addenda.
+type ClinicalNote struct {
+	ID            uuid.UUID     `gorm:"type:uuid;primary_key;"`
+	PatientID     uuid.UUID     `gorm:"type:uuid;not null;index:idx_clinical_notes_timeline,priority:1"`
+	Patient       Patient       `gorm:"foreignKey:PatientID;constraint:OnDelete:CASCADE" json:"-"`
+	AuthorID      uuid.UUID     `gorm:"type:uuid;not null;index"`
+	Author        User          `gorm:"foreignKey:AuthorID" json:"-"`
+	EncounterType EncounterType `gorm:"type:varchar(20);not null"`
+	EncounterAt   time.Time     `gorm:"not null;index:idx_clinical_notes_timeline,priority:2"`
+	Subjective    string        `gorm:"type:text"`
+	Objective     string        `gorm:"type:text"`
+	Assessment    string        `gorm:"type:text"`
+	Plan          string        `gorm:"type:text"`
+	Status        NoteStatus    `gorm:"type:varchar(20);not null"`
+	SignedAt      *time.Time
+	Addenda       []NoteAddendum `gorm:"foreignKey:NoteID;constraint:OnDelete:CASCADE"`
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+// BeforeCreate is a GORM hook for the ClinicalNote model
+func (note *ClinicalNote) BeforeCreate(tx *gorm.DB) (err error) {
+	note.ID = uuid.New()
+	return
+}
+
+// IsEmpty reports whether none of the SOAP sections has any text
+func (note *ClinicalNote) IsEmpty() bool {
+	return strings.TrimSpace(note.Subjective) == "" && strings.TrimSpace(note.Objective) == "" &&
+		strings.TrimSpace(note.Assessment) == "" && strings.TrimSpace(note.Plan) == ""
+}
+
+// NoteAddendum corrects or adds to a signed clinical note. Addenda are
+// never changed once written.
+type NoteAddendum struct {
+	ID        uuid.UUID `gorm:"type:uuid;primary_key;"`
+	NoteID    uuid.UUID `gorm:"type:uuid;not null;index"`
+	AuthorID  uuid.UUID `gorm:"type:uuid;not null"`
+	Text      string    `gorm:"type:text;not null"`
+	CreatedAt time.Time
+}
+
+// BeforeCreate is a GORM hook for the NoteAddendum model
+func (addendum *NoteAddendum) BeforeCreate(tx *gorm.DB) (err error) {
+	addendum.ID = uuid.New()
+	return
+}
+
+// TableName gives the table the Latin plural
+func (NoteAddendum) TableName() string {
+	return "note_addenda"
+}
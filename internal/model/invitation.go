@@ -0,0 +1,28 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Invitation allows a person to create a staff account with a role chosen by an admin.
+// Only the SHA-256 hash of the invite token is stored.
+type Invitation struct {
+	ID          uuid.UUID `gorm:"type:uuid;primary_key;"`
+	Email       string    `gorm:"size:255;not null;index"`
+	Role        Role      `gorm:"type:varchar(20);not null"`
+	TokenHash   string    `gorm:"size:64;not null;unique" json:"-"`
+	ExpiresAt   time.Time `gorm:"not null"`
+	AcceptedAt  *time.Time
+	InvitedByID uuid.UUID // Foreign Key
+	InvitedBy   User      `gorm:"foreignKey:InvitedByID" json:"-"`
+	CreatedAt   time.Time
+}
+
+// BeforeCreate is a GORM hook for the Invitation model
+func (invitation *Invitation) BeforeCreate(tx *gorm.DB) (err error) {
+	invitation.ID = uuid.New()
+	return
+}
@@ -0,0 +1,57 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// LoginAttempt records a single login attempt, successful or not
+type LoginAttempt struct {
+	ID        uuid.UUID  `gorm:"type:uuid;primary_key;"`
+	Email     string     `gorm:"size:255;not null;index"`
+	UserID    *uuid.UUID `gorm:"type:uuid;index"`
+	ClientIP  string     `gorm:"size:45;index"`
+	Success   bool       `gorm:"not null"`
+	Reason    string     `gorm:"size:50"`
+	CreatedAt time.Time  `gorm:"index"`
+}
+
+// BeforeCreate is a GORM hook for the LoginAttempt model
+func (attempt *LoginAttempt) BeforeCreate(tx *gorm.DB) (err error) {
+	attempt.ID = uuid.New()
+	return
+}
+
+// LockoutScope is what a LoginThrottle counts failures for
+type LockoutScope string
+
+const (
+	LockoutScopeAccount LockoutScope = "account"
+	LockoutScopeIP      LockoutScope = "ip"
+)
+
+// LoginThrottle counts recent failed logins for one account or one client IP
+// and holds the time until which further attempts are refused
+type LoginThrottle struct {
+	ID            uuid.UUID    `gorm:"type:uuid;primary_key;"`
+	Scope         LockoutScope `gorm:"type:varchar(20);not null;uniqueIndex:idx_login_throttle_scope_subject"`
+	Subject       string       `gorm:"size:255;not null;uniqueIndex:idx_login_throttle_scope_subject"`
+	Failures      int          `gorm:"not null;default:0"`
+	LastFailureAt time.Time
+	LockedUntil   *time.Time
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+// BeforeCreate is a GORM hook for the LoginThrottle model
+func (throttle *LoginThrottle) BeforeCreate(tx *gorm.DB) (err error) {
+	throttle.ID = uuid.New()
+	return
+}
+
+// IsLocked reports whether attempts are refused at the given time
+func (throttle *LoginThrottle) IsLocked(now time.Time) bool {
+	return throttle.LockedUntil != nil && now.Before(*throttle.LockedUntil)
+}
@@ -0,0 +1,45 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// RecoveryCode is a single-use code that replaces a TOTP code when the
+// authenticator is lost. Only the SHA-256 hash of the code is stored.
+type RecoveryCode struct {
+	ID        uuid.UUID `gorm:"type:uuid;primary_key;"`
+	UserID    uuid.UUID `gorm:"type:uuid;not null;index"`
+	User      User      `gorm:"foreignKey:UserID" json:"-"`
+	CodeHash  string    `gorm:"size:64;not null;unique" json:"-"`
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+// BeforeCreate is a GORM hook for the RecoveryCode model
+func (code *RecoveryCode) BeforeCreate(tx *gorm.DB) (err error) {
+	code.ID = uuid.New()
+	return
+}
+
+// MFAChallenge is issued after a correct password when a second factor is
+// needed. It is exchanged for a session together with a valid code.
+// Only the SHA-256 hash of the challenge token is stored.
+type MFAChallenge struct {
+	ID        uuid.UUID `gorm:"type:uuid;primary_key;"`
+	UserID    uuid.UUID `gorm:"type:uuid;not null;index"`
+	User      User      `gorm:"foreignKey:UserID" json:"-"`
+	TokenHash string    `gorm:"size:64;not null;unique" json:"-"`
+	Attempts  int       `gorm:"not null;default:0"`
+	ExpiresAt time.Time `gorm:"not null"`
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+// BeforeCreate is a GORM hook for the MFAChallenge model
+func (challenge *MFAChallenge) BeforeCreate(tx *gorm.DB) (err error) {
+	challenge.ID = uuid.New()
+	return
+}
@@ -0,0 +1,24 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// PasswordHistory keeps the hash of a password a user has set, so it cannot
+// be reused for a while
+type PasswordHistory struct {
+	ID           uuid.UUID `gorm:"type:uuid;primary_key;"`
+	UserID       uuid.UUID `gorm:"type:uuid;not null;index"`
+	User         User      `gorm:"foreignKey:UserID" json:"-"`
+	PasswordHash string    `gorm:"not null" json:"-"`
+	CreatedAt    time.Time `gorm:"index"`
+}
+
+// BeforeCreate is a GORM hook for the PasswordHistory model
+func (entry *PasswordHistory) BeforeCreate(tx *gorm.DB) (err error) {
+	entry.ID = uuid.New()
+	return
+}
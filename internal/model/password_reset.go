@@ -0,0 +1,26 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// PasswordResetToken is a single-use token sent by email to reset a forgotten password.
+// Only the SHA-256 hash of the token is stored.
+type PasswordResetToken struct {
+	ID        uuid.UUID `gorm:"type:uuid;primary_key;"`
+	UserID    uuid.UUID `gorm:"type:uuid;not null;index"`
+	User      User      `gorm:"foreignKey:UserID" json:"-"`
+	TokenHash string    `gorm:"size:64;not null;unique" json:"-"`
+	ExpiresAt time.Time `gorm:"not null"`
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+// BeforeCreate is a GORM hook for the PasswordResetToken model
+func (token *PasswordResetToken) BeforeCreate(tx *gorm.DB) (err error) {
+	token.ID = uuid.New()
+	return
+}
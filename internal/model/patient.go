@@ -1,24 +1,54 @@
 package model
 
 import (
+	"slices"
 	"time"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
-// Patient represents a patient record
+// Editable patient fields, named as in the API
+const (
+	PatientFieldFullName       = "full_name"
+	PatientFieldDateOfBirth    = "date_of_birth"
+	PatientFieldAddress        = "address"
+	PatientFieldContactNumber  = "contact_number"
+	PatientFieldMedicalHistory = "medical_history"
+)
+
+// PatientEditableFields lists the patient fields each role may change.
+// Roles that are not listed may not change any field.
+var PatientEditableFields = map[Role][]string{
+	Receptionist: {PatientFieldFullName, PatientFieldDateOfBirth, PatientFieldAddress, PatientFieldContactNumber},
+	Doctor:       {PatientFieldFullName, PatientFieldDateOfBirth, PatientFieldAddress, PatientFieldContactNumber, PatientFieldMedicalHistory},
+	Nurse:        {PatientFieldAddress, PatientFieldContactNumber, PatientFieldMedicalHistory},
+	Admin:        {PatientFieldFullName, PatientFieldDateOfBirth, PatientFieldAddress, PatientFieldContactNumber},
+}
+
+// CanEditPatientField reports whether the role may change the patient field
+func (r Role) CanEditPatientField(field string) bool {
+	return slices.Contains(PatientEditableFields[r], field)
+}
+
+// Patient represents a patient record. Deleting a patient only marks the
+// record as deleted; it is purged once the retention period has passed.
 type Patient struct {
-	ID             uuid.UUID `gorm:"type:uuid;primary_key;"`
-	FullName       string    `gorm:"size:255;not null"`
-	DateOfBirth    time.Time
+	ID             uuid.UUID `gorm:"type:uuid;primary_key;index:idx_patients_name,priority:2;index:idx_patients_created,priority:2;index:idx_patients_born,priority:2"`
+	MRN            *string   `gorm:"size:32;uniqueIndex"` // medical record number, nil until backfilled
+	FullName       string    `gorm:"size:255;not null;index:idx_patients_name,priority:1"`
+	DateOfBirth    time.Time `gorm:"index:idx_patients_born,priority:1"`
 	Address        string
 	ContactNumber  string    `gorm:"size:20"`
 	MedicalHistory string    `gorm:"type:text"`
-	RegisteredByID uuid.UUID // Foreign Key
+	RegisteredByID uuid.UUID `gorm:"index"` // Foreign Key
 	RegisteredBy   User      `gorm:"foreignKey:RegisteredByID"`
-	CreatedAt      time.Time
+	Version        int       `gorm:"not null;default:1"` // latest version; writes must name the one they were based on
+	CreatedAt      time.Time `gorm:"index:idx_patients_created,priority:1"`
 	UpdatedAt      time.Time
+	DeletedAt      gorm.DeletedAt `gorm:"index"`
+	DeletedByID    *uuid.UUID     `gorm:"type:uuid"`
+	DeletionReason string         `gorm:"size:500"`
 }
 
 // BeforeCreate is a GORM hook for the Patient model
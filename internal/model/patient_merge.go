@@ -0,0 +1,59 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// PatientMerge records that a duplicate patient record was merged into
+// another. It is kept as a tombstone, so the merged record's ID keeps leading
+// to the patient it became part of.
+type PatientMerge struct {
+	ID              uuid.UUID `gorm:"type:uuid;primary_key;"`
+	SourcePatientID uuid.UUID `gorm:"type:uuid;not null;uniqueIndex"` // the duplicate, deleted by the merge
+	TargetPatientID uuid.UUID `gorm:"type:uuid;not null;index"`       // the patient that remains
+	TargetVersion   int       `gorm:"not null"`                       // the target's version written by the merge
+	MergedByID      uuid.UUID `gorm:"type:uuid;not null"`
+	Reason          string    `gorm:"size:500;not null"`
+	// How many related rows were moved over to the target
+	MovedCareTeamMembers    int       `gorm:"not null"`
+	MovedBreakGlassAccesses int       `gorm:"not null"`
+	MovedAllergies          int       `gorm:"not null;default:0"`
+	MovedClinicalNotes      int       `gorm:"not null;default:0"`
+	MovedVitalSigns         int       `gorm:"not null;default:0"`
+	CreatedAt               time.Time `gorm:"index"`
+}
+
+// BeforeCreate is a GORM hook for the PatientMerge model
+func (merge *PatientMerge) BeforeCreate(tx *gorm.DB) (err error) {
+	merge.ID = uuid.New()
+	return
+}
+
+// MergePatientDetails returns the details of target after source is merged
+// into it. The target's name and date of birth are kept, blank contact
+// details are filled in from the source, and the source's medical history is
+// appended to the target's under a note saying where it came from.
+func MergePatientDetails(target, source *Patient) Patient {
+	merged := *target
+	if strings.TrimSpace(merged.Address) == "" {
+		merged.Address = source.Address
+	}
+	if strings.TrimSpace(merged.ContactNumber) == "" {
+		merged.ContactNumber = source.ContactNumber
+	}
+	history := strings.TrimSpace(source.MedicalHistory)
+	switch {
+	case history == "" || history == strings.TrimSpace(merged.MedicalHistory):
+	case strings.TrimSpace(merged.MedicalHistory) == "":
+		merged.MedicalHistory = source.MedicalHistory
+	default:
+		merged.MedicalHistory = fmt.Sprintf("%s\n\n--- Merged from patient record %s ---\n%s",
+			strings.TrimRight(merged.MedicalHistory, "\n"), source.ID, history)
+	}
+	return merged
+}
@@ -0,0 +1,46 @@
+package model
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestMergePatientDetailsKeepsTargetAndFillsBlanks(t *testing.T) {
+	target := &Patient{ID: uuid.New(), FullName: "John Smith", Address: "", ContactNumber: "555-0100"}
+	source := &Patient{ID: uuid.New(), FullName: "Jon Smith", Address: "1 Baker St", ContactNumber: "555-0199"}
+
+	merged := MergePatientDetails(target, source)
+	if merged.ID != target.ID || merged.FullName != "John Smith" {
+		t.Fatalf("expected the target's identity to be kept, got %s %q", merged.ID, merged.FullName)
+	}
+	if merged.Address != "1 Baker St" {
+		t.Errorf("expected the blank address to be filled in, got %q", merged.Address)
+	}
+	if merged.ContactNumber != "555-0100" {
+		t.Errorf("expected the target's contact number to be kept, got %q", merged.ContactNumber)
+	}
+}
+
+func TestMergePatientDetailsCombinesMedicalHistory(t *testing.T) {
+	target := &Patient{ID: uuid.New(), MedicalHistory: "Asthma\n"}
+	source := &Patient{ID: uuid.New(), MedicalHistory: "Penicillin allergy"}
+
+	merged := MergePatientDetails(target, source)
+	if !strings.HasPrefix(merged.MedicalHistory, "Asthma\n\n") || !strings.HasSuffix(merged.MedicalHistory, "\nPenicillin allergy") {
+		t.Fatalf("expected both histories, got %q", merged.MedicalHistory)
+	}
+	if !strings.Contains(merged.MedicalHistory, source.ID.String()) {
+		t.Errorf("expected the merged history to name the source record, got %q", merged.MedicalHistory)
+	}
+
+	same := MergePatientDetails(target, &Patient{ID: uuid.New(), MedicalHistory: "Asthma"})
+	if same.MedicalHistory != target.MedicalHistory {
+		t.Errorf("expected an identical history not to be repeated, got %q", same.MedicalHistory)
+	}
+	empty := MergePatientDetails(&Patient{ID: uuid.New()}, source)
+	if empty.MedicalHistory != source.MedicalHistory {
+		t.Errorf("expected the source history to be taken over as is, got %q", empty.MedicalHistory)
+	}
+}
@@ -0,0 +1,44 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// PatientPurge is the report of one run of the retention purge. It keeps
+// which deleted patients were removed for good, but none of their details.
+type PatientPurge struct {
+	ID            uuid.UUID `gorm:"type:uuid;primary_key;"`
+	Cutoff        time.Time `gorm:"not null"` // patients deleted before this were purged
+	RetentionDays int       `gorm:"not null"`
+	// TriggeredByID is the admin who ran the purge, nil for a scheduled run
+	TriggeredByID *uuid.UUID      `gorm:"type:uuid"`
+	PurgedCount   int             `gorm:"not null"`
+	Patients      []PurgedPatient `gorm:"foreignKey:PurgeID;constraint:OnDelete:CASCADE"`
+	CreatedAt     time.Time       `gorm:"index"`
+}
+
+// BeforeCreate is a GORM hook for the PatientPurge model
+func (purge *PatientPurge) BeforeCreate(tx *gorm.DB) (err error) {
+	purge.ID = uuid.New()
+	return
+}
+
+// PurgedPatient is a patient removed by a purge
+type PurgedPatient struct {
+	ID             uuid.UUID  `gorm:"type:uuid;primary_key;"`
+	PurgeID        uuid.UUID  `gorm:"type:uuid;not null;index"`
+	PatientID      uuid.UUID  `gorm:"type:uuid;not null;index"`
+	RegisteredAt   time.Time  `gorm:"not null"`
+	DeletedAt      time.Time  `gorm:"not null"`
+	DeletedByID    *uuid.UUID `gorm:"type:uuid"`
+	DeletionReason string     `gorm:"size:500"`
+}
+
+// BeforeCreate is a GORM hook for the PurgedPatient model
+func (purged *PurgedPatient) BeforeCreate(tx *gorm.DB) (err error) {
+	purged.ID = uuid.New()
+	return
+}
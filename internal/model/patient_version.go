@@ -0,0 +1,71 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// PatientChangeType is what produced a version of a patient record
+type PatientChangeType string
+
+const (
+	PatientCreated  PatientChangeType = "create"
+	PatientUpdated  PatientChangeType = "update"
+	PatientRestored PatientChangeType = "restore"
+	// PatientMerged marks the version written when a duplicate record was
+	// merged into the patient
+	PatientMerged PatientChangeType = "merge"
+	// PatientImported marks the first version of a patient that existed
+	// before versions were kept
+	PatientImported PatientChangeType = "import"
+)
+
+// PatientVersion is a snapshot of a patient record as it was after a change.
+// Versions are never changed; restoring an old version writes a new one.
+type PatientVersion struct {
+	ID             uuid.UUID `gorm:"type:uuid;primary_key;"`
+	PatientID      uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_patient_version"`
+	Patient        Patient   `gorm:"foreignKey:PatientID;constraint:OnDelete:CASCADE" json:"-"`
+	Version        int       `gorm:"not null;uniqueIndex:idx_patient_version"`
+	FullName       string    `gorm:"size:255;not null"`
+	DateOfBirth    time.Time
+	Address        string
+	ContactNumber  string            `gorm:"size:20"`
+	MedicalHistory string            `gorm:"type:text"`
+	ChangeType     PatientChangeType `gorm:"type:varchar(20);not null"`
+	// RestoredFrom is the version that was restored, for restore versions
+	RestoredFrom *int
+	ChangedByID  *uuid.UUID `gorm:"type:uuid"`
+	CreatedAt    time.Time  `gorm:"not null;index"`
+}
+
+// BeforeCreate is a GORM hook for the PatientVersion model
+func (version *PatientVersion) BeforeCreate(tx *gorm.DB) (err error) {
+	version.ID = uuid.New()
+	return
+}
+
+// Snapshot copies the patient's current details into the version
+func (version *PatientVersion) Snapshot(patient *Patient) {
+	version.PatientID = patient.ID
+	version.FullName = patient.FullName
+	version.DateOfBirth = patient.DateOfBirth
+	version.Address = patient.Address
+	version.ContactNumber = patient.ContactNumber
+	version.MedicalHistory = patient.MedicalHistory
+}
+
+// Record returns the patient record as it was at this version
+func (version *PatientVersion) Record() Patient {
+	return Patient{
+		ID:             version.PatientID,
+		FullName:       version.FullName,
+		DateOfBirth:    version.DateOfBirth,
+		Address:        version.Address,
+		ContactNumber:  version.ContactNumber,
+		MedicalHistory: version.MedicalHistory,
+		UpdatedAt:      version.CreatedAt,
+	}
+}
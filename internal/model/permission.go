@@ -0,0 +1,65 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Permission is a single action a caller may be allowed to perform
+type Permission string
+
+const (
+	PermissionPatientRead   Permission = "patient:read"
+	PermissionPatientWrite  Permission = "patient:write"
+	PermissionPatientDelete Permission = "patient:delete"
+	// PermissionCareTeamManage allows assigning staff to a patient's care team
+	PermissionCareTeamManage Permission = "care_team:manage"
+)
+
+// AllPermissions lists every known permission
+var AllPermissions = []Permission{
+	PermissionPatientRead,
+	PermissionPatientWrite,
+	PermissionPatientDelete,
+	PermissionCareTeamManage,
+}
+
+// DefaultRolePermissions is what each role may do until an admin changes it.
+// Every pair is seeded into the role_permissions table once, so permissions
+// and roles added by an upgrade get their defaults.
+var DefaultRolePermissions = map[Role][]Permission{
+	Receptionist: {PermissionPatientRead, PermissionPatientWrite, PermissionPatientDelete, PermissionCareTeamManage},
+	Doctor:       {PermissionPatientRead, PermissionPatientWrite},
+	Nurse:        {PermissionPatientRead, PermissionPatientWrite},
+	Admin:        {PermissionCareTeamManage},
+}
+
+// IsValid reports whether the permission is one of the known permissions
+func (p Permission) IsValid() bool {
+	for _, known := range AllPermissions {
+		if p == known {
+			return true
+		}
+	}
+	return false
+}
+
+// RolePermission grants a permission to every user with the role. A row
+// whose grant an admin has taken away is kept with Granted unset, so the
+// default is not seeded again.
+type RolePermission struct {
+	ID         uuid.UUID  `gorm:"type:uuid;primary_key;"`
+	Role       Role       `gorm:"type:varchar(20);not null;uniqueIndex:idx_role_permission"`
+	Permission Permission `gorm:"type:varchar(50);not null;uniqueIndex:idx_role_permission"`
+	Granted    bool       `gorm:"not null;default:true"`
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+// BeforeCreate is a GORM hook for the RolePermission model
+func (rp *RolePermission) BeforeCreate(tx *gorm.DB) (err error) {
+	rp.ID = uuid.New()
+	return
+}
@@ -0,0 +1,53 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Session represents a login on a single device. Every refresh token issued
+// after that login belongs to the same session, so revoking the session
+// revokes the whole refresh token family at once.
+type Session struct {
+	ID         uuid.UUID `gorm:"type:uuid;primary_key;"`
+	UserID     uuid.UUID `gorm:"type:uuid;not null;index"`
+	User       User      `gorm:"foreignKey:UserID" json:"-"`
+	UserAgent  string    `gorm:"size:255"`
+	ClientIP   string    `gorm:"size:45"`
+	ExpiresAt  time.Time `gorm:"not null"`
+	LastUsedAt time.Time
+	RevokedAt  *time.Time
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+// BeforeCreate is a GORM hook for the Session model
+func (session *Session) BeforeCreate(tx *gorm.DB) (err error) {
+	session.ID = uuid.New()
+	return
+}
+
+// IsActive reports whether the session can still be used at the given time
+func (session *Session) IsActive(now time.Time) bool {
+	return session.RevokedAt == nil && now.Before(session.ExpiresAt)
+}
+
+// RefreshToken is a single-use token that can be exchanged for a new access
+// token. Only the SHA-256 hash of the token is stored.
+type RefreshToken struct {
+	ID        uuid.UUID `gorm:"type:uuid;primary_key;"`
+	SessionID uuid.UUID `gorm:"type:uuid;not null;index"`
+	Session   Session   `gorm:"foreignKey:SessionID" json:"-"`
+	TokenHash string    `gorm:"size:64;not null;unique" json:"-"`
+	ExpiresAt time.Time `gorm:"not null"`
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+// BeforeCreate is a GORM hook for the RefreshToken model
+func (token *RefreshToken) BeforeCreate(tx *gorm.DB) (err error) {
+	token.ID = uuid.New()
+	return
+}
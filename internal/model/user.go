@@ -12,15 +12,43 @@ type Role string
 const (
 	Receptionist Role = "receptionist"
 	Doctor       Role = "doctor"
+	Nurse        Role = "nurse"
+	Admin        Role = "admin"
 )
 
-// User represents a user in the system (receptionist or doctor)
+// IsValid reports whether the role is one of the known roles
+func (r Role) IsValid() bool {
+	switch r {
+	case Receptionist, Doctor, Nurse, Admin:
+		return true
+	}
+	return false
+}
+
+// IsClinician reports whether the role treats patients. Clinicians only see
+// the patients whose care team they are on, but see their full chart.
+func (r Role) IsClinician() bool {
+	return r == Doctor || r == Nurse
+}
+
+// User represents a user in the system (receptionist, doctor, nurse or admin)
 type User struct {
-	ID           uuid.UUID `gorm:"type:uuid;primary_key;"`
-	FullName     string    `gorm:"size:255;not null"`
-	Email        string    `gorm:"size:255;not null;unique"`
-	PasswordHash string    `gorm:"not null"`
-	Role         Role      `gorm:"type:varchar(20);not null"`
+	ID            uuid.UUID `gorm:"type:uuid;primary_key;"`
+	FullName      string    `gorm:"size:255;not null"`
+	Email         string    `gorm:"size:255;not null;unique"`
+	PasswordHash  string    `gorm:"not null" json:"-"`
+	Role          Role      `gorm:"type:varchar(20);not null"`
+	Active        bool      `gorm:"not null;default:true"`
+	DeactivatedAt *time.Time
+	// ServiceAccount marks a non-human account used by integrations through
+	// API keys. It has no usable password and cannot log in.
+	ServiceAccount bool `gorm:"not null;default:false"`
+	// MFAEnabled is set once the user has confirmed a TOTP enrollment
+	MFAEnabled bool `gorm:"not null;default:false"`
+	// TOTPSecret is the encrypted TOTP seed, set while enrolling and once enrolled
+	TOTPSecret string `gorm:"size:255" json:"-"`
+	// TOTPLastStep is the time step of the last accepted code, so a code cannot be replayed
+	TOTPLastStep int64 `json:"-"`
 	CreatedAt    time.Time
 	UpdatedAt    time.Time
 }
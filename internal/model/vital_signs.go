@@ -0,0 +1,56 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// VitalSigns is one set of vital signs taken from a patient at the same
+// time. Every measurement is optional, but at least one is present. BMI is
+// computed from the weight and height.
+type VitalSigns struct {
+	ID              uuid.UUID `gorm:"type:uuid;primary_key;"`
+	PatientID       uuid.UUID `gorm:"type:uuid;not null;index:idx_vital_signs_series,priority:1"`
+	Patient         Patient   `gorm:"foreignKey:PatientID;constraint:OnDelete:CASCADE" json:"-"`
+	RecordedByID    uuid.UUID `gorm:"type:uuid;not null"`
+	TakenAt         time.Time `gorm:"not null;index:idx_vital_signs_series,priority:2"`
+	SystolicBP      *float64  `gorm:"column:systolic_bp"`  // mmHg
+	DiastolicBP     *float64  `gorm:"column:diastolic_bp"` // mmHg
+	HeartRate       *float64  // beats per minute
+	RespiratoryRate *float64  // breaths per minute
+	Temperature     *float64  // °C
+	SpO2            *float64  `gorm:"column:spo2"` // %
+	Weight          *float64  // kg
+	Height          *float64  // cm
+	BMI             *float64  `gorm:"column:bmi"` // kg/m²
+	Note            string    `gorm:"type:text"`
+	CreatedAt       time.Time
+}
+
+// BeforeCreate is a GORM hook for the VitalSigns model
+func (v *VitalSigns) BeforeCreate(tx *gorm.DB) (err error) {
+	v.ID = uuid.New()
+	return
+}
+
+// TableName names the table after the already plural type name
+func (VitalSigns) TableName() string {
+	return "vital_signs"
+}
+
+// Values returns the measurements by column name, nil for those not taken
+func (v *VitalSigns) Values() map[string]*float64 {
+	return map[string]*float64{
+		"systolic_bp":      v.SystolicBP,
+		"diastolic_bp":     v.DiastolicBP,
+		"heart_rate":       v.HeartRate,
+		"respiratory_rate": v.RespiratoryRate,
+		"temperature":      v.Temperature,
+		"spo2":             v.SpO2,
+		"weight":           v.Weight,
+		"height":           v.Height,
+		"bmi":              v.BMI,
+	}
+}
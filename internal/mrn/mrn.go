@@ -0,0 +1,137 @@
+// Package mrn formats and checks medical record numbers: a facility prefix,
+// a zero-padded sequence number and a check digit that catches most typing
+// mistakes, such as one wrong digit or two swapped neighbours
+package mrn
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Check digit schemes
+const (
+	// Luhn is the scheme used by payment cards
+	Luhn = "luhn"
+	// Mod11 weights the digits 2 to 7 from the right, repeating, and
+	// writes a check digit of 10 as X
+	Mod11 = "mod11"
+)
+
+// Limits on a format
+const (
+	MaxPrefixLength = 10
+	MinDigits       = 4
+	MaxDigits       = 12
+)
+
+var (
+	// ErrInvalid is returned when a medical record number is malformed,
+	// has another prefix or its check digit does not match
+	ErrInvalid = errors.New("invalid medical record number")
+	// ErrExhausted is returned when a sequence number has more digits than
+	// the format allows
+	ErrExhausted = errors.New("medical record numbers of this format are used up")
+)
+
+// Format describes how medical record numbers look, e.g. HMS00001230 for
+// prefix HMS, 7 digits and a Luhn check digit
+type Format struct {
+	Prefix     string // upper-case letters and digits, may be empty
+	Digits     int    // length of the zero-padded sequence number
+	CheckDigit string // Luhn or Mod11
+}
+
+// Validate reports whether the format can be used
+func (f Format) Validate() error {
+	if len(f.Prefix) > MaxPrefixLength {
+		return fmt.Errorf("prefix must be at most %d characters", MaxPrefixLength)
+	}
+	for _, r := range f.Prefix {
+		if (r < 'A' || r > 'Z') && (r < '0' || r > '9') {
+			return errors.New("prefix may only hold upper-case letters and digits")
+		}
+	}
+	if f.Digits < MinDigits || f.Digits > MaxDigits {
+		return fmt.Errorf("number of digits must be between %d and %d", MinDigits, MaxDigits)
+	}
+	if f.CheckDigit != Luhn && f.CheckDigit != Mod11 {
+		return fmt.Errorf("check digit must be %q or %q", Luhn, Mod11)
+	}
+	return nil
+}
+
+// Generate returns the medical record number for a sequence number
+func (f Format) Generate(sequence int64) (string, error) {
+	digits := fmt.Sprintf("%0*d", f.Digits, sequence)
+	if sequence < 0 || len(digits) > f.Digits {
+		return "", ErrExhausted
+	}
+	return f.Prefix + digits + string(f.checkDigit(digits)), nil
+}
+
+// Parse checks a medical record number as typed by a person and returns it
+// the way Generate writes it. Case, spaces and hyphens are ignored.
+func (f Format) Parse(value string) (string, error) {
+	normalized := strings.Map(func(r rune) rune {
+		if r == ' ' || r == '-' {
+			return -1
+		}
+		return r
+	}, strings.ToUpper(value))
+	if !strings.HasPrefix(normalized, f.Prefix) || len(normalized) != len(f.Prefix)+f.Digits+1 {
+		return "", ErrInvalid
+	}
+	digits := normalized[len(f.Prefix) : len(normalized)-1]
+	for i := 0; i < len(digits); i++ {
+		if digits[i] < '0' || digits[i] > '9' {
+			return "", ErrInvalid
+		}
+	}
+	if normalized[len(normalized)-1] != f.checkDigit(digits) {
+		return "", ErrInvalid
+	}
+	return normalized, nil
+}
+
+// checkDigit returns the check digit of a string of digits
+func (f Format) checkDigit(digits string) byte {
+	if f.CheckDigit == Mod11 {
+		return Mod11Digit(digits)
+	}
+	return LuhnDigit(digits)
+}
+
+// LuhnDigit returns the Luhn check digit of a string of digits
+func LuhnDigit(digits string) byte {
+	sum := 0
+	for i := len(digits) - 1; i >= 0; i-- {
+		d := int(digits[i] - '0')
+		// Double every other digit, starting with the rightmost
+		if (len(digits)-1-i)%2 == 0 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+	}
+	return byte('0' + (10-sum%10)%10)
+}
+
+// Mod11Digit returns the mod 11 check digit of a string of digits: the
+// digits are weighted 2, 3, 4, 5, 6, 7, 2, 3, ... from the right and the
+// check digit makes the weighted sum a multiple of 11, with X standing for 10.
+// No weight is a multiple of 11, so every digit is covered however long the
+// number is.
+func Mod11Digit(digits string) byte {
+	sum := 0
+	for i := len(digits) - 1; i >= 0; i-- {
+		sum += int(digits[i]-'0') * (2 + (len(digits)-1-i)%6)
+	}
+	check := (11 - sum%11) % 11
+	if check == 10 {
+		return 'X'
+	}
+	return byte('0' + check)
+}
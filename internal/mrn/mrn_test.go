@@ -0,0 +1,129 @@
+package mrn
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestLuhnDigitMatchesKnownNumbers(t *testing.T) {
+	cases := map[string]byte{
+		"7992739871":      '3',
+		"0000000":         '0',
+		"0000001":         '8',
+		"411111111111111": '1',
+	}
+	for digits, want := range cases {
+		if got := LuhnDigit(digits); got != want {
+			t.Errorf("LuhnDigit(%s) = %c, want %c", digits, got, want)
+		}
+	}
+}
+
+func TestMod11DigitMatchesKnownNumbers(t *testing.T) {
+	cases := map[string]byte{
+		"261533":    '9',
+		"1234567":   '4',
+		"0000006":   'X',
+		"000000000": '0',
+	}
+	for digits, want := range cases {
+		if got := Mod11Digit(digits); got != want {
+			t.Errorf("Mod11Digit(%s) = %c, want %c", digits, got, want)
+		}
+	}
+}
+
+func TestGenerateAndParseRoundTrip(t *testing.T) {
+	for _, scheme := range []string{Luhn, Mod11} {
+		format := Format{Prefix: "HMS", Digits: 7, CheckDigit: scheme}
+		if err := format.Validate(); err != nil {
+			t.Fatalf("%s: expected valid format, got %v", scheme, err)
+		}
+		for _, sequence := range []int64{0, 1, 42, 1234567, 9999999} {
+			number, err := format.Generate(sequence)
+			if err != nil {
+				t.Fatalf("%s: generate %d: %v", scheme, sequence, err)
+			}
+			if len(number) != 11 {
+				t.Errorf("%s: expected 11 characters, got %q", scheme, number)
+			}
+			parsed, err := format.Parse(" " + number[:3] + "-" + number[3:] + " ")
+			if err != nil || parsed != number {
+				t.Errorf("%s: parse %q = %q, %v", scheme, number, parsed, err)
+			}
+		}
+	}
+}
+
+func TestParseAcceptsLowerCase(t *testing.T) {
+	format := Format{Prefix: "HMS", Digits: 5, CheckDigit: Luhn}
+	number, _ := format.Generate(123)
+	if parsed, err := format.Parse("hms" + number[3:]); err != nil || parsed != number {
+		t.Errorf("expected lower case %q to parse, got %q, %v", number, parsed, err)
+	}
+}
+
+func TestParseRejectsTypingMistakes(t *testing.T) {
+	for _, scheme := range []string{Luhn, Mod11} {
+		format := Format{Prefix: "HMS", Digits: 7, CheckDigit: scheme}
+		number, _ := format.Generate(1234567)
+		digits := []byte(number)
+
+		// Every single wrong digit
+		for i := 3; i < len(digits)-1; i++ {
+			for d := byte('0'); d <= '9'; d++ {
+				if d == number[i] {
+					continue
+				}
+				typo := append([]byte(nil), digits...)
+				typo[i] = d
+				if _, err := format.Parse(string(typo)); !errors.Is(err, ErrInvalid) {
+					t.Errorf("%s: expected %s to be rejected", scheme, typo)
+				}
+			}
+		}
+		// Every swap of two different neighbouring digits
+		for i := 3; i < len(digits)-2; i++ {
+			if digits[i] == digits[i+1] {
+				continue
+			}
+			typo := append([]byte(nil), digits...)
+			typo[i], typo[i+1] = typo[i+1], typo[i]
+			if _, err := format.Parse(string(typo)); !errors.Is(err, ErrInvalid) {
+				t.Errorf("%s: expected %s to be rejected", scheme, typo)
+			}
+		}
+	}
+}
+
+func TestParseRejectsOtherFormats(t *testing.T) {
+	format := Format{Prefix: "HMS", Digits: 7, CheckDigit: Luhn}
+	number, _ := format.Generate(5)
+	for _, value := range []string{"", "ABC" + number[3:], number + "0", number[:len(number)-2], "HMS00A00050"} {
+		if _, err := format.Parse(value); !errors.Is(err, ErrInvalid) {
+			t.Errorf("expected %q to be rejected", value)
+		}
+	}
+}
+
+func TestGenerateRefusesSequenceTooLong(t *testing.T) {
+	format := Format{Digits: 4, CheckDigit: Luhn}
+	if _, err := format.Generate(10000); !errors.Is(err, ErrExhausted) {
+		t.Errorf("expected ErrExhausted, got %v", err)
+	}
+}
+
+func TestValidateRejectsBadFormats(t *testing.T) {
+	formats := map[string]Format{
+		"lower-case prefix": {Prefix: "hms", Digits: 7, CheckDigit: Luhn},
+		"long prefix":       {Prefix: "HOSPITALABC", Digits: 7, CheckDigit: Luhn},
+		"few digits":        {Prefix: "HMS", Digits: 3, CheckDigit: Luhn},
+		"many digits":       {Prefix: "HMS", Digits: 13, CheckDigit: Luhn},
+		"unknown scheme":    {Prefix: "HMS", Digits: 7, CheckDigit: "verhoeff"},
+	}
+	for name, format := range formats {
+		if err := format.Validate(); err == nil {
+			t.Errorf("%s: expected format to be rejected", name)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+// Package password decides which passwords staff accounts may use
+package password
+
+import (
+	_ "embed"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+//go:embed common_passwords.txt
+var commonPasswordsFile string
+
+// commonPasswords is the bundled denylist, lower cased
+var commonPasswords = parseDenylist(commonPasswordsFile)
+
+// Policy describes the rules a new password must follow
+type Policy struct {
+	MinLength int
+	MaxLength int
+	// MinClasses is how many of lower case, upper case, digits and symbols must be used
+	MinClasses int
+	// HistorySize is how many previous passwords of a user may not be reused
+	HistorySize int
+}
+
+// DefaultPolicy returns the policy used when none is configured
+func DefaultPolicy() Policy {
+	return Policy{
+		MinLength:   12,
+		MaxLength:   72,
+		MinClasses:  3,
+		HistorySize: 5,
+	}
+}
+
+// PolicyError lists every rule a password breaks
+type PolicyError struct {
+	Problems []string
+}
+
+func (e *PolicyError) Error() string {
+	return "password does not meet the policy: " + strings.Join(e.Problems, "; ")
+}
+
+// Validate checks a password against the policy. userInputs are values such
+// as the user's email and name that the password must not contain.
+func (p Policy) Validate(password string, userInputs ...string) error {
+	var problems []string
+
+	length := utf8.RuneCountInString(password)
+	if length < p.MinLength {
+		problems = append(problems, fmt.Sprintf("must be at least %d characters long", p.MinLength))
+	}
+	// bcrypt only uses the first 72 bytes, so the limit is in bytes
+	if p.MaxLength > 0 && len(password) > p.MaxLength {
+		problems = append(problems, fmt.Sprintf("must be at most %d bytes long", p.MaxLength))
+	}
+	if classes := characterClasses(password); classes < p.MinClasses {
+		problems = append(problems, fmt.Sprintf("must use at least %d of: lower case letters, upper case letters, digits, symbols", p.MinClasses))
+	}
+	if IsCommon(password) {
+		problems = append(problems, "is too common")
+	}
+
+	lower := strings.ToLower(password)
+	for _, input := range userInputs {
+		for _, part := range strings.FieldsFunc(strings.ToLower(input), isSeparator) {
+			if utf8.RuneCountInString(part) >= 4 && strings.Contains(lower, part) {
+				problems = append(problems, "must not contain your name or email")
+				break
+			}
+		}
+	}
+
+	if len(problems) > 0 {
+		return &PolicyError{Problems: dedupe(problems)}
+	}
+	return nil
+}
+
+// IsCommon reports whether a password is on the bundled denylist, ignoring
+// case and any digits or symbols appended to it
+func IsCommon(password string) bool {
+	lower := strings.ToLower(strings.TrimSpace(password))
+	if commonPasswords[lower] {
+		return true
+	}
+	stripped := strings.TrimRightFunc(lower, func(r rune) bool {
+		return unicode.IsDigit(r) || unicode.IsPunct(r) || unicode.IsSymbol(r)
+	})
+	return stripped != "" && commonPasswords[stripped]
+}
+
+// characterClasses counts the kinds of characters used in a password
+func characterClasses(password string) int {
+	var lower, upper, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			symbol = true
+		}
+	}
+	count := 0
+	for _, used := range []bool{lower, upper, digit, symbol} {
+		if used {
+			count++
+		}
+	}
+	return count
+}
+
+// isSeparator splits names and emails into the parts checked against the password
+func isSeparator(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+}
+
+// parseDenylist reads one password per line, skipping blank lines and comments
+func parseDenylist(file string) map[string]bool {
+	list := make(map[string]bool)
+	for _, line := range strings.Split(file, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		list[strings.ToLower(line)] = true
+	}
+	return list
+}
+
+// dedupe removes repeated problems while keeping their order
+func dedupe(problems []string) []string {
+	seen := make(map[string]bool, len(problems))
+	result := problems[:0]
+	for _, problem := range problems {
+		if !seen[problem] {
+			seen[problem] = true
+			result = append(result, problem)
+		}
+	}
+	return result
+}
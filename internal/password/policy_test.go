@@ -0,0 +1,42 @@
+package password
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidateAcceptsStrongPassword(t *testing.T) {
+	if err := DefaultPolicy().Validate("Correct-Horse-Battery-9", "jane.doe@example.com", "Jane Doe"); err != nil {
+		t.Fatalf("expected password to be accepted, got %v", err)
+	}
+}
+
+func TestValidateRejectsWeakPasswords(t *testing.T) {
+	passwords := map[string]string{
+		"too short":        "Ab1!xyz",
+		"one class":        "abcdefghijklmnop",
+		"common":           "Password123!",
+		"common, any case": "QWERTYUIOP1234",
+		"contains name":    "Jane-Doe-2024!x",
+		"contains email":   "my Example pass 1!",
+		"over 72 bytes":    "Aa1!" + string(make([]byte, 80)),
+	}
+	for name, password := range passwords {
+		err := DefaultPolicy().Validate(password, "jane.doe@example.com", "Jane Doe")
+		var policyErr *PolicyError
+		if !errors.As(err, &policyErr) {
+			t.Errorf("%s: expected a policy error, got %v", name, err)
+		}
+	}
+}
+
+func TestIsCommonIgnoresAppendedDigitsAndSymbols(t *testing.T) {
+	for _, password := range []string{"letmein", "LetMeIn2024", "hospital!!", "Welcome1"} {
+		if !IsCommon(password) {
+			t.Errorf("expected %q to be common", password)
+		}
+	}
+	if IsCommon("violet-lantern-orbit") {
+		t.Error("expected an uncommon password not to be flagged")
+	}
+}
@@ -0,0 +1,203 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/RohanDSkaria/hospital-management-system/internal/model"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrActiveAllergies is returned when no known allergies is asserted for
+	// a patient who has active allergies
+	ErrActiveAllergies = errors.New("patient has active allergies")
+	// ErrDuplicateAllergy is returned when an allergy would be a second
+	// active allergy of the patient to the same substance
+	ErrDuplicateAllergy = errors.New("patient already has an active allergy to this substance")
+)
+
+// AllergyRepository defines the interface for patient allergy data operations
+type AllergyRepository interface {
+	Create(allergy *model.Allergy) error
+	Update(allergy *model.Allergy) error
+	FindByID(patientID, id uuid.UUID) (*model.Allergy, error)
+	ListByPatient(patientID uuid.UUID, statuses []model.AllergyStatus) ([]model.Allergy, error)
+	FindNoKnownAllergies(patientID uuid.UUID) (*model.NoKnownAllergies, error)
+	AssertNoKnownAllergies(assertion *model.NoKnownAllergies) error
+	WithdrawNoKnownAllergies(patientID uuid.UUID) error
+}
+
+// allergyRepository is the implementation of AllergyRepository
+type allergyRepository struct {
+	db *gorm.DB
+}
+
+// NewAllergyRepository creates a new allergy repository
+func NewAllergyRepository(db *gorm.DB) AllergyRepository {
+	return &allergyRepository{db: db}
+}
+
+// Create records an allergy. Recording an active allergy withdraws any no
+// known allergies assertion. It returns ErrDuplicateAllergy if the allergy
+// is active and the patient already has an active allergy to the substance.
+func (r *allergyRepository) Create(allergy *model.Allergy) error {
+	allergy.Version = 1
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := lockPatient(tx, allergy.PatientID); err != nil {
+			return err
+		}
+		if err := checkDuplicate(tx, allergy); err != nil {
+			return err
+		}
+		if err := tx.Create(allergy).Error; err != nil {
+			return err
+		}
+		return withdrawIfActive(tx, allergy)
+	})
+}
+
+// Update saves an allergy's details. Making it active withdraws any no known
+// allergies assertion. The allergy's Version must still be the one stored,
+// or ErrVersionConflict is returned; on success it is the new version. Like
+// Create, it returns ErrDuplicateAllergy.
+func (r *allergyRepository) Update(allergy *model.Allergy) error {
+	expected := allergy.Version
+	now := time.Now()
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := lockPatient(tx, allergy.PatientID); err != nil {
+			return err
+		}
+		if err := checkDuplicate(tx, allergy); err != nil {
+			return err
+		}
+		result := tx.Model(&model.Allergy{}).
+			Where("id = ? AND version = ?", allergy.ID, expected).
+			Updates(map[string]interface{}{
+				"substance":     allergy.Substance,
+				"category":      allergy.Category,
+				"reaction":      allergy.Reaction,
+				"severity":      allergy.Severity,
+				"status":        allergy.Status,
+				"updated_by_id": allergy.UpdatedByID,
+				"updated_at":    now,
+				"version":       expected + 1,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrVersionConflict
+		}
+		allergy.Version = expected + 1
+		allergy.UpdatedAt = now
+		return withdrawIfActive(tx, allergy)
+	})
+}
+
+// FindByID returns one of a patient's allergies
+func (r *allergyRepository) FindByID(patientID, id uuid.UUID) (*model.Allergy, error) {
+	var allergy model.Allergy
+	err := r.db.Where("id = ? AND patient_id = ?", id, patientID).First(&allergy).Error
+	if err != nil {
+		return nil, err
+	}
+	return &allergy, nil
+}
+
+// ListByPatient returns a patient's allergies with one of the statuses, or
+// all of them if no status is given, most severe and then most recent first
+func (r *allergyRepository) ListByPatient(patientID uuid.UUID, statuses []model.AllergyStatus) ([]model.Allergy, error) {
+	query := r.db.Where("patient_id = ?", patientID)
+	if len(statuses) > 0 {
+		query = query.Where("status IN ?", statuses)
+	}
+	var allergies []model.Allergy
+	err := query.Order("CASE severity WHEN 'severe' THEN 0 WHEN 'moderate' THEN 1 ELSE 2 END, recorded_at DESC").
+		Find(&allergies).Error
+	return allergies, err
+}
+
+// FindNoKnownAllergies returns the patient's no known allergies assertion
+func (r *allergyRepository) FindNoKnownAllergies(patientID uuid.UUID) (*model.NoKnownAllergies, error) {
+	var assertion model.NoKnownAllergies
+	err := r.db.Where("patient_id = ?", patientID).First(&assertion).Error
+	if err != nil {
+		return nil, err
+	}
+	return &assertion, nil
+}
+
+// AssertNoKnownAllergies records that the patient has no known allergies,
+// replacing an earlier assertion. It returns ErrActiveAllergies if the
+// patient has any.
+func (r *allergyRepository) AssertNoKnownAllergies(assertion *model.NoKnownAllergies) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := lockPatient(tx, assertion.PatientID); err != nil {
+			return err
+		}
+		var active int64
+		err := tx.Model(&model.Allergy{}).
+			Where("patient_id = ? AND status = ?", assertion.PatientID, model.AllergyActive).
+			Count(&active).Error
+		if err != nil {
+			return err
+		}
+		if active > 0 {
+			return ErrActiveAllergies
+		}
+		return tx.Save(assertion).Error
+	})
+}
+
+// WithdrawNoKnownAllergies removes the patient's no known allergies
+// assertion. It returns gorm.ErrRecordNotFound if there was none.
+func (r *allergyRepository) WithdrawNoKnownAllergies(patientID uuid.UUID) error {
+	result := r.db.Where("patient_id = ?", patientID).Delete(&model.NoKnownAllergies{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// lockPatient locks a patient's row until the end of the transaction, so
+// changes to the patient's allergies and assertion cannot interleave
+func lockPatient(tx *gorm.DB, patientID uuid.UUID) error {
+	var patient model.Patient
+	return tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").Where("id = ?", patientID).First(&patient).Error
+}
+
+// checkDuplicate returns ErrDuplicateAllergy if the allergy is active and
+// the patient has another active allergy to the same substance. The patient
+// must be locked, so two of them cannot be saved at once.
+func checkDuplicate(tx *gorm.DB, allergy *model.Allergy) error {
+	if allergy.Status != model.AllergyActive {
+		return nil
+	}
+	var duplicates int64
+	err := tx.Model(&model.Allergy{}).
+		Where("patient_id = ? AND status = ? AND id <> ?", allergy.PatientID, model.AllergyActive, allergy.ID).
+		Where("lower(substance) = lower(?)", allergy.Substance).
+		Count(&duplicates).Error
+	if err != nil {
+		return err
+	}
+	if duplicates > 0 {
+		return ErrDuplicateAllergy
+	}
+	return nil
+}
+
+// withdrawIfActive removes the no known allergies assertion of the patient
+// of an active allergy
+func withdrawIfActive(tx *gorm.DB, allergy *model.Allergy) error {
+	if allergy.Status != model.AllergyActive {
+		return nil
+	}
+	return tx.Where("patient_id = ?", allergy.PatientID).Delete(&model.NoKnownAllergies{}).Error
+}
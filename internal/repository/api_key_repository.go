@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/RohanDSkaria/hospital-management-system/internal/model"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// APIKeyFilter narrows down the API keys returned by List
+type APIKeyFilter struct {
+	OwnerID        *uuid.UUID
+	IncludeRevoked bool
+}
+
+// APIKeyRepository defines the interface for API key data operations
+type APIKeyRepository interface {
+	Create(key *model.APIKey) error
+	FindByPrefix(prefix string) (*model.APIKey, error)
+	FindByID(id uuid.UUID) (*model.APIKey, error)
+	List(filter APIKeyFilter) ([]model.APIKey, error)
+	Revoke(id uuid.UUID, at time.Time) error
+	TouchLastUsed(id uuid.UUID, at time.Time, clientIP string) error
+}
+
+// apiKeyRepository is the implementation of APIKeyRepository
+type apiKeyRepository struct {
+	db *gorm.DB
+}
+
+// NewAPIKeyRepository creates a new API key repository
+func NewAPIKeyRepository(db *gorm.DB) APIKeyRepository {
+	return &apiKeyRepository{db: db}
+}
+
+// Create persists a new API key
+func (r *apiKeyRepository) Create(key *model.APIKey) error {
+	return r.db.Create(key).Error
+}
+
+// FindByPrefix finds an API key by its public prefix, with its owner
+func (r *apiKeyRepository) FindByPrefix(prefix string) (*model.APIKey, error) {
+	var key model.APIKey
+	err := r.db.Preload("Owner").Where("prefix = ?", prefix).First(&key).Error
+	if err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// FindByID finds an API key by its ID
+func (r *apiKeyRepository) FindByID(id uuid.UUID) (*model.APIKey, error) {
+	var key model.APIKey
+	err := r.db.Where("id = ?", id).First(&key).Error
+	if err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// List returns the API keys matching the filter, newest first
+func (r *apiKeyRepository) List(filter APIKeyFilter) ([]model.APIKey, error) {
+	query := r.db.Model(&model.APIKey{})
+	if filter.OwnerID != nil {
+		query = query.Where("owner_id = ?", *filter.OwnerID)
+	}
+	if !filter.IncludeRevoked {
+		query = query.Where("revoked_at IS NULL")
+	}
+
+	var keys []model.APIKey
+	err := query.Order("created_at DESC").Find(&keys).Error
+	return keys, err
+}
+
+// Revoke marks an API key as revoked. Revoking a revoked key keeps the original time.
+func (r *apiKeyRepository) Revoke(id uuid.UUID, at time.Time) error {
+	return r.db.Model(&model.APIKey{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", at).Error
+}
+
+// TouchLastUsed records when and from where an API key was last used
+func (r *apiKeyRepository) TouchLastUsed(id uuid.UUID, at time.Time, clientIP string) error {
+	return r.db.Model(&model.APIKey{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{"last_used_at": at, "last_used_ip": clientIP}).Error
+}
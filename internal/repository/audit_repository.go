@@ -0,0 +1,120 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/RohanDSkaria/hospital-management-system/internal/model"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// auditChainLock is the Postgres advisory lock that serialises appends, so
+// every entry chains to the one committed right before it
+const auditChainLock = 0x61756469
+
+// AuditFilter narrows down the audit entries returned by List
+type AuditFilter struct {
+	PatientID *uuid.UUID
+	ActorID   *uuid.UUID
+	Action    string
+	From      *time.Time
+	To        *time.Time
+	Limit     int
+	Offset    int
+}
+
+// AuditRepository defines the interface for audit log data operations
+type AuditRepository interface {
+	Append(entry *model.AuditEntry) error
+	List(filter AuditFilter) ([]model.AuditEntry, int64, error)
+	Walk(batchSize int, fn func(entries []model.AuditEntry) error) error
+}
+
+// auditRepository is the implementation of AuditRepository
+type auditRepository struct {
+	db *gorm.DB
+}
+
+// NewAuditRepository creates a new audit repository
+func NewAuditRepository(db *gorm.DB) AuditRepository {
+	return &auditRepository{db: db}
+}
+
+// Append adds an entry to the end of the hash chain. It fills in the ID,
+// sequence, timestamp and hashes.
+func (r *auditRepository) Append(entry *model.AuditEntry) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", auditChainLock).Error; err != nil {
+			return err
+		}
+
+		var last model.AuditEntry
+		err := tx.Select("sequence", "hash").Order("sequence DESC").Limit(1).Find(&last).Error
+		if err != nil {
+			return err
+		}
+		entry.PrevHash = model.AuditGenesisHash
+		if last.Hash != "" {
+			entry.PrevHash = last.Hash
+		}
+		entry.Sequence = last.Sequence + 1
+		entry.ID = uuid.New()
+		// Postgres keeps microseconds, so hash what will be read back
+		entry.CreatedAt = time.Now().UTC().Truncate(time.Microsecond)
+		entry.Hash = entry.ComputeHash()
+		return tx.Create(entry).Error
+	})
+}
+
+// List returns a page of audit entries, newest first, and the total number of matches
+func (r *auditRepository) List(filter AuditFilter) ([]model.AuditEntry, int64, error) {
+	query := r.db.Model(&model.AuditEntry{})
+	if filter.PatientID != nil {
+		query = query.Where("patient_id = ?", *filter.PatientID)
+	}
+	if filter.ActorID != nil {
+		query = query.Where("actor_id = ?", *filter.ActorID)
+	}
+	if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+	if filter.From != nil {
+		query = query.Where("created_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("created_at < ?", *filter.To)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var entries []model.AuditEntry
+	err := query.Order("sequence DESC").
+		Limit(filter.Limit).
+		Offset(filter.Offset).
+		Find(&entries).Error
+	return entries, total, err
+}
+
+// Walk calls fn with every audit entry in chain order, batchSize at a time
+func (r *auditRepository) Walk(batchSize int, fn func(entries []model.AuditEntry) error) error {
+	var after int64
+	for {
+		var entries []model.AuditEntry
+		err := r.db.Where("sequence > ?", after).
+			Order("sequence").
+			Limit(batchSize).
+			Find(&entries).Error
+		if err != nil {
+			return err
+		}
+		if len(entries) == 0 {
+			return nil
+		}
+		if err := fn(entries); err != nil {
+			return err
+		}
+		after = entries[len(entries)-1].Sequence
+	}
+}
@@ -0,0 +1,120 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/RohanDSkaria/hospital-management-system/internal/model"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// BreakGlassFilter narrows down the break-glass accesses returned by List
+type BreakGlassFilter struct {
+	Reviewed  *bool
+	PatientID *uuid.UUID
+	UserID    *uuid.UUID
+	Limit     int
+	Offset    int
+}
+
+// BreakGlassRepository defines the interface for break-glass data operations
+type BreakGlassRepository interface {
+	Create(access *model.BreakGlassAccess) error
+	FindActive(userID, patientID uuid.UUID, now time.Time) (*model.BreakGlassAccess, error)
+	FindByID(id uuid.UUID) (*model.BreakGlassAccess, error)
+	List(filter BreakGlassFilter) ([]model.BreakGlassAccess, int64, error)
+	AddEvent(event *model.BreakGlassEvent) error
+	Review(id, reviewerID uuid.UUID, outcome model.BreakGlassOutcome, note string, at time.Time) (bool, error)
+}
+
+// breakGlassRepository is the implementation of BreakGlassRepository
+type breakGlassRepository struct {
+	db *gorm.DB
+}
+
+// NewBreakGlassRepository creates a new break-glass repository
+func NewBreakGlassRepository(db *gorm.DB) BreakGlassRepository {
+	return &breakGlassRepository{db: db}
+}
+
+// Create stores a new break-glass grant
+func (r *breakGlassRepository) Create(access *model.BreakGlassAccess) error {
+	return r.db.Create(access).Error
+}
+
+// FindActive returns the newest unexpired grant of a user for a patient
+func (r *breakGlassRepository) FindActive(userID, patientID uuid.UUID, now time.Time) (*model.BreakGlassAccess, error) {
+	var access model.BreakGlassAccess
+	err := r.db.Where("user_id = ? AND patient_id = ? AND expires_at > ?", userID, patientID, now).
+		Order("expires_at DESC").
+		First(&access).Error
+	if err != nil {
+		return nil, err
+	}
+	return &access, nil
+}
+
+// FindByID returns a grant with its requester, patient and every recorded use
+func (r *breakGlassRepository) FindByID(id uuid.UUID) (*model.BreakGlassAccess, error) {
+	var access model.BreakGlassAccess
+	err := r.db.Preload("User").
+		Preload("Patient").
+		Preload("Events", func(db *gorm.DB) *gorm.DB { return db.Order("created_at") }).
+		Where("id = ?", id).
+		First(&access).Error
+	if err != nil {
+		return nil, err
+	}
+	return &access, nil
+}
+
+// List returns a page of grants, oldest first so the review queue is worked
+// in order, and the total number of matches
+func (r *breakGlassRepository) List(filter BreakGlassFilter) ([]model.BreakGlassAccess, int64, error) {
+	query := r.db.Model(&model.BreakGlassAccess{})
+	if filter.Reviewed != nil {
+		if *filter.Reviewed {
+			query = query.Where("reviewed_at IS NOT NULL")
+		} else {
+			query = query.Where("reviewed_at IS NULL")
+		}
+	}
+	if filter.PatientID != nil {
+		query = query.Where("patient_id = ?", *filter.PatientID)
+	}
+	if filter.UserID != nil {
+		query = query.Where("user_id = ?", *filter.UserID)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var accesses []model.BreakGlassAccess
+	err := query.Preload("User").
+		Preload("Patient").
+		Order("created_at").
+		Limit(filter.Limit).
+		Offset(filter.Offset).
+		Find(&accesses).Error
+	return accesses, total, err
+}
+
+// AddEvent records a use of a grant
+func (r *breakGlassRepository) AddEvent(event *model.BreakGlassEvent) error {
+	return r.db.Create(event).Error
+}
+
+// Review signs off a grant that has not been reviewed yet. It reports
+// whether the grant was still waiting for review.
+func (r *breakGlassRepository) Review(id, reviewerID uuid.UUID, outcome model.BreakGlassOutcome, note string, at time.Time) (bool, error) {
+	result := r.db.Model(&model.BreakGlassAccess{}).
+		Where("id = ? AND reviewed_at IS NULL", id).
+		Updates(map[string]interface{}{
+			"reviewed_at":    at,
+			"reviewed_by_id": reviewerID,
+			"outcome":        outcome,
+			"review_note":    note,
+		})
+	return result.RowsAffected > 0, result.Error
+}
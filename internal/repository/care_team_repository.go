@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"github.com/RohanDSkaria/hospital-management-system/internal/model"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// CareTeamRepository defines the interface for care team data operations
+type CareTeamRepository interface {
+	Add(member *model.CareTeamMember) error
+	Remove(patientID, userID uuid.UUID) error
+	ListByPatient(patientID uuid.UUID) ([]model.CareTeamMember, error)
+	IsMember(patientID, userID uuid.UUID) (bool, error)
+}
+
+// careTeamRepository is the implementation of CareTeamRepository
+type careTeamRepository struct {
+	db *gorm.DB
+}
+
+// NewCareTeamRepository creates a new care team repository
+func NewCareTeamRepository(db *gorm.DB) CareTeamRepository {
+	return &careTeamRepository{db: db}
+}
+
+// Add assigns a staff member to a patient's care team
+func (r *careTeamRepository) Add(member *model.CareTeamMember) error {
+	return r.db.Create(member).Error
+}
+
+// Remove takes a staff member off a patient's care team. It returns
+// gorm.ErrRecordNotFound if they were not on it.
+func (r *careTeamRepository) Remove(patientID, userID uuid.UUID) error {
+	result := r.db.Where("patient_id = ? AND user_id = ?", patientID, userID).Delete(&model.CareTeamMember{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// ListByPatient returns the care team of a patient with the members' accounts
+func (r *careTeamRepository) ListByPatient(patientID uuid.UUID) ([]model.CareTeamMember, error) {
+	var members []model.CareTeamMember
+	err := r.db.Preload("User").
+		Where("patient_id = ?", patientID).
+		Order("created_at").
+		Find(&members).Error
+	return members, err
+}
+
+// IsMember reports whether a user is on a patient's care team
+func (r *careTeamRepository) IsMember(patientID, userID uuid.UUID) (bool, error) {
+	var count int64
+	err := r.db.Model(&model.CareTeamMember{}).
+		Where("patient_id = ? AND user_id = ?", patientID, userID).
+		Count(&count).Error
+	return count > 0, err
+}
@@ -0,0 +1,191 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/RohanDSkaria/hospital-management-system/internal/model"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrNoteNotDraft is returned when a note that was signed meanwhile is
+	// edited, signed or discarded as a draft
+	ErrNoteNotDraft = errors.New("note is no longer a draft")
+	// ErrNoteNotSigned is returned when an addendum is added to a draft
+	ErrNoteNotSigned = errors.New("note is not signed")
+)
+
+// ClinicalNoteFilter selects a patient's clinical notes. Drafts are only
+// returned to their author.
+type ClinicalNoteFilter struct {
+	PatientID uuid.UUID
+	ViewerID  uuid.UUID
+	Statuses  []model.NoteStatus
+	From      *time.Time // encounters at or after
+	To        *time.Time // encounters before
+	// Chronological orders the notes oldest encounter first instead of
+	// newest first
+	Chronological bool
+	Limit         int
+	Offset        int
+}
+
+// ClinicalNoteRepository defines the interface for clinical note data operations
+type ClinicalNoteRepository interface {
+	Create(note *model.ClinicalNote) error
+	FindByID(patientID, id uuid.UUID) (*model.ClinicalNote, error)
+	List(filter ClinicalNoteFilter) ([]model.ClinicalNote, int64, error)
+	UpdateDraft(note *model.ClinicalNote) error
+	DeleteDraft(id uuid.UUID) error
+	Sign(note *model.ClinicalNote) error
+	AddAddendum(note *model.ClinicalNote, addendum *model.NoteAddendum) error
+}
+
+// clinicalNoteRepository is the implementation of ClinicalNoteRepository
+type clinicalNoteRepository struct {
+	db *gorm.DB
+}
+
+// NewClinicalNoteRepository creates a new clinical note repository
+func NewClinicalNoteRepository(db *gorm.DB) ClinicalNoteRepository {
+	return &clinicalNoteRepository{db: db}
+}
+
+// Create saves a new note
+func (r *clinicalNoteRepository) Create(note *model.ClinicalNote) error {
+	return r.db.Create(note).Error
+}
+
+// FindByID returns one of a patient's notes with its addenda, oldest first
+func (r *clinicalNoteRepository) FindByID(patientID, id uuid.UUID) (*model.ClinicalNote, error) {
+	var note model.ClinicalNote
+	err := r.db.Preload("Addenda", func(db *gorm.DB) *gorm.DB {
+		return db.Order("created_at")
+	}).Where("id = ? AND patient_id = ?", id, patientID).First(&note).Error
+	if err != nil {
+		return nil, err
+	}
+	return &note, nil
+}
+
+// List returns a page of the notes matching the filter with their addenda,
+// and the total number of matching notes
+func (r *clinicalNoteRepository) List(filter ClinicalNoteFilter) ([]model.ClinicalNote, int64, error) {
+	query := r.db.Model(&model.ClinicalNote{}).
+		Where("patient_id = ?", filter.PatientID).
+		Where("status <> ? OR author_id = ?", model.NoteDraft, filter.ViewerID)
+	if len(filter.Statuses) > 0 {
+		query = query.Where("status IN ?", filter.Statuses)
+	}
+	if filter.From != nil {
+		query = query.Where("encounter_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("encounter_at < ?", *filter.To)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	order := "encounter_at DESC, created_at DESC"
+	if filter.Chronological {
+		order = "encounter_at, created_at"
+	}
+	var notes []model.ClinicalNote
+	err := query.Preload("Addenda", func(db *gorm.DB) *gorm.DB {
+		return db.Order("created_at")
+	}).Order(order).
+		Limit(filter.Limit).
+		Offset(filter.Offset).
+		Find(&notes).Error
+	return notes, total, err
+}
+
+// UpdateDraft saves the encounter details and SOAP sections of a draft. It
+// returns ErrNoteNotDraft if the note was signed meanwhile.
+func (r *clinicalNoteRepository) UpdateDraft(note *model.ClinicalNote) error {
+	note.UpdatedAt = time.Now()
+	result := r.db.Model(&model.ClinicalNote{}).
+		Where("id = ? AND status = ?", note.ID, model.NoteDraft).
+		Updates(map[string]interface{}{
+			"encounter_type": note.EncounterType,
+			"encounter_at":   note.EncounterAt,
+			"subjective":     note.Subjective,
+			"objective":      note.Objective,
+			"assessment":     note.Assessment,
+			"plan":           note.Plan,
+			"updated_at":     note.UpdatedAt,
+		})
+	return draftResult(result)
+}
+
+// DeleteDraft removes a draft for good. It returns ErrNoteNotDraft if the
+// note was signed meanwhile.
+func (r *clinicalNoteRepository) DeleteDraft(id uuid.UUID) error {
+	return draftResult(r.db.Where("id = ? AND status = ?", id, model.NoteDraft).Delete(&model.ClinicalNote{}))
+}
+
+// Sign marks a draft as signed. It returns ErrNoteNotDraft if it was signed
+// meanwhile.
+func (r *clinicalNoteRepository) Sign(note *model.ClinicalNote) error {
+	now := time.Now()
+	result := r.db.Model(&model.ClinicalNote{}).
+		Where("id = ? AND status = ?", note.ID, model.NoteDraft).
+		Updates(map[string]interface{}{"status": model.NoteSigned, "signed_at": now, "updated_at": now})
+	if err := draftResult(result); err != nil {
+		return err
+	}
+	note.Status = model.NoteSigned
+	note.SignedAt = &now
+	note.UpdatedAt = now
+	return nil
+}
+
+// AddAddendum adds an addendum to a signed note and marks the note amended.
+// It returns ErrNoteNotSigned if the note is a draft.
+func (r *clinicalNoteRepository) AddAddendum(note *model.ClinicalNote, addendum *model.NoteAddendum) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var current model.ClinicalNote
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Select("id", "status").
+			Where("id = ?", note.ID).
+			First(&current).Error
+		if err != nil {
+			return err
+		}
+		if current.Status == model.NoteDraft {
+			return ErrNoteNotSigned
+		}
+		addendum.NoteID = note.ID
+		if err := tx.Create(addendum).Error; err != nil {
+			return err
+		}
+		now := time.Now()
+		err = tx.Model(&model.ClinicalNote{}).
+			Where("id = ?", note.ID).
+			Updates(map[string]interface{}{"status": model.NoteAmended, "updated_at": now}).Error
+		if err != nil {
+			return err
+		}
+		note.Status = model.NoteAmended
+		note.UpdatedAt = now
+		note.Addenda = append(note.Addenda, *addendum)
+		return nil
+	})
+}
+
+// draftResult turns the result of a write limited to drafts into
+// ErrNoteNotDraft if no draft was written
+func draftResult(result *gorm.DB) error {
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNoteNotDraft
+	}
+	return nil
+}
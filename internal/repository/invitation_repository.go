@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/RohanDSkaria/hospital-management-system/internal/model"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// InvitationRepository defines the interface for invitation data operations
+type InvitationRepository interface {
+	Create(invitation *model.Invitation) error
+	FindByTokenHash(hash string) (*model.Invitation, error)
+	MarkAccepted(id uuid.UUID, at time.Time) (bool, error)
+}
+
+// invitationRepository is the implementation of InvitationRepository
+type invitationRepository struct {
+	db *gorm.DB
+}
+
+// NewInvitationRepository creates a new invitation repository
+func NewInvitationRepository(db *gorm.DB) InvitationRepository {
+	return &invitationRepository{db: db}
+}
+
+// Create persists a new invitation
+func (r *invitationRepository) Create(invitation *model.Invitation) error {
+	return r.db.Create(invitation).Error
+}
+
+// FindByTokenHash finds an invitation by the hash of its token
+func (r *invitationRepository) FindByTokenHash(hash string) (*model.Invitation, error) {
+	var invitation model.Invitation
+	err := r.db.Where("token_hash = ?", hash).First(&invitation).Error
+	if err != nil {
+		return nil, err
+	}
+	return &invitation, nil
+}
+
+// MarkAccepted atomically marks an invitation as accepted.
+// It returns false if the invitation had already been accepted.
+func (r *invitationRepository) MarkAccepted(id uuid.UUID, at time.Time) (bool, error) {
+	result := r.db.Model(&model.Invitation{}).
+		Where("id = ? AND accepted_at IS NULL", id).
+		Update("accepted_at", at)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
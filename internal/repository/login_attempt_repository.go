@@ -0,0 +1,139 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/RohanDSkaria/hospital-management-system/internal/model"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// LoginAttemptFilter narrows down the login attempts returned by ListAttempts
+type LoginAttemptFilter struct {
+	Email    string
+	ClientIP string
+	Success  *bool
+	Limit    int
+	Offset   int
+}
+
+// LoginAttemptRepository defines the interface for login attempt and throttle data operations
+type LoginAttemptRepository interface {
+	RecordAttempt(attempt *model.LoginAttempt) error
+	ListAttempts(filter LoginAttemptFilter) ([]model.LoginAttempt, int64, error)
+	FindThrottles(email, clientIP string) ([]model.LoginThrottle, error)
+	RecordFailure(scope model.LockoutScope, subject string, apply func(throttle *model.LoginThrottle)) (*model.LoginThrottle, error)
+	ResetThrottle(scope model.LockoutScope, subject string) error
+	ListThrottles(now, failedSince time.Time) ([]model.LoginThrottle, error)
+	DeleteThrottle(id uuid.UUID) error
+	DeleteStaleThrottles(now, failedBefore time.Time) (int64, error)
+}
+
+// loginAttemptRepository is the implementation of LoginAttemptRepository
+type loginAttemptRepository struct {
+	db *gorm.DB
+}
+
+// NewLoginAttemptRepository creates a new login attempt repository
+func NewLoginAttemptRepository(db *gorm.DB) LoginAttemptRepository {
+	return &loginAttemptRepository{db: db}
+}
+
+// RecordAttempt persists a login attempt event
+func (r *loginAttemptRepository) RecordAttempt(attempt *model.LoginAttempt) error {
+	return r.db.Create(attempt).Error
+}
+
+// ListAttempts returns a page of login attempts, newest first, and the total number of matches
+func (r *loginAttemptRepository) ListAttempts(filter LoginAttemptFilter) ([]model.LoginAttempt, int64, error) {
+	query := r.db.Model(&model.LoginAttempt{})
+	if filter.Email != "" {
+		query = query.Where("email = ?", filter.Email)
+	}
+	if filter.ClientIP != "" {
+		query = query.Where("client_ip = ?", filter.ClientIP)
+	}
+	if filter.Success != nil {
+		query = query.Where("success = ?", *filter.Success)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var attempts []model.LoginAttempt
+	err := query.Order("created_at DESC").Limit(filter.Limit).Offset(filter.Offset).Find(&attempts).Error
+	return attempts, total, err
+}
+
+// FindThrottles returns the existing throttles for an account email and a client IP
+func (r *loginAttemptRepository) FindThrottles(email, clientIP string) ([]model.LoginThrottle, error) {
+	var throttles []model.LoginThrottle
+	err := r.db.Where("(scope = ? AND subject = ?) OR (scope = ? AND subject = ?)",
+		model.LockoutScopeAccount, email, model.LockoutScopeIP, clientIP).
+		Find(&throttles).Error
+	return throttles, err
+}
+
+// RecordFailure loads the throttle for a scope and subject under a row lock,
+// creating it if needed, lets apply update it and saves the result. The lock
+// makes concurrent failures for the same subject count correctly.
+func (r *loginAttemptRepository) RecordFailure(scope model.LockoutScope, subject string, apply func(throttle *model.LoginThrottle)) (*model.LoginThrottle, error) {
+	var throttle model.LoginThrottle
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&model.LoginThrottle{Scope: scope, Subject: subject}).Error
+		if err != nil {
+			return err
+		}
+		err = tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("scope = ? AND subject = ?", scope, subject).
+			First(&throttle).Error
+		if err != nil {
+			return err
+		}
+		apply(&throttle)
+		return tx.Save(&throttle).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &throttle, nil
+}
+
+// ResetThrottle clears the failures of a scope and subject
+func (r *loginAttemptRepository) ResetThrottle(scope model.LockoutScope, subject string) error {
+	return r.db.Where("scope = ? AND subject = ?", scope, subject).Delete(&model.LoginThrottle{}).Error
+}
+
+// ListThrottles returns throttles that are currently locked or have failed
+// attempts since the given time, most recent failure first
+func (r *loginAttemptRepository) ListThrottles(now, failedSince time.Time) ([]model.LoginThrottle, error) {
+	var throttles []model.LoginThrottle
+	err := r.db.Where("locked_until > ? OR last_failure_at > ?", now, failedSince).
+		Order("last_failure_at DESC").
+		Find(&throttles).Error
+	return throttles, err
+}
+
+// DeleteThrottle removes a throttle, clearing its lockout
+func (r *loginAttemptRepository) DeleteThrottle(id uuid.UUID) error {
+	result := r.db.Where("id = ?", id).Delete(&model.LoginThrottle{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// DeleteStaleThrottles removes throttles that are no longer locked and whose
+// last failure was before the given time, returning how many were removed
+func (r *loginAttemptRepository) DeleteStaleThrottles(now, failedBefore time.Time) (int64, error) {
+	result := r.db.Where("(locked_until IS NULL OR locked_until <= ?) AND last_failure_at < ?", now, failedBefore).
+		Delete(&model.LoginThrottle{})
+	return result.RowsAffected, result.Error
+}
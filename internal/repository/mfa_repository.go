@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/RohanDSkaria/hospital-management-system/internal/model"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// MFARepository defines the interface for recovery code and MFA challenge data operations
+type MFARepository interface {
+	ReplaceRecoveryCodes(userID uuid.UUID, codes []model.RecoveryCode) error
+	DeleteRecoveryCodes(userID uuid.UUID) error
+	UseRecoveryCode(userID uuid.UUID, hash string, at time.Time) (bool, error)
+	CountUnusedRecoveryCodes(userID uuid.UUID) (int64, error)
+	CreateChallenge(challenge *model.MFAChallenge) error
+	FindChallengeByTokenHash(hash string) (*model.MFAChallenge, error)
+	IncrementChallengeAttempts(id uuid.UUID) error
+	MarkChallengeUsed(id uuid.UUID, at time.Time) (bool, error)
+}
+
+// mfaRepository is the implementation of MFARepository
+type mfaRepository struct {
+	db *gorm.DB
+}
+
+// NewMFARepository creates a new MFA repository
+func NewMFARepository(db *gorm.DB) MFARepository {
+	return &mfaRepository{db: db}
+}
+
+// ReplaceRecoveryCodes deletes the existing recovery codes of a user and stores new ones
+func (r *mfaRepository) ReplaceRecoveryCodes(userID uuid.UUID, codes []model.RecoveryCode) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&model.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&codes).Error
+	})
+}
+
+// DeleteRecoveryCodes deletes every recovery code of a user
+func (r *mfaRepository) DeleteRecoveryCodes(userID uuid.UUID) error {
+	return r.db.Where("user_id = ?", userID).Delete(&model.RecoveryCode{}).Error
+}
+
+// UseRecoveryCode atomically marks an unused recovery code of a user as used.
+// It returns false if there is no such unused code.
+func (r *mfaRepository) UseRecoveryCode(userID uuid.UUID, hash string, at time.Time) (bool, error) {
+	result := r.db.Model(&model.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, hash).
+		Update("used_at", at)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// CountUnusedRecoveryCodes returns how many recovery codes a user has left
+func (r *mfaRepository) CountUnusedRecoveryCodes(userID uuid.UUID) (int64, error) {
+	var count int64
+	err := r.db.Model(&model.RecoveryCode{}).
+		Where("user_id = ? AND used_at IS NULL", userID).
+		Count(&count).Error
+	return count, err
+}
+
+// CreateChallenge persists a new MFA challenge
+func (r *mfaRepository) CreateChallenge(challenge *model.MFAChallenge) error {
+	return r.db.Create(challenge).Error
+}
+
+// FindChallengeByTokenHash finds a challenge by the hash of its token, with its user
+func (r *mfaRepository) FindChallengeByTokenHash(hash string) (*model.MFAChallenge, error) {
+	var challenge model.MFAChallenge
+	err := r.db.Preload("User").Where("token_hash = ?", hash).First(&challenge).Error
+	if err != nil {
+		return nil, err
+	}
+	return &challenge, nil
+}
+
+// IncrementChallengeAttempts counts a wrong code entered for a challenge
+func (r *mfaRepository) IncrementChallengeAttempts(id uuid.UUID) error {
+	return r.db.Model(&model.MFAChallenge{}).
+		Where("id = ?", id).
+		Update("attempts", gorm.Expr("attempts + 1")).Error
+}
+
+// MarkChallengeUsed atomically marks a challenge as used.
+// It returns false if the challenge had already been used.
+func (r *mfaRepository) MarkChallengeUsed(id uuid.UUID, at time.Time) (bool, error) {
+	result := r.db.Model(&model.MFAChallenge{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", at)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"github.com/RohanDSkaria/hospital-management-system/internal/model"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// PasswordHistoryRepository defines the interface for password history data operations
+type PasswordHistoryRepository interface {
+	Add(entry *model.PasswordHistory) error
+	ListRecent(userID uuid.UUID, limit int) ([]model.PasswordHistory, error)
+	Prune(userID uuid.UUID, keep int) error
+}
+
+// passwordHistoryRepository is the implementation of PasswordHistoryRepository
+type passwordHistoryRepository struct {
+	db *gorm.DB
+}
+
+// NewPasswordHistoryRepository creates a new password history repository
+func NewPasswordHistoryRepository(db *gorm.DB) PasswordHistoryRepository {
+	return &passwordHistoryRepository{db: db}
+}
+
+// Add records a password hash in the history of a user
+func (r *passwordHistoryRepository) Add(entry *model.PasswordHistory) error {
+	return r.db.Create(entry).Error
+}
+
+// ListRecent returns the most recent password hashes of a user, newest first
+func (r *passwordHistoryRepository) ListRecent(userID uuid.UUID, limit int) ([]model.PasswordHistory, error) {
+	var entries []model.PasswordHistory
+	err := r.db.Where("user_id = ?", userID).
+		Order("created_at DESC").
+		Limit(limit).
+		Find(&entries).Error
+	return entries, err
+}
+
+// Prune deletes all but the newest keep entries of a user
+func (r *passwordHistoryRepository) Prune(userID uuid.UUID, keep int) error {
+	recent := r.db.Model(&model.PasswordHistory{}).
+		Select("id").
+		Where("user_id = ?", userID).
+		Order("created_at DESC").
+		Limit(keep)
+	return r.db.Where("user_id = ? AND id NOT IN (?)", userID, recent).
+		Delete(&model.PasswordHistory{}).Error
+}
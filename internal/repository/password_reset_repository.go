@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/RohanDSkaria/hospital-management-system/internal/model"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// PasswordResetRepository defines the interface for password reset token data operations
+type PasswordResetRepository interface {
+	Create(token *model.PasswordResetToken) error
+	FindByTokenHash(hash string) (*model.PasswordResetToken, error)
+	MarkUsed(id uuid.UUID, at time.Time) (bool, error)
+	InvalidateForUser(userID uuid.UUID, at time.Time) error
+}
+
+// passwordResetRepository is the implementation of PasswordResetRepository
+type passwordResetRepository struct {
+	db *gorm.DB
+}
+
+// NewPasswordResetRepository creates a new password reset repository
+func NewPasswordResetRepository(db *gorm.DB) PasswordResetRepository {
+	return &passwordResetRepository{db: db}
+}
+
+// Create persists a new reset token
+func (r *passwordResetRepository) Create(token *model.PasswordResetToken) error {
+	return r.db.Create(token).Error
+}
+
+// FindByTokenHash finds a reset token by the hash of its value
+func (r *passwordResetRepository) FindByTokenHash(hash string) (*model.PasswordResetToken, error) {
+	var token model.PasswordResetToken
+	err := r.db.Where("token_hash = ?", hash).First(&token).Error
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// MarkUsed atomically marks a reset token as used.
+// It returns false if the token had already been used.
+func (r *passwordResetRepository) MarkUsed(id uuid.UUID, at time.Time) (bool, error) {
+	result := r.db.Model(&model.PasswordResetToken{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", at)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// InvalidateForUser marks every outstanding reset token of a user as used
+func (r *passwordResetRepository) InvalidateForUser(userID uuid.UUID, at time.Time) error {
+	return r.db.Model(&model.PasswordResetToken{}).
+		Where("user_id = ? AND used_at IS NULL", userID).
+		Update("used_at", at).Error
+}
@@ -0,0 +1,158 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/RohanDSkaria/hospital-management-system/internal/model"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// PatientMergeRepository defines the interface for merging duplicate patients
+type PatientMergeRepository interface {
+	Merge(merge *model.PatientMerge, source, target *model.Patient, version *model.PatientVersion) error
+	FindBySource(sourceID uuid.UUID) (*model.PatientMerge, error)
+	List(limit, offset int) ([]model.PatientMerge, int64, error)
+}
+
+// patientMergeRepository is the implementation of PatientMergeRepository
+type patientMergeRepository struct {
+	db *gorm.DB
+}
+
+// NewPatientMergeRepository creates a new patient merge repository
+func NewPatientMergeRepository(db *gorm.DB) PatientMergeRepository {
+	return &patientMergeRepository{db: db}
+}
+
+// Merge merges source into target in one transaction. target holds the
+// merged details and is saved as a new version; source is deleted. The
+// source's care team members not already on the target's team, its
+// break-glass grants, allergies, clinical notes and vital signs are moved to
+// the target, tombstones that led to the source now lead to the target, and
+// the merge is saved. The target keeps a no known allergies assertion, its
+// own or else the source's, only if it has no active allergies after the
+// merge. Both patients must still be at the versions they were read at, or
+// ErrVersionConflict is returned.
+func (r *patientMergeRepository) Merge(merge *model.PatientMerge, source, target *model.Patient, version *model.PatientVersion) error {
+	expected := target.Version
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&model.Patient{}).
+			Where("id = ? AND version = ?", source.ID, source.Version).
+			Updates(map[string]interface{}{
+				"deleted_at":      time.Now(),
+				"deleted_by_id":   merge.MergedByID,
+				"deletion_reason": "merged into patient " + target.ID.String(),
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrVersionConflict
+		}
+		if err := updatePatient(tx, target, version); err != nil {
+			return err
+		}
+		merge.TargetVersion = target.Version
+
+		moved := tx.Exec(`UPDATE care_team_members SET patient_id = ?
+			WHERE patient_id = ? AND user_id NOT IN (SELECT user_id FROM care_team_members WHERE patient_id = ?)`,
+			target.ID, source.ID, target.ID)
+		if moved.Error != nil {
+			return moved.Error
+		}
+		merge.MovedCareTeamMembers = int(moved.RowsAffected)
+		if err := tx.Where("patient_id = ?", source.ID).Delete(&model.CareTeamMember{}).Error; err != nil {
+			return err
+		}
+
+		moved = tx.Model(&model.BreakGlassAccess{}).Where("patient_id = ?", source.ID).Update("patient_id", target.ID)
+		if moved.Error != nil {
+			return moved.Error
+		}
+		merge.MovedBreakGlassAccesses = int(moved.RowsAffected)
+
+		moved = tx.Model(&model.Allergy{}).Where("patient_id = ?", source.ID).Update("patient_id", target.ID)
+		if moved.Error != nil {
+			return moved.Error
+		}
+		merge.MovedAllergies = int(moved.RowsAffected)
+		if err := mergeNoKnownAllergies(tx, source.ID, target.ID); err != nil {
+			return err
+		}
+
+		// Signed notes keep their update time; only addenda may change it
+		moved = tx.Model(&model.ClinicalNote{}).Where("patient_id = ?", source.ID).UpdateColumn("patient_id", target.ID)
+		if moved.Error != nil {
+			return moved.Error
+		}
+		merge.MovedClinicalNotes = int(moved.RowsAffected)
+
+		moved = tx.Model(&model.VitalSigns{}).Where("patient_id = ?", source.ID).Update("patient_id", target.ID)
+		if moved.Error != nil {
+			return moved.Error
+		}
+		merge.MovedVitalSigns = int(moved.RowsAffected)
+
+		err := tx.Model(&model.PatientMerge{}).
+			Where("target_patient_id = ?", source.ID).
+			Update("target_patient_id", target.ID).Error
+		if err != nil {
+			return err
+		}
+		return tx.Create(merge).Error
+	})
+	if err != nil {
+		target.Version = expected
+	}
+	return err
+}
+
+// mergeNoKnownAllergies settles the no known allergies assertions of a merge
+// once the allergies have been moved: the target loses its assertion if it
+// now has active allergies, takes over the source's if it has none of its
+// own, and the source's is removed
+func mergeNoKnownAllergies(tx *gorm.DB, sourceID, targetID uuid.UUID) error {
+	var active int64
+	err := tx.Model(&model.Allergy{}).
+		Where("patient_id = ? AND status = ?", targetID, model.AllergyActive).
+		Count(&active).Error
+	if err != nil {
+		return err
+	}
+	if active > 0 {
+		return tx.Where("patient_id IN ?", []uuid.UUID{sourceID, targetID}).Delete(&model.NoKnownAllergies{}).Error
+	}
+	var own int64
+	if err := tx.Model(&model.NoKnownAllergies{}).Where("patient_id = ?", targetID).Count(&own).Error; err != nil {
+		return err
+	}
+	if own > 0 {
+		return tx.Where("patient_id = ?", sourceID).Delete(&model.NoKnownAllergies{}).Error
+	}
+	return tx.Model(&model.NoKnownAllergies{}).Where("patient_id = ?", sourceID).Update("patient_id", targetID).Error
+}
+
+// FindBySource returns the merge that removed the patient
+func (r *patientMergeRepository) FindBySource(sourceID uuid.UUID) (*model.PatientMerge, error) {
+	var merge model.PatientMerge
+	err := r.db.Where("source_patient_id = ?", sourceID).First(&merge).Error
+	if err != nil {
+		return nil, err
+	}
+	return &merge, nil
+}
+
+// List returns a page of merges, newest first, and the total number of merges
+func (r *patientMergeRepository) List(limit, offset int) ([]model.PatientMerge, int64, error) {
+	var total int64
+	if err := r.db.Model(&model.PatientMerge{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var merges []model.PatientMerge
+	err := r.db.Order("created_at DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&merges).Error
+	return merges, total, err
+}
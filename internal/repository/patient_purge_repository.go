@@ -0,0 +1,112 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/RohanDSkaria/hospital-management-system/internal/model"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// PatientPurgeRepository defines the interface for permanently removing deleted patients
+type PatientPurgeRepository interface {
+	FindPurgeable(cutoff time.Time) ([]model.PurgedPatient, error)
+	Purge(purge *model.PatientPurge) error
+	List(limit, offset int) ([]model.PatientPurge, int64, error)
+	FindByID(id uuid.UUID) (*model.PatientPurge, error)
+}
+
+// patientPurgeRepository is the implementation of PatientPurgeRepository
+type patientPurgeRepository struct {
+	db *gorm.DB
+}
+
+// NewPatientPurgeRepository creates a new patient purge repository
+func NewPatientPurgeRepository(db *gorm.DB) PatientPurgeRepository {
+	return &patientPurgeRepository{db: db}
+}
+
+// FindPurgeable returns the patients that were deleted before the cutoff
+func (r *patientPurgeRepository) FindPurgeable(cutoff time.Time) ([]model.PurgedPatient, error) {
+	return findPurgeable(r.db, cutoff)
+}
+
+// Purge permanently removes every patient deleted before purge.Cutoff, with
+// everything that belongs to them, and saves the purge report. Patients
+// restored in the meantime are left alone.
+func (r *patientPurgeRepository) Purge(purge *model.PatientPurge) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		purged, err := findPurgeable(tx.Clauses(clause.Locking{Strength: "UPDATE"}), purge.Cutoff)
+		if err != nil {
+			return err
+		}
+		purge.Patients = purged
+		purge.PurgedCount = len(purged)
+		if len(purged) > 0 {
+			ids := make([]uuid.UUID, 0, len(purged))
+			for _, patient := range purged {
+				ids = append(ids, patient.PatientID)
+			}
+			if err := tx.Unscoped().Where("id IN ?", ids).Delete(&model.Patient{}).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Create(purge).Error
+	})
+}
+
+// List returns a page of purge reports, newest first, without the purged patients
+func (r *patientPurgeRepository) List(limit, offset int) ([]model.PatientPurge, int64, error) {
+	var total int64
+	if err := r.db.Model(&model.PatientPurge{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var purges []model.PatientPurge
+	err := r.db.Order("created_at DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&purges).Error
+	return purges, total, err
+}
+
+// FindByID returns a purge report with the patients it removed
+func (r *patientPurgeRepository) FindByID(id uuid.UUID) (*model.PatientPurge, error) {
+	var purge model.PatientPurge
+	err := r.db.Preload("Patients", func(db *gorm.DB) *gorm.DB {
+		return db.Order("deleted_at")
+	}).Where("id = ?", id).First(&purge).Error
+	if err != nil {
+		return nil, err
+	}
+	return &purge, nil
+}
+
+// findPurgeable lists the patients deleted before the cutoff as purge report
+// rows. Patients merged into another record are never purged: their versions
+// are the history of the details merged into the target.
+func findPurgeable(db *gorm.DB, cutoff time.Time) ([]model.PurgedPatient, error) {
+	// The subquery needs a fresh session: built on db it would share the
+	// conditions and lock of the outer query
+	merged := db.Session(&gorm.Session{NewDB: true}).Model(&model.PatientMerge{}).Select("source_patient_id")
+	var patients []model.Patient
+	err := db.Unscoped().
+		Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff).
+		Where("id NOT IN (?)", merged).
+		Order("deleted_at").
+		Find(&patients).Error
+	if err != nil {
+		return nil, err
+	}
+	purged := make([]model.PurgedPatient, 0, len(patients))
+	for _, patient := range patients {
+		purged = append(purged, model.PurgedPatient{
+			PatientID:      patient.ID,
+			RegisteredAt:   patient.CreatedAt,
+			DeletedAt:      patient.DeletedAt.Time,
+			DeletedByID:    patient.DeletedByID,
+			DeletionReason: patient.DeletionReason,
+		})
+	}
+	return purged, nil
+}
@@ -0,0 +1,130 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/RohanDSkaria/hospital-management-system/internal/model"
+	"github.com/google/uuid"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/logger"
+)
+
+// errNoDatabase is returned by the fake connection if a dry run still tries
+// to reach the database
+var errNoDatabase = errors.New("dry run reached the database")
+
+// fakeConnPool stands in for a Postgres connection in dry runs. It can begin
+// and end transactions but runs no statement.
+type fakeConnPool struct{}
+
+func (p *fakeConnPool) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return nil, errNoDatabase
+}
+
+func (p *fakeConnPool) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return nil, errNoDatabase
+}
+
+func (p *fakeConnPool) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return nil, errNoDatabase
+}
+
+func (p *fakeConnPool) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return nil
+}
+
+func (p *fakeConnPool) BeginTx(ctx context.Context, opts *sql.TxOptions) (gorm.ConnPool, error) {
+	return &fakeTxPool{}, nil
+}
+
+type fakeTxPool struct {
+	fakeConnPool
+}
+
+func (p *fakeTxPool) Commit() error   { return nil }
+func (p *fakeTxPool) Rollback() error { return nil }
+
+// sqlRecorder is a GORM logger that keeps every statement it is shown
+type sqlRecorder struct {
+	logger.Interface
+	statements []string
+}
+
+func (r *sqlRecorder) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	sql, _ := fc()
+	r.statements = append(r.statements, sql)
+}
+
+// newDryRunDB returns a Postgres GORM connection that builds statements
+// without running them, and the recorder that collects them
+func newDryRunDB(t *testing.T) (*gorm.DB, *sqlRecorder) {
+	t.Helper()
+	recorder := &sqlRecorder{Interface: logger.Discard}
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: &fakeConnPool{}}), &gorm.Config{
+		DryRun:               true,
+		DisableAutomaticPing: true,
+		Logger:               recorder,
+	})
+	if err != nil {
+		t.Fatalf("open dry run database: %v", err)
+	}
+	return db, recorder
+}
+
+func TestFindPurgeableSQL(t *testing.T) {
+	cutoff := time.Date(2016, time.March, 2, 0, 0, 0, 0, time.UTC)
+	const purgeable = `SELECT * FROM "patients" WHERE (deleted_at IS NOT NULL AND deleted_at < '2016-03-02 00:00:00') ` +
+		`AND id NOT IN (SELECT "source_patient_id" FROM "patient_merges") ORDER BY deleted_at`
+	cases := []struct {
+		name   string
+		locked bool
+		want   string
+	}{
+		{name: "report", want: purgeable},
+		{name: "locked for the purge", locked: true, want: purgeable + " FOR UPDATE"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			db, recorder := newDryRunDB(t)
+			if tc.locked {
+				db = db.Clauses(clause.Locking{Strength: "UPDATE"})
+			}
+			if _, err := findPurgeable(db, cutoff); err != nil {
+				t.Fatalf("findPurgeable: %v", err)
+			}
+			if len(recorder.statements) != 1 {
+				t.Fatalf("expected one statement, got %q", recorder.statements)
+			}
+			if got := strings.Join(strings.Fields(recorder.statements[0]), " "); got != tc.want {
+				t.Errorf("got  %s\nwant %s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestPurgeLocksThePatientsAndSavesTheReport(t *testing.T) {
+	db, recorder := newDryRunDB(t)
+	purge := &model.PatientPurge{Cutoff: time.Date(2016, time.March, 2, 0, 0, 0, 0, time.UTC), RetentionDays: 3650}
+	if err := NewPatientPurgeRepository(db).Purge(purge); err != nil {
+		t.Fatalf("Purge: %v", err)
+	}
+	if len(recorder.statements) != 2 {
+		t.Fatalf("expected the select and the report insert, got %q", recorder.statements)
+	}
+	if got := recorder.statements[0]; !strings.HasPrefix(got, `SELECT * FROM "patients"`) || !strings.HasSuffix(got, "FOR UPDATE") {
+		t.Errorf("expected the purgeable patients to be locked, got %s", got)
+	}
+	if got := recorder.statements[1]; !strings.HasPrefix(got, `INSERT INTO "patient_purges"`) {
+		t.Errorf("expected the report to be saved, got %s", got)
+	}
+	if purge.PurgedCount != 0 || purge.ID == uuid.Nil {
+		t.Errorf("expected an empty report with an ID, got %+v", purge)
+	}
+}
@@ -1,47 +1,441 @@
 package repository
 
 import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
 	"github.com/RohanDSkaria/hospital-management-system/internal/model"
+	"github.com/RohanDSkaria/hospital-management-system/internal/mrn"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrVersionConflict is returned when a patient or allergy was changed after
+// the version a write was based on
+var ErrVersionConflict = errors.New("record was changed by someone else")
+
+// Patient sort orders
+const (
+	PatientSortName        = "name"
+	PatientSortCreatedAt   = "created_at"
+	PatientSortDateOfBirth = "date_of_birth"
+)
+
+// patientSortColumns maps each sort order to its column. Every sort is
+// broken by id, so the order is stable.
+var patientSortColumns = map[string]string{
+	PatientSortName:        "full_name",
+	PatientSortCreatedAt:   "created_at",
+	PatientSortDateOfBirth: "date_of_birth",
+}
+
+// PatientCursor is the position of the last patient of a page: the value of
+// the sort column and the ID
+type PatientCursor struct {
+	Value interface{}
+	ID    uuid.UUID
+}
+
+// PatientFilter narrows down and orders the patients returned by List
+type PatientFilter struct {
+	// CareTeamMemberID limits the patients to those on the user's care teams
+	CareTeamMemberID *uuid.UUID
+	RegisteredByID   *uuid.UUID
+	CreatedFrom      *time.Time
+	CreatedTo        *time.Time
+	BornAfter        *time.Time
+	BornOnOrBefore   *time.Time
+	Sort             string // one of the PatientSort orders
+	Descending       bool
+	After            *PatientCursor
+	Limit            int
+}
+
+// IsValidPatientSort reports whether sort is a known patient sort order
+func IsValidPatientSort(sort string) bool {
+	_, ok := patientSortColumns[sort]
+	return ok
+}
+
+// PatientSearch is a ranked patient search. Terms are the lower-case words of
+// the query, made of letters and digits only, and are matched against the
+// name and address; Digits are matched against the digits of the contact
+// number. The date of birth, when set, must match exactly.
+type PatientSearch struct {
+	Terms       []string
+	NameTerms   []string // the terms matched by how they sound
+	Digits      string
+	DateOfBirth *time.Time
+	// CareTeamMemberID limits the patients to those on the user's care teams
+	CareTeamMemberID *uuid.UUID
+	Limit            int
+}
+
+// PatientMatch is a patient found by Search and how well it matched
+type PatientMatch struct {
+	model.Patient
+	Rank float64
+}
+
+// PatientLookalike is a patient who may be the same person as one being
+// registered, with how closely the names compare
+type PatientLookalike struct {
+	model.Patient
+	NameSimilarity  float64 // trigram similarity of the names, from 0 to 1
+	NameSoundsAlike bool    // every word of the new name sounds like a word of this one
+}
+
+// The expressions searched by Search. They must stay the same as the ones
+// indexed in the database package, or the indexes are not used.
+const (
+	patientSearchDocument = `to_tsvector('simple', coalesce(full_name, '') || ' ' || coalesce(address, ''))`
+	patientSearchPhonetic = `to_tsvector('simple', patient_name_phonetic(full_name))`
+	patientSearchDigits   = `regexp_replace(contact_number, '[^0-9]', '', 'g')`
 )
 
 type PatientRepository interface {
-	Create(patient *model.Patient) error
-	FindAll() ([]model.Patient, error)
+	Create(patient *model.Patient, version *model.PatientVersion) error
+	List(filter PatientFilter) ([]model.Patient, error)
+	Count(filter PatientFilter) (int64, error)
+	Search(search PatientSearch) ([]PatientMatch, error)
+	FindLookalikes(fullName string, dob time.Time, contactPattern string, limit int) ([]PatientLookalike, error)
 	FindByID(id uuid.UUID) (*model.Patient, error)
-	Update(patient *model.Patient) error
-	Delete(id uuid.UUID) error
+	FindIDByMRN(number string) (uuid.UUID, error)
+	AssignMissingMRNs(limit int) (int, error)
+	CountMissingMRNs() (int64, error)
+	Update(patient *model.Patient, version *model.PatientVersion) error
+	Delete(id uuid.UUID, expectedVersion int, deletedByID uuid.UUID, reason string) error
+	FindDeleted(limit, offset int) ([]model.Patient, int64, error)
+	FindDeletedByID(id uuid.UUID) (*model.Patient, error)
+	Undelete(id uuid.UUID) error
 }
 
 type patientRepository struct {
-	db *gorm.DB
+	db        *gorm.DB
+	mrnFormat mrn.Format
 }
 
-func NewPatientRepository(db *gorm.DB) PatientRepository {
-	return &patientRepository{db: db}
+// NewPatientRepository creates a new patient repository. New patients get a
+// medical record number in mrnFormat.
+func NewPatientRepository(db *gorm.DB, mrnFormat mrn.Format) PatientRepository {
+	return &patientRepository{db: db, mrnFormat: mrnFormat}
 }
 
-func (r *patientRepository) Create(patient *model.Patient) error {
-	return r.db.Create(patient).Error
+// Create saves a new patient with the next medical record number and its
+// first version. The caller sets who made the change and how; the snapshot
+// is filled in here.
+func (r *patientRepository) Create(patient *model.Patient, version *model.PatientVersion) error {
+	patient.Version = 1
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		number, err := r.nextMRN(tx)
+		if err != nil {
+			return err
+		}
+		patient.MRN = &number
+		if err := tx.Create(patient).Error; err != nil {
+			return err
+		}
+		return nextPatientVersion(tx, patient, version)
+	})
 }
 
-func (r *patientRepository) FindAll() ([]model.Patient, error) {
+// List returns up to filter.Limit patients matching the filter in a stable
+// order, starting after filter.After
+func (r *patientRepository) List(filter PatientFilter) ([]model.Patient, error) {
+	column := patientSortColumns[filter.Sort]
+	direction, compare := "ASC", ">"
+	if filter.Descending {
+		direction, compare = "DESC", "<"
+	}
+	query := r.filtered(filter)
+	if filter.After != nil {
+		query = query.Where(fmt.Sprintf("(%s, id) %s (?, ?)", column, compare), filter.After.Value, filter.After.ID)
+	}
 	var patients []model.Patient
-	err := r.db.Find(&patients).Error
+	err := query.Order(fmt.Sprintf("%s %s, id %s", column, direction, direction)).
+		Limit(filter.Limit).
+		Find(&patients).Error
 	return patients, err
 }
 
+// Count returns the number of patients matching the filter, ignoring the
+// cursor and limit
+func (r *patientRepository) Count(filter PatientFilter) (int64, error) {
+	var total int64
+	err := r.filtered(filter).Count(&total).Error
+	return total, err
+}
+
+// filtered applies the filter's conditions to a patient query
+func (r *patientRepository) filtered(filter PatientFilter) *gorm.DB {
+	query := r.db.Model(&model.Patient{})
+	if filter.CareTeamMemberID != nil {
+		query = query.Where("id IN (?)", r.db.Model(&model.CareTeamMember{}).Select("patient_id").Where("user_id = ?", *filter.CareTeamMemberID))
+	}
+	if filter.RegisteredByID != nil {
+		query = query.Where("registered_by_id = ?", *filter.RegisteredByID)
+	}
+	if filter.CreatedFrom != nil {
+		query = query.Where("created_at >= ?", *filter.CreatedFrom)
+	}
+	if filter.CreatedTo != nil {
+		query = query.Where("created_at < ?", *filter.CreatedTo)
+	}
+	if filter.BornAfter != nil {
+		query = query.Where("date_of_birth > ?", *filter.BornAfter)
+	}
+	if filter.BornOnOrBefore != nil {
+		query = query.Where("date_of_birth <= ?", *filter.BornOnOrBefore)
+	}
+	return query
+}
+
+// Search returns the best patient matches for a search, best first. A patient
+// matches on any of: a word of the name or address starting with every term,
+// a name or address close to the terms despite typos, a name that sounds
+// like the terms, or a contact number containing the digits.
+func (r *patientRepository) Search(search PatientSearch) ([]PatientMatch, error) {
+	args := map[string]interface{}{"limit": search.Limit}
+	var matches, ranks []string
+	if len(search.Terms) > 0 {
+		prefixes := make([]string, len(search.Terms))
+		for i, term := range search.Terms {
+			prefixes[i] = term + ":*"
+		}
+		args["prefixes"] = strings.Join(prefixes, " & ")
+		args["text"] = strings.Join(search.Terms, " ")
+		matches = append(matches,
+			patientSearchDocument+` @@ to_tsquery('simple', @prefixes)`,
+			`@text <% full_name`,
+			`@text <% address`)
+		ranks = append(ranks,
+			`ts_rank(`+patientSearchDocument+`, to_tsquery('simple', @prefixes))`,
+			`word_similarity(@text, full_name)`,
+			`0.5 * word_similarity(@text, coalesce(address, ''))`)
+	}
+	if len(search.NameTerms) > 0 {
+		args["name"] = strings.Join(search.NameTerms, " ")
+		sounds := patientSearchPhonetic + ` @@ to_tsquery('simple', replace(patient_name_phonetic(@name), ' ', ' & '))`
+		matches = append(matches, sounds)
+		ranks = append(ranks, `CASE WHEN `+sounds+` THEN 0.5 ELSE 0 END`)
+	}
+	if search.Digits != "" {
+		args["digits"] = "%" + search.Digits + "%"
+		contains := patientSearchDigits + ` LIKE @digits`
+		matches = append(matches, contains)
+		ranks = append(ranks, `CASE WHEN `+contains+` THEN 1 ELSE 0 END`)
+	}
+
+	conditions := []string{"deleted_at IS NULL"}
+	if len(matches) > 0 {
+		conditions = append(conditions, "("+strings.Join(matches, " OR ")+")")
+	}
+	if search.DateOfBirth != nil {
+		args["dob"] = *search.DateOfBirth
+		conditions = append(conditions, "date_of_birth = @dob")
+	}
+	if search.CareTeamMemberID != nil {
+		args["member"] = *search.CareTeamMemberID
+		conditions = append(conditions, "id IN (SELECT patient_id FROM care_team_members WHERE user_id = @member)")
+	}
+	rank := "0"
+	if len(ranks) > 0 {
+		rank = strings.Join(ranks, " + ")
+	}
+
+	var found []PatientMatch
+	err := r.db.Raw(`SELECT *, `+rank+` AS rank FROM patients
+		WHERE `+strings.Join(conditions, " AND ")+`
+		ORDER BY rank DESC, full_name, id
+		LIMIT @limit`, args).Scan(&found).Error
+	return found, err
+}
+
+// FindLookalikes returns up to limit patients who share the date of birth of
+// a new patient, whose name is similar to or sounds like theirs, or, if
+// contactPattern is set, the digits of whose contact number match that LIKE
+// pattern. The most similar names come first.
+func (r *patientRepository) FindLookalikes(fullName string, dob time.Time, contactPattern string, limit int) ([]PatientLookalike, error) {
+	sounds := patientSearchPhonetic + ` @@ to_tsquery('simple', replace(patient_name_phonetic(@name), ' ', ' & '))`
+	conditions := []string{"date_of_birth = @dob", "full_name % @name", sounds}
+	args := map[string]interface{}{"name": fullName, "dob": dob, "limit": limit}
+	if contactPattern != "" {
+		conditions = append(conditions, patientSearchDigits+" LIKE @digits")
+		args["digits"] = contactPattern
+	}
+	var found []PatientLookalike
+	err := r.db.Raw(`SELECT *, similarity(full_name, @name) AS name_similarity,
+			coalesce(`+sounds+`, false) AS name_sounds_alike
+		FROM patients
+		WHERE deleted_at IS NULL AND (`+strings.Join(conditions, " OR ")+`)
+		ORDER BY name_similarity DESC, id
+		LIMIT @limit`, args).Scan(&found).Error
+	return found, err
+}
+
 func (r *patientRepository) FindByID(id uuid.UUID) (*model.Patient, error) {
 	var patient model.Patient
 	err := r.db.Where("id = ?", id).First(&patient).Error
 	return &patient, err
 }
 
-func (r *patientRepository) Update(patient *model.Patient) error {
-	return r.db.Save(patient).Error
+// FindIDByMRN returns the ID of the patient with the medical record number,
+// deleted or not
+func (r *patientRepository) FindIDByMRN(number string) (uuid.UUID, error) {
+	var patient model.Patient
+	err := r.db.Unscoped().Select("id").Where("mrn = ?", number).First(&patient).Error
+	return patient.ID, err
+}
+
+// AssignMissingMRNs gives up to limit patients without a medical record
+// number one, oldest first, and returns how many it numbered. Deleted
+// patients are numbered too, as they may be restored.
+func (r *patientRepository) AssignMissingMRNs(limit int) (int, error) {
+	assigned := 0
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var patients []model.Patient
+		err := tx.Unscoped().Select("id").
+			Where("mrn IS NULL").
+			Order("created_at, id").
+			Limit(limit).
+			Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Find(&patients).Error
+		if err != nil {
+			return err
+		}
+		for _, patient := range patients {
+			number, err := r.nextMRN(tx)
+			if err != nil {
+				return err
+			}
+			if err := tx.Unscoped().Model(&model.Patient{}).Where("id = ?", patient.ID).Update("mrn", number).Error; err != nil {
+				return err
+			}
+		}
+		assigned = len(patients)
+		return nil
+	})
+	return assigned, err
+}
+
+// CountMissingMRNs returns how many patients have no medical record number
+func (r *patientRepository) CountMissingMRNs() (int64, error) {
+	var total int64
+	err := r.db.Unscoped().Model(&model.Patient{}).Where("mrn IS NULL").Count(&total).Error
+	return total, err
+}
+
+// nextMRN takes the next number from the medical record number sequence.
+// Numbers taken by transactions that roll back are skipped, never reused.
+func (r *patientRepository) nextMRN(tx *gorm.DB) (string, error) {
+	var sequence int64
+	if err := tx.Raw("SELECT nextval('patient_mrn_seq')").Scan(&sequence).Error; err != nil {
+		return "", err
+	}
+	return r.mrnFormat.Generate(sequence)
+}
+
+// Update saves a patient's details and records them as a new version. The
+// patient's Version must still be the one stored, or ErrVersionConflict is
+// returned; on success it is the number of the new version.
+func (r *patientRepository) Update(patient *model.Patient, version *model.PatientVersion) error {
+	expected := patient.Version
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		return updatePatient(tx, patient, version)
+	})
+	if err != nil {
+		patient.Version = expected
+	}
+	return err
+}
+
+// updatePatient saves a patient's details as a new version within a
+// transaction, as Update does
+func updatePatient(tx *gorm.DB, patient *model.Patient, version *model.PatientVersion) error {
+	expected := patient.Version
+	now := time.Now()
+	result := tx.Model(&model.Patient{}).
+		Where("id = ? AND version = ?", patient.ID, expected).
+		Updates(map[string]interface{}{
+			"full_name":       patient.FullName,
+			"date_of_birth":   patient.DateOfBirth,
+			"address":         patient.Address,
+			"contact_number":  patient.ContactNumber,
+			"medical_history": patient.MedicalHistory,
+			"version":         expected + 1,
+			"updated_at":      now,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrVersionConflict
+	}
+	patient.Version = expected + 1
+	patient.UpdatedAt = now
+	return nextPatientVersion(tx, patient, version)
+}
+
+// Delete marks a patient as deleted by a user. The record stays until it is
+// purged after the retention period. It returns ErrVersionConflict if the
+// patient was changed after expectedVersion.
+func (r *patientRepository) Delete(id uuid.UUID, expectedVersion int, deletedByID uuid.UUID, reason string) error {
+	result := r.db.Model(&model.Patient{}).Where("id = ? AND version = ?", id, expectedVersion).Updates(map[string]interface{}{
+		"deleted_at":      time.Now(),
+		"deleted_by_id":   deletedByID,
+		"deletion_reason": reason,
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrVersionConflict
+	}
+	return nil
+}
+
+// FindDeleted returns a page of deleted patients, most recently deleted first,
+// and the total number of deleted patients. Patients deleted by a merge are
+// left out; they are listed with the merges.
+func (r *patientRepository) FindDeleted(limit, offset int) ([]model.Patient, int64, error) {
+	query := r.db.Unscoped().Model(&model.Patient{}).
+		Where("deleted_at IS NOT NULL").
+		Where("id NOT IN (?)", r.db.Model(&model.PatientMerge{}).Select("source_patient_id"))
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var patients []model.Patient
+	err := query.Order("deleted_at DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&patients).Error
+	return patients, total, err
+}
+
+// FindDeletedByID returns a deleted patient
+func (r *patientRepository) FindDeletedByID(id uuid.UUID) (*model.Patient, error) {
+	var patient model.Patient
+	err := r.db.Unscoped().Where("id = ? AND deleted_at IS NOT NULL", id).First(&patient).Error
+	if err != nil {
+		return nil, err
+	}
+	return &patient, nil
 }
 
-func (r *patientRepository) Delete(id uuid.UUID) error {
-	return r.db.Delete(&model.Patient{}, id).Error
+// Undelete brings a deleted patient back
+func (r *patientRepository) Undelete(id uuid.UUID) error {
+	result := r.db.Unscoped().Model(&model.Patient{}).
+		Where("id = ? AND deleted_at IS NOT NULL", id).
+		Updates(map[string]interface{}{"deleted_at": nil, "deleted_by_id": nil, "deletion_reason": ""})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
 }
@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/RohanDSkaria/hospital-management-system/internal/model"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// PatientVersionRepository defines the interface for reading patient record history.
+// Versions are written by PatientRepository together with the change they record.
+type PatientVersionRepository interface {
+	ListByPatient(patientID uuid.UUID, limit, offset int) ([]model.PatientVersion, int64, error)
+	FindByVersion(patientID uuid.UUID, version int) (*model.PatientVersion, error)
+	FindAsOf(patientID uuid.UUID, at time.Time) (*model.PatientVersion, error)
+}
+
+// patientVersionRepository is the implementation of PatientVersionRepository
+type patientVersionRepository struct {
+	db *gorm.DB
+}
+
+// NewPatientVersionRepository creates a new patient version repository
+func NewPatientVersionRepository(db *gorm.DB) PatientVersionRepository {
+	return &patientVersionRepository{db: db}
+}
+
+// ListByPatient returns a page of a patient's versions, newest first, and the total number of versions
+func (r *patientVersionRepository) ListByPatient(patientID uuid.UUID, limit, offset int) ([]model.PatientVersion, int64, error) {
+	query := r.db.Model(&model.PatientVersion{}).Where("patient_id = ?", patientID)
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var versions []model.PatientVersion
+	err := query.Order("version DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&versions).Error
+	return versions, total, err
+}
+
+// FindByVersion returns one version of a patient record
+func (r *patientVersionRepository) FindByVersion(patientID uuid.UUID, version int) (*model.PatientVersion, error) {
+	var patientVersion model.PatientVersion
+	err := r.db.Where("patient_id = ? AND version = ?", patientID, version).First(&patientVersion).Error
+	if err != nil {
+		return nil, err
+	}
+	return &patientVersion, nil
+}
+
+// FindAsOf returns the version of a patient record that was current at the
+// given time. It returns gorm.ErrRecordNotFound if the record did not exist yet.
+func (r *patientVersionRepository) FindAsOf(patientID uuid.UUID, at time.Time) (*model.PatientVersion, error) {
+	var patientVersion model.PatientVersion
+	err := r.db.Where("patient_id = ? AND created_at <= ?", patientID, at).
+		Order("version DESC").
+		First(&patientVersion).Error
+	if err != nil {
+		return nil, err
+	}
+	return &patientVersion, nil
+}
+
+// nextPatientVersion writes a snapshot of the patient as the version named by
+// patient.Version. It must run in the transaction that changed the patient.
+func nextPatientVersion(tx *gorm.DB, patient *model.Patient, version *model.PatientVersion) error {
+	version.Snapshot(patient)
+	version.Version = patient.Version
+	version.CreatedAt = patient.UpdatedAt
+	return tx.Create(version).Error
+}
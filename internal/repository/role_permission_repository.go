@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"github.com/RohanDSkaria/hospital-management-system/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// RolePermissionRepository defines the interface for role permission data operations
+type RolePermissionRepository interface {
+	ListGranted() ([]model.RolePermission, error)
+	ReplaceForRole(role model.Role, permissions []model.Permission) error
+	SeedDefaults(defaults map[model.Role][]model.Permission) error
+}
+
+// rolePermissionRepository is the implementation of RolePermissionRepository
+type rolePermissionRepository struct {
+	db *gorm.DB
+}
+
+// NewRolePermissionRepository creates a new role permission repository
+func NewRolePermissionRepository(db *gorm.DB) RolePermissionRepository {
+	return &rolePermissionRepository{db: db}
+}
+
+// rolePermissionKey is the unique key of a role permission
+var rolePermissionKey = []clause.Column{{Name: "role"}, {Name: "permission"}}
+
+// ListGranted returns every permission currently granted to every role
+func (r *rolePermissionRepository) ListGranted() ([]model.RolePermission, error) {
+	var grants []model.RolePermission
+	err := r.db.Where("granted = ?", true).Order("role, permission").Find(&grants).Error
+	return grants, err
+}
+
+// ReplaceForRole sets the permissions of a role in one transaction. Grants
+// that are taken away are kept as not granted rather than deleted.
+func (r *rolePermissionRepository) ReplaceForRole(role model.Role, permissions []model.Permission) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.RolePermission{}).Where("role = ?", role).Update("granted", false).Error; err != nil {
+			return err
+		}
+		for _, permission := range permissions {
+			grant := &model.RolePermission{Role: role, Permission: permission, Granted: true}
+			err := tx.Clauses(clause.OnConflict{
+				Columns:   rolePermissionKey,
+				DoUpdates: clause.Assignments(map[string]interface{}{"granted": true, "updated_at": gorm.Expr("NOW()")}),
+			}).Create(grant).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// SeedDefaults stores every default grant that has never been stored, so a
+// new installation starts with the built-in mapping and an upgrade grants new
+// permissions without undoing an admin's changes
+func (r *rolePermissionRepository) SeedDefaults(defaults map[model.Role][]model.Permission) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		for role, permissions := range defaults {
+			for _, permission := range permissions {
+				grant := &model.RolePermission{Role: role, Permission: permission, Granted: true}
+				if err := tx.Clauses(clause.OnConflict{Columns: rolePermissionKey, DoNothing: true}).Create(grant).Error; err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,100 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/RohanDSkaria/hospital-management-system/internal/model"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// SessionRepository defines the interface for session and refresh token data operations
+type SessionRepository interface {
+	CreateSession(session *model.Session) error
+	FindSessionByID(id uuid.UUID) (*model.Session, error)
+	TouchSession(id uuid.UUID, at time.Time) error
+	RevokeSession(id uuid.UUID, at time.Time) error
+	RevokeAllForUser(userID uuid.UUID, at time.Time) error
+	RevokeOtherSessions(userID, keepID uuid.UUID, at time.Time) error
+	CreateRefreshToken(token *model.RefreshToken) error
+	FindRefreshTokenByHash(hash string) (*model.RefreshToken, error)
+	MarkRefreshTokenUsed(id uuid.UUID, at time.Time) (bool, error)
+}
+
+// sessionRepository is the implementation of SessionRepository
+type sessionRepository struct {
+	db *gorm.DB
+}
+
+// NewSessionRepository creates a new session repository
+func NewSessionRepository(db *gorm.DB) SessionRepository {
+	return &sessionRepository{db: db}
+}
+
+// CreateSession persists a new session
+func (r *sessionRepository) CreateSession(session *model.Session) error {
+	return r.db.Create(session).Error
+}
+
+// FindSessionByID finds a session by its ID, together with the user it belongs to
+func (r *sessionRepository) FindSessionByID(id uuid.UUID) (*model.Session, error) {
+	var session model.Session
+	err := r.db.Preload("User").Where("sessions.id = ?", id).First(&session).Error
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// TouchSession records the last time a session was used to refresh tokens
+func (r *sessionRepository) TouchSession(id uuid.UUID, at time.Time) error {
+	return r.db.Model(&model.Session{}).Where("id = ?", id).Update("last_used_at", at).Error
+}
+
+// RevokeSession revokes a single session, which invalidates all of its refresh tokens
+func (r *sessionRepository) RevokeSession(id uuid.UUID, at time.Time) error {
+	return r.db.Model(&model.Session{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", at).Error
+}
+
+// RevokeAllForUser revokes every active session belonging to a user
+func (r *sessionRepository) RevokeAllForUser(userID uuid.UUID, at time.Time) error {
+	return r.db.Model(&model.Session{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", at).Error
+}
+
+// RevokeOtherSessions revokes every active session of a user except the one given
+func (r *sessionRepository) RevokeOtherSessions(userID, keepID uuid.UUID, at time.Time) error {
+	return r.db.Model(&model.Session{}).
+		Where("user_id = ? AND id <> ? AND revoked_at IS NULL", userID, keepID).
+		Update("revoked_at", at).Error
+}
+
+// CreateRefreshToken persists a new refresh token
+func (r *sessionRepository) CreateRefreshToken(token *model.RefreshToken) error {
+	return r.db.Create(token).Error
+}
+
+// FindRefreshTokenByHash finds a refresh token by the hash of its value
+func (r *sessionRepository) FindRefreshTokenByHash(hash string) (*model.RefreshToken, error) {
+	var token model.RefreshToken
+	err := r.db.Where("token_hash = ?", hash).First(&token).Error
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// MarkRefreshTokenUsed atomically marks a refresh token as used.
+// It returns false if the token had already been used, which indicates reuse.
+func (r *sessionRepository) MarkRefreshTokenUsed(id uuid.UUID, at time.Time) (bool, error) {
+	result := r.db.Model(&model.RefreshToken{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", at)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
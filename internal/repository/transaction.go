@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"github.com/RohanDSkaria/hospital-management-system/internal/mrn"
+	"gorm.io/gorm"
+)
+
+// Tx hands out repositories that all work in the same database transaction.
+// Their own transactions become savepoints in it.
+type Tx interface {
+	Patients() PatientRepository
+	CareTeam() CareTeamRepository
+	BreakGlass() BreakGlassRepository
+	PatientMerges() PatientMergeRepository
+	PatientPurges() PatientPurgeRepository
+	Allergies() AllergyRepository
+	ClinicalNotes() ClinicalNoteRepository
+	VitalSigns() VitalSignsRepository
+	Audit() AuditRepository
+}
+
+// Transactor runs changes that span several repositories in one transaction
+type Transactor interface {
+	// Transaction commits everything done through tx if fn returns nil and
+	// rolls it all back otherwise
+	Transaction(fn func(tx Tx) error) error
+}
+
+// transactor is the implementation of Transactor
+type transactor struct {
+	db        *gorm.DB
+	mrnFormat mrn.Format
+}
+
+// NewTransactor creates a new transactor. Patients created in a transaction
+// get a medical record number in mrnFormat.
+func NewTransactor(db *gorm.DB, mrnFormat mrn.Format) Transactor {
+	return &transactor{db: db, mrnFormat: mrnFormat}
+}
+
+// Transaction runs fn in a database transaction
+func (t *transactor) Transaction(fn func(tx Tx) error) error {
+	return t.db.Transaction(func(db *gorm.DB) error {
+		return fn(&tx{db: db, mrnFormat: t.mrnFormat})
+	})
+}
+
+// tx is the implementation of Tx
+type tx struct {
+	db        *gorm.DB
+	mrnFormat mrn.Format
+}
+
+func (t *tx) Patients() PatientRepository           { return NewPatientRepository(t.db, t.mrnFormat) }
+func (t *tx) CareTeam() CareTeamRepository          { return NewCareTeamRepository(t.db) }
+func (t *tx) BreakGlass() BreakGlassRepository      { return NewBreakGlassRepository(t.db) }
+func (t *tx) PatientMerges() PatientMergeRepository { return NewPatientMergeRepository(t.db) }
+func (t *tx) PatientPurges() PatientPurgeRepository { return NewPatientPurgeRepository(t.db) }
+func (t *tx) Allergies() AllergyRepository          { return NewAllergyRepository(t.db) }
+func (t *tx) ClinicalNotes() ClinicalNoteRepository { return NewClinicalNoteRepository(t.db) }
+func (t *tx) VitalSigns() VitalSignsRepository      { return NewVitalSignsRepository(t.db) }
+func (t *tx) Audit() AuditRepository                { return NewAuditRepository(t.db) }
@@ -1,14 +1,40 @@
 package repository
 
 import (
+	"errors"
+	"slices"
+	"strings"
+
 	"github.com/RohanDSkaria/hospital-management-system/internal/model"
+	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// ErrNoAdminLeft is returned when an update would leave no active admin
+var ErrNoAdminLeft = errors.New("update would leave no active admin")
+
+// UserFilter narrows down the users returned by List
+type UserFilter struct {
+	Query  string // matched against name and email
+	Role   model.Role
+	Active *bool
+	// ServiceAccount selects only service accounts (true) or only people (false)
+	ServiceAccount *bool
+	Limit          int
+	Offset         int
+}
+
 // UserRepository defines the interface for user data operations
 type UserRepository interface {
 	SaveUser(user *model.User) error
 	FindByEmail(email string) (*model.User, error)
+	FindByID(id uuid.UUID) (*model.User, error)
+	Update(user *model.User) error
+	UpdateKeepingAdmin(user *model.User) error
+	CountByRole(role model.Role) (int64, error)
+	List(filter UserFilter) ([]model.User, int64, error)
+	AdvanceTOTPStep(id uuid.UUID, step int64) (bool, error)
 }
 
 // userRepository is the implementation of UserRepository
@@ -35,3 +61,95 @@ func (r *userRepository) FindByEmail(email string) (*model.User, error) {
 	}
 	return &user, nil
 }
+
+// FindByID finds a user by their ID
+func (r *userRepository) FindByID(id uuid.UUID) (*model.User, error) {
+	var user model.User
+	err := r.db.Where("id = ?", id).First(&user).Error
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// Update saves changes to an existing user
+func (r *userRepository) Update(user *model.User) error {
+	return r.db.Save(user).Error
+}
+
+// UpdateKeepingAdmin saves changes to an existing user unless they would
+// leave no active admin. The active admins are locked while they are
+// counted, so two admins demoted at the same time cannot both go.
+func (r *userRepository) UpdateKeepingAdmin(user *model.User) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var admins []uuid.UUID
+		err := tx.Model(&model.User{}).
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("role = ? AND active", model.Admin).
+			Pluck("id", &admins).Error
+		if err != nil {
+			return err
+		}
+		if leavesNoAdmin(admins, user) {
+			return ErrNoAdminLeft
+		}
+		return tx.Save(user).Error
+	})
+}
+
+// leavesNoAdmin reports whether saving the user removes the only active admin
+func leavesNoAdmin(admins []uuid.UUID, user *model.User) bool {
+	stillAdmin := user.Role == model.Admin && user.Active
+	return !stillAdmin && len(admins) == 1 && slices.Contains(admins, user.ID)
+}
+
+// CountByRole counts the active users that have the given role
+func (r *userRepository) CountByRole(role model.Role) (int64, error) {
+	var count int64
+	err := r.db.Model(&model.User{}).Where("role = ? AND active", role).Count(&count).Error
+	return count, err
+}
+
+// List returns a page of users matching the filter, ordered by name, and the total number of matches
+func (r *userRepository) List(filter UserFilter) ([]model.User, int64, error) {
+	query := r.db.Model(&model.User{})
+	if filter.Query != "" {
+		pattern := "%" + escapeLike(filter.Query) + "%"
+		query = query.Where("full_name ILIKE ? OR email ILIKE ?", pattern, pattern)
+	}
+	if filter.Role != "" {
+		query = query.Where("role = ?", filter.Role)
+	}
+	if filter.Active != nil {
+		query = query.Where("active = ?", *filter.Active)
+	}
+	if filter.ServiceAccount != nil {
+		query = query.Where("service_account = ?", *filter.ServiceAccount)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var users []model.User
+	err := query.Order("full_name, id").Limit(filter.Limit).Offset(filter.Offset).Find(&users).Error
+	return users, total, err
+}
+
+// escapeLike escapes the wildcard characters of a LIKE pattern
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+// AdvanceTOTPStep atomically records the time step of an accepted TOTP code.
+// It returns false if a code of this or a later step was already accepted.
+func (r *userRepository) AdvanceTOTPStep(id uuid.UUID, step int64) (bool, error) {
+	result := r.db.Model(&model.User{}).
+		Where("id = ? AND totp_last_step < ?", id, step).
+		Update("totp_last_step", step)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
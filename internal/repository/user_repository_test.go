@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/RohanDSkaria/hospital-management-system/internal/model"
+	"github.com/google/uuid"
+)
+
+func TestLeavesNoAdmin(t *testing.T) {
+	admin, other := uuid.New(), uuid.New()
+	cases := []struct {
+		name   string
+		admins []uuid.UUID
+		user   model.User
+		want   bool
+	}{
+		{name: "last admin demoted", admins: []uuid.UUID{admin}, user: model.User{ID: admin, Role: model.Doctor, Active: true}, want: true},
+		{name: "last admin deactivated", admins: []uuid.UUID{admin}, user: model.User{ID: admin, Role: model.Admin}, want: true},
+		{name: "one of two admins demoted", admins: []uuid.UUID{admin, other}, user: model.User{ID: admin, Role: model.Doctor, Active: true}},
+		{name: "last admin renamed", admins: []uuid.UUID{admin}, user: model.User{ID: admin, Role: model.Admin, Active: true, FullName: "Jane"}},
+		{name: "someone else demoted", admins: []uuid.UUID{admin}, user: model.User{ID: other, Role: model.Nurse}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := leavesNoAdmin(tc.admins, &tc.user); got != tc.want {
+				t.Errorf("leavesNoAdmin() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestUpdateKeepingAdminLocksTheAdmins(t *testing.T) {
+	db, recorder := newDryRunDB(t)
+	user := &model.User{ID: uuid.New(), Role: model.Doctor, Active: true}
+	if err := NewUserRepository(db).UpdateKeepingAdmin(user); err != nil {
+		t.Fatalf("UpdateKeepingAdmin: %v", err)
+	}
+	if len(recorder.statements) != 2 {
+		t.Fatalf("expected the admin count and the update, got %q", recorder.statements)
+	}
+	if got := recorder.statements[0]; !strings.HasPrefix(got, `SELECT "id" FROM "users" WHERE role = 'admin' AND active`) || !strings.HasSuffix(got, "FOR UPDATE") {
+		t.Errorf("expected the active admins to be locked, got %s", got)
+	}
+	if got := recorder.statements[1]; !strings.HasPrefix(got, `UPDATE "users" SET`) {
+		t.Errorf("expected the user to be saved, got %s", got)
+	}
+}
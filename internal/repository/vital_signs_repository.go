@@ -0,0 +1,112 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/RohanDSkaria/hospital-management-system/internal/model"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// VitalSignsFilter selects a patient's vital signs
+type VitalSignsFilter struct {
+	PatientID uuid.UUID
+	From      *time.Time // taken at or after
+	To        *time.Time // taken before
+	Limit     int
+	Offset    int
+}
+
+// VitalSignsRepository defines the interface for vital signs data operations
+type VitalSignsRepository interface {
+	CreateBatch(observations []model.VitalSigns) error
+	List(filter VitalSignsFilter) ([]model.VitalSigns, int64, error)
+	Trend(patientID uuid.UUID, column string, from, to *time.Time, limit int) ([]model.VitalSigns, error)
+	LatestHeight(patientID uuid.UUID, before time.Time) (*model.VitalSigns, error)
+}
+
+// vitalSignsRepository is the implementation of VitalSignsRepository
+type vitalSignsRepository struct {
+	db *gorm.DB
+}
+
+// NewVitalSignsRepository creates a new vital signs repository
+func NewVitalSignsRepository(db *gorm.DB) VitalSignsRepository {
+	return &vitalSignsRepository{db: db}
+}
+
+// CreateBatch saves all observations or none of them
+func (r *vitalSignsRepository) CreateBatch(observations []model.VitalSigns) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return tx.Create(&observations).Error
+	})
+}
+
+// List returns a page of the vital signs matching the filter, most recently
+// taken first, and the total number of matching observations
+func (r *vitalSignsRepository) List(filter VitalSignsFilter) ([]model.VitalSigns, int64, error) {
+	query := r.db.Model(&model.VitalSigns{}).Where("patient_id = ?", filter.PatientID)
+	query = takenBetween(query, filter.From, filter.To)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var observations []model.VitalSigns
+	err := query.Order("taken_at DESC, created_at DESC").
+		Limit(filter.Limit).
+		Offset(filter.Offset).
+		Find(&observations).Error
+	return observations, total, err
+}
+
+// Trend returns the most recent observations, up to limit, in which the
+// column was measured, oldest first. Only the ID, the time taken and the
+// column are loaded. The column must be one of the measurement columns.
+func (r *vitalSignsRepository) Trend(patientID uuid.UUID, column string, from, to *time.Time, limit int) ([]model.VitalSigns, error) {
+	query := r.db.Model(&model.VitalSigns{}).
+		Select("id", "taken_at", column).
+		Where("patient_id = ?", patientID).
+		Where(clause.Expr{SQL: "? IS NOT NULL", Vars: []interface{}{clause.Column{Name: column}}})
+	query = takenBetween(query, from, to)
+
+	var observations []model.VitalSigns
+	if err := query.Order("taken_at DESC").Limit(limit).Find(&observations).Error; err != nil {
+		return nil, err
+	}
+	for i, j := 0, len(observations)-1; i < j; i, j = i+1, j-1 {
+		observations[i], observations[j] = observations[j], observations[i]
+	}
+	return observations, nil
+}
+
+// LatestHeight returns the time and height of the patient's most recent
+// observation with a height taken at or before the given time, or nil if
+// there is none
+func (r *vitalSignsRepository) LatestHeight(patientID uuid.UUID, before time.Time) (*model.VitalSigns, error) {
+	var observation model.VitalSigns
+	err := r.db.Select("taken_at", "height").
+		Where("patient_id = ? AND taken_at <= ? AND height IS NOT NULL", patientID, before).
+		Order("taken_at DESC").
+		First(&observation).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &observation, nil
+}
+
+// takenBetween limits a vital signs query to the times taken in [from, to)
+func takenBetween(query *gorm.DB, from, to *time.Time) *gorm.DB {
+	if from != nil {
+		query = query.Where("taken_at >= ?", *from)
+	}
+	if to != nil {
+		query = query.Where("taken_at < ?", *to)
+	}
+	return query
+}
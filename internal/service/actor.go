@@ -0,0 +1,19 @@
+package service
+
+import (
+	"github.com/RohanDSkaria/hospital-management-system/internal/model"
+	"github.com/google/uuid"
+)
+
+// Actor is the authenticated user a service call is made for. Services that
+// filter records by who is asking take it as their first argument. The
+// request details are kept for the audit log.
+type Actor struct {
+	UserID    uuid.UUID
+	Role      model.Role
+	RequestID string
+	ClientIP  string
+}
+
+// SystemActor is who scheduled jobs act as in the audit log
+var SystemActor = Actor{Role: model.Role("system")}
@@ -0,0 +1,357 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/RohanDSkaria/hospital-management-system/internal/model"
+	"github.com/RohanDSkaria/hospital-management-system/internal/repository"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrClinicalDataForbidden is returned when a non-clinician tries to view or record clinical data
+	ErrClinicalDataForbidden = errors.New("only doctors and nurses can view or record clinical data")
+	// ErrInvalidAllergy is returned when an allergy is missing a substance or has an unknown code
+	ErrInvalidAllergy = errors.New("invalid allergy")
+	// ErrAllergyNotFound is returned when a patient has no such allergy
+	ErrAllergyNotFound = errors.New("allergy not found")
+	// ErrDuplicateAllergy is returned when a patient already has an active allergy to the substance
+	ErrDuplicateAllergy = errors.New("patient already has an active allergy to this substance")
+	// ErrAllergyVersionMismatch is returned when an allergy was changed by someone else while it was being changed
+	ErrAllergyVersionMismatch = errors.New("allergy was changed by someone else, fetch it again and retry")
+	// ErrActiveAllergies is returned when no known allergies is asserted for a patient with active allergies
+	ErrActiveAllergies = errors.New("patient has active allergies, mark them inactive, resolved or entered in error first")
+	// ErrNoKnownAllergiesNotFound is returned when a patient has no no-known-allergies assertion to withdraw
+	ErrNoKnownAllergiesNotFound = errors.New("no known allergies has not been asserted for this patient")
+)
+
+// AllergyInput is an allergy as entered by a clinician
+type AllergyInput struct {
+	Substance string
+	Category  model.AllergyCategory
+	Reaction  string
+	Severity  model.AllergySeverity
+	Status    model.AllergyStatus // active if empty
+}
+
+// AllergySummary is what a clinician opening a patient must see about their
+// allergies: the active ones, or that the patient has none
+type AllergySummary struct {
+	Active           []model.Allergy
+	NoKnownAllergies *model.NoKnownAllergies
+}
+
+// AllergyService defines the interface for recording patients' allergies
+type AllergyService interface {
+	ListAllergies(actor Actor, patientID uuid.UUID, statuses []model.AllergyStatus) ([]model.Allergy, *model.NoKnownAllergies, error)
+	GetAllergy(actor Actor, patientID, allergyID uuid.UUID) (*model.Allergy, error)
+	CreateAllergy(actor Actor, patientID uuid.UUID, input AllergyInput) (*model.Allergy, error)
+	UpdateAllergy(actor Actor, patientID, allergyID uuid.UUID, input AllergyInput) (*model.Allergy, error)
+	DeleteAllergy(actor Actor, patientID, allergyID uuid.UUID) (*model.Allergy, error)
+	AssertNoKnownAllergies(actor Actor, patientID uuid.UUID) (*model.NoKnownAllergies, error)
+	WithdrawNoKnownAllergies(actor Actor, patientID uuid.UUID) error
+}
+
+type allergyService struct {
+	allergyRepo repository.AllergyRepository
+	patientRepo repository.PatientRepository
+	transactor  repository.Transactor
+	access      *PatientAccess
+	audit       AuditService
+}
+
+// NewAllergyService creates a new allergy service
+func NewAllergyService(allergyRepo repository.AllergyRepository, patientRepo repository.PatientRepository, transactor repository.Transactor, access *PatientAccess, audit AuditService) AllergyService {
+	return &allergyService{allergyRepo: allergyRepo, patientRepo: patientRepo, transactor: transactor, access: access, audit: audit}
+}
+
+// ListAllergies returns a patient's allergies with one of the statuses, and
+// their no known allergies assertion if there is one. Without statuses,
+// every allergy but those entered in error is returned.
+func (s *allergyService) ListAllergies(actor Actor, patientID uuid.UUID, statuses []model.AllergyStatus) ([]model.Allergy, *model.NoKnownAllergies, error) {
+	for _, status := range statuses {
+		if !status.IsValid() {
+			return nil, nil, fmt.Errorf("%w: unknown status %q", ErrInvalidAllergy, status)
+		}
+	}
+	if len(statuses) == 0 {
+		statuses = []model.AllergyStatus{model.AllergyActive, model.AllergyInactive, model.AllergyResolved}
+	}
+	breakGlassID, err := s.authorize(actor, patientID, ChartActionViewAllergy, AuditAllergyList)
+	if err != nil {
+		return nil, nil, err
+	}
+	allergies, err := s.allergyRepo.ListByPatient(patientID, statuses)
+	if err != nil {
+		return nil, nil, err
+	}
+	assertion, err := findNoKnownAllergies(s.allergyRepo, patientID)
+	if err != nil {
+		return nil, nil, err
+	}
+	event := AuditEvent{Action: AuditAllergyList, PatientID: &patientID, BreakGlassAccessID: breakGlassID}
+	if err := recordAudit(s.audit, actor, event); err != nil {
+		return nil, nil, err
+	}
+	return allergies, assertion, nil
+}
+
+// GetAllergy returns one of a patient's allergies
+func (s *allergyService) GetAllergy(actor Actor, patientID, allergyID uuid.UUID) (*model.Allergy, error) {
+	breakGlassID, err := s.authorize(actor, patientID, ChartActionViewAllergy, AuditAllergyView)
+	if err != nil {
+		return nil, err
+	}
+	allergy, err := s.find(patientID, allergyID)
+	if err != nil {
+		return nil, err
+	}
+	event := AuditEvent{Action: AuditAllergyView, PatientID: &patientID, BreakGlassAccessID: breakGlassID, Changes: allergyRef(allergy)}
+	if err := recordAudit(s.audit, actor, event); err != nil {
+		return nil, err
+	}
+	return allergy, nil
+}
+
+// CreateAllergy records an allergy. Recording an active allergy withdraws
+// the patient's no known allergies assertion.
+func (s *allergyService) CreateAllergy(actor Actor, patientID uuid.UUID, input AllergyInput) (*model.Allergy, error) {
+	input, err := input.normalize()
+	if err != nil {
+		return nil, err
+	}
+	breakGlassID, err := s.authorize(actor, patientID, ChartActionEditAllergy, AuditAllergyCreate)
+	if err != nil {
+		return nil, err
+	}
+	allergy := &model.Allergy{PatientID: patientID, RecordedByID: actor.UserID, RecordedAt: time.Now()}
+	input.apply(allergy)
+	err = s.transactor.Transaction(func(tx repository.Tx) error {
+		if err := tx.Allergies().Create(allergy); err != nil {
+			return allergyConflict(err)
+		}
+		event := AuditEvent{Action: AuditAllergyCreate, PatientID: &patientID, BreakGlassAccessID: breakGlassID, Changes: allergyChanges(nil, allergy)}
+		return s.audit.RecordIn(tx, actor, event)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return allergy, nil
+}
+
+// UpdateAllergy replaces the details of an allergy, for example to mark it
+// resolved. Making it active withdraws the patient's no known allergies
+// assertion.
+func (s *allergyService) UpdateAllergy(actor Actor, patientID, allergyID uuid.UUID, input AllergyInput) (*model.Allergy, error) {
+	input, err := input.normalize()
+	if err != nil {
+		return nil, err
+	}
+	breakGlassID, err := s.authorize(actor, patientID, ChartActionEditAllergy, AuditAllergyUpdate)
+	if err != nil {
+		return nil, err
+	}
+	allergy, err := s.find(patientID, allergyID)
+	if err != nil {
+		return nil, err
+	}
+	before := *allergy
+	input.apply(allergy)
+	return s.save(actor, AuditAllergyUpdate, &before, allergy, breakGlassID)
+}
+
+// DeleteAllergy marks an allergy as entered in error. It is kept for the
+// record but no longer listed unless asked for.
+func (s *allergyService) DeleteAllergy(actor Actor, patientID, allergyID uuid.UUID) (*model.Allergy, error) {
+	breakGlassID, err := s.authorize(actor, patientID, ChartActionEditAllergy, AuditAllergyDelete)
+	if err != nil {
+		return nil, err
+	}
+	allergy, err := s.find(patientID, allergyID)
+	if err != nil {
+		return nil, err
+	}
+	before := *allergy
+	allergy.Status = model.AllergyEnteredInError
+	return s.save(actor, AuditAllergyDelete, &before, allergy, breakGlassID)
+}
+
+// AssertNoKnownAllergies records that the actor checked with the patient and
+// found no allergies. It fails with ErrActiveAllergies if the patient has any.
+func (s *allergyService) AssertNoKnownAllergies(actor Actor, patientID uuid.UUID) (*model.NoKnownAllergies, error) {
+	breakGlassID, err := s.authorize(actor, patientID, ChartActionEditAllergy, AuditNoKnownAllergies)
+	if err != nil {
+		return nil, err
+	}
+	assertion := &model.NoKnownAllergies{PatientID: patientID, AssertedByID: actor.UserID, AssertedAt: time.Now()}
+	err = s.transactor.Transaction(func(tx repository.Tx) error {
+		if err := tx.Allergies().AssertNoKnownAllergies(assertion); err != nil {
+			return err
+		}
+		changes := map[string]model.FieldChange{"no_known_allergies": {New: true}}
+		return s.audit.RecordIn(tx, actor, AuditEvent{Action: AuditNoKnownAllergies, PatientID: &patientID, BreakGlassAccessID: breakGlassID, Changes: changes})
+	})
+	if errors.Is(err, repository.ErrActiveAllergies) {
+		return nil, ErrActiveAllergies
+	}
+	if err != nil {
+		return nil, err
+	}
+	return assertion, nil
+}
+
+// WithdrawNoKnownAllergies removes the patient's no known allergies
+// assertion, leaving their allergies unknown
+func (s *allergyService) WithdrawNoKnownAllergies(actor Actor, patientID uuid.UUID) error {
+	breakGlassID, err := s.authorize(actor, patientID, ChartActionEditAllergy, AuditNoKnownWithdrawn)
+	if err != nil {
+		return err
+	}
+	err = s.transactor.Transaction(func(tx repository.Tx) error {
+		if err := tx.Allergies().WithdrawNoKnownAllergies(patientID); err != nil {
+			return err
+		}
+		changes := map[string]model.FieldChange{"no_known_allergies": {Old: true}}
+		return s.audit.RecordIn(tx, actor, AuditEvent{Action: AuditNoKnownWithdrawn, PatientID: &patientID, BreakGlassAccessID: breakGlassID, Changes: changes})
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrNoKnownAllergiesNotFound
+	}
+	return err
+}
+
+// authorize checks that the actor may work with the patient's allergies
+func (s *allergyService) authorize(actor Actor, patientID uuid.UUID, chartAction, auditAction string) (*uuid.UUID, error) {
+	_, breakGlassID, err := authorizeClinical(s.patientRepo, s.access, s.audit, actor, patientID, chartAction, auditAction)
+	return breakGlassID, err
+}
+
+// find loads one of a patient's allergies
+func (s *allergyService) find(patientID, allergyID uuid.UUID) (*model.Allergy, error) {
+	allergy, err := s.allergyRepo.FindByID(patientID, allergyID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrAllergyNotFound
+	}
+	return allergy, err
+}
+
+// allergyConflict turns the repository errors for an allergy that clashes
+// with another or was changed meanwhile into the service's errors
+func allergyConflict(err error) error {
+	switch {
+	case errors.Is(err, repository.ErrDuplicateAllergy):
+		return ErrDuplicateAllergy
+	case errors.Is(err, repository.ErrVersionConflict):
+		return ErrAllergyVersionMismatch
+	}
+	return err
+}
+
+// save saves a changed allergy and audits the change
+func (s *allergyService) save(actor Actor, action string, before, allergy *model.Allergy, breakGlassID *uuid.UUID) (*model.Allergy, error) {
+	allergy.UpdatedByID = &actor.UserID
+	err := s.transactor.Transaction(func(tx repository.Tx) error {
+		if err := tx.Allergies().Update(allergy); err != nil {
+			return allergyConflict(err)
+		}
+		event := AuditEvent{Action: action, PatientID: &allergy.PatientID, BreakGlassAccessID: breakGlassID, Changes: allergyChanges(before, allergy)}
+		return s.audit.RecordIn(tx, actor, event)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return allergy, nil
+}
+
+// allergyRef identifies an allergy in the audit log
+func allergyRef(allergy *model.Allergy) map[string]model.FieldChange {
+	return map[string]model.FieldChange{"allergy_id": {New: allergy.ID}}
+}
+
+// allergyChanges returns the changes between two states of an allergy for
+// the audit log; before is nil for a new allergy. The status is written out,
+// the clinical details are only marked as changed.
+func allergyChanges(before, after *model.Allergy) map[string]model.FieldChange {
+	changes := allergyRef(after)
+	if before == nil {
+		before = &model.Allergy{}
+	}
+	if before.Status != after.Status {
+		changes["status"] = fieldChange(string(before.Status), string(after.Status))
+	}
+	details := map[string]bool{
+		"substance": before.Substance != after.Substance,
+		"category":  before.Category != after.Category,
+		"reaction":  before.Reaction != after.Reaction,
+		"severity":  before.Severity != after.Severity,
+	}
+	for field, changed := range details {
+		if changed {
+			changes[field] = model.FieldChange{Redacted: true}
+		}
+	}
+	return changes
+}
+
+// normalize trims the input, defaults the status to active and checks the
+// substance and codes
+func (input AllergyInput) normalize() (AllergyInput, error) {
+	input.Substance = strings.TrimSpace(input.Substance)
+	input.Reaction = strings.TrimSpace(input.Reaction)
+	if input.Status == "" {
+		input.Status = model.AllergyActive
+	}
+	switch {
+	case input.Substance == "":
+		return input, fmt.Errorf("%w: substance is required", ErrInvalidAllergy)
+	case len(input.Substance) > 255:
+		return input, fmt.Errorf("%w: substance must be at most 255 characters", ErrInvalidAllergy)
+	case !input.Category.IsValid():
+		return input, fmt.Errorf("%w: category must be food, medication, environment or biologic", ErrInvalidAllergy)
+	case !input.Severity.IsValid():
+		return input, fmt.Errorf("%w: severity must be mild, moderate or severe", ErrInvalidAllergy)
+	case !input.Status.IsValid():
+		return input, fmt.Errorf("%w: status must be active, inactive, resolved or entered_in_error", ErrInvalidAllergy)
+	}
+	return input, nil
+}
+
+// apply copies the input onto an allergy
+func (input AllergyInput) apply(allergy *model.Allergy) {
+	allergy.Substance = input.Substance
+	allergy.Category = input.Category
+	allergy.Reaction = input.Reaction
+	allergy.Severity = input.Severity
+	allergy.Status = input.Status
+}
+
+// allergySummary returns the patient's active allergies and no known
+// allergies assertion
+func allergySummary(allergyRepo repository.AllergyRepository, patientID uuid.UUID) (*AllergySummary, error) {
+	active, err := allergyRepo.ListByPatient(patientID, []model.AllergyStatus{model.AllergyActive})
+	if err != nil {
+		return nil, err
+	}
+	assertion, err := findNoKnownAllergies(allergyRepo, patientID)
+	if err != nil {
+		return nil, err
+	}
+	return &AllergySummary{Active: active, NoKnownAllergies: assertion}, nil
+}
+
+// findNoKnownAllergies returns the patient's no known allergies assertion,
+// or nil if there is none
+func findNoKnownAllergies(allergyRepo repository.AllergyRepository, patientID uuid.UUID) (*model.NoKnownAllergies, error) {
+	assertion, err := allergyRepo.FindNoKnownAllergies(patientID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return assertion, nil
+}
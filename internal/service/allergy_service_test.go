@@ -0,0 +1,180 @@
+package service
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/RohanDSkaria/hospital-management-system/internal/model"
+	"github.com/google/uuid"
+)
+
+func TestAllergyInputNormalize(t *testing.T) {
+	valid := AllergyInput{Substance: "Penicillin", Category: model.AllergyMedication, Severity: model.AllergySevere}
+	with := func(change func(input *AllergyInput)) AllergyInput {
+		input := valid
+		change(&input)
+		return input
+	}
+
+	cases := []struct {
+		name    string
+		input   AllergyInput
+		want    AllergyInput
+		wantErr bool
+	}{
+		{
+			name:  "trims and defaults to active",
+			input: with(func(input *AllergyInput) { input.Substance = "  Penicillin "; input.Reaction = " hives\n" }),
+			want:  with(func(input *AllergyInput) { input.Reaction = "hives"; input.Status = model.AllergyActive }),
+		},
+		{
+			name:  "keeps the given status",
+			input: with(func(input *AllergyInput) { input.Status = model.AllergyResolved }),
+			want:  with(func(input *AllergyInput) { input.Status = model.AllergyResolved }),
+		},
+		{name: "blank substance", input: with(func(input *AllergyInput) { input.Substance = "   " }), wantErr: true},
+		{name: "substance too long", input: with(func(input *AllergyInput) { input.Substance = strings.Repeat("a", 256) }), wantErr: true},
+		{name: "unknown category", input: with(func(input *AllergyInput) { input.Category = "pollen" }), wantErr: true},
+		{name: "unknown severity", input: with(func(input *AllergyInput) { input.Severity = "fatal" }), wantErr: true},
+		{name: "unknown status", input: with(func(input *AllergyInput) { input.Status = "suspected" }), wantErr: true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := tc.input.normalize()
+			if tc.wantErr {
+				if !errors.Is(err, ErrInvalidAllergy) {
+					t.Fatalf("expected ErrInvalidAllergy, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("normalize: %v", err)
+			}
+			if got != tc.want {
+				t.Errorf("normalize() = %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestAllergyChangesRedactsClinicalDetails(t *testing.T) {
+	recorded := &model.Allergy{
+		ID:        uuid.New(),
+		Substance: "Peanuts",
+		Category:  model.AllergyFood,
+		Reaction:  "Anaphylaxis",
+		Severity:  model.AllergySevere,
+		Status:    model.AllergyActive,
+	}
+	changed := func(change func(allergy *model.Allergy)) *model.Allergy {
+		allergy := *recorded
+		change(&allergy)
+		return &allergy
+	}
+
+	cases := []struct {
+		name   string
+		before *model.Allergy
+		after  *model.Allergy
+		want   map[string]model.FieldChange
+	}{
+		{
+			name:  "new allergy",
+			after: recorded,
+			want: map[string]model.FieldChange{
+				"status":    {New: "active"},
+				"substance": {Redacted: true},
+				"category":  {Redacted: true},
+				"reaction":  {Redacted: true},
+				"severity":  {Redacted: true},
+			},
+		},
+		{
+			name:   "status written out",
+			before: recorded,
+			after:  changed(func(allergy *model.Allergy) { allergy.Status = model.AllergyResolved }),
+			want:   map[string]model.FieldChange{"status": {Old: "active", New: "resolved"}},
+		},
+		{
+			name:   "reaction only marked",
+			before: recorded,
+			after:  changed(func(allergy *model.Allergy) { allergy.Reaction = "Hives" }),
+			want:   map[string]model.FieldChange{"reaction": {Redacted: true}},
+		},
+		{
+			name:   "nothing changed",
+			before: recorded,
+			after:  recorded,
+			want:   map[string]model.FieldChange{},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			changes := allergyChanges(tc.before, tc.after)
+			if got := changes["allergy_id"]; got.New != recorded.ID {
+				t.Errorf("expected the allergy ID to be recorded, got %+v", got)
+			}
+			delete(changes, "allergy_id")
+			if len(changes) != len(tc.want) {
+				t.Fatalf("allergyChanges() = %+v, want %+v", changes, tc.want)
+			}
+			for field, want := range tc.want {
+				if got := changes[field]; got != want {
+					t.Errorf("%s: got %+v, want %+v", field, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestWithdrawNoKnownAllergies(t *testing.T) {
+	cases := []struct {
+		name       string
+		actor      func(w *testWard) Actor
+		asserted   bool
+		wantErr    error
+		wantAudit  []string
+		wantKeeps  bool
+		wantDenied bool
+	}{
+		{name: "care team doctor", actor: func(w *testWard) Actor { return w.doctor }, asserted: true, wantAudit: []string{AuditNoKnownWithdrawn}},
+		{name: "nothing asserted", actor: func(w *testWard) Actor { return w.doctor }, wantErr: ErrNoKnownAllergiesNotFound},
+		{name: "doctor off the care team", actor: func(w *testWard) Actor { return w.outsider }, asserted: true, wantErr: ErrNotOnCareTeam, wantAudit: []string{AuditNoKnownWithdrawn}, wantKeeps: true, wantDenied: true},
+		{name: "receptionist", actor: func(w *testWard) Actor { return w.receptionist }, asserted: true, wantErr: ErrClinicalDataForbidden, wantAudit: []string{AuditNoKnownWithdrawn}, wantKeeps: true, wantDenied: true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ward := newTestWard()
+			allergies := &fakeAllergyRepository{assertions: map[uuid.UUID]model.NoKnownAllergies{}}
+			if tc.asserted {
+				allergies.assertions[ward.patientID] = model.NoKnownAllergies{PatientID: ward.patientID, AssertedByID: ward.doctor.UserID, AssertedAt: time.Now()}
+			}
+			ward.tx.allergies = allergies
+			service := NewAllergyService(allergies, ward.patients, ward.transactor(), ward.access, ward.audit)
+
+			err := service.WithdrawNoKnownAllergies(tc.actor(ward), ward.patientID)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("expected %v, got %v", tc.wantErr, err)
+			}
+			if _, kept := allergies.assertions[ward.patientID]; tc.asserted && kept != tc.wantKeeps {
+				t.Errorf("assertion kept = %v, want %v", kept, tc.wantKeeps)
+			}
+			if got := ward.audit.actions(); !slices.Equal(got, tc.wantAudit) {
+				t.Fatalf("audited %v, want %v", got, tc.wantAudit)
+			}
+			if len(tc.wantAudit) == 0 {
+				return
+			}
+			event := ward.audit.events[0]
+			if denied := event.Err != nil; denied != tc.wantDenied {
+				t.Errorf("audited as denied = %v, want %v", denied, tc.wantDenied)
+			}
+			if !tc.wantDenied && event.Changes["no_known_allergies"] != (model.FieldChange{Old: true}) {
+				t.Errorf("expected the withdrawal in the changes, got %+v", event.Changes)
+			}
+		})
+	}
+}
@@ -0,0 +1,199 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/RohanDSkaria/hospital-management-system/internal/model"
+	"github.com/RohanDSkaria/hospital-management-system/internal/repository"
+	"github.com/RohanDSkaria/hospital-management-system/pkg/utils"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	// APIKeyDefaultTTL is the lifetime of an API key created without an expiry
+	APIKeyDefaultTTL = 90 * 24 * time.Hour
+	// APIKeyMaxTTL is the longest lifetime an API key can be created with
+	APIKeyMaxTTL = 365 * 24 * time.Hour
+	// APIKeyPrefix starts every API key so leaked keys are easy to recognise
+	APIKeyPrefix = "hms_"
+	// apiKeyTouchInterval limits how often the last use of a key is written
+	apiKeyTouchInterval = time.Minute
+	// unusablePasswordHash is stored for service accounts; no password matches it
+	unusablePasswordHash = "!"
+	// serviceAccountDomain is the reserved domain of generated service account emails
+	serviceAccountDomain = "service-accounts.invalid"
+)
+
+var (
+	// ErrInvalidAPIKey is returned when an API key is malformed, unknown, expired or revoked
+	ErrInvalidAPIKey = errors.New("invalid API key")
+	// ErrInvalidScope is returned when a requested scope is unknown or not granted to the key owner's role
+	ErrInvalidScope = errors.New("scopes must be known permissions granted to the owner's role")
+	// ErrInvalidExpiry is returned when an API key expiry is in the past or too far away
+	ErrInvalidExpiry = errors.New("expiry must be in the future and within 365 days")
+	// ErrInactiveOwner is returned when creating a key for a deactivated user
+	ErrInactiveOwner = errors.New("the owner of the key is deactivated")
+	// ErrInvalidServiceAccountRole is returned when a service account would get the admin role
+	ErrInvalidServiceAccountRole = errors.New("service accounts cannot be admins")
+)
+
+// APIKeyService defines the interface for API keys and service accounts
+type APIKeyService interface {
+	CreateServiceAccount(name string, role model.Role) (*model.User, error)
+	CreateKey(ownerID uuid.UUID, name string, scopes []model.Permission, expiresAt *time.Time, createdByID uuid.UUID) (*model.APIKey, string, error)
+	ListKeys(filter repository.APIKeyFilter) ([]model.APIKey, error)
+	RevokeKey(id uuid.UUID) (*model.APIKey, error)
+	Authenticate(rawKey, clientIP string) (*model.APIKey, error)
+}
+
+type apiKeyService struct {
+	apiKeyRepo  repository.APIKeyRepository
+	userRepo    repository.UserRepository
+	permissions PermissionService
+}
+
+// NewAPIKeyService creates a new API key service
+func NewAPIKeyService(apiKeyRepo repository.APIKeyRepository, userRepo repository.UserRepository, permissions PermissionService) APIKeyService {
+	return &apiKeyService{apiKeyRepo: apiKeyRepo, userRepo: userRepo, permissions: permissions}
+}
+
+// CreateServiceAccount creates a non-human account that integrations act as
+// through API keys. It gets a generated email and cannot log in.
+func (s *apiKeyService) CreateServiceAccount(name string, role model.Role) (*model.User, error) {
+	if !role.IsValid() {
+		return nil, ErrInvalidRole
+	}
+	if role == model.Admin {
+		return nil, ErrInvalidServiceAccountRole
+	}
+
+	suffix, err := randomHex(4)
+	if err != nil {
+		return nil, err
+	}
+	account := &model.User{
+		FullName:       name,
+		Email:          "svc-" + suffix + "@" + serviceAccountDomain,
+		PasswordHash:   unusablePasswordHash,
+		Role:           role,
+		Active:         true,
+		ServiceAccount: true,
+	}
+	if err := s.userRepo.SaveUser(account); err != nil {
+		return nil, err
+	}
+	return account, nil
+}
+
+// CreateKey creates an API key for a user or service account and returns it
+// together with the plain key. The key is only returned here.
+func (s *apiKeyService) CreateKey(ownerID uuid.UUID, name string, scopes []model.Permission, expiresAt *time.Time, createdByID uuid.UUID) (*model.APIKey, string, error) {
+	owner, err := s.userRepo.FindByID(ownerID)
+	if err != nil {
+		return nil, "", err
+	}
+	if !owner.Active {
+		return nil, "", ErrInactiveOwner
+	}
+	if len(scopes) == 0 {
+		return nil, "", ErrInvalidScope
+	}
+	for _, scope := range scopes {
+		if !scope.IsValid() || !s.permissions.HasPermission(owner.Role, scope) {
+			return nil, "", ErrInvalidScope
+		}
+	}
+
+	now := time.Now()
+	if expiresAt == nil {
+		defaultExpiry := now.Add(APIKeyDefaultTTL)
+		expiresAt = &defaultExpiry
+	}
+	if !expiresAt.After(now) || expiresAt.After(now.Add(APIKeyMaxTTL)) {
+		return nil, "", ErrInvalidExpiry
+	}
+
+	prefix, err := randomHex(6)
+	if err != nil {
+		return nil, "", err
+	}
+	secret, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return nil, "", err
+	}
+	key := &model.APIKey{
+		Name:        name,
+		Prefix:      prefix,
+		SecretHash:  utils.HashToken(secret),
+		Scopes:      scopes,
+		OwnerID:     owner.ID,
+		CreatedByID: createdByID,
+		ExpiresAt:   expiresAt,
+	}
+	if err := s.apiKeyRepo.Create(key); err != nil {
+		return nil, "", err
+	}
+	return key, APIKeyPrefix + prefix + "." + secret, nil
+}
+
+// ListKeys returns the API keys matching the filter
+func (s *apiKeyService) ListKeys(filter repository.APIKeyFilter) ([]model.APIKey, error) {
+	return s.apiKeyRepo.List(filter)
+}
+
+// RevokeKey revokes an API key; it stops working immediately
+func (s *apiKeyService) RevokeKey(id uuid.UUID) (*model.APIKey, error) {
+	if err := s.apiKeyRepo.Revoke(id, time.Now()); err != nil {
+		return nil, err
+	}
+	return s.apiKeyRepo.FindByID(id)
+}
+
+// Authenticate checks a plain API key and returns it with its owner
+func (s *apiKeyService) Authenticate(rawKey, clientIP string) (*model.APIKey, error) {
+	prefix, secret, ok := strings.Cut(strings.TrimPrefix(rawKey, APIKeyPrefix), ".")
+	if !ok || !strings.HasPrefix(rawKey, APIKeyPrefix) || prefix == "" || secret == "" {
+		return nil, ErrInvalidAPIKey
+	}
+
+	key, err := s.apiKeyRepo.FindByPrefix(prefix)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidAPIKey
+		}
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare([]byte(key.SecretHash), []byte(utils.HashToken(secret))) != 1 {
+		return nil, ErrInvalidAPIKey
+	}
+
+	now := time.Now()
+	if !key.IsActive(now) {
+		return nil, ErrInvalidAPIKey
+	}
+	if !key.Owner.Active {
+		return nil, ErrAccountDeactivated
+	}
+
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) > apiKeyTouchInterval || key.LastUsedIP != clientIP {
+		if err := s.apiKeyRepo.TouchLastUsed(key.ID, now, clientIP); err != nil {
+			logError("record API key use", err)
+		}
+	}
+	return key, nil
+}
+
+// randomHex returns n random bytes, hex encoded
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,242 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/RohanDSkaria/hospital-management-system/internal/model"
+	"github.com/RohanDSkaria/hospital-management-system/internal/repository"
+	"github.com/google/uuid"
+)
+
+// Audited actions
+const (
+	AuditPatientList       = "patient.list"
+	AuditPatientView       = "patient.view"
+	AuditPatientSearch     = "patient.search"
+	AuditPatientCreate     = "patient.create"
+	AuditDuplicateCheck    = "patient.duplicate_check"
+	AuditDuplicateOverride = "patient.duplicate_override"
+	AuditPatientMerge      = "patient.merge"
+	AuditPatientUpdate     = "patient.update"
+	AuditPatientDelete     = "patient.delete"
+	AuditPatientHistory    = "patient.history"
+	AuditPatientRestore    = "patient.restore"
+	AuditPatientDeleted    = "patient.list_deleted"
+	AuditPatientUndelete   = "patient.undelete"
+	AuditPatientPurge      = "patient.purge"
+	AuditCareTeamView      = "care_team.view"
+	AuditCareTeamAssign    = "care_team.assign"
+	AuditCareTeamUnassign  = "care_team.unassign"
+	AuditBreakGlass        = "break_glass.request"
+	AuditAllergyList       = "allergy.list"
+	AuditAllergyView       = "allergy.view"
+	AuditAllergyCreate     = "allergy.create"
+	AuditAllergyUpdate     = "allergy.update"
+	AuditAllergyDelete     = "allergy.delete"
+	AuditNoKnownAllergies  = "allergy.none_asserted"
+	AuditNoKnownWithdrawn  = "allergy.none_withdrawn"
+	AuditNoteList          = "note.list"
+	AuditNoteTimeline      = "note.timeline"
+	AuditNoteView          = "note.view"
+	AuditNoteCreate        = "note.create"
+	AuditNoteUpdate        = "note.update"
+	AuditNoteDiscard       = "note.discard"
+	AuditNoteSign          = "note.sign"
+	AuditNoteAddendum      = "note.addendum"
+	AuditVitalsRecord      = "vitals.record"
+	AuditVitalsList        = "vitals.list"
+	AuditVitalsTrend       = "vitals.trend"
+)
+
+// auditVerifyBatchSize is how many entries are read at a time when the chain is verified
+const auditVerifyBatchSize = 1000
+
+// AuditEvent is what a service reports to the audit log
+type AuditEvent struct {
+	Action             string
+	PatientID          *uuid.UUID
+	BreakGlassAccessID *uuid.UUID
+	Changes            map[string]model.FieldChange
+	// Err is the error the call ended with. Denied calls are recorded as
+	// such; calls that failed for other reasons did nothing worth recording.
+	Err error
+}
+
+// AuditVerification is the result of checking the audit log's hash chain
+type AuditVerification struct {
+	Checked  int64  `json:"checked"`
+	Valid    bool   `json:"valid"`
+	BrokenAt *int64 `json:"broken_at_sequence,omitempty"`
+	Problem  string `json:"problem,omitempty"`
+}
+
+// AuditService defines the interface for the patient audit log
+type AuditService interface {
+	Record(actor Actor, event AuditEvent) error
+	RecordIn(tx repository.Tx, actor Actor, event AuditEvent) error
+	List(filter repository.AuditFilter) ([]model.AuditEntry, int64, error)
+	Verify() (*AuditVerification, error)
+}
+
+type auditService struct {
+	auditRepo repository.AuditRepository
+}
+
+// NewAuditService creates a new audit service
+func NewAuditService(auditRepo repository.AuditRepository) AuditService {
+	return &auditService{auditRepo: auditRepo}
+}
+
+// Record appends an event to the audit log. Callers must not hand out
+// patient data when it fails.
+func (s *auditService) Record(actor Actor, event AuditEvent) error {
+	return s.record(s.auditRepo, actor, event)
+}
+
+// RecordIn appends an event to the audit log in the transaction that makes
+// the change it describes, so neither is committed without the other. It
+// locks the audit log until the transaction ends, so it comes last.
+func (s *auditService) RecordIn(tx repository.Tx, actor Actor, event AuditEvent) error {
+	return s.record(tx.Audit(), actor, event)
+}
+
+// record appends an event to the audit log through auditRepo
+func (s *auditService) record(auditRepo repository.AuditRepository, actor Actor, event AuditEvent) error {
+	outcome := model.AuditSuccess
+	if event.Err != nil {
+		if !isDenial(event.Err) {
+			return nil
+		}
+		outcome = model.AuditDenied
+	}
+
+	entry := &model.AuditEntry{
+		ActorID:            actor.UserID,
+		ActorRole:          actor.Role,
+		Action:             event.Action,
+		Outcome:            outcome,
+		PatientID:          event.PatientID,
+		BreakGlassAccessID: event.BreakGlassAccessID,
+		RequestID:          actor.RequestID,
+		ClientIP:           actor.ClientIP,
+	}
+	if len(event.Changes) > 0 {
+		changes, err := json.Marshal(event.Changes)
+		if err != nil {
+			return err
+		}
+		entry.Changes = string(changes)
+	}
+	return auditRepo.Append(entry)
+}
+
+// List returns the audit entries matching the filter
+func (s *auditService) List(filter repository.AuditFilter) ([]model.AuditEntry, int64, error) {
+	return s.auditRepo.List(filter)
+}
+
+// Verify walks the whole audit log and reports the first entry that was
+// altered, removed or inserted out of order
+func (s *auditService) Verify() (*AuditVerification, error) {
+	result := &AuditVerification{Valid: true}
+	chain := model.NewAuditChain()
+	errBroken := errors.New("chain broken")
+	err := s.auditRepo.Walk(auditVerifyBatchSize, func(entries []model.AuditEntry) error {
+		for i := range entries {
+			if err := chain.Next(&entries[i]); err != nil {
+				sequence := entries[i].Sequence
+				result.Valid = false
+				result.BrokenAt = &sequence
+				result.Problem = err.Error()
+				return errBroken
+			}
+			result.Checked++
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errBroken) {
+		return nil, err
+	}
+	return result, nil
+}
+
+// isDenial reports whether an error means the caller was not allowed to do
+// what they tried
+func isDenial(err error) bool {
+	return errors.Is(err, ErrNotOnCareTeam) ||
+		errors.Is(err, ErrMedicalHistoryForbidden) ||
+		errors.Is(err, ErrPatientFieldForbidden) ||
+		errors.Is(err, ErrClinicalDataForbidden)
+}
+
+// recordAudit records an event and returns the call's own error, or the
+// audit error if the event could not be recorded
+func recordAudit(audit AuditService, actor Actor, event AuditEvent) error {
+	if err := audit.Record(actor, event); err != nil {
+		return err
+	}
+	return event.Err
+}
+
+// patientField is a patient field covered by the audit diff
+type patientField struct {
+	name     string
+	clinical bool
+	value    func(p *model.Patient) interface{}
+}
+
+// auditedPatientFields lists the fields whose changes are audited. Clinical
+// fields are only marked as changed.
+var auditedPatientFields = []patientField{
+	{model.PatientFieldFullName, false, func(p *model.Patient) interface{} { return p.FullName }},
+	{model.PatientFieldDateOfBirth, false, func(p *model.Patient) interface{} { return p.DateOfBirth.Format("2006-01-02") }},
+	{model.PatientFieldAddress, false, func(p *model.Patient) interface{} { return p.Address }},
+	{model.PatientFieldContactNumber, false, func(p *model.Patient) interface{} { return p.ContactNumber }},
+	{model.PatientFieldMedicalHistory, true, func(p *model.Patient) interface{} { return p.MedicalHistory }},
+}
+
+// patientChanges returns the field-level diff between two states of a
+// patient for the audit log. before is nil for a new patient and after is nil
+// for a deleted one.
+func patientChanges(before, after *model.Patient) map[string]model.FieldChange {
+	return diffPatients(before, after, true)
+}
+
+// diffPatients returns the field-level diff between two states of a patient.
+// With redactClinical, changed clinical fields are marked but their values left out.
+func diffPatients(before, after *model.Patient, redactClinical bool) map[string]model.FieldChange {
+	changes := make(map[string]model.FieldChange)
+	for _, field := range auditedPatientFields {
+		var oldValue, newValue interface{}
+		if before != nil {
+			oldValue = field.value(before)
+		}
+		if after != nil {
+			newValue = field.value(after)
+		}
+		if oldValue == newValue || isEmptyValue(oldValue) && isEmptyValue(newValue) {
+			continue
+		}
+		if field.clinical && redactClinical {
+			changes[field.name] = model.FieldChange{Redacted: true}
+		} else {
+			changes[field.name] = model.FieldChange{Old: oldValue, New: newValue}
+		}
+	}
+	return changes
+}
+
+// fieldChange returns the change of a field for the audit log, leaving out
+// a blank old value
+func fieldChange(oldValue, newValue interface{}) model.FieldChange {
+	if isEmptyValue(oldValue) {
+		return model.FieldChange{New: newValue}
+	}
+	return model.FieldChange{Old: oldValue, New: newValue}
+}
+
+// isEmptyValue reports whether a field value is missing or blank
+func isEmptyValue(value interface{}) bool {
+	return value == nil || value == ""
+}
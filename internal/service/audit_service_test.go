@@ -0,0 +1,45 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/RohanDSkaria/hospital-management-system/internal/model"
+)
+
+func TestDiffPatientsRedactsClinicalFieldsForNonClinicians(t *testing.T) {
+	before := &model.Patient{FullName: "Jane Doe", MedicalHistory: "Asthma"}
+	after := &model.Patient{FullName: "Jane Roe", MedicalHistory: "Asthma, hypertension"}
+
+	cases := []struct {
+		role     model.Role
+		redacted bool
+	}{
+		{model.Doctor, false},
+		{model.Nurse, false},
+		{model.Receptionist, true},
+		{model.Admin, true},
+	}
+	for _, tc := range cases {
+		changes := diffPatients(before, after, !tc.role.IsClinician())
+		history := changes[model.PatientFieldMedicalHistory]
+		if tc.redacted {
+			if !history.Redacted || history.Old != nil || history.New != nil {
+				t.Errorf("%s: expected medical history to be redacted, got %+v", tc.role, history)
+			}
+		} else if history.Redacted || history.Old != "Asthma" || history.New != "Asthma, hypertension" {
+			t.Errorf("%s: expected medical history values, got %+v", tc.role, history)
+		}
+		if name := changes[model.PatientFieldFullName]; name.Old != "Jane Doe" || name.New != "Jane Roe" {
+			t.Errorf("%s: expected full name values, got %+v", tc.role, name)
+		}
+	}
+}
+
+func TestPatientChangesAlwaysRedactsClinicalFields(t *testing.T) {
+	before := &model.Patient{MedicalHistory: "Asthma"}
+	after := &model.Patient{MedicalHistory: "Asthma, hypertension"}
+	history := patientChanges(before, after)[model.PatientFieldMedicalHistory]
+	if !history.Redacted || history.Old != nil || history.New != nil {
+		t.Errorf("expected the audit log to redact the medical history, got %+v", history)
+	}
+}
@@ -2,81 +2,418 @@ package service
 
 import (
 	"errors"
+	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/RohanDSkaria/hospital-management-system/internal/auth"
 	"github.com/RohanDSkaria/hospital-management-system/internal/model"
 	"github.com/RohanDSkaria/hospital-management-system/internal/repository"
 	"github.com/RohanDSkaria/hospital-management-system/pkg/utils"
+	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
+const (
+	// MFAChallengeTTL is how long the second login step can be completed after the password
+	MFAChallengeTTL = 5 * time.Minute
+	// MaxMFAAttempts is how many wrong codes an MFA challenge accepts before it is discarded
+	MaxMFAAttempts = 5
+)
+
+var (
+	// ErrInvalidCredentials is returned when the email or password is wrong
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	// ErrInvalidRefreshToken is returned when a refresh token is unknown, expired or revoked
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	// ErrRefreshTokenReused is returned when an already used refresh token is presented again
+	ErrRefreshTokenReused = errors.New("refresh token reuse detected, session revoked")
+	// ErrSessionRevoked is returned when an access token belongs to a revoked or expired session
+	ErrSessionRevoked = errors.New("session has been revoked")
+	// ErrInvalidInvitation is returned when an invite token is unknown, expired or already used
+	ErrInvalidInvitation = errors.New("invalid or expired invitation")
+	// ErrAccountDeactivated is returned when a deactivated user tries to authenticate
+	ErrAccountDeactivated = errors.New("account has been deactivated")
+	// ErrInvalidMFAChallenge is returned when an MFA token is unknown, expired, used or has too many failed codes
+	ErrInvalidMFAChallenge = errors.New("invalid or expired MFA token, log in again")
+)
+
+// TokenPair is the set of tokens returned after a successful login or refresh
+type TokenPair struct {
+	AccessToken  string
+	RefreshToken string
+	ExpiresIn    time.Duration
+}
+
+// LoginResult is the outcome of a correct password. Either Tokens is set, or
+// a second factor is needed and MFAToken must be exchanged with a code.
+type LoginResult struct {
+	Tokens *TokenPair
+	// MFAToken identifies the pending MFA challenge
+	MFAToken string
+	// MFAEnrollmentRequired is set when the user's role requires MFA but they
+	// have not enrolled yet; they must enroll before completing the login
+	MFAEnrollmentRequired bool
+	MFAExpiresIn          time.Duration
+}
+
 // AuthService defines the interface for authentication services
 type AuthService interface {
-	RegisterUser(fullName, email, password string, role model.Role) (*model.User, error)
-	LoginUser(email, password string) (string, error)
+	RegisterUser(inviteToken, fullName, password string) (*model.User, error)
+	LoginUser(email, password, userAgent, clientIP string) (*LoginResult, error)
+	BeginMFAEnrollment(mfaToken string) (*MFAEnrollment, error)
+	CompleteMFALogin(mfaToken, code, userAgent, clientIP string) (*TokenPair, []string, error)
+	RefreshToken(refreshToken string) (*TokenPair, error)
+	Logout(sessionID uuid.UUID) error
+	LogoutAll(userID uuid.UUID) error
+	ValidateSession(sessionID, userID uuid.UUID) error
 }
 
 type authService struct {
-	userRepo repository.UserRepository
+	userRepo       repository.UserRepository
+	sessionRepo    repository.SessionRepository
+	invitationRepo repository.InvitationRepository
+	mfaRepo        repository.MFARepository
+	passwords      *Passwords
+	lockout        LockoutService
+	mfa            MFAService
+	keyManager     *auth.KeyManager
 }
 
 // NewAuthService creates a new auth service
-func NewAuthService(userRepo repository.UserRepository) AuthService {
-	return &authService{userRepo: userRepo}
+func NewAuthService(userRepo repository.UserRepository, sessionRepo repository.SessionRepository, invitationRepo repository.InvitationRepository, mfaRepo repository.MFARepository, passwords *Passwords, lockout LockoutService, mfa MFAService, keyManager *auth.KeyManager) AuthService {
+	return &authService{
+		userRepo:       userRepo,
+		sessionRepo:    sessionRepo,
+		invitationRepo: invitationRepo,
+		mfaRepo:        mfaRepo,
+		passwords:      passwords,
+		lockout:        lockout,
+		mfa:            mfa,
+		keyManager:     keyManager,
+	}
 }
 
 // LoginUser handles the business logic for user login
-func (s *authService) LoginUser(email, password string) (string, error) {
-	// 1. Find the user by email
+func (s *authService) LoginUser(email, password, userAgent, clientIP string) (*LoginResult, error) {
+	// 1. Refuse attempts while the account or IP is locked out
+	if err := s.lockout.Check(email, clientIP); err != nil {
+		return nil, err
+	}
+
+	// 2. Find the user by email
 	user, err := s.userRepo.FindByEmail(email)
 	if err != nil {
-		// If the record is not found, or any other DB error, return invalid credentials
-		return "", errors.New("invalid credentials")
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+		s.passwords.VerifyDummy(password)
+		s.lockout.RecordFailure(email, clientIP, nil, "unknown_email")
+		return nil, ErrInvalidCredentials
+	}
+
+	// 3. Compare the provided password with the stored hash
+	if !s.passwords.Verify(password, user.PasswordHash) {
+		s.lockout.RecordFailure(email, clientIP, &user.ID, "bad_password")
+		return nil, ErrInvalidCredentials
+	}
+	if !user.Active {
+		s.lockout.RecordFailure(email, clientIP, &user.ID, "deactivated")
+		return nil, ErrAccountDeactivated
+	}
+	// The plain password is only known now, so this is when an outdated hash can be upgraded
+	if s.passwords.NeedsRehash(user.PasswordHash) {
+		s.rehash(user, password)
+	}
+
+	// 4. Ask for a second factor if the user has one or their role requires it.
+	// The failure count is only reset once the whole login succeeds, otherwise
+	// knowing the password would allow unlimited guessing of codes.
+	if user.MFAEnabled || s.mfa.IsRequired(user.Role) {
+		token, err := utils.GenerateRandomToken(32)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.mfaRepo.CreateChallenge(&model.MFAChallenge{
+			UserID:    user.ID,
+			TokenHash: utils.HashToken(token),
+			ExpiresAt: time.Now().Add(MFAChallengeTTL),
+		}); err != nil {
+			return nil, err
+		}
+		return &LoginResult{
+			MFAToken:              token,
+			MFAEnrollmentRequired: !user.MFAEnabled,
+			MFAExpiresIn:          MFAChallengeTTL,
+		}, nil
+	}
+	s.lockout.RecordSuccess(email, clientIP, user.ID)
+
+	// 5. Start a new session for this device and issue its tokens
+	tokens, err := s.startSession(user, userAgent, clientIP)
+	if err != nil {
+		return nil, err
+	}
+	return &LoginResult{Tokens: tokens}, nil
+}
+
+// BeginMFAEnrollment starts a TOTP enrollment for a user who must enroll to
+// finish logging in
+func (s *authService) BeginMFAEnrollment(mfaToken string) (*MFAEnrollment, error) {
+	challenge, err := s.findChallenge(mfaToken)
+	if err != nil {
+		return nil, err
+	}
+	return s.mfa.BeginEnrollment(challenge.UserID)
+}
+
+// CompleteMFALogin exchanges an MFA token and a code for a new session. If the
+// user was enrolling, the enrollment is confirmed and the new recovery codes
+// are returned as well.
+func (s *authService) CompleteMFALogin(mfaToken, code, userAgent, clientIP string) (*TokenPair, []string, error) {
+	challenge, err := s.findChallenge(mfaToken)
+	if err != nil {
+		return nil, nil, err
+	}
+	user := &challenge.User
+	if err := s.lockout.Check(user.Email, clientIP); err != nil {
+		return nil, nil, err
+	}
+	if !user.Active {
+		return nil, nil, ErrAccountDeactivated
+	}
+
+	var recoveryCodes []string
+	if user.MFAEnabled {
+		err = s.mfa.Verify(user, code)
+	} else {
+		recoveryCodes, err = s.mfa.ConfirmEnrollment(user.ID, code)
+	}
+	if errors.Is(err, ErrInvalidMFACode) {
+		s.lockout.RecordFailure(user.Email, clientIP, &user.ID, "bad_mfa_code")
+		if err := s.mfaRepo.IncrementChallengeAttempts(challenge.ID); err != nil {
+			return nil, nil, err
+		}
+		return nil, nil, err
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	used, err := s.mfaRepo.MarkChallengeUsed(challenge.ID, time.Now())
+	if err != nil {
+		return nil, nil, err
+	}
+	if !used {
+		return nil, nil, ErrInvalidMFAChallenge
+	}
+	s.lockout.RecordSuccess(user.Email, clientIP, user.ID)
+
+	tokens, err := s.startSession(user, userAgent, clientIP)
+	if err != nil {
+		return nil, nil, err
+	}
+	return tokens, recoveryCodes, nil
+}
+
+// rehash stores a new hash of a password made with the configured algorithm
+// and cost. Failing to upgrade must not fail the login, so errors are logged.
+func (s *authService) rehash(user *model.User, password string) {
+	hashedPassword, err := s.passwords.Hash(password)
+	if err != nil {
+		logError("rehash password", err)
+		return
+	}
+	user.PasswordHash = hashedPassword
+	if err := s.userRepo.Update(user); err != nil {
+		logError("store rehashed password", err)
+	}
+}
+
+// findChallenge looks up a pending MFA challenge by its token
+func (s *authService) findChallenge(mfaToken string) (*model.MFAChallenge, error) {
+	challenge, err := s.mfaRepo.FindChallengeByTokenHash(utils.HashToken(mfaToken))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidMFAChallenge
+		}
+		return nil, err
+	}
+	if challenge.UsedAt != nil || challenge.Attempts >= MaxMFAAttempts || time.Now().After(challenge.ExpiresAt) {
+		return nil, ErrInvalidMFAChallenge
+	}
+	return challenge, nil
+}
+
+// startSession creates a new session for a device and issues its first tokens
+func (s *authService) startSession(user *model.User, userAgent, clientIP string) (*TokenPair, error) {
+	now := time.Now()
+	session := &model.Session{
+		UserID:     user.ID,
+		UserAgent:  truncate(userAgent, 255),
+		ClientIP:   clientIP,
+		ExpiresAt:  now.Add(auth.SessionTTL),
+		LastUsedAt: now,
+	}
+	if err := s.sessionRepo.CreateSession(session); err != nil {
+		return nil, err
+	}
+	return s.issueTokens(user, session)
+}
+
+// RefreshToken exchanges a refresh token for a new token pair. Refresh tokens
+// are single use; presenting a used one revokes the whole session.
+func (s *authService) RefreshToken(refreshToken string) (*TokenPair, error) {
+	now := time.Now()
+
+	token, err := s.sessionRepo.FindRefreshTokenByHash(utils.HashToken(refreshToken))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidRefreshToken
+		}
+		return nil, err
 	}
 
-	// 2. Compare the provided password with the stored hash
-	if !utils.CheckPasswordHash(password, user.PasswordHash) {
-		return "", errors.New("invalid credentials")
+	session, err := s.sessionRepo.FindSessionByID(token.SessionID)
+	if err != nil {
+		return nil, err
+	}
+	if !session.IsActive(now) {
+		return nil, ErrInvalidRefreshToken
 	}
 
-	// 3. Generate a JWT
-	token, err := auth.GenerateToken(user.ID, user.Role)
+	// A token that was already rotated must never be seen again. If it is,
+	// either the client or an attacker holds a stolen copy, so kill the family.
+	if token.UsedAt != nil {
+		if err := s.sessionRepo.RevokeSession(session.ID, now); err != nil {
+			return nil, err
+		}
+		return nil, ErrRefreshTokenReused
+	}
+	if now.After(token.ExpiresAt) {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	marked, err := s.sessionRepo.MarkRefreshTokenUsed(token.ID, now)
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+	if !marked {
+		// Lost a race with a concurrent refresh using the same token
+		if err := s.sessionRepo.RevokeSession(session.ID, now); err != nil {
+			return nil, err
+		}
+		return nil, ErrRefreshTokenReused
+	}
+
+	if !session.User.Active {
+		return nil, ErrAccountDeactivated
+	}
+	if err := s.sessionRepo.TouchSession(session.ID, now); err != nil {
+		return nil, err
 	}
 
-	return token, nil
+	return s.issueTokens(&session.User, session)
+}
+
+// Logout revokes a single session
+func (s *authService) Logout(sessionID uuid.UUID) error {
+	return s.sessionRepo.RevokeSession(sessionID, time.Now())
 }
 
-// RegisterUser handles the business logic for creating a new user
-func (s *authService) RegisterUser(fullName, email, password string, role model.Role) (*model.User, error) {
-	// 1. Check if user already exists
-	existingUser, err := s.userRepo.FindByEmail(email)
-	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+// LogoutAll revokes every session of a user, logging them out on all devices
+func (s *authService) LogoutAll(userID uuid.UUID) error {
+	return s.sessionRepo.RevokeAllForUser(userID, time.Now())
+}
+
+// ValidateSession checks that the session an access token was issued for is
+// still active and that its user has not been deactivated in the meantime
+func (s *authService) ValidateSession(sessionID, userID uuid.UUID) error {
+	session, err := s.sessionRepo.FindSessionByID(sessionID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrSessionRevoked
+		}
+		return err
+	}
+	if session.UserID != userID || !session.IsActive(time.Now()) {
+		return ErrSessionRevoked
+	}
+	if !session.User.Active {
+		return ErrAccountDeactivated
+	}
+	return nil
+}
+
+// RegisterUser creates an account from an invitation. The email and role come
+// from the invitation, so a self-registering user cannot choose their own role.
+func (s *authService) RegisterUser(inviteToken, fullName, password string) (*model.User, error) {
+	// 1. Look up the invitation
+	invitation, err := s.invitationRepo.FindByTokenHash(utils.HashToken(inviteToken))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidInvitation
+		}
 		return nil, err
 	}
-	if existingUser != nil {
-		return nil, errors.New("user with this email already exists")
+	if invitation.AcceptedAt != nil || time.Now().After(invitation.ExpiresAt) {
+		return nil, ErrInvalidInvitation
 	}
 
-	// 2. Hash the password
-	hashedPassword, err := utils.HashPassword(password)
+	// 2. Create the account. The email comes from the invitation and is unique,
+	// so two concurrent registrations with the same token cannot both succeed:
+	// the slower one gets ErrUserExists.
+	user, err := createUser(s.userRepo, s.passwords, fullName, invitation.Email, password, invitation.Role)
 	if err != nil {
 		return nil, err
 	}
 
-	// 3. Create the user model
-	newUser := &model.User{
-		FullName:     fullName,
-		Email:        email,
-		PasswordHash: hashedPassword,
-		Role:         role,
+	// 3. Consume the invitation so it cannot be used again
+	if _, err := s.invitationRepo.MarkAccepted(invitation.ID, time.Now()); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// issueTokens creates a new access token and refresh token for a session
+func (s *authService) issueTokens(user *model.User, session *model.Session) (*TokenPair, error) {
+	accessToken, err := s.keyManager.GenerateToken(user.ID, user.Role, session.ID)
+	if err != nil {
+		return nil, err
 	}
 
-	// 4. Save the new user to the database
-	if err := s.userRepo.SaveUser(newUser); err != nil {
+	refreshToken, err := utils.GenerateRandomToken(32)
+	if err != nil {
 		return nil, err
 	}
+	expiresAt := time.Now().Add(auth.RefreshTokenTTL)
+	if expiresAt.After(session.ExpiresAt) {
+		expiresAt = session.ExpiresAt
+	}
+	if err := s.sessionRepo.CreateRefreshToken(&model.RefreshToken{
+		SessionID: session.ID,
+		TokenHash: utils.HashToken(refreshToken),
+		ExpiresAt: expiresAt,
+	}); err != nil {
+		return nil, err
+	}
+
+	return &TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    auth.AccessTokenTTL,
+	}, nil
+}
 
-	return newUser, nil
+// truncate shortens s to at most n bytes without splitting a character.
+// Invalid UTF-8, which Postgres would refuse to store, is replaced first.
+func truncate(s string, n int) string {
+	s = strings.ToValidUTF8(s, "\uFFFD")
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
 }
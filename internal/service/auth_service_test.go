@@ -0,0 +1,35 @@
+package service
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestTruncateKeepsWholeCharacters(t *testing.T) {
+	cases := []struct {
+		name string
+		s    string
+		n    int
+		want string
+	}{
+		{name: "short enough", s: "curl/8.5", n: 255, want: "curl/8.5"},
+		{name: "ASCII", s: "Mozilla/5.0", n: 7, want: "Mozilla"},
+		{name: "cut inside a character", s: "Navigateur é", n: 12, want: "Navigateur "},
+		{name: "cut after a character", s: "Navigateur é", n: 13, want: "Navigateur é"},
+		{name: "cut inside an emoji", s: "app 📱 v2", n: 6, want: "app "},
+		{name: "invalid UTF-8", s: "agent\xff", n: 255, want: "agent�"},
+		{name: "long agent", s: strings.Repeat("ж", 200), n: 255, want: strings.Repeat("ж", 127)},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := truncate(tc.s, tc.n)
+			if got != tc.want {
+				t.Errorf("truncate() = %q, want %q", got, tc.want)
+			}
+			if len(got) > tc.n || !utf8.ValidString(got) {
+				t.Errorf("truncate() = %q is not valid UTF-8 of at most %d bytes", got, tc.n)
+			}
+		})
+	}
+}
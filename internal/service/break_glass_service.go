@@ -0,0 +1,116 @@
+package service
+
+import (
+	"errors"
+	"time"
+
+	"github.com/RohanDSkaria/hospital-management-system/internal/model"
+	"github.com/RohanDSkaria/hospital-management-system/internal/repository"
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrBreakGlassNotClinician is returned when someone other than a doctor or nurse breaks the glass
+	ErrBreakGlassNotClinician = errors.New("only doctors and nurses can use break-glass access")
+	// ErrBreakGlassNotNeeded is returned when the clinician is already on the patient's care team
+	ErrBreakGlassNotNeeded = errors.New("you are already on this patient's care team")
+	// ErrInvalidBreakGlassOutcome is returned when a review outcome is unknown
+	ErrInvalidBreakGlassOutcome = errors.New("outcome must be justified or unjustified")
+	// ErrBreakGlassAlreadyReviewed is returned when a break-glass access has already been signed off
+	ErrBreakGlassAlreadyReviewed = errors.New("break-glass access has already been reviewed")
+)
+
+// BreakGlassService defines the interface for emergency access to patient charts
+type BreakGlassService interface {
+	Request(actor Actor, patientID uuid.UUID, reason, clientIP string) (*model.BreakGlassAccess, error)
+	List(filter repository.BreakGlassFilter) ([]model.BreakGlassAccess, int64, error)
+	Get(id uuid.UUID) (*model.BreakGlassAccess, error)
+	Review(id, reviewerID uuid.UUID, outcome model.BreakGlassOutcome, note string) (*model.BreakGlassAccess, error)
+}
+
+type breakGlassService struct {
+	breakGlassRepo repository.BreakGlassRepository
+	careTeamRepo   repository.CareTeamRepository
+	patientRepo    repository.PatientRepository
+	transactor     repository.Transactor
+	audit          AuditService
+	duration       time.Duration
+}
+
+// NewBreakGlassService creates a new break-glass service. Grants last for duration.
+func NewBreakGlassService(breakGlassRepo repository.BreakGlassRepository, careTeamRepo repository.CareTeamRepository, patientRepo repository.PatientRepository, transactor repository.Transactor, audit AuditService, duration time.Duration) BreakGlassService {
+	return &breakGlassService{
+		breakGlassRepo: breakGlassRepo,
+		careTeamRepo:   careTeamRepo,
+		patientRepo:    patientRepo,
+		transactor:     transactor,
+		audit:          audit,
+		duration:       duration,
+	}
+}
+
+// Request grants a clinician time-boxed access to a patient's chart. The
+// grant goes into the admin review queue straight away.
+func (s *breakGlassService) Request(actor Actor, patientID uuid.UUID, reason, clientIP string) (*model.BreakGlassAccess, error) {
+	if !actor.Role.IsClinician() {
+		return nil, ErrBreakGlassNotClinician
+	}
+	if _, err := s.patientRepo.FindByID(patientID); err != nil {
+		return nil, err
+	}
+	member, err := s.careTeamRepo.IsMember(patientID, actor.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if member {
+		return nil, ErrBreakGlassNotNeeded
+	}
+
+	access := &model.BreakGlassAccess{
+		PatientID: patientID,
+		UserID:    actor.UserID,
+		Reason:    reason,
+		ClientIP:  clientIP,
+		ExpiresAt: time.Now().Add(s.duration),
+	}
+	err = s.transactor.Transaction(func(tx repository.Tx) error {
+		if err := tx.BreakGlass().Create(access); err != nil {
+			return err
+		}
+		return s.audit.RecordIn(tx, actor, AuditEvent{Action: AuditBreakGlass, PatientID: &patientID, BreakGlassAccessID: &access.ID})
+	})
+	if err != nil {
+		return nil, err
+	}
+	// Reload with the requester and patient so the response names them
+	return s.breakGlassRepo.FindByID(access.ID)
+}
+
+// List returns the break-glass accesses matching the filter
+func (s *breakGlassService) List(filter repository.BreakGlassFilter) ([]model.BreakGlassAccess, int64, error) {
+	return s.breakGlassRepo.List(filter)
+}
+
+// Get returns a break-glass access with everything done under it
+func (s *breakGlassService) Get(id uuid.UUID) (*model.BreakGlassAccess, error) {
+	return s.breakGlassRepo.FindByID(id)
+}
+
+// Review signs off a break-glass access. Each access is reviewed once.
+func (s *breakGlassService) Review(id, reviewerID uuid.UUID, outcome model.BreakGlassOutcome, note string) (*model.BreakGlassAccess, error) {
+	if !outcome.IsValid() {
+		return nil, ErrInvalidBreakGlassOutcome
+	}
+	reviewed, err := s.breakGlassRepo.Review(id, reviewerID, outcome, note, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	access, err := s.breakGlassRepo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if !reviewed {
+		return nil, ErrBreakGlassAlreadyReviewed
+	}
+	return access, nil
+}
@@ -0,0 +1,119 @@
+package service
+
+import (
+	"errors"
+
+	"github.com/RohanDSkaria/hospital-management-system/internal/model"
+	"github.com/RohanDSkaria/hospital-management-system/internal/repository"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrInvalidCareTeamRole is returned when a care team role is unknown
+	ErrInvalidCareTeamRole = errors.New("care team role must be attending, consulting or nurse")
+	// ErrIneligibleCareTeamMember is returned when a user cannot fill the requested care team role
+	ErrIneligibleCareTeamMember = errors.New("only active doctors can attend or consult and only active nurses can nurse")
+	// ErrAlreadyOnCareTeam is returned when a user is already on the patient's care team
+	ErrAlreadyOnCareTeam = errors.New("user is already on this patient's care team")
+	// ErrCareTeamUserNotFound is returned when the user to assign does not exist
+	ErrCareTeamUserNotFound = errors.New("user not found")
+)
+
+// CareTeamService defines the interface for managing patients' care teams
+type CareTeamService interface {
+	List(actor Actor, patientID uuid.UUID) ([]model.CareTeamMember, error)
+	Assign(actor Actor, patientID, userID uuid.UUID, role model.CareTeamRole) (*model.CareTeamMember, error)
+	Unassign(actor Actor, patientID, userID uuid.UUID) error
+}
+
+type careTeamService struct {
+	careTeamRepo repository.CareTeamRepository
+	patientRepo  repository.PatientRepository
+	userRepo     repository.UserRepository
+	transactor   repository.Transactor
+	access       *PatientAccess
+	audit        AuditService
+}
+
+// NewCareTeamService creates a new care team service
+func NewCareTeamService(careTeamRepo repository.CareTeamRepository, patientRepo repository.PatientRepository, userRepo repository.UserRepository, transactor repository.Transactor, access *PatientAccess, audit AuditService) CareTeamService {
+	return &careTeamService{careTeamRepo: careTeamRepo, patientRepo: patientRepo, userRepo: userRepo, transactor: transactor, access: access, audit: audit}
+}
+
+// List returns a patient's care team. Clinicians can only see the teams of
+// patients whose chart they may open.
+func (s *careTeamService) List(actor Actor, patientID uuid.UUID) ([]model.CareTeamMember, error) {
+	if _, err := s.patientRepo.FindByID(patientID); err != nil {
+		return nil, err
+	}
+	breakGlassID, err := s.access.authorize(actor, patientID, ChartActionViewCareTeam)
+	if err != nil {
+		return nil, recordAudit(s.audit, actor, AuditEvent{Action: AuditCareTeamView, PatientID: &patientID, Err: err})
+	}
+	members, err := s.careTeamRepo.ListByPatient(patientID)
+	if err != nil {
+		return nil, err
+	}
+	event := AuditEvent{Action: AuditCareTeamView, PatientID: &patientID, BreakGlassAccessID: breakGlassID}
+	if err := recordAudit(s.audit, actor, event); err != nil {
+		return nil, err
+	}
+	return members, nil
+}
+
+// Assign puts a doctor or nurse on a patient's care team
+func (s *careTeamService) Assign(actor Actor, patientID, userID uuid.UUID, role model.CareTeamRole) (*model.CareTeamMember, error) {
+	if !role.IsValid() {
+		return nil, ErrInvalidCareTeamRole
+	}
+	if _, err := s.patientRepo.FindByID(patientID); err != nil {
+		return nil, err
+	}
+	user, err := s.userRepo.FindByID(userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrCareTeamUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !user.Active || user.ServiceAccount || !role.AllowsRole(user.Role) {
+		return nil, ErrIneligibleCareTeamMember
+	}
+
+	member, err := s.careTeamRepo.IsMember(patientID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if member {
+		return nil, ErrAlreadyOnCareTeam
+	}
+
+	assignment := &model.CareTeamMember{PatientID: patientID, UserID: userID, Role: role, AssignedByID: actor.UserID}
+	err = s.transactor.Transaction(func(tx repository.Tx) error {
+		if err := tx.CareTeam().Add(assignment); err != nil {
+			return err
+		}
+		changes := map[string]model.FieldChange{
+			"user_id": {New: userID.String()},
+			"role":    {New: string(role)},
+		}
+		return s.audit.RecordIn(tx, actor, AuditEvent{Action: AuditCareTeamAssign, PatientID: &patientID, Changes: changes})
+	})
+	if err != nil {
+		return nil, err
+	}
+	assignment.User = *user
+	return assignment, nil
+}
+
+// Unassign takes a user off a patient's care team
+func (s *careTeamService) Unassign(actor Actor, patientID, userID uuid.UUID) error {
+	return s.transactor.Transaction(func(tx repository.Tx) error {
+		if err := tx.CareTeam().Remove(patientID, userID); err != nil {
+			return err
+		}
+		changes := map[string]model.FieldChange{"user_id": {Old: userID.String()}}
+		return s.audit.RecordIn(tx, actor, AuditEvent{Action: AuditCareTeamUnassign, PatientID: &patientID, Changes: changes})
+	})
+}
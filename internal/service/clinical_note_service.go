@@ -0,0 +1,387 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/RohanDSkaria/hospital-management-system/internal/model"
+	"github.com/RohanDSkaria/hospital-management-system/internal/repository"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// clockSkew is how far in the future an encounter or measurement may be
+// dated, to allow for clocks that are slightly off
+const clockSkew = 5 * time.Minute
+
+var (
+	// ErrInvalidNote is returned when a clinical note or addendum is incomplete or malformed
+	ErrInvalidNote = errors.New("invalid clinical note")
+	// ErrNoteNotFound is returned when a patient has no such note, or it is
+	// someone else's draft, which only its author can see and change
+	ErrNoteNotFound = errors.New("clinical note not found")
+	// ErrNoteSigned is returned when a signed note is changed, signed again or discarded
+	ErrNoteSigned = errors.New("note is signed and cannot change, add an addendum instead")
+	// ErrNoteNotSigned is returned when an addendum is added to a draft
+	ErrNoteNotSigned = errors.New("drafts cannot have addenda, edit the draft instead")
+)
+
+// NoteInput is the encounter details and SOAP sections of a note as entered
+// by a clinician
+type NoteInput struct {
+	EncounterType model.EncounterType
+	EncounterAt   time.Time // now if zero
+	Subjective    string
+	Objective     string
+	Assessment    string
+	Plan          string
+}
+
+// NoteListQuery selects the notes to list
+type NoteListQuery struct {
+	Statuses []model.NoteStatus
+	Limit    int
+	Offset   int
+}
+
+// TimelineQuery selects the part of a patient's timeline to return
+type TimelineQuery struct {
+	From   *time.Time
+	To     *time.Time
+	Limit  int
+	Offset int
+}
+
+// ClinicalNoteService defines the interface for patients' clinical notes
+type ClinicalNoteService interface {
+	ListNotes(actor Actor, patientID uuid.UUID, query NoteListQuery) ([]model.ClinicalNote, int64, error)
+	Timeline(actor Actor, patientID uuid.UUID, query TimelineQuery) ([]model.ClinicalNote, int64, error)
+	GetNote(actor Actor, patientID, noteID uuid.UUID) (*model.ClinicalNote, error)
+	CreateNote(actor Actor, patientID uuid.UUID, input NoteInput) (*model.ClinicalNote, error)
+	UpdateNote(actor Actor, patientID, noteID uuid.UUID, input NoteInput) (*model.ClinicalNote, error)
+	DiscardNote(actor Actor, patientID, noteID uuid.UUID) error
+	SignNote(actor Actor, patientID, noteID uuid.UUID) (*model.ClinicalNote, error)
+	AddAddendum(actor Actor, patientID, noteID uuid.UUID, text string) (*model.ClinicalNote, error)
+}
+
+type clinicalNoteService struct {
+	noteRepo    repository.ClinicalNoteRepository
+	patientRepo repository.PatientRepository
+	transactor  repository.Transactor
+	access      *PatientAccess
+	audit       AuditService
+}
+
+// NewClinicalNoteService creates a new clinical note service
+func NewClinicalNoteService(noteRepo repository.ClinicalNoteRepository, patientRepo repository.PatientRepository, transactor repository.Transactor, access *PatientAccess, audit AuditService) ClinicalNoteService {
+	return &clinicalNoteService{noteRepo: noteRepo, patientRepo: patientRepo, transactor: transactor, access: access, audit: audit}
+}
+
+// ListNotes returns a page of a patient's notes, newest encounter first. The
+// actor's own drafts are included, other people's are not.
+func (s *clinicalNoteService) ListNotes(actor Actor, patientID uuid.UUID, query NoteListQuery) ([]model.ClinicalNote, int64, error) {
+	for _, status := range query.Statuses {
+		if !status.IsValid() {
+			return nil, 0, fmt.Errorf("%w: unknown status %q", ErrInvalidNote, status)
+		}
+	}
+	filter := repository.ClinicalNoteFilter{PatientID: patientID, ViewerID: actor.UserID, Statuses: query.Statuses, Limit: query.Limit, Offset: query.Offset}
+	return s.list(actor, AuditNoteList, filter)
+}
+
+// Timeline returns a page of a patient's signed notes with their addenda,
+// oldest encounter first
+func (s *clinicalNoteService) Timeline(actor Actor, patientID uuid.UUID, query TimelineQuery) ([]model.ClinicalNote, int64, error) {
+	if query.From != nil && query.To != nil && !query.From.Before(*query.To) {
+		return nil, 0, fmt.Errorf("%w: from must be before to", ErrInvalidNote)
+	}
+	filter := repository.ClinicalNoteFilter{
+		PatientID:     patientID,
+		ViewerID:      actor.UserID,
+		Statuses:      []model.NoteStatus{model.NoteSigned, model.NoteAmended},
+		From:          query.From,
+		To:            query.To,
+		Chronological: true,
+		Limit:         query.Limit,
+		Offset:        query.Offset,
+	}
+	return s.list(actor, AuditNoteTimeline, filter)
+}
+
+// GetNote returns one of a patient's notes with its addenda
+func (s *clinicalNoteService) GetNote(actor Actor, patientID, noteID uuid.UUID) (*model.ClinicalNote, error) {
+	breakGlassID, err := s.authorize(actor, patientID, ChartActionViewNotes, AuditNoteView)
+	if err != nil {
+		return nil, err
+	}
+	note, err := s.find(actor, patientID, noteID)
+	if err != nil {
+		return nil, err
+	}
+	event := AuditEvent{Action: AuditNoteView, PatientID: &patientID, BreakGlassAccessID: breakGlassID, Changes: noteRef(note)}
+	if err := recordAudit(s.audit, actor, event); err != nil {
+		return nil, err
+	}
+	return note, nil
+}
+
+// CreateNote starts a draft note for an encounter, written by the actor
+func (s *clinicalNoteService) CreateNote(actor Actor, patientID uuid.UUID, input NoteInput) (*model.ClinicalNote, error) {
+	input, err := input.normalize(time.Now())
+	if err != nil {
+		return nil, err
+	}
+	breakGlassID, err := s.authorize(actor, patientID, ChartActionEditNotes, AuditNoteCreate)
+	if err != nil {
+		return nil, err
+	}
+	note := &model.ClinicalNote{PatientID: patientID, AuthorID: actor.UserID, Status: model.NoteDraft}
+	input.apply(note)
+	err = s.transactor.Transaction(func(tx repository.Tx) error {
+		if err := tx.ClinicalNotes().Create(note); err != nil {
+			return err
+		}
+		event := AuditEvent{Action: AuditNoteCreate, PatientID: &patientID, BreakGlassAccessID: breakGlassID, Changes: noteChanges(nil, note)}
+		return s.audit.RecordIn(tx, actor, event)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return note, nil
+}
+
+// UpdateNote replaces the encounter details and SOAP sections of a draft.
+// Only its author may change it, and only until it is signed.
+func (s *clinicalNoteService) UpdateNote(actor Actor, patientID, noteID uuid.UUID, input NoteInput) (*model.ClinicalNote, error) {
+	input, err := input.normalize(time.Now())
+	if err != nil {
+		return nil, err
+	}
+	note, breakGlassID, err := s.findDraft(actor, patientID, noteID, AuditNoteUpdate)
+	if err != nil {
+		return nil, err
+	}
+	before := *note
+	input.apply(note)
+	err = s.transactor.Transaction(func(tx repository.Tx) error {
+		if err := tx.ClinicalNotes().UpdateDraft(note); err != nil {
+			return noteSigned(err)
+		}
+		event := AuditEvent{Action: AuditNoteUpdate, PatientID: &patientID, BreakGlassAccessID: breakGlassID, Changes: noteChanges(&before, note)}
+		return s.audit.RecordIn(tx, actor, event)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return note, nil
+}
+
+// DiscardNote deletes a draft. Only its author may discard it, and only
+// until it is signed.
+func (s *clinicalNoteService) DiscardNote(actor Actor, patientID, noteID uuid.UUID) error {
+	note, breakGlassID, err := s.findDraft(actor, patientID, noteID, AuditNoteDiscard)
+	if err != nil {
+		return err
+	}
+	return s.transactor.Transaction(func(tx repository.Tx) error {
+		if err := tx.ClinicalNotes().DeleteDraft(note.ID); err != nil {
+			return noteSigned(err)
+		}
+		changes := noteRef(note)
+		changes["status"] = model.FieldChange{Old: string(model.NoteDraft)}
+		return s.audit.RecordIn(tx, actor, AuditEvent{Action: AuditNoteDiscard, PatientID: &patientID, BreakGlassAccessID: breakGlassID, Changes: changes})
+	})
+}
+
+// SignNote signs a draft, making it part of the patient's record. Only its
+// author may sign it, and it must have at least one SOAP section.
+func (s *clinicalNoteService) SignNote(actor Actor, patientID, noteID uuid.UUID) (*model.ClinicalNote, error) {
+	note, breakGlassID, err := s.findDraft(actor, patientID, noteID, AuditNoteSign)
+	if err != nil {
+		return nil, err
+	}
+	if note.IsEmpty() {
+		return nil, fmt.Errorf("%w: write at least one SOAP section before signing", ErrInvalidNote)
+	}
+	before := *note
+	err = s.transactor.Transaction(func(tx repository.Tx) error {
+		if err := tx.ClinicalNotes().Sign(note); err != nil {
+			return noteSigned(err)
+		}
+		event := AuditEvent{Action: AuditNoteSign, PatientID: &patientID, BreakGlassAccessID: breakGlassID, Changes: noteChanges(&before, note)}
+		return s.audit.RecordIn(tx, actor, event)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return note, nil
+}
+
+// AddAddendum corrects or adds to a signed note, which is marked amended.
+// Any clinician who may open the patient's chart can add one.
+func (s *clinicalNoteService) AddAddendum(actor Actor, patientID, noteID uuid.UUID, text string) (*model.ClinicalNote, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil, fmt.Errorf("%w: addendum text is required", ErrInvalidNote)
+	}
+	breakGlassID, err := s.authorize(actor, patientID, ChartActionEditNotes, AuditNoteAddendum)
+	if err != nil {
+		return nil, err
+	}
+	note, err := s.find(actor, patientID, noteID)
+	if err != nil {
+		return nil, err
+	}
+	if note.Status == model.NoteDraft {
+		return nil, ErrNoteNotSigned
+	}
+	before := *note
+	addendum := &model.NoteAddendum{AuthorID: actor.UserID, Text: text}
+	err = s.transactor.Transaction(func(tx repository.Tx) error {
+		if err := tx.ClinicalNotes().AddAddendum(note, addendum); err != nil {
+			return err
+		}
+		changes := noteChanges(&before, note)
+		changes["addendum_id"] = model.FieldChange{New: addendum.ID}
+		changes["addendum"] = model.FieldChange{Redacted: true}
+		return s.audit.RecordIn(tx, actor, AuditEvent{Action: AuditNoteAddendum, PatientID: &patientID, BreakGlassAccessID: breakGlassID, Changes: changes})
+	})
+	if errors.Is(err, repository.ErrNoteNotSigned) {
+		return nil, ErrNoteNotSigned
+	}
+	if err != nil {
+		return nil, err
+	}
+	return note, nil
+}
+
+// list returns a page of notes after checking access, audited under action
+func (s *clinicalNoteService) list(actor Actor, action string, filter repository.ClinicalNoteFilter) ([]model.ClinicalNote, int64, error) {
+	breakGlassID, err := s.authorize(actor, filter.PatientID, ChartActionViewNotes, action)
+	if err != nil {
+		return nil, 0, err
+	}
+	notes, total, err := s.noteRepo.List(filter)
+	if err != nil {
+		return nil, 0, err
+	}
+	event := AuditEvent{Action: action, PatientID: &filter.PatientID, BreakGlassAccessID: breakGlassID}
+	if err := recordAudit(s.audit, actor, event); err != nil {
+		return nil, 0, err
+	}
+	return notes, total, nil
+}
+
+// authorize checks that the actor may work with the patient's notes
+func (s *clinicalNoteService) authorize(actor Actor, patientID uuid.UUID, chartAction, auditAction string) (*uuid.UUID, error) {
+	_, breakGlassID, err := authorizeClinical(s.patientRepo, s.access, s.audit, actor, patientID, chartAction, auditAction)
+	return breakGlassID, err
+}
+
+// find loads one of a patient's notes. Other people's drafts are not found.
+func (s *clinicalNoteService) find(actor Actor, patientID, noteID uuid.UUID) (*model.ClinicalNote, error) {
+	note, err := s.noteRepo.FindByID(patientID, noteID)
+	if errors.Is(err, gorm.ErrRecordNotFound) || err == nil && note.Status == model.NoteDraft && note.AuthorID != actor.UserID {
+		return nil, ErrNoteNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return note, nil
+}
+
+// findDraft loads a draft the actor wrote and may still change, recording a
+// denial under the audit action
+func (s *clinicalNoteService) findDraft(actor Actor, patientID, noteID uuid.UUID, auditAction string) (*model.ClinicalNote, *uuid.UUID, error) {
+	breakGlassID, err := s.authorize(actor, patientID, ChartActionEditNotes, auditAction)
+	if err != nil {
+		return nil, nil, err
+	}
+	note, err := s.find(actor, patientID, noteID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if note.Status != model.NoteDraft {
+		return nil, nil, ErrNoteSigned
+	}
+	return note, breakGlassID, nil
+}
+
+// noteSigned turns a repository error for a draft that was signed meanwhile
+// into ErrNoteSigned
+func noteSigned(err error) error {
+	if errors.Is(err, repository.ErrNoteNotDraft) {
+		return ErrNoteSigned
+	}
+	return err
+}
+
+// normalize trims the input, dates the encounter now if it has no date and
+// checks the encounter details
+func (input NoteInput) normalize(now time.Time) (NoteInput, error) {
+	input.Subjective = strings.TrimSpace(input.Subjective)
+	input.Objective = strings.TrimSpace(input.Objective)
+	input.Assessment = strings.TrimSpace(input.Assessment)
+	input.Plan = strings.TrimSpace(input.Plan)
+	if input.EncounterAt.IsZero() {
+		input.EncounterAt = now
+	}
+	if !input.EncounterType.IsValid() {
+		return input, fmt.Errorf("%w: encounter type must be outpatient, inpatient, emergency or telehealth", ErrInvalidNote)
+	}
+	if input.EncounterAt.After(now.Add(clockSkew)) {
+		return input, fmt.Errorf("%w: encounter cannot be in the future", ErrInvalidNote)
+	}
+	return input, nil
+}
+
+// apply copies the input onto a note
+func (input NoteInput) apply(note *model.ClinicalNote) {
+	note.EncounterType = input.EncounterType
+	note.EncounterAt = input.EncounterAt
+	note.Subjective = input.Subjective
+	note.Objective = input.Objective
+	note.Assessment = input.Assessment
+	note.Plan = input.Plan
+}
+
+// noteRef identifies a note in the audit log
+func noteRef(note *model.ClinicalNote) map[string]model.FieldChange {
+	return map[string]model.FieldChange{"note_id": {New: note.ID}}
+}
+
+// noteChanges returns the changes between two states of a note for the audit
+// log; before is nil for a new note. The encounter details and status are
+// written out, the SOAP sections are only marked as changed.
+func noteChanges(before, after *model.ClinicalNote) map[string]model.FieldChange {
+	changes := noteRef(after)
+	if before == nil {
+		before = &model.ClinicalNote{}
+	}
+	if before.Status != after.Status {
+		changes["status"] = fieldChange(string(before.Status), string(after.Status))
+	}
+	if before.EncounterType != after.EncounterType {
+		changes["encounter_type"] = fieldChange(string(before.EncounterType), string(after.EncounterType))
+	}
+	if !before.EncounterAt.Equal(after.EncounterAt) {
+		changes["encounter_at"] = model.FieldChange{New: after.EncounterAt}
+		if !before.EncounterAt.IsZero() {
+			changes["encounter_at"] = model.FieldChange{Old: before.EncounterAt, New: after.EncounterAt}
+		}
+	}
+	sections := map[string]bool{
+		"subjective": before.Subjective != after.Subjective,
+		"objective":  before.Objective != after.Objective,
+		"assessment": before.Assessment != after.Assessment,
+		"plan":       before.Plan != after.Plan,
+	}
+	for section, changed := range sections {
+		if changed {
+			changes[section] = model.FieldChange{Redacted: true}
+		}
+	}
+	return changes
+}
@@ -0,0 +1,243 @@
+package service
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/RohanDSkaria/hospital-management-system/internal/model"
+	"github.com/google/uuid"
+)
+
+func TestNoteInputNormalize(t *testing.T) {
+	now := time.Date(2026, time.March, 2, 10, 0, 0, 0, time.UTC)
+	cases := []struct {
+		name    string
+		input   NoteInput
+		want    NoteInput
+		wantErr bool
+	}{
+		{
+			name:  "trims sections and dates the encounter now",
+			input: NoteInput{EncounterType: model.EncounterOutpatient, Subjective: "  Cough for 3 days\n", Plan: " Rest "},
+			want:  NoteInput{EncounterType: model.EncounterOutpatient, EncounterAt: now, Subjective: "Cough for 3 days", Plan: "Rest"},
+		},
+		{
+			name:  "allows for clock skew",
+			input: NoteInput{EncounterType: model.EncounterEmergency, EncounterAt: now.Add(clockSkew)},
+			want:  NoteInput{EncounterType: model.EncounterEmergency, EncounterAt: now.Add(clockSkew)},
+		},
+		{name: "unknown encounter type", input: NoteInput{EncounterType: "house_call"}, wantErr: true},
+		{name: "encounter in the future", input: NoteInput{EncounterType: model.EncounterInpatient, EncounterAt: now.Add(time.Hour)}, wantErr: true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := tc.input.normalize(now)
+			if tc.wantErr {
+				if !errors.Is(err, ErrInvalidNote) {
+					t.Fatalf("expected ErrInvalidNote, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("normalize: %v", err)
+			}
+			if got != tc.want {
+				t.Errorf("normalize() = %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestNoteChangesRedactsSOAPSections(t *testing.T) {
+	encounterAt := time.Date(2026, time.March, 2, 9, 30, 0, 0, time.UTC)
+	draft := &model.ClinicalNote{
+		ID:            uuid.New(),
+		EncounterType: model.EncounterOutpatient,
+		EncounterAt:   encounterAt,
+		Status:        model.NoteDraft,
+		Subjective:    "Cough for 3 days",
+		Assessment:    "Viral infection",
+	}
+	changed := func(change func(note *model.ClinicalNote)) *model.ClinicalNote {
+		note := *draft
+		change(&note)
+		return &note
+	}
+
+	cases := []struct {
+		name   string
+		before *model.ClinicalNote
+		after  *model.ClinicalNote
+		want   map[string]model.FieldChange
+	}{
+		{
+			name:  "new draft",
+			after: draft,
+			want: map[string]model.FieldChange{
+				"status":         {New: "draft"},
+				"encounter_type": {New: "outpatient"},
+				"encounter_at":   {New: encounterAt},
+				"subjective":     {Redacted: true},
+				"assessment":     {Redacted: true},
+			},
+		},
+		{
+			name:   "signed",
+			before: draft,
+			after:  changed(func(note *model.ClinicalNote) { note.Status = model.NoteSigned }),
+			want:   map[string]model.FieldChange{"status": {Old: "draft", New: "signed"}},
+		},
+		{
+			name:   "plan written",
+			before: draft,
+			after:  changed(func(note *model.ClinicalNote) { note.Plan = "Rest and fluids" }),
+			want:   map[string]model.FieldChange{"plan": {Redacted: true}},
+		},
+		{
+			name:   "encounter redated",
+			before: draft,
+			after:  changed(func(note *model.ClinicalNote) { note.EncounterAt = encounterAt.Add(time.Hour) }),
+			want:   map[string]model.FieldChange{"encounter_at": {Old: encounterAt, New: encounterAt.Add(time.Hour)}},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			changes := noteChanges(tc.before, tc.after)
+			if got := changes["note_id"]; got.New != draft.ID {
+				t.Errorf("expected the note ID to be recorded, got %+v", got)
+			}
+			delete(changes, "note_id")
+			if len(changes) != len(tc.want) {
+				t.Fatalf("noteChanges() = %+v, want %+v", changes, tc.want)
+			}
+			for field, want := range tc.want {
+				if got := changes[field]; got != want {
+					t.Errorf("%s: got %+v, want %+v", field, got, want)
+				}
+			}
+		})
+	}
+}
+
+// newTestNoteService returns a clinical note service for the ward with one
+// note, written by the ward's doctor
+func newTestNoteService(ward *testWard, note model.ClinicalNote) (ClinicalNoteService, *fakeClinicalNoteRepository) {
+	notes := &fakeClinicalNoteRepository{notes: map[uuid.UUID]model.ClinicalNote{note.ID: note}}
+	ward.tx.notes = notes
+	return NewClinicalNoteService(notes, ward.patients, ward.transactor(), ward.access, ward.audit), notes
+}
+
+func TestNoteStatusChanges(t *testing.T) {
+	type action func(service ClinicalNoteService, actor Actor, patientID, noteID uuid.UUID) error
+	update := func(service ClinicalNoteService, actor Actor, patientID, noteID uuid.UUID) error {
+		_, err := service.UpdateNote(actor, patientID, noteID, NoteInput{EncounterType: model.EncounterOutpatient, Plan: "Rest"})
+		return err
+	}
+	sign := func(service ClinicalNoteService, actor Actor, patientID, noteID uuid.UUID) error {
+		_, err := service.SignNote(actor, patientID, noteID)
+		return err
+	}
+	discard := func(service ClinicalNoteService, actor Actor, patientID, noteID uuid.UUID) error {
+		return service.DiscardNote(actor, patientID, noteID)
+	}
+	addendum := func(service ClinicalNoteService, actor Actor, patientID, noteID uuid.UUID) error {
+		_, err := service.AddAddendum(actor, patientID, noteID, "Chest X-ray clear")
+		return err
+	}
+
+	cases := []struct {
+		name       string
+		status     model.NoteStatus
+		empty      bool
+		byNurse    bool
+		action     action
+		wantErr    error
+		wantStatus model.NoteStatus // "" if the note is gone
+		wantAudit  string
+	}{
+		{name: "author updates draft", status: model.NoteDraft, action: update, wantStatus: model.NoteDraft, wantAudit: AuditNoteUpdate},
+		{name: "author signs draft", status: model.NoteDraft, action: sign, wantStatus: model.NoteSigned, wantAudit: AuditNoteSign},
+		{name: "author discards draft", status: model.NoteDraft, action: discard, wantAudit: AuditNoteDiscard},
+		{name: "empty draft cannot be signed", status: model.NoteDraft, empty: true, action: sign, wantErr: ErrInvalidNote, wantStatus: model.NoteDraft},
+		{name: "draft has no addenda", status: model.NoteDraft, action: addendum, wantErr: ErrNoteNotSigned, wantStatus: model.NoteDraft},
+		{name: "colleague cannot sign draft", status: model.NoteDraft, byNurse: true, action: sign, wantErr: ErrNoteNotFound, wantStatus: model.NoteDraft},
+		{name: "signed note cannot be updated", status: model.NoteSigned, action: update, wantErr: ErrNoteSigned, wantStatus: model.NoteSigned},
+		{name: "signed note cannot be signed again", status: model.NoteSigned, action: sign, wantErr: ErrNoteSigned, wantStatus: model.NoteSigned},
+		{name: "signed note cannot be discarded", status: model.NoteSigned, action: discard, wantErr: ErrNoteSigned, wantStatus: model.NoteSigned},
+		{name: "addendum amends signed note", status: model.NoteSigned, byNurse: true, action: addendum, wantStatus: model.NoteAmended, wantAudit: AuditNoteAddendum},
+		{name: "amended note takes more addenda", status: model.NoteAmended, action: addendum, wantStatus: model.NoteAmended, wantAudit: AuditNoteAddendum},
+		{name: "amended note cannot be updated", status: model.NoteAmended, action: update, wantErr: ErrNoteSigned, wantStatus: model.NoteAmended},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ward := newTestWard()
+			note := model.ClinicalNote{
+				ID:            uuid.New(),
+				PatientID:     ward.patientID,
+				AuthorID:      ward.doctor.UserID,
+				EncounterType: model.EncounterOutpatient,
+				EncounterAt:   time.Now().Add(-time.Hour),
+				Status:        tc.status,
+				Subjective:    "Cough for 3 days",
+			}
+			if tc.empty {
+				note.Subjective = ""
+			}
+			service, notes := newTestNoteService(ward, note)
+			actor := ward.doctor
+			if tc.byNurse {
+				actor = ward.nurse
+			}
+
+			err := tc.action(service, actor, ward.patientID, note.ID)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("expected %v, got %v", tc.wantErr, err)
+			}
+			if got := notes.notes[note.ID].Status; got != tc.wantStatus {
+				t.Errorf("status = %q, want %q", got, tc.wantStatus)
+			}
+			var wantAudit []string
+			if tc.wantAudit != "" {
+				wantAudit = []string{tc.wantAudit}
+			}
+			if got := ward.audit.actions(); !slices.Equal(got, wantAudit) {
+				t.Errorf("audited %v, want %v", got, wantAudit)
+			}
+		})
+	}
+}
+
+func TestGetNoteHidesOtherAuthorsDrafts(t *testing.T) {
+	cases := []struct {
+		name    string
+		status  model.NoteStatus
+		byNurse bool
+		wantErr error
+	}{
+		{name: "author sees own draft", status: model.NoteDraft},
+		{name: "colleague does not see draft", status: model.NoteDraft, byNurse: true, wantErr: ErrNoteNotFound},
+		{name: "colleague sees signed note", status: model.NoteSigned, byNurse: true},
+		{name: "colleague sees amended note", status: model.NoteAmended, byNurse: true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ward := newTestWard()
+			note := model.ClinicalNote{ID: uuid.New(), PatientID: ward.patientID, AuthorID: ward.doctor.UserID, Status: tc.status}
+			service, _ := newTestNoteService(ward, note)
+			actor := ward.doctor
+			if tc.byNurse {
+				actor = ward.nurse
+			}
+
+			got, err := service.GetNote(actor, ward.patientID, note.ID)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("expected %v, got %v", tc.wantErr, err)
+			}
+			if err == nil && got.ID != note.ID {
+				t.Errorf("got note %s, want %s", got.ID, note.ID)
+			}
+		})
+	}
+}
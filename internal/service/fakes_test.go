@@ -0,0 +1,269 @@
+package service
+
+import (
+	"slices"
+	"time"
+
+	"github.com/RohanDSkaria/hospital-management-system/internal/model"
+	"github.com/RohanDSkaria/hospital-management-system/internal/repository"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// The fakes below keep what the services under test need in memory. Each
+// embeds its interface, so a method a test was not written for panics.
+
+type fakePatientRepository struct {
+	repository.PatientRepository
+	patients map[uuid.UUID]model.Patient
+}
+
+func (r *fakePatientRepository) FindByID(id uuid.UUID) (*model.Patient, error) {
+	patient, ok := r.patients[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &patient, nil
+}
+
+// fakeUserRepository fails every save with saveErr. Its active admins are
+// the admins among users; an update that would remove the last one is refused.
+type fakeUserRepository struct {
+	repository.UserRepository
+	users   map[uuid.UUID]model.User
+	saveErr error
+}
+
+func (r *fakeUserRepository) FindByEmail(email string) (*model.User, error) {
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *fakeUserRepository) FindByID(id uuid.UUID) (*model.User, error) {
+	user, ok := r.users[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &user, nil
+}
+
+func (r *fakeUserRepository) SaveUser(user *model.User) error {
+	return r.saveErr
+}
+
+func (r *fakeUserRepository) UpdateKeepingAdmin(user *model.User) error {
+	admins := 0
+	for _, existing := range r.users {
+		if existing.ID != user.ID && existing.Role == model.Admin && existing.Active {
+			admins++
+		}
+	}
+	if admins == 0 && (user.Role != model.Admin || !user.Active) && r.users[user.ID].Role == model.Admin {
+		return repository.ErrNoAdminLeft
+	}
+	r.users[user.ID] = *user
+	return nil
+}
+
+// fakeSessionRepository remembers whose sessions were revoked
+type fakeSessionRepository struct {
+	repository.SessionRepository
+	revoked []uuid.UUID
+}
+
+func (r *fakeSessionRepository) RevokeAllForUser(userID uuid.UUID, at time.Time) error {
+	r.revoked = append(r.revoked, userID)
+	return nil
+}
+
+type fakeCareTeamRepository struct {
+	repository.CareTeamRepository
+	members map[uuid.UUID][]uuid.UUID // user IDs by patient ID
+}
+
+func (r *fakeCareTeamRepository) IsMember(patientID, userID uuid.UUID) (bool, error) {
+	return slices.Contains(r.members[patientID], userID), nil
+}
+
+// fakeBreakGlassRepository has no grants
+type fakeBreakGlassRepository struct {
+	repository.BreakGlassRepository
+}
+
+func (r *fakeBreakGlassRepository) FindActive(userID, patientID uuid.UUID, now time.Time) (*model.BreakGlassAccess, error) {
+	return nil, gorm.ErrRecordNotFound
+}
+
+// fakeAuditService keeps the events that would have been recorded
+type fakeAuditService struct {
+	AuditService
+	events []AuditEvent
+}
+
+func (s *fakeAuditService) Record(actor Actor, event AuditEvent) error {
+	if event.Err != nil && !isDenial(event.Err) {
+		return nil
+	}
+	s.events = append(s.events, event)
+	return nil
+}
+
+func (s *fakeAuditService) RecordIn(tx repository.Tx, actor Actor, event AuditEvent) error {
+	return s.Record(actor, event)
+}
+
+// actions returns the actions of the recorded events in order
+func (s *fakeAuditService) actions() []string {
+	actions := make([]string, 0, len(s.events))
+	for _, event := range s.events {
+		actions = append(actions, event.Action)
+	}
+	return actions
+}
+
+// fakeTransactor runs the work straight away on the fake repositories
+type fakeTransactor struct {
+	tx *fakeTx
+}
+
+func (t *fakeTransactor) Transaction(fn func(tx repository.Tx) error) error {
+	return fn(t.tx)
+}
+
+type fakeTx struct {
+	repository.Tx
+	allergies repository.AllergyRepository
+	notes     repository.ClinicalNoteRepository
+	purges    repository.PatientPurgeRepository
+}
+
+func (t *fakeTx) Allergies() repository.AllergyRepository          { return t.allergies }
+func (t *fakeTx) ClinicalNotes() repository.ClinicalNoteRepository { return t.notes }
+func (t *fakeTx) PatientPurges() repository.PatientPurgeRepository { return t.purges }
+
+// fakePatientPurgeRepository finds and purges the deleted patients it holds
+type fakePatientPurgeRepository struct {
+	repository.PatientPurgeRepository
+	deleted []model.PurgedPatient
+	purged  bool
+}
+
+func (r *fakePatientPurgeRepository) FindPurgeable(cutoff time.Time) ([]model.PurgedPatient, error) {
+	var purgeable []model.PurgedPatient
+	for _, patient := range r.deleted {
+		if patient.DeletedAt.Before(cutoff) {
+			purgeable = append(purgeable, patient)
+		}
+	}
+	return purgeable, nil
+}
+
+func (r *fakePatientPurgeRepository) Purge(purge *model.PatientPurge) error {
+	purgeable, _ := r.FindPurgeable(purge.Cutoff)
+	purge.Patients = purgeable
+	purge.PurgedCount = len(purgeable)
+	r.purged = true
+	return nil
+}
+
+type fakeAllergyRepository struct {
+	repository.AllergyRepository
+	assertions map[uuid.UUID]model.NoKnownAllergies
+}
+
+func (r *fakeAllergyRepository) WithdrawNoKnownAllergies(patientID uuid.UUID) error {
+	if _, ok := r.assertions[patientID]; !ok {
+		return gorm.ErrRecordNotFound
+	}
+	delete(r.assertions, patientID)
+	return nil
+}
+
+// fakeClinicalNoteRepository follows the status rules of the real one
+type fakeClinicalNoteRepository struct {
+	repository.ClinicalNoteRepository
+	notes map[uuid.UUID]model.ClinicalNote
+}
+
+func (r *fakeClinicalNoteRepository) FindByID(patientID, id uuid.UUID) (*model.ClinicalNote, error) {
+	note, ok := r.notes[id]
+	if !ok || note.PatientID != patientID {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &note, nil
+}
+
+func (r *fakeClinicalNoteRepository) UpdateDraft(note *model.ClinicalNote) error {
+	if r.notes[note.ID].Status != model.NoteDraft {
+		return repository.ErrNoteNotDraft
+	}
+	r.notes[note.ID] = *note
+	return nil
+}
+
+func (r *fakeClinicalNoteRepository) DeleteDraft(id uuid.UUID) error {
+	if r.notes[id].Status != model.NoteDraft {
+		return repository.ErrNoteNotDraft
+	}
+	delete(r.notes, id)
+	return nil
+}
+
+func (r *fakeClinicalNoteRepository) Sign(note *model.ClinicalNote) error {
+	if r.notes[note.ID].Status != model.NoteDraft {
+		return repository.ErrNoteNotDraft
+	}
+	now := time.Now()
+	note.Status = model.NoteSigned
+	note.SignedAt = &now
+	r.notes[note.ID] = *note
+	return nil
+}
+
+func (r *fakeClinicalNoteRepository) AddAddendum(note *model.ClinicalNote, addendum *model.NoteAddendum) error {
+	if r.notes[note.ID].Status == model.NoteDraft {
+		return repository.ErrNoteNotSigned
+	}
+	addendum.ID = uuid.New()
+	addendum.NoteID = note.ID
+	note.Status = model.NoteAmended
+	note.Addenda = append(note.Addenda, *addendum)
+	r.notes[note.ID] = *note
+	return nil
+}
+
+// testWard is a patient with a doctor and a nurse on their care team, a
+// doctor who is not, and a receptionist, for the services under test to work
+// with
+type testWard struct {
+	patientID    uuid.UUID
+	doctor       Actor
+	nurse        Actor
+	outsider     Actor
+	receptionist Actor
+	patients     *fakePatientRepository
+	access       *PatientAccess
+	audit        *fakeAuditService
+	tx           *fakeTx
+}
+
+func newTestWard() *testWard {
+	patientID := uuid.New()
+	doctor := Actor{UserID: uuid.New(), Role: model.Doctor}
+	nurse := Actor{UserID: uuid.New(), Role: model.Nurse}
+	careTeam := &fakeCareTeamRepository{members: map[uuid.UUID][]uuid.UUID{patientID: {doctor.UserID, nurse.UserID}}}
+	return &testWard{
+		patientID:    patientID,
+		doctor:       doctor,
+		nurse:        nurse,
+		outsider:     Actor{UserID: uuid.New(), Role: model.Doctor},
+		receptionist: Actor{UserID: uuid.New(), Role: model.Receptionist},
+		patients:     &fakePatientRepository{patients: map[uuid.UUID]model.Patient{patientID: {ID: patientID, FullName: "Jane Doe"}}},
+		access:       NewPatientAccess(careTeam, &fakeBreakGlassRepository{}),
+		audit:        &fakeAuditService{},
+		tx:           &fakeTx{},
+	}
+}
+
+func (w *testWard) transactor() repository.Transactor {
+	return &fakeTransactor{tx: w.tx}
+}
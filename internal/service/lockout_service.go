@@ -0,0 +1,203 @@
+package service
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/RohanDSkaria/hospital-management-system/internal/model"
+	"github.com/RohanDSkaria/hospital-management-system/internal/repository"
+	"github.com/google/uuid"
+)
+
+// LoginLockedError is returned when login attempts for an account or client
+// IP are temporarily refused after too many failures
+type LoginLockedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LoginLockedError) Error() string {
+	return fmt.Sprintf("too many failed login attempts, try again in %d seconds", int(math.Ceil(e.RetryAfter.Seconds())))
+}
+
+// LockoutPolicy controls how failed logins are throttled
+type LockoutPolicy struct {
+	// BackoffAfter is the number of failures after which each further failure
+	// delays the next attempt, doubling every time starting at BaseDelay
+	BackoffAfter int
+	BaseDelay    time.Duration
+	MaxDelay     time.Duration
+	// AccountLockoutAfter failures for one account lock it for LockoutDuration
+	AccountLockoutAfter int
+	// IPLockoutAfter failures from one client IP lock it for LockoutDuration.
+	// This is higher than the account limit because many users can share an IP.
+	IPLockoutAfter  int
+	LockoutDuration time.Duration
+	// FailureWindow is how long failures are remembered after the last one
+	FailureWindow time.Duration
+}
+
+// DefaultLockoutPolicy returns the lockout policy used when none is configured
+func DefaultLockoutPolicy() LockoutPolicy {
+	return LockoutPolicy{
+		BackoffAfter:        3,
+		BaseDelay:           time.Second,
+		MaxDelay:            time.Minute,
+		AccountLockoutAfter: 10,
+		IPLockoutAfter:      50,
+		LockoutDuration:     15 * time.Minute,
+		FailureWindow:       time.Hour,
+	}
+}
+
+// LockoutService defines the interface for throttling failed logins
+type LockoutService interface {
+	Check(email, clientIP string) error
+	RecordFailure(email, clientIP string, userID *uuid.UUID, reason string)
+	RecordSuccess(email, clientIP string, userID uuid.UUID)
+	ListLockouts() ([]model.LoginThrottle, error)
+	ClearLockout(id uuid.UUID) error
+	PruneThrottles() (int64, error)
+	ListAttempts(filter repository.LoginAttemptFilter) ([]model.LoginAttempt, int64, error)
+}
+
+type lockoutService struct {
+	attemptRepo repository.LoginAttemptRepository
+	policy      LockoutPolicy
+}
+
+// NewLockoutService creates a new lockout service
+func NewLockoutService(attemptRepo repository.LoginAttemptRepository, policy LockoutPolicy) LockoutService {
+	return &lockoutService{attemptRepo: attemptRepo, policy: policy}
+}
+
+// Check returns a LoginLockedError if the account or the client IP is currently locked
+func (s *lockoutService) Check(email, clientIP string) error {
+	throttles, err := s.attemptRepo.FindThrottles(normalizeEmail(email), clientIP)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	var retryAfter time.Duration
+	for _, throttle := range throttles {
+		if throttle.IsLocked(now) {
+			if wait := throttle.LockedUntil.Sub(now); wait > retryAfter {
+				retryAfter = wait
+			}
+		}
+	}
+	if retryAfter > 0 {
+		s.record(email, clientIP, nil, false, "locked")
+		return &LoginLockedError{RetryAfter: retryAfter}
+	}
+	return nil
+}
+
+// RecordFailure counts a failed attempt against both the account and the
+// client IP and records the event. Failures here are logged, never returned,
+// so bookkeeping problems cannot turn into a different login response.
+func (s *lockoutService) RecordFailure(email, clientIP string, userID *uuid.UUID, reason string) {
+	s.record(email, clientIP, userID, false, reason)
+
+	s.fail(model.LockoutScopeAccount, normalizeEmail(email), s.policy.AccountLockoutAfter)
+	if clientIP != "" {
+		s.fail(model.LockoutScopeIP, clientIP, s.policy.IPLockoutAfter)
+	}
+}
+
+// RecordSuccess records a successful login and clears the account's failures.
+// The IP counter is kept so one valid account cannot be used to reset it
+// while spraying passwords against others.
+func (s *lockoutService) RecordSuccess(email, clientIP string, userID uuid.UUID) {
+	s.record(email, clientIP, &userID, true, "")
+	if err := s.attemptRepo.ResetThrottle(model.LockoutScopeAccount, normalizeEmail(email)); err != nil {
+		logError("reset login throttle", err)
+	}
+}
+
+// ListLockouts returns the accounts and IPs that are locked or have recent failures
+func (s *lockoutService) ListLockouts() ([]model.LoginThrottle, error) {
+	now := time.Now()
+	return s.attemptRepo.ListThrottles(now, now.Add(-s.policy.FailureWindow))
+}
+
+// ClearLockout removes a lockout and its failure count
+func (s *lockoutService) ClearLockout(id uuid.UUID) error {
+	return s.attemptRepo.DeleteThrottle(id)
+}
+
+// PruneThrottles deletes the throttles of accounts and IPs that are not
+// locked and whose failures have been forgotten, so one-off addresses do not
+// pile up. It returns how many were deleted.
+func (s *lockoutService) PruneThrottles() (int64, error) {
+	now := time.Now()
+	return s.attemptRepo.DeleteStaleThrottles(now, now.Add(-s.policy.FailureWindow))
+}
+
+// ListAttempts returns recorded login attempts
+func (s *lockoutService) ListAttempts(filter repository.LoginAttemptFilter) ([]model.LoginAttempt, int64, error) {
+	filter.Email = normalizeEmail(filter.Email)
+	return s.attemptRepo.ListAttempts(filter)
+}
+
+// fail increments the failure counter of a subject and applies backoff or lockout
+func (s *lockoutService) fail(scope model.LockoutScope, subject string, lockoutAfter int) {
+	now := time.Now()
+	_, err := s.attemptRepo.RecordFailure(scope, subject, func(throttle *model.LoginThrottle) {
+		if now.Sub(throttle.LastFailureAt) > s.policy.FailureWindow {
+			throttle.Failures = 0
+		}
+		throttle.Failures++
+		throttle.LastFailureAt = now
+
+		if delay := s.policy.delay(throttle.Failures, lockoutAfter); delay > 0 {
+			lockedUntil := now.Add(delay)
+			throttle.LockedUntil = &lockedUntil
+		}
+	})
+	if err != nil {
+		logError("record login failure", err)
+	}
+}
+
+// delay returns how long to refuse attempts after the given number of failures
+func (p LockoutPolicy) delay(failures, lockoutAfter int) time.Duration {
+	if failures >= lockoutAfter {
+		return p.LockoutDuration
+	}
+	if failures < p.BackoffAfter {
+		return 0
+	}
+	delay := p.BaseDelay << (failures - p.BackoffAfter)
+	if delay > p.MaxDelay || delay <= 0 {
+		delay = p.MaxDelay
+	}
+	return delay
+}
+
+// record stores a login attempt event
+func (s *lockoutService) record(email, clientIP string, userID *uuid.UUID, success bool, reason string) {
+	err := s.attemptRepo.RecordAttempt(&model.LoginAttempt{
+		Email:    normalizeEmail(email),
+		UserID:   userID,
+		ClientIP: clientIP,
+		Success:  success,
+		Reason:   reason,
+	})
+	if err != nil {
+		logError("record login attempt", err)
+	}
+}
+
+// normalizeEmail makes sure differently cased emails share one counter
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// logError logs an error that must not change the outcome of the request
+func logError(action string, err error) {
+	log.Printf("Failed to %s: %v", action, err)
+}
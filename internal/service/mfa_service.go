@@ -0,0 +1,291 @@
+package service
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/RohanDSkaria/hospital-management-system/internal/auth"
+	"github.com/RohanDSkaria/hospital-management-system/internal/model"
+	"github.com/RohanDSkaria/hospital-management-system/internal/repository"
+	"github.com/RohanDSkaria/hospital-management-system/pkg/utils"
+	"github.com/google/uuid"
+)
+
+const (
+	// RecoveryCodeCount is how many recovery codes are issued at once
+	RecoveryCodeCount = 10
+	// recoveryCodeSize is the number of random bytes in a recovery code
+	recoveryCodeSize = 10
+)
+
+var (
+	// ErrMFAAlreadyEnabled is returned when enrolling a user who already uses MFA
+	ErrMFAAlreadyEnabled = errors.New("multi-factor authentication is already enabled")
+	// ErrMFANotEnabled is returned when an action needs MFA but the user has not enrolled
+	ErrMFANotEnabled = errors.New("multi-factor authentication is not enabled")
+	// ErrMFAEnrollmentNotStarted is returned when confirming an enrollment that was never started
+	ErrMFAEnrollmentNotStarted = errors.New("multi-factor authentication enrollment has not been started")
+	// ErrMFARequired is returned when a user tries to turn off MFA that their role requires
+	ErrMFARequired = errors.New("multi-factor authentication is required for your role")
+	// ErrInvalidMFACode is returned when a TOTP or recovery code is wrong or was already used
+	ErrInvalidMFACode = errors.New("invalid authentication code")
+)
+
+// MFAEnrollment holds what an authenticator app needs to be set up
+type MFAEnrollment struct {
+	Secret          string
+	ProvisioningURI string
+}
+
+// MFAService defines the interface for TOTP multi-factor authentication
+type MFAService interface {
+	IsRequired(role model.Role) bool
+	BeginEnrollment(userID uuid.UUID) (*MFAEnrollment, error)
+	ConfirmEnrollment(userID uuid.UUID, code string) ([]string, error)
+	Verify(user *model.User, code string) error
+	RegenerateRecoveryCodes(userID uuid.UUID, code string) ([]string, error)
+	Disable(userID uuid.UUID, password, code string) error
+	Reset(userID uuid.UUID) (*model.User, error)
+}
+
+type mfaService struct {
+	userRepo      repository.UserRepository
+	mfaRepo       repository.MFARepository
+	sessionRepo   repository.SessionRepository
+	passwords     *Passwords
+	secretBox     *auth.SecretBox
+	issuer        string
+	requiredRoles map[model.Role]bool
+}
+
+// NewMFAService creates a new MFA service. Users with one of requiredRoles
+// must complete a TOTP enrollment before they can log in.
+func NewMFAService(userRepo repository.UserRepository, mfaRepo repository.MFARepository, sessionRepo repository.SessionRepository, passwords *Passwords, secretBox *auth.SecretBox, issuer string, requiredRoles []model.Role) MFAService {
+	required := make(map[model.Role]bool, len(requiredRoles))
+	for _, role := range requiredRoles {
+		required[role] = true
+	}
+	return &mfaService{
+		userRepo:      userRepo,
+		mfaRepo:       mfaRepo,
+		sessionRepo:   sessionRepo,
+		passwords:     passwords,
+		secretBox:     secretBox,
+		issuer:        issuer,
+		requiredRoles: required,
+	}
+}
+
+// IsRequired reports whether users with the role must use MFA
+func (s *mfaService) IsRequired(role model.Role) bool {
+	return s.requiredRoles[role]
+}
+
+// BeginEnrollment generates a new TOTP secret for a user who has not enrolled
+// yet. MFA is only switched on once a code from it is confirmed.
+func (s *mfaService) BeginEnrollment(userID uuid.UUID) (*MFAEnrollment, error) {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.MFAEnabled {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	secret, err := auth.GenerateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+	sealed, err := s.secretBox.Seal(secret)
+	if err != nil {
+		return nil, err
+	}
+	user.TOTPSecret = sealed
+	user.TOTPLastStep = 0
+	if err := s.userRepo.Update(user); err != nil {
+		return nil, err
+	}
+
+	return &MFAEnrollment{
+		Secret:          secret,
+		ProvisioningURI: auth.TOTPProvisioningURI(s.issuer, user.Email, secret),
+	}, nil
+}
+
+// ConfirmEnrollment turns on MFA once the user proves their authenticator
+// works, and returns a fresh set of recovery codes. The codes are only
+// returned here; the database keeps their hashes.
+func (s *mfaService) ConfirmEnrollment(userID uuid.UUID, code string) ([]string, error) {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.MFAEnabled {
+		return nil, ErrMFAAlreadyEnabled
+	}
+	if user.TOTPSecret == "" {
+		return nil, ErrMFAEnrollmentNotStarted
+	}
+	if err := s.verifyTOTP(user, code); err != nil {
+		return nil, err
+	}
+
+	codes, err := s.replaceRecoveryCodes(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	user.MFAEnabled = true
+	if err := s.userRepo.Update(user); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// Verify checks a TOTP code or, failing that, a recovery code of an enrolled
+// user. Each code is accepted only once.
+func (s *mfaService) Verify(user *model.User, code string) error {
+	if !user.MFAEnabled {
+		return ErrMFANotEnabled
+	}
+	if len(strings.TrimSpace(code)) == auth.TOTPDigits {
+		return s.verifyTOTP(user, code)
+	}
+
+	used, err := s.mfaRepo.UseRecoveryCode(user.ID, hashRecoveryCode(code), time.Now())
+	if err != nil {
+		return err
+	}
+	if !used {
+		return ErrInvalidMFACode
+	}
+	return nil
+}
+
+// RegenerateRecoveryCodes replaces all recovery codes of an enrolled user
+func (s *mfaService) RegenerateRecoveryCodes(userID uuid.UUID, code string) ([]string, error) {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if !user.MFAEnabled {
+		return nil, ErrMFANotEnabled
+	}
+	if err := s.verifyTOTP(user, code); err != nil {
+		return nil, err
+	}
+	return s.replaceRecoveryCodes(user.ID)
+}
+
+// Disable turns off MFA for a user whose role does not require it. Both the
+// password and a current code are needed, so a stolen session is not enough.
+func (s *mfaService) Disable(userID uuid.UUID, password, code string) error {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return err
+	}
+	if !user.MFAEnabled {
+		return ErrMFANotEnabled
+	}
+	if s.IsRequired(user.Role) {
+		return ErrMFARequired
+	}
+	if !s.passwords.Verify(password, user.PasswordHash) {
+		return ErrIncorrectPassword
+	}
+	if err := s.Verify(user, code); err != nil {
+		return err
+	}
+	return s.clear(user)
+}
+
+// Reset removes the MFA enrollment of a user who lost their authenticator and
+// their recovery codes, and logs them out everywhere. They have to enroll
+// again on their next login if their role requires it.
+func (s *mfaService) Reset(userID uuid.UUID) (*model.User, error) {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.clear(user); err != nil {
+		return nil, err
+	}
+	if err := s.sessionRepo.RevokeAllForUser(user.ID, time.Now()); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// verifyTOTP checks a TOTP code against the user's secret and refuses a code
+// from a time step that was already used
+func (s *mfaService) verifyTOTP(user *model.User, code string) error {
+	secret, err := s.secretBox.Open(user.TOTPSecret)
+	if err != nil {
+		return err
+	}
+	step, ok := auth.ValidateTOTP(secret, code, time.Now())
+	if !ok || step <= user.TOTPLastStep {
+		return ErrInvalidMFACode
+	}
+	advanced, err := s.userRepo.AdvanceTOTPStep(user.ID, step)
+	if err != nil {
+		return err
+	}
+	if !advanced {
+		return ErrInvalidMFACode
+	}
+	user.TOTPLastStep = step
+	return nil
+}
+
+// clear turns off MFA for a user and deletes their secret and recovery codes
+func (s *mfaService) clear(user *model.User) error {
+	user.MFAEnabled = false
+	user.TOTPSecret = ""
+	user.TOTPLastStep = 0
+	if err := s.userRepo.Update(user); err != nil {
+		return err
+	}
+	return s.mfaRepo.DeleteRecoveryCodes(user.ID)
+}
+
+// replaceRecoveryCodes generates a new set of recovery codes for a user,
+// invalidating the previous set
+func (s *mfaService) replaceRecoveryCodes(userID uuid.UUID) ([]string, error) {
+	codes := make([]string, 0, RecoveryCodeCount)
+	records := make([]model.RecoveryCode, 0, RecoveryCodeCount)
+	for i := 0; i < RecoveryCodeCount; i++ {
+		code, err := generateRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+		records = append(records, model.RecoveryCode{UserID: userID, CodeHash: hashRecoveryCode(code)})
+	}
+	if err := s.mfaRepo.ReplaceRecoveryCodes(userID, records); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// generateRecoveryCode returns a random code formatted as xxxx-xxxx-xxxx-xxxx
+func generateRecoveryCode() (string, error) {
+	raw := make([]byte, recoveryCodeSize)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	encoded := strings.ToLower(base32.StdEncoding.EncodeToString(raw))
+	groups := make([]string, 0, len(encoded)/4)
+	for i := 0; i < len(encoded); i += 4 {
+		groups = append(groups, encoded[i:i+4])
+	}
+	return strings.Join(groups, "-"), nil
+}
+
+// hashRecoveryCode hashes a recovery code, ignoring case, spaces and dashes
+func hashRecoveryCode(code string) string {
+	normalized := strings.NewReplacer("-", "", " ", "").Replace(strings.ToLower(code))
+	return utils.HashToken(normalized)
+}
@@ -0,0 +1,181 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"time"
+
+	"github.com/RohanDSkaria/hospital-management-system/internal/mailer"
+	"github.com/RohanDSkaria/hospital-management-system/internal/model"
+	"github.com/RohanDSkaria/hospital-management-system/internal/repository"
+	"github.com/RohanDSkaria/hospital-management-system/pkg/utils"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// PasswordResetTTL is how long a password reset token stays valid
+const PasswordResetTTL = time.Hour
+
+var (
+	// ErrIncorrectPassword is returned when the current password supplied for a change is wrong
+	ErrIncorrectPassword = errors.New("current password is incorrect")
+	// ErrInvalidResetToken is returned when a reset token is unknown, expired or already used
+	ErrInvalidResetToken = errors.New("invalid or expired reset token")
+)
+
+// PasswordService defines the interface for changing and resetting passwords
+type PasswordService interface {
+	ChangePassword(userID, sessionID uuid.UUID, currentPassword, newPassword string) error
+	RequestPasswordReset(email string) error
+	ResetPassword(token, newPassword string) error
+}
+
+type passwordService struct {
+	userRepo    repository.UserRepository
+	sessionRepo repository.SessionRepository
+	resetRepo   repository.PasswordResetRepository
+	passwords   *Passwords
+	mailer      mailer.Mailer
+	resetURL    string
+}
+
+// NewPasswordService creates a new password service. resetURL is the page of
+// the front end that accepts a reset token in its "token" query parameter.
+func NewPasswordService(userRepo repository.UserRepository, sessionRepo repository.SessionRepository, resetRepo repository.PasswordResetRepository, passwords *Passwords, m mailer.Mailer, resetURL string) PasswordService {
+	return &passwordService{userRepo: userRepo, sessionRepo: sessionRepo, resetRepo: resetRepo, passwords: passwords, mailer: m, resetURL: resetURL}
+}
+
+// ChangePassword changes the password of a logged in user. Every other
+// session is revoked; the session making the change stays logged in.
+func (s *passwordService) ChangePassword(userID, sessionID uuid.UUID, currentPassword, newPassword string) error {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return err
+	}
+	if !s.passwords.Verify(currentPassword, user.PasswordHash) {
+		return ErrIncorrectPassword
+	}
+	if err := s.passwords.Validate(user, newPassword); err != nil {
+		return err
+	}
+	if err := s.setPassword(user, newPassword); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	if err := s.resetRepo.InvalidateForUser(user.ID, now); err != nil {
+		return err
+	}
+	return s.sessionRepo.RevokeOtherSessions(user.ID, sessionID, now)
+}
+
+// RequestPasswordReset emails a reset link to the user. It deliberately
+// reports success for unknown or deactivated accounts so the endpoint cannot
+// be used to find out which emails have an account. The link is issued and
+// sent in the background, so a known email costs the caller no more time
+// than an unknown one.
+func (s *passwordService) RequestPasswordReset(email string) error {
+	user, err := s.userRepo.FindByEmail(email)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+	if !user.Active || user.ServiceAccount {
+		return nil
+	}
+	go func() {
+		// The caller must not learn whether the account exists, so only log it
+		if err := s.sendResetLink(user); err != nil {
+			log.Printf("Failed to send password reset link: %v", err)
+		}
+	}()
+	return nil
+}
+
+// sendResetLink replaces the user's reset links with a new one and emails it
+func (s *passwordService) sendResetLink(user *model.User) error {
+	token, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	// Only the newest link should work
+	if err := s.resetRepo.InvalidateForUser(user.ID, now); err != nil {
+		return err
+	}
+	if err := s.resetRepo.Create(&model.PasswordResetToken{
+		UserID:    user.ID,
+		TokenHash: utils.HashToken(token),
+		ExpiresAt: now.Add(PasswordResetTTL),
+	}); err != nil {
+		return err
+	}
+
+	link := s.resetURL + "?token=" + url.QueryEscape(token)
+	return s.mailer.Send(mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hello %s,\n\n"+
+			"Someone asked to reset the password of your account. If it was you, open the link below within %d minutes:\n\n"+
+			"%s\n\n"+
+			"If you did not ask for this, you can ignore this email.\n",
+			user.FullName, int(PasswordResetTTL.Minutes()), link),
+	})
+}
+
+// ResetPassword sets a new password using a reset token and logs the user out everywhere
+func (s *passwordService) ResetPassword(token, newPassword string) error {
+	resetToken, err := s.resetRepo.FindByTokenHash(utils.HashToken(token))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrInvalidResetToken
+		}
+		return err
+	}
+	now := time.Now()
+	if resetToken.UsedAt != nil || now.After(resetToken.ExpiresAt) {
+		return ErrInvalidResetToken
+	}
+
+	user, err := s.userRepo.FindByID(resetToken.UserID)
+	if err != nil {
+		return err
+	}
+	if !user.Active {
+		return ErrInvalidResetToken
+	}
+	// Check the password before using up the token, so the user can try again
+	if err := s.passwords.Validate(user, newPassword); err != nil {
+		return err
+	}
+
+	used, err := s.resetRepo.MarkUsed(resetToken.ID, now)
+	if err != nil {
+		return err
+	}
+	if !used {
+		return ErrInvalidResetToken
+	}
+
+	if err := s.setPassword(user, newPassword); err != nil {
+		return err
+	}
+	return s.sessionRepo.RevokeAllForUser(user.ID, now)
+}
+
+// setPassword hashes and stores a validated new password and records it in the history
+func (s *passwordService) setPassword(user *model.User, newPassword string) error {
+	hashedPassword, err := s.passwords.Hash(newPassword)
+	if err != nil {
+		return err
+	}
+	user.PasswordHash = hashedPassword
+	if err := s.userRepo.Update(user); err != nil {
+		return err
+	}
+	return s.passwords.Remember(user.ID, hashedPassword)
+}
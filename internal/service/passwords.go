@@ -0,0 +1,92 @@
+package service
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/RohanDSkaria/hospital-management-system/internal/model"
+	"github.com/RohanDSkaria/hospital-management-system/internal/password"
+	"github.com/RohanDSkaria/hospital-management-system/internal/repository"
+	"github.com/RohanDSkaria/hospital-management-system/pkg/utils"
+	"github.com/google/uuid"
+)
+
+// ErrPasswordReused is returned when a new password matches one of the user's recent passwords
+var ErrPasswordReused = errors.New("password was used recently, choose a different one")
+
+// Passwords hashes, verifies and vets passwords for every service that sets
+// or checks one, so the policy and hash settings are applied the same way
+type Passwords struct {
+	hasher      *utils.PasswordHasher
+	policy      password.Policy
+	historyRepo repository.PasswordHistoryRepository
+
+	dummyOnce sync.Once
+	dummyHash string
+}
+
+// NewPasswords creates a Passwords from the configured hasher and policy
+func NewPasswords(hasher *utils.PasswordHasher, policy password.Policy, historyRepo repository.PasswordHistoryRepository) *Passwords {
+	return &Passwords{hasher: hasher, policy: policy, historyRepo: historyRepo}
+}
+
+// Verify compares a password with a stored hash
+func (p *Passwords) Verify(plain, hash string) bool {
+	return p.hasher.Verify(plain, hash)
+}
+
+// VerifyDummy spends the same time as checking a real password, so a login
+// for an unknown email cannot be told apart by its response time
+func (p *Passwords) VerifyDummy(plain string) {
+	p.dummyOnce.Do(func() {
+		p.dummyHash, _ = p.hasher.Hash("dummy password for timing")
+	})
+	p.hasher.Verify(plain, p.dummyHash)
+}
+
+// NeedsRehash reports whether a stored hash is older than the configured algorithm or cost
+func (p *Passwords) NeedsRehash(hash string) bool {
+	return p.hasher.NeedsRehash(hash)
+}
+
+// Hash hashes a password with the configured algorithm
+func (p *Passwords) Hash(plain string) (string, error) {
+	return p.hasher.Hash(plain)
+}
+
+// Validate checks a new password against the policy and, for an existing
+// user, against their current and recent passwords
+func (p *Passwords) Validate(user *model.User, plain string) error {
+	if err := p.policy.Validate(plain, user.Email, user.FullName); err != nil {
+		return err
+	}
+	if user.ID == uuid.Nil || p.policy.HistorySize <= 0 {
+		return nil
+	}
+
+	if user.PasswordHash != "" && p.hasher.Verify(plain, user.PasswordHash) {
+		return ErrPasswordReused
+	}
+	history, err := p.historyRepo.ListRecent(user.ID, p.policy.HistorySize)
+	if err != nil {
+		return err
+	}
+	for _, entry := range history {
+		if entry.PasswordHash != user.PasswordHash && p.hasher.Verify(plain, entry.PasswordHash) {
+			return ErrPasswordReused
+		}
+	}
+	return nil
+}
+
+// Remember adds a newly set password hash to the user's history and forgets
+// the ones that are too old to matter
+func (p *Passwords) Remember(userID uuid.UUID, hash string) error {
+	if p.policy.HistorySize <= 0 {
+		return nil
+	}
+	if err := p.historyRepo.Add(&model.PasswordHistory{UserID: userID, PasswordHash: hash}); err != nil {
+		return err
+	}
+	return p.historyRepo.Prune(userID, p.policy.HistorySize)
+}
@@ -0,0 +1,205 @@
+package service
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/RohanDSkaria/hospital-management-system/internal/model"
+	"github.com/google/uuid"
+)
+
+// Duplicate detection settings. Each field that can be compared adds its
+// weight times how well it matched to the score; the score is then divided
+// by the total weight of the fields compared, so it runs from 0 to 1.
+const (
+	duplicateNameWeight  = 0.45
+	duplicateDOBWeight   = 0.35
+	duplicatePhoneWeight = 0.20
+	// duplicateThreshold is the score from which a patient is reported as a
+	// possible duplicate
+	duplicateThreshold = 0.7
+	// duplicateSoundsAlikeScore is the name score of names that sound alike
+	// but are spelled differently
+	duplicateSoundsAlikeScore = 0.8
+	// duplicatePhoneDigits is how many trailing digits of two phone numbers
+	// must be equal for them to match, so country and area prefixes may differ
+	duplicatePhoneDigits = 7
+	// duplicateLookalikes is how many patients are compared at most
+	duplicateLookalikes = 50
+	// maxDuplicateCandidates is how many possible duplicates are reported
+	maxDuplicateCandidates = 5
+)
+
+// Reasons a patient is reported as a possible duplicate
+const (
+	DuplicateSameName          = "same_name"
+	DuplicateSimilarName       = "similar_name"
+	DuplicateNameSoundsAlike   = "name_sounds_alike"
+	DuplicateSameDateOfBirth   = "same_date_of_birth"
+	DuplicateSimilarBirthDate  = "similar_date_of_birth"
+	DuplicateSameContactNumber = "same_contact_number"
+)
+
+// DuplicateCandidate is an existing patient who may be the one being
+// registered. It holds demographics only, never the medical history. A
+// clinician only gets the ID of a candidate whose care team they are not on.
+type DuplicateCandidate struct {
+	PatientID     uuid.UUID  `json:"patient_id"`
+	FullName      string     `json:"full_name,omitempty"`
+	DateOfBirth   *time.Time `json:"date_of_birth,omitempty"`
+	ContactNumber string     `json:"contact_number,omitempty"`
+	Score         float64    `json:"score,omitempty"`
+	Reasons       []string   `json:"reasons,omitempty"`
+}
+
+// PossibleDuplicatesError is returned when a new patient looks like one who
+// is already registered. The patient is not created unless the caller
+// confirms that it is a different person.
+type PossibleDuplicatesError struct {
+	Candidates []DuplicateCandidate
+}
+
+func (e *PossibleDuplicatesError) Error() string {
+	return "this patient may already be registered, check the candidates or confirm it is a different person"
+}
+
+// findDuplicates returns the registered patients who may be the new patient,
+// most likely first. Clinicians may not see the details of patients outside
+// their care teams, so those candidates only carry their ID.
+func (s *patientService) findDuplicates(actor Actor, patient *model.Patient) ([]DuplicateCandidate, error) {
+	digits := lastDigits(patient.ContactNumber, duplicatePhoneDigits)
+	var digitsPattern string
+	if len(digits) == duplicatePhoneDigits {
+		digitsPattern = "%" + digits
+	}
+	lookalikes, err := s.patientRepo.FindLookalikes(patient.FullName, patient.DateOfBirth, digitsPattern, duplicateLookalikes)
+	if err != nil {
+		return nil, err
+	}
+
+	var candidates []DuplicateCandidate
+	for i := range lookalikes {
+		lookalike := &lookalikes[i]
+		score, reasons := duplicateScore(patient, lookalike.Patient, lookalike.NameSimilarity, lookalike.NameSoundsAlike)
+		if score < duplicateThreshold {
+			continue
+		}
+		candidates = append(candidates, DuplicateCandidate{
+			PatientID:     lookalike.ID,
+			FullName:      lookalike.FullName,
+			DateOfBirth:   &lookalike.DateOfBirth,
+			ContactNumber: lookalike.ContactNumber,
+			Score:         math.Round(score*100) / 100,
+			Reasons:       reasons,
+		})
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].Score > candidates[j].Score })
+	if len(candidates) > maxDuplicateCandidates {
+		candidates = candidates[:maxDuplicateCandidates]
+	}
+
+	if actor.Role.IsClinician() {
+		for i := range candidates {
+			member, err := s.careTeamRepo.IsMember(candidates[i].PatientID, actor.UserID)
+			if err != nil {
+				return nil, err
+			}
+			if !member {
+				candidates[i] = DuplicateCandidate{PatientID: candidates[i].PatientID}
+			}
+		}
+	}
+	return candidates, nil
+}
+
+// candidateIDs returns the IDs of duplicate candidates for the audit log
+func candidateIDs(candidates []DuplicateCandidate) []uuid.UUID {
+	ids := make([]uuid.UUID, len(candidates))
+	for i, candidate := range candidates {
+		ids[i] = candidate.PatientID
+	}
+	return ids
+}
+
+// duplicateScore rates how likely the existing patient is the new one, from
+// 0 to 1, and says which fields matched. Contact numbers are only compared
+// when both patients have one.
+func duplicateScore(patient *model.Patient, existing model.Patient, nameSimilarity float64, soundsAlike bool) (float64, []string) {
+	var reasons []string
+	total, weights := 0.0, 0.0
+
+	name := nameSimilarity
+	switch {
+	case strings.EqualFold(strings.Join(strings.Fields(patient.FullName), " "), strings.Join(strings.Fields(existing.FullName), " ")):
+		name = 1
+		reasons = append(reasons, DuplicateSameName)
+	case soundsAlike && name < duplicateSoundsAlikeScore:
+		name = duplicateSoundsAlikeScore
+		reasons = append(reasons, DuplicateNameSoundsAlike)
+	case name >= 0.5:
+		reasons = append(reasons, DuplicateSimilarName)
+	}
+	total += duplicateNameWeight * name
+	weights += duplicateNameWeight
+
+	switch dobSimilarity(patient.DateOfBirth, existing.DateOfBirth) {
+	case 1:
+		total += duplicateDOBWeight
+		reasons = append(reasons, DuplicateSameDateOfBirth)
+	case 0.5:
+		total += duplicateDOBWeight * 0.5
+		reasons = append(reasons, DuplicateSimilarBirthDate)
+	}
+	weights += duplicateDOBWeight
+
+	newDigits := lastDigits(patient.ContactNumber, duplicatePhoneDigits)
+	oldDigits := lastDigits(existing.ContactNumber, duplicatePhoneDigits)
+	if len(newDigits) == duplicatePhoneDigits && len(oldDigits) == duplicatePhoneDigits {
+		if newDigits == oldDigits {
+			total += duplicatePhoneWeight
+			reasons = append(reasons, DuplicateSameContactNumber)
+		}
+		weights += duplicatePhoneWeight
+	}
+	return total / weights, reasons
+}
+
+// dobSimilarity returns 1 for the same date of birth, 0.5 for dates that
+// look like a typing mistake of each other (day and month swapped, or one of
+// day, month and year different) and 0 otherwise
+func dobSimilarity(a, b time.Time) float64 {
+	ay, am, ad := a.Date()
+	by, bm, bd := b.Date()
+	if ay == by && am == bm && ad == bd {
+		return 1
+	}
+	if ay == by && int(am) == bd && ad == int(bm) {
+		return 0.5
+	}
+	same := 0
+	for _, equal := range []bool{ay == by, am == bm, ad == bd} {
+		if equal {
+			same++
+		}
+	}
+	if same == 2 {
+		return 0.5
+	}
+	return 0
+}
+
+// lastDigits returns up to n of the last digits in a phone number
+func lastDigits(phone string, n int) string {
+	var digits []byte
+	for i := 0; i < len(phone); i++ {
+		if phone[i] >= '0' && phone[i] <= '9' {
+			digits = append(digits, phone[i])
+		}
+	}
+	if len(digits) > n {
+		digits = digits[len(digits)-n:]
+	}
+	return string(digits)
+}
@@ -0,0 +1,202 @@
+package service
+
+import (
+	"errors"
+	"time"
+
+	"github.com/RohanDSkaria/hospital-management-system/internal/model"
+	"github.com/RohanDSkaria/hospital-management-system/internal/repository"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ErrPatientVersionNotFound is returned when a patient has no such version
+var ErrPatientVersionNotFound = errors.New("patient version not found")
+
+// PatientDiff is the difference between two versions of a patient record
+type PatientDiff struct {
+	From    int                          `json:"from"`
+	To      int                          `json:"to"`
+	Changes map[string]model.FieldChange `json:"changes"`
+}
+
+// PatientHistoryService defines the interface for a patient record's version history
+type PatientHistoryService interface {
+	ListVersions(actor Actor, patientID uuid.UUID, limit, offset int) ([]model.PatientVersion, int64, error)
+	GetVersion(actor Actor, patientID uuid.UUID, version int) (*model.PatientVersion, error)
+	GetAsOf(actor Actor, patientID uuid.UUID, at time.Time) (*model.PatientVersion, error)
+	Diff(actor Actor, patientID uuid.UUID, from, to int) (*PatientDiff, error)
+	Restore(actor Actor, patientID uuid.UUID, version int) (*model.Patient, error)
+}
+
+type patientHistoryService struct {
+	patientRepo repository.PatientRepository
+	versionRepo repository.PatientVersionRepository
+	transactor  repository.Transactor
+	access      *PatientAccess
+	audit       AuditService
+}
+
+// NewPatientHistoryService creates a new patient history service
+func NewPatientHistoryService(patientRepo repository.PatientRepository, versionRepo repository.PatientVersionRepository, transactor repository.Transactor, access *PatientAccess, audit AuditService) PatientHistoryService {
+	return &patientHistoryService{patientRepo: patientRepo, versionRepo: versionRepo, transactor: transactor, access: access, audit: audit}
+}
+
+// ListVersions returns a page of a patient's versions, newest first
+func (s *patientHistoryService) ListVersions(actor Actor, patientID uuid.UUID, limit, offset int) ([]model.PatientVersion, int64, error) {
+	breakGlassID, err := s.authorize(actor, patientID, ChartActionViewHistory)
+	if err != nil {
+		return nil, 0, err
+	}
+	versions, total, err := s.versionRepo.ListByPatient(patientID, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	if err := s.recordView(actor, patientID, breakGlassID); err != nil {
+		return nil, 0, err
+	}
+	for i := range versions {
+		redactVersion(actor, &versions[i])
+	}
+	return versions, total, nil
+}
+
+// GetVersion returns one version of a patient record
+func (s *patientHistoryService) GetVersion(actor Actor, patientID uuid.UUID, version int) (*model.PatientVersion, error) {
+	breakGlassID, err := s.authorize(actor, patientID, ChartActionViewHistory)
+	if err != nil {
+		return nil, err
+	}
+	patientVersion, err := s.findVersion(patientID, version)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.recordView(actor, patientID, breakGlassID); err != nil {
+		return nil, err
+	}
+	redactVersion(actor, patientVersion)
+	return patientVersion, nil
+}
+
+// GetAsOf returns the version of a patient record that was current at the
+// given time
+func (s *patientHistoryService) GetAsOf(actor Actor, patientID uuid.UUID, at time.Time) (*model.PatientVersion, error) {
+	breakGlassID, err := s.authorize(actor, patientID, ChartActionViewHistory)
+	if err != nil {
+		return nil, err
+	}
+	patientVersion, err := s.versionRepo.FindAsOf(patientID, at)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrPatientVersionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := s.recordView(actor, patientID, breakGlassID); err != nil {
+		return nil, err
+	}
+	redactVersion(actor, patientVersion)
+	return patientVersion, nil
+}
+
+// Diff returns the fields that differ between two versions of a patient
+// record. Clinical fields are only marked as changed for non-clinicians.
+func (s *patientHistoryService) Diff(actor Actor, patientID uuid.UUID, from, to int) (*PatientDiff, error) {
+	breakGlassID, err := s.authorize(actor, patientID, ChartActionViewHistory)
+	if err != nil {
+		return nil, err
+	}
+	fromVersion, err := s.findVersion(patientID, from)
+	if err != nil {
+		return nil, err
+	}
+	toVersion, err := s.findVersion(patientID, to)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.recordView(actor, patientID, breakGlassID); err != nil {
+		return nil, err
+	}
+	before, after := fromVersion.Record(), toVersion.Record()
+	changes := diffPatients(&before, &after, !actor.Role.IsClinician())
+	return &PatientDiff{From: from, To: to, Changes: changes}, nil
+}
+
+// Restore brings a patient record back to an old version by writing it as a
+// new version, so nothing in the history is lost. The actor's role must be
+// allowed to change every field the restore changes. Non-clinicians cannot
+// set the medical history, so it is kept as it is when they restore.
+func (s *patientHistoryService) Restore(actor Actor, patientID uuid.UUID, version int) (*model.Patient, error) {
+	patient, err := s.patientRepo.FindByID(patientID)
+	if err != nil {
+		return nil, err
+	}
+	breakGlassID, err := s.access.authorize(actor, patientID, ChartActionRestore)
+	if err != nil {
+		return nil, recordAudit(s.audit, actor, AuditEvent{Action: AuditPatientRestore, PatientID: &patientID, Err: err})
+	}
+	old, err := s.findVersion(patientID, version)
+	if err != nil {
+		return nil, err
+	}
+	before := *patient
+
+	patient.FullName = old.FullName
+	patient.DateOfBirth = old.DateOfBirth
+	patient.Address = old.Address
+	patient.ContactNumber = old.ContactNumber
+	if actor.Role.IsClinician() {
+		patient.MedicalHistory = old.MedicalHistory
+	}
+	if err := checkEditableFields(actor, &before, patient); err != nil {
+		return nil, recordAudit(s.audit, actor, AuditEvent{Action: AuditPatientRestore, PatientID: &patientID, BreakGlassAccessID: breakGlassID, Err: err})
+	}
+
+	restored := &model.PatientVersion{ChangeType: model.PatientRestored, RestoredFrom: &version, ChangedByID: &actor.UserID}
+	err = s.transactor.Transaction(func(tx repository.Tx) error {
+		if err := tx.Patients().Update(patient, restored); err != nil {
+			return versionConflict(err)
+		}
+		event := AuditEvent{Action: AuditPatientRestore, PatientID: &patientID, BreakGlassAccessID: breakGlassID, Changes: patientChanges(&before, patient)}
+		return s.audit.RecordIn(tx, actor, event)
+	})
+	if err != nil {
+		return nil, err
+	}
+	redact(actor, patient)
+	return patient, nil
+}
+
+// authorize checks that the patient exists and the actor may open their
+// chart. Refusals are recorded in the audit log.
+func (s *patientHistoryService) authorize(actor Actor, patientID uuid.UUID, action string) (*uuid.UUID, error) {
+	if _, err := s.patientRepo.FindByID(patientID); err != nil {
+		return nil, err
+	}
+	breakGlassID, err := s.access.authorize(actor, patientID, action)
+	if err != nil {
+		return nil, recordAudit(s.audit, actor, AuditEvent{Action: AuditPatientHistory, PatientID: &patientID, Err: err})
+	}
+	return breakGlassID, nil
+}
+
+// recordView records that the actor looked at a patient's history
+func (s *patientHistoryService) recordView(actor Actor, patientID uuid.UUID, breakGlassID *uuid.UUID) error {
+	return recordAudit(s.audit, actor, AuditEvent{Action: AuditPatientHistory, PatientID: &patientID, BreakGlassAccessID: breakGlassID})
+}
+
+// findVersion returns one version of a patient record or ErrPatientVersionNotFound
+func (s *patientHistoryService) findVersion(patientID uuid.UUID, version int) (*model.PatientVersion, error) {
+	patientVersion, err := s.versionRepo.FindByVersion(patientID, version)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrPatientVersionNotFound
+	}
+	return patientVersion, err
+}
+
+// redactVersion removes what the actor's role may not see from a patient version
+func redactVersion(actor Actor, version *model.PatientVersion) {
+	if !actor.Role.IsClinician() {
+		version.MedicalHistory = ""
+	}
+}
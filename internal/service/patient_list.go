@@ -0,0 +1,125 @@
+package service
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/RohanDSkaria/hospital-management-system/internal/model"
+	"github.com/RohanDSkaria/hospital-management-system/internal/repository"
+	"github.com/google/uuid"
+)
+
+// ErrInvalidPatientQuery is returned when a patient listing has an unknown
+// sort, a bad cursor or contradictory filters
+var ErrInvalidPatientQuery = errors.New("invalid patient query")
+
+// PatientListQuery selects a page of patients. Sort is name, created_at or
+// date_of_birth, prefixed with - for descending order. Cursor is the
+// NextCursor of the previous page and must be used with the same sort.
+type PatientListQuery struct {
+	Sort           string
+	Cursor         string
+	Limit          int
+	RegisteredByID *uuid.UUID
+	CreatedFrom    *time.Time
+	CreatedTo      *time.Time
+	MinAge         *int
+	MaxAge         *int
+	IncludeTotal   bool
+}
+
+// PatientPage is a page of patients. NextCursor is empty on the last page and
+// Total is only set when it was asked for.
+type PatientPage struct {
+	Patients   []model.Patient
+	NextCursor string
+	Total      *int64
+}
+
+// patientCursor is what a cursor encodes: the sort it belongs to and the
+// position of the last patient of the page
+type patientCursor struct {
+	Sort  string    `json:"s"`
+	Value string    `json:"v"`
+	ID    uuid.UUID `json:"id"`
+}
+
+// filter turns the query into a repository filter. Ages are counted in whole
+// years on the given day.
+func (q PatientListQuery) filter(now time.Time) (repository.PatientFilter, error) {
+	filter := repository.PatientFilter{
+		Sort:           strings.TrimPrefix(q.Sort, "-"),
+		Descending:     strings.HasPrefix(q.Sort, "-"),
+		RegisteredByID: q.RegisteredByID,
+		CreatedFrom:    q.CreatedFrom,
+		CreatedTo:      q.CreatedTo,
+	}
+	if !repository.IsValidPatientSort(filter.Sort) {
+		return filter, fmt.Errorf("%w: sort must be name, created_at or date_of_birth, with - for descending", ErrInvalidPatientQuery)
+	}
+	if q.MinAge != nil && q.MaxAge != nil && *q.MinAge > *q.MaxAge {
+		return filter, fmt.Errorf("%w: min_age is greater than max_age", ErrInvalidPatientQuery)
+	}
+	if q.CreatedFrom != nil && q.CreatedTo != nil && !q.CreatedFrom.Before(*q.CreatedTo) {
+		return filter, fmt.Errorf("%w: created_from must be before created_to", ErrInvalidPatientQuery)
+	}
+	if q.MinAge != nil {
+		bornBy := now.AddDate(-*q.MinAge, 0, 0)
+		filter.BornOnOrBefore = &bornBy
+	}
+	if q.MaxAge != nil {
+		bornAfter := now.AddDate(-*q.MaxAge-1, 0, 0)
+		filter.BornAfter = &bornAfter
+	}
+	if q.Cursor != "" {
+		after, err := decodePatientCursor(q.Cursor, q.Sort)
+		if err != nil {
+			return filter, err
+		}
+		filter.After = after
+	}
+	return filter, nil
+}
+
+// encodePatientCursor returns the cursor of the page that starts after the patient
+func encodePatientCursor(query PatientListQuery, patient *model.Patient) string {
+	cursor := patientCursor{Sort: query.Sort, ID: patient.ID}
+	switch strings.TrimPrefix(query.Sort, "-") {
+	case repository.PatientSortName:
+		cursor.Value = patient.FullName
+	case repository.PatientSortDateOfBirth:
+		cursor.Value = patient.DateOfBirth.Format(time.RFC3339Nano)
+	default:
+		cursor.Value = patient.CreatedAt.Format(time.RFC3339Nano)
+	}
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodePatientCursor reads a cursor made by encodePatientCursor for the same sort
+func decodePatientCursor(encoded, sort string) (*repository.PatientCursor, error) {
+	errBadCursor := fmt.Errorf("%w: cursor is not valid for this listing", ErrInvalidPatientQuery)
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, errBadCursor
+	}
+	var cursor patientCursor
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.Sort != sort {
+		return nil, errBadCursor
+	}
+	after := &repository.PatientCursor{ID: cursor.ID}
+	if strings.TrimPrefix(sort, "-") == repository.PatientSortName {
+		after.Value = cursor.Value
+		return after, nil
+	}
+	value, err := time.Parse(time.RFC3339Nano, cursor.Value)
+	if err != nil {
+		return nil, errBadCursor
+	}
+	after.Value = value
+	return after, nil
+}
@@ -0,0 +1,129 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/RohanDSkaria/hospital-management-system/internal/model"
+	"github.com/RohanDSkaria/hospital-management-system/internal/repository"
+	"github.com/google/uuid"
+)
+
+func TestPatientCursorRoundTrip(t *testing.T) {
+	patient := &model.Patient{
+		ID:          uuid.New(),
+		FullName:    "Jane Doe",
+		DateOfBirth: time.Date(1961, time.March, 2, 0, 0, 0, 0, time.UTC),
+		CreatedAt:   time.Date(2026, time.January, 5, 8, 30, 0, 123456789, time.UTC),
+	}
+	cases := []struct {
+		sort string
+		want interface{}
+	}{
+		{sort: "name", want: "Jane Doe"},
+		{sort: "-name", want: "Jane Doe"},
+		{sort: "created_at", want: patient.CreatedAt},
+		{sort: "-date_of_birth", want: patient.DateOfBirth},
+	}
+	for _, tc := range cases {
+		t.Run(tc.sort, func(t *testing.T) {
+			encoded := encodePatientCursor(PatientListQuery{Sort: tc.sort}, patient)
+			after, err := decodePatientCursor(encoded, tc.sort)
+			if err != nil {
+				t.Fatalf("decodePatientCursor: %v", err)
+			}
+			if after.ID != patient.ID {
+				t.Errorf("ID = %s, want %s", after.ID, patient.ID)
+			}
+			if after.Value != tc.want {
+				t.Errorf("Value = %v, want %v", after.Value, tc.want)
+			}
+		})
+	}
+}
+
+func TestDecodePatientCursorRejectsForeignCursors(t *testing.T) {
+	patient := &model.Patient{ID: uuid.New(), FullName: "Jane Doe", CreatedAt: time.Now()}
+	cases := []struct {
+		name    string
+		encoded string
+		sort    string
+	}{
+		{name: "other sort", encoded: encodePatientCursor(PatientListQuery{Sort: "name"}, patient), sort: "-name"},
+		{name: "not base64", encoded: "not a cursor!", sort: "name"},
+		{name: "not JSON", encoded: "bm90IGpzb24", sort: "name"},
+		{name: "bad time", encoded: "eyJzIjoiY3JlYXRlZF9hdCIsInYiOiJ5ZXN0ZXJkYXkifQ", sort: "created_at"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := decodePatientCursor(tc.encoded, tc.sort); !errors.Is(err, ErrInvalidPatientQuery) {
+				t.Errorf("expected ErrInvalidPatientQuery, got %v", err)
+			}
+		})
+	}
+}
+
+func TestPatientListQueryFilter(t *testing.T) {
+	now := time.Date(2026, time.March, 2, 10, 0, 0, 0, time.UTC)
+	age := func(years int) *int { return &years }
+	at := func(t time.Time) *time.Time { return &t }
+	patient := &model.Patient{ID: uuid.New(), FullName: "Jane Doe"}
+
+	cases := []struct {
+		name    string
+		query   PatientListQuery
+		check   func(t *testing.T, filter repository.PatientFilter)
+		wantErr bool
+	}{
+		{
+			name:  "descending sort",
+			query: PatientListQuery{Sort: "-date_of_birth"},
+			check: func(t *testing.T, filter repository.PatientFilter) {
+				if filter.Sort != repository.PatientSortDateOfBirth || !filter.Descending || filter.After != nil {
+					t.Errorf("got sort %q descending %v after %v", filter.Sort, filter.Descending, filter.After)
+				}
+			},
+		},
+		{
+			name:  "ages become birth dates",
+			query: PatientListQuery{Sort: "name", MinAge: age(65), MaxAge: age(70)},
+			check: func(t *testing.T, filter repository.PatientFilter) {
+				if want := time.Date(1961, time.March, 2, 10, 0, 0, 0, time.UTC); !filter.BornOnOrBefore.Equal(want) {
+					t.Errorf("BornOnOrBefore = %v, want %v", filter.BornOnOrBefore, want)
+				}
+				if want := time.Date(1955, time.March, 2, 10, 0, 0, 0, time.UTC); !filter.BornAfter.Equal(want) {
+					t.Errorf("BornAfter = %v, want %v", filter.BornAfter, want)
+				}
+			},
+		},
+		{
+			name:  "cursor continues after the patient",
+			query: PatientListQuery{Sort: "name", Cursor: encodePatientCursor(PatientListQuery{Sort: "name"}, patient)},
+			check: func(t *testing.T, filter repository.PatientFilter) {
+				if filter.After == nil || filter.After.ID != patient.ID || filter.After.Value != patient.FullName {
+					t.Errorf("After = %+v, want the position of %s", filter.After, patient.FullName)
+				}
+			},
+		},
+		{name: "unknown sort", query: PatientListQuery{Sort: "mrn"}, wantErr: true},
+		{name: "cursor of another sort", query: PatientListQuery{Sort: "created_at", Cursor: encodePatientCursor(PatientListQuery{Sort: "name"}, patient)}, wantErr: true},
+		{name: "min age above max age", query: PatientListQuery{Sort: "name", MinAge: age(70), MaxAge: age(65)}, wantErr: true},
+		{name: "empty created range", query: PatientListQuery{Sort: "name", CreatedFrom: at(now), CreatedTo: at(now)}, wantErr: true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			filter, err := tc.query.filter(now)
+			if tc.wantErr {
+				if !errors.Is(err, ErrInvalidPatientQuery) {
+					t.Fatalf("expected ErrInvalidPatientQuery, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("filter: %v", err)
+			}
+			tc.check(t, filter)
+		})
+	}
+}
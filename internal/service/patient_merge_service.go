@@ -0,0 +1,115 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/RohanDSkaria/hospital-management-system/internal/model"
+	"github.com/RohanDSkaria/hospital-management-system/internal/repository"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrSelfMerge is returned when a patient is merged into itself
+	ErrSelfMerge = errors.New("a patient cannot be merged into itself")
+	// ErrPatientMerged is returned when a patient that was merged into
+	// another is restored
+	ErrPatientMerged = errors.New("patient was merged into another patient")
+)
+
+// PatientMergedError is returned when a patient that was merged into another
+// is looked up. TargetID is the patient it is now part of.
+type PatientMergedError struct {
+	TargetID uuid.UUID
+}
+
+func (e *PatientMergedError) Error() string {
+	return fmt.Sprintf("patient was merged into patient %s", e.TargetID)
+}
+
+// Unwrap lets errors.Is match ErrPatientMerged
+func (e *PatientMergedError) Unwrap() error {
+	return ErrPatientMerged
+}
+
+// PatientMergeService defines the interface for merging duplicate patients
+type PatientMergeService interface {
+	Merge(actor Actor, sourceID, targetID uuid.UUID, reason string) (*model.PatientMerge, *model.Patient, error)
+	ListMerges(limit, offset int) ([]model.PatientMerge, int64, error)
+}
+
+type patientMergeService struct {
+	patientRepo repository.PatientRepository
+	mergeRepo   repository.PatientMergeRepository
+	transactor  repository.Transactor
+	audit       AuditService
+}
+
+// NewPatientMergeService creates a new patient merge service
+func NewPatientMergeService(patientRepo repository.PatientRepository, mergeRepo repository.PatientMergeRepository, transactor repository.Transactor, audit AuditService) PatientMergeService {
+	return &patientMergeService{patientRepo: patientRepo, mergeRepo: mergeRepo, transactor: transactor, audit: audit}
+}
+
+// Merge merges the source patient, a duplicate, into the target patient and
+// returns the merge and the target as it is now. The target keeps its name
+// and date of birth and gains the source's missing contact details, medical
+// history, care team, break-glass grants, allergies, clinical notes and
+// vital signs. The source is deleted and its ID leads to the target from
+// then on. Both sides are audited.
+func (s *patientMergeService) Merge(actor Actor, sourceID, targetID uuid.UUID, reason string) (*model.PatientMerge, *model.Patient, error) {
+	if sourceID == targetID {
+		return nil, nil, ErrSelfMerge
+	}
+	source, err := s.patientRepo.FindByID(sourceID)
+	if err != nil {
+		return nil, nil, err
+	}
+	target, err := s.patientRepo.FindByID(targetID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	before := *target
+	merged := model.MergePatientDetails(target, source)
+	merge := &model.PatientMerge{SourcePatientID: sourceID, TargetPatientID: targetID, MergedByID: actor.UserID, Reason: reason}
+	version := &model.PatientVersion{ChangeType: model.PatientMerged, ChangedByID: &actor.UserID}
+	err = s.transactor.Transaction(func(tx repository.Tx) error {
+		if err := tx.PatientMerges().Merge(merge, source, &merged, version); err != nil {
+			return versionConflict(err)
+		}
+		changes := patientChanges(&before, &merged)
+		changes["merged_from"] = model.FieldChange{New: sourceID}
+		if err := s.audit.RecordIn(tx, actor, AuditEvent{Action: AuditPatientMerge, PatientID: &targetID, Changes: changes}); err != nil {
+			return err
+		}
+		changes = map[string]model.FieldChange{"merged_into": {New: targetID}}
+		return s.audit.RecordIn(tx, actor, AuditEvent{Action: AuditPatientMerge, PatientID: &sourceID, Changes: changes})
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	redact(actor, &merged)
+	return merge, &merged, nil
+}
+
+// ListMerges returns a page of merges, newest first
+func (s *patientMergeService) ListMerges(limit, offset int) ([]model.PatientMerge, int64, error) {
+	return s.mergeRepo.List(limit, offset)
+}
+
+// mergedInto returns a PatientMergedError if the missing patient was merged
+// into another, and err otherwise
+func mergedInto(mergeRepo repository.PatientMergeRepository, id uuid.UUID, err error) error {
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	merge, findErr := mergeRepo.FindBySource(id)
+	if errors.Is(findErr, gorm.ErrRecordNotFound) {
+		return err
+	}
+	if findErr != nil {
+		return findErr
+	}
+	return &PatientMergedError{TargetID: merge.TargetPatientID}
+}
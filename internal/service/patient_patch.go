@@ -0,0 +1,159 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/RohanDSkaria/hospital-management-system/internal/jsonpatch"
+	"github.com/RohanDSkaria/hospital-management-system/internal/model"
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrInvalidPatientPatch is returned when a patch is malformed or leaves the patient invalid
+	ErrInvalidPatientPatch = errors.New("invalid patient patch")
+	// ErrPatientPatchTestFailed is returned when a JSON Patch test operation does not match the patient
+	ErrPatientPatchTestFailed = errors.New("patient does not match the patch's test operation")
+)
+
+// Patch formats
+const (
+	MergePatch = "merge-patch"
+	JSONPatch  = "json-patch"
+)
+
+// PatientPatch is a partial update of a patient, as an RFC 7396 merge patch
+// or an RFC 6902 JSON Patch against the patient's editable fields
+type PatientPatch struct {
+	Format   string
+	Document []byte
+}
+
+// PatchPatient changes only the fields named by the patch. The patch is
+// applied to the fields the actor can see, so non-clinicians cannot read or
+// set the medical history through it.
+func (s *patientService) PatchPatient(actor Actor, id uuid.UUID, expectedVersion int, patch PatientPatch) (*model.Patient, error) {
+	patient, breakGlassID, err := s.findAuthorized(actor, id, ChartActionUpdate)
+	if err != nil {
+		return nil, recordAudit(s.audit, actor, AuditEvent{Action: AuditPatientUpdate, PatientID: &id, BreakGlassAccessID: breakGlassID, Err: err})
+	}
+	if patient.Version != expectedVersion {
+		return nil, ErrPatientVersionMismatch
+	}
+
+	var document interface{}
+	switch patch.Format {
+	case MergePatch:
+		document, err = jsonpatch.MergePatch(patientDocument(actor, patient), patch.Document)
+	case JSONPatch:
+		document, err = jsonpatch.Apply(patientDocument(actor, patient), patch.Document)
+	default:
+		return nil, fmt.Errorf("%w: unknown format %q", ErrInvalidPatientPatch, patch.Format)
+	}
+	if errors.Is(err, jsonpatch.ErrTestFailed) {
+		return nil, ErrPatientPatchTestFailed
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPatientPatch, err)
+	}
+
+	before := *patient
+	if err := applyPatientDocument(actor, patient, document); err != nil {
+		if errors.Is(err, ErrMedicalHistoryForbidden) {
+			return nil, recordAudit(s.audit, actor, AuditEvent{Action: AuditPatientUpdate, PatientID: &id, BreakGlassAccessID: breakGlassID, Err: err})
+		}
+		return nil, err
+	}
+	return s.saveUpdate(actor, &before, patient, breakGlassID)
+}
+
+// patientDocument returns the editable fields of a patient that the actor
+// may see, as a decoded JSON object
+func patientDocument(actor Actor, patient *model.Patient) map[string]interface{} {
+	document := map[string]interface{}{
+		model.PatientFieldFullName:      patient.FullName,
+		model.PatientFieldDateOfBirth:   patient.DateOfBirth.Format(time.RFC3339Nano),
+		model.PatientFieldAddress:       patient.Address,
+		model.PatientFieldContactNumber: patient.ContactNumber,
+	}
+	if actor.Role.IsClinician() {
+		document[model.PatientFieldMedicalHistory] = patient.MedicalHistory
+	}
+	return document
+}
+
+// applyPatientDocument validates a patched document and copies it onto the
+// patient. Optional fields that were removed are cleared.
+func applyPatientDocument(actor Actor, patient *model.Patient, document interface{}) error {
+	fields, ok := document.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("%w: the patient must stay a JSON object", ErrInvalidPatientPatch)
+	}
+	var problems []string
+	text := func(field string, maxLength int) string {
+		value, present := fields[field]
+		if !present || value == nil {
+			return ""
+		}
+		str, ok := value.(string)
+		if !ok {
+			problems = append(problems, field+" must be a string")
+			return ""
+		}
+		if maxLength > 0 && utf8.RuneCountInString(str) > maxLength {
+			problems = append(problems, fmt.Sprintf("%s must be at most %d characters", field, maxLength))
+		}
+		return str
+	}
+	var unknown []string
+	for field := range fields {
+		switch field {
+		case model.PatientFieldFullName, model.PatientFieldDateOfBirth, model.PatientFieldAddress, model.PatientFieldContactNumber:
+		case model.PatientFieldMedicalHistory:
+			if !actor.Role.IsClinician() {
+				return ErrMedicalHistoryForbidden
+			}
+		default:
+			unknown = append(unknown, field)
+		}
+	}
+	slices.Sort(unknown)
+	for _, field := range unknown {
+		problems = append(problems, "unknown field "+field)
+	}
+
+	fullName := text(model.PatientFieldFullName, 255)
+	if strings.TrimSpace(fullName) == "" {
+		problems = append(problems, "full_name is required")
+	}
+	var dob time.Time
+	if value := text(model.PatientFieldDateOfBirth, 0); value == "" {
+		problems = append(problems, "date_of_birth is required")
+	} else if parsed, err := time.Parse(time.RFC3339, value); err != nil {
+		problems = append(problems, "date_of_birth must be an RFC 3339 time")
+	} else {
+		dob = parsed
+	}
+	address := text(model.PatientFieldAddress, 0)
+	contact := text(model.PatientFieldContactNumber, 20)
+	history := text(model.PatientFieldMedicalHistory, 0)
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: %s", ErrInvalidPatientPatch, strings.Join(problems, "; "))
+	}
+
+	patient.FullName = fullName
+	// Keep the stored time when the patch did not change the date
+	if !dob.Equal(patient.DateOfBirth) {
+		patient.DateOfBirth = dob
+	}
+	patient.Address = address
+	patient.ContactNumber = contact
+	if actor.Role.IsClinician() {
+		patient.MedicalHistory = history
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package service
+
+import (
+	"errors"
+	"time"
+
+	"github.com/RohanDSkaria/hospital-management-system/internal/model"
+	"github.com/RohanDSkaria/hospital-management-system/internal/repository"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// PatientRetentionService defines the interface for deleted patients and their purge
+type PatientRetentionService interface {
+	ListDeleted(actor Actor, limit, offset int) ([]model.Patient, int64, error)
+	Undelete(actor Actor, id uuid.UUID) (*model.Patient, error)
+	Purge(actor Actor, dryRun bool) (*model.PatientPurge, error)
+	ListPurges(limit, offset int) ([]model.PatientPurge, int64, error)
+	GetPurge(id uuid.UUID) (*model.PatientPurge, error)
+	RetentionDays() int
+}
+
+type patientRetentionService struct {
+	patientRepo   repository.PatientRepository
+	purgeRepo     repository.PatientPurgeRepository
+	mergeRepo     repository.PatientMergeRepository
+	transactor    repository.Transactor
+	audit         AuditService
+	retentionDays int
+}
+
+// NewPatientRetentionService creates a new patient retention service. Deleted
+// patients are kept for retentionDays before they can be purged.
+func NewPatientRetentionService(patientRepo repository.PatientRepository, purgeRepo repository.PatientPurgeRepository, mergeRepo repository.PatientMergeRepository, transactor repository.Transactor, audit AuditService, retentionDays int) PatientRetentionService {
+	return &patientRetentionService{patientRepo: patientRepo, purgeRepo: purgeRepo, mergeRepo: mergeRepo, transactor: transactor, audit: audit, retentionDays: retentionDays}
+}
+
+// ListDeleted returns a page of deleted patients, most recently deleted first
+func (s *patientRetentionService) ListDeleted(actor Actor, limit, offset int) ([]model.Patient, int64, error) {
+	patients, total, err := s.patientRepo.FindDeleted(limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	changes := map[string]model.FieldChange{"patient_ids": {New: patientIDs(patients)}}
+	if err := recordAudit(s.audit, actor, AuditEvent{Action: AuditPatientDeleted, Changes: changes}); err != nil {
+		return nil, 0, err
+	}
+	for i := range patients {
+		redact(actor, &patients[i])
+	}
+	return patients, total, nil
+}
+
+// Undelete brings back a deleted patient that has not been purged yet.
+// Patients deleted by a merge stay merged.
+func (s *patientRetentionService) Undelete(actor Actor, id uuid.UUID) (*model.Patient, error) {
+	patient, err := s.patientRepo.FindDeletedByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.mergeRepo.FindBySource(id); err == nil {
+		return nil, ErrPatientMerged
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	err = s.transactor.Transaction(func(tx repository.Tx) error {
+		if err := tx.Patients().Undelete(id); err != nil {
+			return err
+		}
+		changes := map[string]model.FieldChange{"deleted": {Old: true, New: false}}
+		return s.audit.RecordIn(tx, actor, AuditEvent{Action: AuditPatientUndelete, PatientID: &id, Changes: changes})
+	})
+	if err != nil {
+		return nil, err
+	}
+	patient.DeletedAt.Valid = false
+	patient.DeletedByID = nil
+	patient.DeletionReason = ""
+	redact(actor, patient)
+	return patient, nil
+}
+
+// Purge permanently removes the patients deleted longer ago than the
+// retention period and returns the purge report. A dry run only reports
+// what would be purged and saves nothing.
+func (s *patientRetentionService) Purge(actor Actor, dryRun bool) (*model.PatientPurge, error) {
+	purge := &model.PatientPurge{
+		Cutoff:        time.Now().AddDate(0, 0, -s.retentionDays),
+		RetentionDays: s.retentionDays,
+	}
+	if actor.UserID != uuid.Nil {
+		purge.TriggeredByID = &actor.UserID
+	}
+	if dryRun {
+		purged, err := s.purgeRepo.FindPurgeable(purge.Cutoff)
+		if err != nil {
+			return nil, err
+		}
+		purge.Patients = purged
+		purge.PurgedCount = len(purged)
+		return purge, nil
+	}
+
+	err := s.transactor.Transaction(func(tx repository.Tx) error {
+		if err := tx.PatientPurges().Purge(purge); err != nil {
+			return err
+		}
+		for _, purged := range purge.Patients {
+			patientID := purged.PatientID
+			if err := s.audit.RecordIn(tx, actor, AuditEvent{Action: AuditPatientPurge, PatientID: &patientID}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return purge, nil
+}
+
+// ListPurges returns a page of purge reports, newest first
+func (s *patientRetentionService) ListPurges(limit, offset int) ([]model.PatientPurge, int64, error) {
+	return s.purgeRepo.List(limit, offset)
+}
+
+// GetPurge returns a purge report with the patients it removed
+func (s *patientRetentionService) GetPurge(id uuid.UUID) (*model.PatientPurge, error) {
+	return s.purgeRepo.FindByID(id)
+}
+
+// RetentionDays returns how long deleted patients are kept
+func (s *patientRetentionService) RetentionDays() int {
+	return s.retentionDays
+}
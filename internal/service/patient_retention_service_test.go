@@ -0,0 +1,55 @@
+package service
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/RohanDSkaria/hospital-management-system/internal/model"
+	"github.com/google/uuid"
+)
+
+func TestPurge(t *testing.T) {
+	const retentionDays = 30
+	expired := model.PurgedPatient{PatientID: uuid.New(), DeletedAt: time.Now().AddDate(0, 0, -retentionDays-1)}
+	recent := model.PurgedPatient{PatientID: uuid.New(), DeletedAt: time.Now().AddDate(0, 0, -1)}
+
+	cases := []struct {
+		name       string
+		dryRun     bool
+		wantPurged bool
+		wantAudit  []string
+	}{
+		{name: "purge", wantPurged: true, wantAudit: []string{AuditPatientPurge}},
+		{name: "dry run", dryRun: true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ward := newTestWard()
+			purges := &fakePatientPurgeRepository{deleted: []model.PurgedPatient{expired, recent}}
+			ward.tx.purges = purges
+			service := NewPatientRetentionService(ward.patients, purges, nil, ward.transactor(), ward.audit, retentionDays)
+			admin := Actor{UserID: uuid.New(), Role: model.Admin}
+
+			purge, err := service.Purge(admin, tc.dryRun)
+			if err != nil {
+				t.Fatalf("Purge: %v", err)
+			}
+			if purges.purged != tc.wantPurged {
+				t.Errorf("purged = %v, want %v", purges.purged, tc.wantPurged)
+			}
+			if purge.PurgedCount != 1 || len(purge.Patients) != 1 || purge.Patients[0].PatientID != expired.PatientID {
+				t.Errorf("expected only the expired patient in the report, got %+v", purge.Patients)
+			}
+			if purge.RetentionDays != retentionDays || purge.TriggeredByID == nil || *purge.TriggeredByID != admin.UserID {
+				t.Errorf("expected the retention and the admin in the report, got %+v", purge)
+			}
+			if got := ward.audit.actions(); !slices.Equal(got, tc.wantAudit) {
+				t.Fatalf("audited %v, want %v", got, tc.wantAudit)
+			}
+			if tc.wantPurged && *ward.audit.events[0].PatientID != expired.PatientID {
+				t.Errorf("audited patient %s, want %s", *ward.audit.events[0].PatientID, expired.PatientID)
+			}
+		})
+	}
+}
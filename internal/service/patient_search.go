@@ -0,0 +1,235 @@
+package service
+
+import (
+	"fmt"
+	"html"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/RohanDSkaria/hospital-management-system/internal/model"
+	"github.com/RohanDSkaria/hospital-management-system/internal/repository"
+	"github.com/google/uuid"
+)
+
+// Limits on a patient search
+const (
+	maxPatientSearchLength = 100
+	minPatientSearchLength = 2
+	minPatientSearchDigits = 3
+)
+
+// PatientSearchQuery is a free-text patient search. Text may hold a name,
+// part of an address or phone number, and a date of birth as YYYY-MM-DD.
+type PatientSearchQuery struct {
+	Text        string
+	DateOfBirth *time.Time
+	Limit       int
+}
+
+// PatientSearchResult is a patient found by a search. Highlights holds the
+// matched fields with the matching parts wrapped in <mark> tags; the rest of
+// the text is HTML-escaped.
+type PatientSearchResult struct {
+	Patient    model.Patient     `json:"patient"`
+	Score      float64           `json:"score"`
+	Highlights map[string]string `json:"highlights,omitempty"`
+}
+
+// SearchPatients returns the patients best matching the search, best first.
+// Clinicians only find patients whose care team they are on.
+func (s *patientService) SearchPatients(actor Actor, query PatientSearchQuery) ([]PatientSearchResult, error) {
+	if query.Limit < 1 {
+		return nil, fmt.Errorf("%w: limit must be positive", ErrInvalidPatientQuery)
+	}
+	search, err := parsePatientSearch(query.Text, query.DateOfBirth)
+	if err != nil {
+		return nil, err
+	}
+	search.Limit = query.Limit
+	if actor.Role.IsClinician() {
+		search.CareTeamMemberID = &actor.UserID
+	}
+
+	matches, err := s.patientRepo.Search(search)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]uuid.UUID, len(matches))
+	for i := range matches {
+		ids[i] = matches[i].Patient.ID
+	}
+	changes := map[string]model.FieldChange{"patient_ids": {New: ids}}
+	if err := recordAudit(s.audit, actor, AuditEvent{Action: AuditPatientSearch, Changes: changes}); err != nil {
+		return nil, err
+	}
+	results := make([]PatientSearchResult, len(matches))
+	for i := range matches {
+		patient := matches[i].Patient
+		redact(actor, &patient)
+		results[i] = PatientSearchResult{
+			Patient:    patient,
+			Score:      matches[i].Rank,
+			Highlights: patientHighlights(&patient, search),
+		}
+	}
+	return results, nil
+}
+
+// parsePatientSearch splits search text into what is matched against each
+// field. Text that only holds digits and phone punctuation is taken as a
+// phone number; anything else as words of a name or address.
+func parsePatientSearch(text string, dob *time.Time) (repository.PatientSearch, error) {
+	search := repository.PatientSearch{DateOfBirth: dob}
+	if utf8.RuneCountInString(text) > maxPatientSearchLength {
+		return search, fmt.Errorf("%w: search text is longer than %d characters", ErrInvalidPatientQuery, maxPatientSearchLength)
+	}
+
+	var rest []string
+	for _, field := range strings.Fields(text) {
+		date, err := time.Parse("2006-01-02", field)
+		if err != nil {
+			rest = append(rest, field)
+			continue
+		}
+		if search.DateOfBirth != nil && !search.DateOfBirth.Equal(date) {
+			return search, fmt.Errorf("%w: search holds two different dates of birth", ErrInvalidPatientQuery)
+		}
+		search.DateOfBirth = &date
+	}
+	remaining := strings.Join(rest, " ")
+
+	if digits, ok := phoneDigits(remaining); ok {
+		search.Digits = digits
+		return search, nil
+	}
+	length := 0
+	for _, term := range strings.FieldsFunc(strings.ToLower(remaining), isNotWordRune) {
+		search.Terms = append(search.Terms, term)
+		if strings.IndexFunc(term, unicode.IsLetter) >= 0 {
+			search.NameTerms = append(search.NameTerms, term)
+		}
+		length += utf8.RuneCountInString(term)
+	}
+	if length < minPatientSearchLength && (length > 0 || search.DateOfBirth == nil) {
+		return search, fmt.Errorf("%w: search for at least %d letters or digits, or a date of birth", ErrInvalidPatientQuery, minPatientSearchLength)
+	}
+	return search, nil
+}
+
+// phoneDigits returns the digits of text if it looks like (part of) a phone number
+func phoneDigits(text string) (string, bool) {
+	var digits strings.Builder
+	for _, r := range text {
+		switch {
+		case r >= '0' && r <= '9':
+			digits.WriteRune(r)
+		case strings.ContainsRune(" +-().", r):
+		default:
+			return "", false
+		}
+	}
+	return digits.String(), digits.Len() >= minPatientSearchDigits
+}
+
+// isNotWordRune reports whether r separates the words of search text
+func isNotWordRune(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+}
+
+// patientHighlights marks where the search matched the patient's fields.
+// Fields matched only by a typo-tolerant or phonetic comparison are left out.
+func patientHighlights(patient *model.Patient, search repository.PatientSearch) map[string]string {
+	highlights := make(map[string]string)
+	if len(search.Terms) > 0 {
+		if marked, ok := highlightTerms(patient.FullName, search.Terms); ok {
+			highlights[model.PatientFieldFullName] = marked
+		}
+		if marked, ok := highlightTerms(patient.Address, search.Terms); ok {
+			highlights[model.PatientFieldAddress] = marked
+		}
+	}
+	if search.Digits != "" {
+		if marked, ok := highlightDigits(patient.ContactNumber, search.Digits); ok {
+			highlights[model.PatientFieldContactNumber] = marked
+		}
+	}
+	if len(highlights) == 0 {
+		return nil
+	}
+	return highlights
+}
+
+// highlightTerms marks the start of every word of text that begins with one
+// of the terms, which are lower case. It reports whether anything was marked.
+func highlightTerms(text string, terms []string) (string, bool) {
+	var b strings.Builder
+	marked := false
+	runes := []rune(text)
+	for i := 0; i < len(runes); {
+		if isNotWordRune(runes[i]) {
+			b.WriteString(html.EscapeString(string(runes[i])))
+			i++
+			continue
+		}
+		end := i
+		for end < len(runes) && !isNotWordRune(runes[end]) {
+			end++
+		}
+		word := runes[i:end]
+		if n := longestTermPrefix(word, terms); n > 0 {
+			marked = true
+			b.WriteString("<mark>" + html.EscapeString(string(word[:n])) + "</mark>")
+			word = word[n:]
+		}
+		b.WriteString(html.EscapeString(string(word)))
+		i = end
+	}
+	return b.String(), marked
+}
+
+// longestTermPrefix returns the length of the longest term the word starts
+// with, ignoring case, or 0 if it starts with none
+func longestTermPrefix(word []rune, terms []string) int {
+	longest := 0
+	for _, term := range terms {
+		prefix := []rune(term)
+		if len(prefix) <= longest || len(prefix) > len(word) {
+			continue
+		}
+		matches := true
+		for i, r := range prefix {
+			if unicode.ToLower(word[i]) != r {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			longest = len(prefix)
+		}
+	}
+	return longest
+}
+
+// highlightDigits marks the first run of text whose digits, skipping any
+// punctuation between them, are the searched digits
+func highlightDigits(text, digits string) (string, bool) {
+	runes := []rune(text)
+	var positions []int
+	var all strings.Builder
+	for i, r := range runes {
+		if r >= '0' && r <= '9' {
+			positions = append(positions, i)
+			all.WriteRune(r)
+		}
+	}
+	start := strings.Index(all.String(), digits)
+	if start < 0 {
+		return html.EscapeString(text), false
+	}
+	from, to := positions[start], positions[start+len(digits)-1]+1
+	return html.EscapeString(string(runes[:from])) +
+		"<mark>" + html.EscapeString(string(runes[from:to])) + "</mark>" +
+		html.EscapeString(string(runes[to:])), true
+}
@@ -0,0 +1,108 @@
+package service
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParsePatientSearch(t *testing.T) {
+	dob := time.Date(1961, time.March, 2, 0, 0, 0, 0, time.UTC)
+	otherDOB := time.Date(1970, time.July, 14, 0, 0, 0, 0, time.UTC)
+	cases := []struct {
+		name      string
+		text      string
+		dob       *time.Time
+		wantTerms []string
+		wantNames []string
+		wantDigit string
+		wantDOB   *time.Time
+		wantErr   bool
+	}{
+		{name: "name words", text: "Jane  O'Neil", wantTerms: []string{"jane", "o", "neil"}, wantNames: []string{"jane", "o", "neil"}},
+		{name: "address with house number", text: "12 Baker St", wantTerms: []string{"12", "baker", "st"}, wantNames: []string{"baker", "st"}},
+		{name: "phone number", text: "+1 (555) 010-99", wantDigit: "155501099"},
+		{name: "name and date of birth", text: "doe 1961-03-02", wantTerms: []string{"doe"}, wantNames: []string{"doe"}, wantDOB: &dob},
+		{name: "date of birth alone", text: "1961-03-02", wantDOB: &dob},
+		{name: "date of birth given twice", text: "1961-03-02", dob: &dob, wantDOB: &dob},
+		{name: "two dates of birth", text: "1961-03-02", dob: &otherDOB, wantErr: true},
+		{name: "too short", text: "j", wantErr: true},
+		{name: "only punctuation", text: "--", wantErr: true},
+		{name: "too long", text: strings.Repeat("a", maxPatientSearchLength+1), wantErr: true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			search, err := parsePatientSearch(tc.text, tc.dob)
+			if tc.wantErr {
+				if !errors.Is(err, ErrInvalidPatientQuery) {
+					t.Fatalf("expected ErrInvalidPatientQuery, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parsePatientSearch: %v", err)
+			}
+			if !slices.Equal(search.Terms, tc.wantTerms) {
+				t.Errorf("Terms = %q, want %q", search.Terms, tc.wantTerms)
+			}
+			if !slices.Equal(search.NameTerms, tc.wantNames) {
+				t.Errorf("NameTerms = %q, want %q", search.NameTerms, tc.wantNames)
+			}
+			if search.Digits != tc.wantDigit {
+				t.Errorf("Digits = %q, want %q", search.Digits, tc.wantDigit)
+			}
+			if (search.DateOfBirth == nil) != (tc.wantDOB == nil) || tc.wantDOB != nil && !search.DateOfBirth.Equal(*tc.wantDOB) {
+				t.Errorf("DateOfBirth = %v, want %v", search.DateOfBirth, tc.wantDOB)
+			}
+		})
+	}
+}
+
+func TestHighlightTerms(t *testing.T) {
+	cases := []struct {
+		name       string
+		text       string
+		terms      []string
+		want       string
+		wantMarked bool
+	}{
+		{name: "word prefix", text: "Jane Doe", terms: []string{"ja"}, want: "<mark>Ja</mark>ne Doe", wantMarked: true},
+		{name: "every matching word", text: "Anna Annabel", terms: []string{"ann"}, want: "<mark>Ann</mark>a <mark>Ann</mark>abel", wantMarked: true},
+		{name: "longest term wins", text: "Johnson", terms: []string{"jo", "john"}, want: "<mark>John</mark>son", wantMarked: true},
+		{name: "not inside a word", text: "Maryjane", terms: []string{"jane"}, want: "Maryjane"},
+		{name: "non-ASCII letters", text: "Zoë Ærø", terms: []string{"æ"}, want: "Zoë <mark>Æ</mark>rø", wantMarked: true},
+		{name: "escapes HTML", text: "<b>Jane</b> & co", terms: []string{"jane"}, want: "&lt;b&gt;<mark>Jane</mark>&lt;/b&gt; &amp; co", wantMarked: true},
+		{name: "term longer than word", text: "Jo", terms: []string{"joan"}, want: "Jo"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, marked := highlightTerms(tc.text, tc.terms)
+			if got != tc.want || marked != tc.wantMarked {
+				t.Errorf("highlightTerms() = %q, %v, want %q, %v", got, marked, tc.want, tc.wantMarked)
+			}
+		})
+	}
+}
+
+func TestHighlightDigits(t *testing.T) {
+	cases := []struct {
+		name       string
+		text       string
+		digits     string
+		want       string
+		wantMarked bool
+	}{
+		{name: "across punctuation", text: "+1 (555) 010-9999", digits: "5550109", want: "+1 (<mark>555) 010-9</mark>999", wantMarked: true},
+		{name: "no match", text: "555-0100", digits: "999", want: "555-0100"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, marked := highlightDigits(tc.text, tc.digits)
+			if got != tc.want || marked != tc.wantMarked {
+				t.Errorf("highlightDigits() = %q, %v, want %q, %v", got, marked, tc.want, tc.wantMarked)
+			}
+		})
+	}
+}
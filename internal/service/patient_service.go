@@ -1,65 +1,321 @@
 package service
 
 import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
 	"github.com/RohanDSkaria/hospital-management-system/internal/model"
+	"github.com/RohanDSkaria/hospital-management-system/internal/mrn"
 	"github.com/RohanDSkaria/hospital-management-system/internal/repository"
 	"github.com/google/uuid"
-	"time"
+)
+
+var (
+	// ErrNotOnCareTeam is returned when a clinician opens a patient whose care team they are not on
+	ErrNotOnCareTeam = errors.New("you are not on this patient's care team, request break-glass access in an emergency")
+	// ErrMedicalHistoryForbidden is returned when a non-clinician tries to set a patient's medical history
+	ErrMedicalHistoryForbidden = errors.New("your role cannot view or change medical history")
+	// ErrPatientFieldForbidden is returned when an update changes a field the caller's role may not change
+	ErrPatientFieldForbidden = errors.New("your role cannot change these patient fields")
+	// ErrInvalidMRN is returned when a medical record number is malformed or its check digit is wrong
+	ErrInvalidMRN = errors.New("invalid medical record number, check it was typed correctly")
+	// ErrPatientVersionMismatch is returned when a write is based on an outdated version of the patient
+	ErrPatientVersionMismatch = errors.New("patient was changed by someone else, fetch it again and retry")
 )
 
 type PatientService interface {
-	CreatePatient(fullName, address, contact string, dob time.Time, history string, registeredByID uuid.UUID) (*model.Patient, error)
-	GetAllPatients() ([]model.Patient, error)
-	GetPatientByID(id uuid.UUID) (*model.Patient, error)
-	UpdatePatient(id uuid.UUID, fullName, address, contact string, dob time.Time, history string) (*model.Patient, error)
-	DeletePatient(id uuid.UUID) error
+	CreatePatient(actor Actor, fullName, address, contact string, dob time.Time, history string, allowDuplicate bool) (*model.Patient, error)
+	GetAllPatients(actor Actor, query PatientListQuery) (*PatientPage, error)
+	SearchPatients(actor Actor, query PatientSearchQuery) ([]PatientSearchResult, error)
+	GetPatientByID(actor Actor, id uuid.UUID) (*model.Patient, *AllergySummary, error)
+	GetPatientByMRN(actor Actor, number string) (*model.Patient, *AllergySummary, error)
+	UpdatePatient(actor Actor, id uuid.UUID, expectedVersion int, fullName, address, contact string, dob time.Time, history string) (*model.Patient, error)
+	PatchPatient(actor Actor, id uuid.UUID, expectedVersion int, patch PatientPatch) (*model.Patient, error)
+	DeletePatient(actor Actor, id uuid.UUID, expectedVersion int, reason string) error
 }
 
 type patientService struct {
-	patientRepo repository.PatientRepository
+	patientRepo  repository.PatientRepository
+	careTeamRepo repository.CareTeamRepository
+	mergeRepo    repository.PatientMergeRepository
+	allergyRepo  repository.AllergyRepository
+	transactor   repository.Transactor
+	access       *PatientAccess
+	audit        AuditService
+	mrnFormat    mrn.Format
 }
 
-func NewPatientService(repo repository.PatientRepository) PatientService {
-	return &patientService{patientRepo: repo}
+func NewPatientService(repo repository.PatientRepository, careTeamRepo repository.CareTeamRepository, mergeRepo repository.PatientMergeRepository, allergyRepo repository.AllergyRepository, transactor repository.Transactor, access *PatientAccess, audit AuditService, mrnFormat mrn.Format) PatientService {
+	return &patientService{patientRepo: repo, careTeamRepo: careTeamRepo, mergeRepo: mergeRepo, allergyRepo: allergyRepo, transactor: transactor, access: access, audit: audit, mrnFormat: mrnFormat}
 }
 
-func (s *patientService) CreatePatient(fullName, address, contact string, dob time.Time, history string, registeredByID uuid.UUID) (*model.Patient, error) {
+// CreatePatient registers a patient. A clinician who registers a patient
+// joins their care team, so they can see the record they created. If the
+// patient looks like one already registered, a PossibleDuplicatesError is
+// returned instead, unless allowDuplicate confirms it is a different person;
+// that confirmation is audited.
+func (s *patientService) CreatePatient(actor Actor, fullName, address, contact string, dob time.Time, history string, allowDuplicate bool) (*model.Patient, error) {
+	if !actor.Role.IsClinician() && history != "" {
+		return nil, recordAudit(s.audit, actor, AuditEvent{Action: AuditPatientCreate, Err: ErrMedicalHistoryForbidden})
+	}
 	patient := &model.Patient{
 		FullName:       fullName,
 		Address:        address,
 		ContactNumber:  contact,
 		DateOfBirth:    dob,
 		MedicalHistory: history,
-		RegisteredByID: registeredByID,
+		RegisteredByID: actor.UserID,
+	}
+	duplicates, err := s.findDuplicates(actor, patient)
+	if err != nil {
+		return nil, err
+	}
+	if len(duplicates) > 0 && !allowDuplicate {
+		changes := map[string]model.FieldChange{"possible_duplicates": {New: candidateIDs(duplicates)}}
+		if err := recordAudit(s.audit, actor, AuditEvent{Action: AuditDuplicateCheck, Changes: changes}); err != nil {
+			return nil, err
+		}
+		return nil, &PossibleDuplicatesError{Candidates: duplicates}
 	}
-	err := s.patientRepo.Create(patient)
-	return patient, err
+	version := &model.PatientVersion{ChangeType: model.PatientCreated, ChangedByID: &actor.UserID}
+	err = s.transactor.Transaction(func(tx repository.Tx) error {
+		if err := tx.Patients().Create(patient, version); err != nil {
+			return err
+		}
+		if actor.Role.IsClinician() {
+			teamRole := model.CareTeamAttending
+			if actor.Role == model.Nurse {
+				teamRole = model.CareTeamNurse
+			}
+			member := &model.CareTeamMember{PatientID: patient.ID, UserID: actor.UserID, Role: teamRole, AssignedByID: actor.UserID}
+			if err := tx.CareTeam().Add(member); err != nil {
+				return err
+			}
+		}
+
+		event := AuditEvent{Action: AuditPatientCreate, PatientID: &patient.ID, Changes: patientChanges(nil, patient)}
+		if err := s.audit.RecordIn(tx, actor, event); err != nil {
+			return err
+		}
+		if len(duplicates) == 0 {
+			return nil
+		}
+		changes := map[string]model.FieldChange{"possible_duplicates": {New: candidateIDs(duplicates)}}
+		return s.audit.RecordIn(tx, actor, AuditEvent{Action: AuditDuplicateOverride, PatientID: &patient.ID, Changes: changes})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return patient, nil
 }
 
-func (s *patientService) GetAllPatients() ([]model.Patient, error) {
-	return s.patientRepo.FindAll()
+// GetAllPatients returns a page of the patients the actor may see: clinicians
+// get the patients on their care teams, everyone else gets demographics of all
+func (s *patientService) GetAllPatients(actor Actor, query PatientListQuery) (*PatientPage, error) {
+	if query.Sort == "" {
+		query.Sort = repository.PatientSortCreatedAt
+	}
+	if query.Limit < 1 {
+		return nil, fmt.Errorf("%w: limit must be positive", ErrInvalidPatientQuery)
+	}
+	filter, err := query.filter(time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if actor.Role.IsClinician() {
+		filter.CareTeamMemberID = &actor.UserID
+	}
+
+	// Fetch one more than asked for to know whether there is a next page
+	filter.Limit = query.Limit + 1
+	patients, err := s.patientRepo.List(filter)
+	if err != nil {
+		return nil, err
+	}
+	page := &PatientPage{Patients: patients}
+	if len(patients) > query.Limit {
+		page.Patients = patients[:query.Limit]
+		page.NextCursor = encodePatientCursor(query, &page.Patients[query.Limit-1])
+	}
+	if query.IncludeTotal {
+		total, err := s.patientRepo.Count(filter)
+		if err != nil {
+			return nil, err
+		}
+		page.Total = &total
+	}
+
+	changes := map[string]model.FieldChange{"patient_ids": {New: patientIDs(page.Patients)}}
+	if err := recordAudit(s.audit, actor, AuditEvent{Action: AuditPatientList, Changes: changes}); err != nil {
+		return nil, err
+	}
+	for i := range page.Patients {
+		redact(actor, &page.Patients[i])
+	}
+	return page, nil
 }
 
-func (s *patientService) GetPatientByID(id uuid.UUID) (*model.Patient, error) {
-	return s.patientRepo.FindByID(id)
+// GetPatientByID returns a patient. Clinicians also get the patient's active
+// allergies, or their no known allergies assertion, so a prescriber is
+// always warned; for other roles the summary is nil.
+func (s *patientService) GetPatientByID(actor Actor, id uuid.UUID) (*model.Patient, *AllergySummary, error) {
+	patient, breakGlassID, err := s.findAuthorized(actor, id, ChartActionView)
+	var allergies *AllergySummary
+	if err == nil && actor.Role.IsClinician() {
+		allergies, err = allergySummary(s.allergyRepo, id)
+	}
+	if err == nil || errors.Is(err, ErrNotOnCareTeam) {
+		err = recordAudit(s.audit, actor, AuditEvent{Action: AuditPatientView, PatientID: &id, BreakGlassAccessID: breakGlassID, Err: err})
+	}
+	if err != nil {
+		return nil, nil, mergedInto(s.mergeRepo, id, err)
+	}
+	redact(actor, patient)
+	return patient, allergies, nil
 }
 
-func (s *patientService) UpdatePatient(id uuid.UUID, fullName, address, contact string, dob time.Time, history string) (*model.Patient, error) {
-	patient, err := s.patientRepo.FindByID(id)
+// GetPatientByMRN returns the patient with the medical record number, which
+// may be typed with spaces, hyphens or in lower case. It is looked up like
+// GetPatientByID, so the number of a merged patient leads to the patient it
+// was merged into.
+func (s *patientService) GetPatientByMRN(actor Actor, number string) (*model.Patient, *AllergySummary, error) {
+	number, err := s.mrnFormat.Parse(number)
 	if err != nil {
-		return nil, err
+		return nil, nil, ErrInvalidMRN
+	}
+	id, err := s.patientRepo.FindIDByMRN(number)
+	if err != nil {
+		return nil, nil, err
 	}
+	return s.GetPatientByID(actor, id)
+}
+
+// UpdatePatient replaces a patient's details. The update must be based on the
+// patient's current version. Non-clinicians cannot see the medical history,
+// so it is kept as it is when they leave it empty.
+func (s *patientService) UpdatePatient(actor Actor, id uuid.UUID, expectedVersion int, fullName, address, contact string, dob time.Time, history string) (*model.Patient, error) {
+	patient, breakGlassID, err := s.findAuthorized(actor, id, ChartActionUpdate)
+	if err == nil && !actor.Role.IsClinician() && history != "" {
+		err = ErrMedicalHistoryForbidden
+	}
+	if err != nil {
+		return nil, recordAudit(s.audit, actor, AuditEvent{Action: AuditPatientUpdate, PatientID: &id, BreakGlassAccessID: breakGlassID, Err: err})
+	}
+	if patient.Version != expectedVersion {
+		return nil, ErrPatientVersionMismatch
+	}
+	before := *patient
+
 	// Update fields
 	patient.FullName = fullName
 	patient.Address = address
 	patient.ContactNumber = contact
 	patient.DateOfBirth = dob
-	patient.MedicalHistory = history
+	if actor.Role.IsClinician() {
+		patient.MedicalHistory = history
+	}
+	return s.saveUpdate(actor, &before, patient, breakGlassID)
+}
+
+// saveUpdate saves the changes made to a patient as a new version. The actor's
+// role must be allowed to change every field that differs from before.
+func (s *patientService) saveUpdate(actor Actor, before, patient *model.Patient, breakGlassID *uuid.UUID) (*model.Patient, error) {
+	if err := checkEditableFields(actor, before, patient); err != nil {
+		return nil, recordAudit(s.audit, actor, AuditEvent{Action: AuditPatientUpdate, PatientID: &patient.ID, BreakGlassAccessID: breakGlassID, Err: err})
+	}
+
+	version := &model.PatientVersion{ChangeType: model.PatientUpdated, ChangedByID: &actor.UserID}
+	err := s.transactor.Transaction(func(tx repository.Tx) error {
+		if err := tx.Patients().Update(patient, version); err != nil {
+			return versionConflict(err)
+		}
+		event := AuditEvent{Action: AuditPatientUpdate, PatientID: &patient.ID, BreakGlassAccessID: breakGlassID, Changes: patientChanges(before, patient)}
+		return s.audit.RecordIn(tx, actor, event)
+	})
+	if err != nil {
+		return nil, err
+	}
+	redact(actor, patient)
+	return patient, nil
+}
+
+// DeletePatient marks a patient as deleted. The delete must be based on the
+// patient's current version. The record can be restored by an admin until it
+// is purged after the retention period.
+func (s *patientService) DeletePatient(actor Actor, id uuid.UUID, expectedVersion int, reason string) error {
+	patient, breakGlassID, err := s.findAuthorized(actor, id, ChartActionDelete)
+	if err != nil {
+		return recordAudit(s.audit, actor, AuditEvent{Action: AuditPatientDelete, PatientID: &id, BreakGlassAccessID: breakGlassID, Err: err})
+	}
+	if patient.Version != expectedVersion {
+		return ErrPatientVersionMismatch
+	}
+	changes := map[string]model.FieldChange{"deleted": {New: true}}
+	if reason != "" {
+		changes["deletion_reason"] = model.FieldChange{New: reason}
+	}
+	return s.transactor.Transaction(func(tx repository.Tx) error {
+		if err := tx.Patients().Delete(id, expectedVersion, actor.UserID, reason); err != nil {
+			return versionConflict(err)
+		}
+		return s.audit.RecordIn(tx, actor, AuditEvent{Action: AuditPatientDelete, PatientID: &id, BreakGlassAccessID: breakGlassID, Changes: changes})
+	})
+}
 
-	err = s.patientRepo.Update(patient)
-	return patient, err
+// findAuthorized loads a patient the actor may open for the action. It also
+// returns the break-glass grant that was used, if any.
+func (s *patientService) findAuthorized(actor Actor, id uuid.UUID, action string) (*model.Patient, *uuid.UUID, error) {
+	patient, err := s.patientRepo.FindByID(id)
+	if err != nil {
+		return nil, nil, err
+	}
+	breakGlassID, err := s.access.authorize(actor, id, action)
+	if err != nil {
+		return nil, nil, err
+	}
+	return patient, breakGlassID, nil
 }
 
-func (s *patientService) DeletePatient(id uuid.UUID) error {
-	return s.patientRepo.Delete(id)
+// checkEditableFields returns ErrPatientFieldForbidden, naming the fields,
+// if the actor's role may not change every field that differs between the
+// two states of a patient
+func checkEditableFields(actor Actor, before, after *model.Patient) error {
+	var forbidden []string
+	for field := range diffPatients(before, after, false) {
+		if !actor.Role.CanEditPatientField(field) {
+			forbidden = append(forbidden, field)
+		}
+	}
+	if len(forbidden) == 0 {
+		return nil
+	}
+	slices.Sort(forbidden)
+	return fmt.Errorf("%w: %s", ErrPatientFieldForbidden, strings.Join(forbidden, ", "))
+}
+
+// versionConflict turns a repository version conflict into ErrPatientVersionMismatch
+func versionConflict(err error) error {
+	if errors.Is(err, repository.ErrVersionConflict) {
+		return ErrPatientVersionMismatch
+	}
+	return err
+}
+
+// patientIDs returns the IDs of the patients, for the audit log
+func patientIDs(patients []model.Patient) []uuid.UUID {
+	ids := make([]uuid.UUID, 0, len(patients))
+	for i := range patients {
+		ids = append(ids, patients[i].ID)
+	}
+	return ids
+}
+
+// redact removes what the actor's role may not see from a patient record
+func redact(actor Actor, patient *model.Patient) {
+	if !actor.Role.IsClinician() {
+		patient.MedicalHistory = ""
+	}
 }
@@ -0,0 +1,128 @@
+package service
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/RohanDSkaria/hospital-management-system/internal/model"
+	"github.com/RohanDSkaria/hospital-management-system/internal/repository"
+)
+
+// permissionCacheTTL is how long the role permissions are cached before they
+// are read again, so changes made on another server are picked up
+const permissionCacheTTL = 30 * time.Second
+
+// ErrInvalidPermission is returned when a permission is not one of the known permissions
+var ErrInvalidPermission = errors.New("unknown permission")
+
+// PermissionService defines the interface for the role to permission mapping
+type PermissionService interface {
+	HasPermission(role model.Role, permission model.Permission) bool
+	RolePermissions() (map[model.Role][]model.Permission, error)
+	SetRolePermissions(role model.Role, permissions []model.Permission) ([]model.Permission, error)
+}
+
+type permissionService struct {
+	repo repository.RolePermissionRepository
+
+	mu       sync.RWMutex
+	grants   map[model.Role]map[model.Permission]bool
+	loadedAt time.Time
+}
+
+// NewPermissionService creates a permission service. It seeds the default
+// grants that are not in the database yet and loads the mapping.
+func NewPermissionService(repo repository.RolePermissionRepository) (PermissionService, error) {
+	if err := repo.SeedDefaults(model.DefaultRolePermissions); err != nil {
+		return nil, err
+	}
+	s := &permissionService{repo: repo}
+	if err := s.reload(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// HasPermission reports whether the role grants the permission. If the
+// mapping cannot be refreshed the last loaded one is used.
+func (s *permissionService) HasPermission(role model.Role, permission model.Permission) bool {
+	s.mu.RLock()
+	stale := time.Since(s.loadedAt) > permissionCacheTTL
+	s.mu.RUnlock()
+	if stale {
+		if err := s.reload(); err != nil {
+			logError("reload role permissions", err)
+		}
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.grants[role][permission]
+}
+
+// RolePermissions returns the permissions of every role
+func (s *permissionService) RolePermissions() (map[model.Role][]model.Permission, error) {
+	grants, err := s.repo.ListGranted()
+	if err != nil {
+		return nil, err
+	}
+	mapping := map[model.Role][]model.Permission{
+		model.Receptionist: {},
+		model.Doctor:       {},
+		model.Nurse:        {},
+		model.Admin:        {},
+	}
+	for _, grant := range grants {
+		mapping[grant.Role] = append(mapping[grant.Role], grant.Permission)
+	}
+	return mapping, nil
+}
+
+// SetRolePermissions replaces the permissions of a role. It takes effect
+// immediately on this server and within permissionCacheTTL on others.
+func (s *permissionService) SetRolePermissions(role model.Role, permissions []model.Permission) ([]model.Permission, error) {
+	if !role.IsValid() {
+		return nil, ErrInvalidRole
+	}
+	seen := make(map[model.Permission]bool, len(permissions))
+	unique := make([]model.Permission, 0, len(permissions))
+	for _, permission := range permissions {
+		if !permission.IsValid() {
+			return nil, ErrInvalidPermission
+		}
+		if !seen[permission] {
+			seen[permission] = true
+			unique = append(unique, permission)
+		}
+	}
+
+	if err := s.repo.ReplaceForRole(role, unique); err != nil {
+		return nil, err
+	}
+	if err := s.reload(); err != nil {
+		return nil, err
+	}
+	return unique, nil
+}
+
+// reload reads the mapping from the database into the cache
+func (s *permissionService) reload() error {
+	grants, err := s.repo.ListGranted()
+	if err != nil {
+		return err
+	}
+	mapping := make(map[model.Role]map[model.Permission]bool)
+	for _, grant := range grants {
+		if mapping[grant.Role] == nil {
+			mapping[grant.Role] = make(map[model.Permission]bool)
+		}
+		mapping[grant.Role][grant.Permission] = true
+	}
+
+	s.mu.Lock()
+	s.grants = mapping
+	s.loadedAt = time.Now()
+	s.mu.Unlock()
+	return nil
+}
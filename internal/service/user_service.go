@@ -0,0 +1,222 @@
+package service
+
+import (
+	"errors"
+	"time"
+
+	"github.com/RohanDSkaria/hospital-management-system/internal/model"
+	"github.com/RohanDSkaria/hospital-management-system/internal/repository"
+	"github.com/RohanDSkaria/hospital-management-system/pkg/utils"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// InvitationTTL is how long an invite token can be used to register
+const InvitationTTL = 72 * time.Hour
+
+var (
+	// ErrUserExists is returned when an account with the same email already exists
+	ErrUserExists = errors.New("user with this email already exists")
+	// ErrInvalidRole is returned when an unknown role is requested
+	ErrInvalidRole = errors.New("invalid role specified")
+	// ErrLastAdmin is returned when a change would leave the system without an admin
+	ErrLastAdmin = errors.New("cannot remove the last admin")
+	// ErrDeactivateSelf is returned when an admin tries to deactivate their own account
+	ErrDeactivateSelf = errors.New("you cannot deactivate your own account")
+)
+
+// UserService defines the interface for staff account management by admins
+type UserService interface {
+	CreateUser(fullName, email, password string, role model.Role) (*model.User, error)
+	InviteUser(email string, role model.Role, invitedByID uuid.UUID) (*model.Invitation, string, error)
+	ChangeRole(id uuid.UUID, role model.Role) (*model.User, error)
+	ListUsers(filter repository.UserFilter) ([]model.User, int64, error)
+	GetUser(id uuid.UUID) (*model.User, error)
+	DeactivateUser(id, actorID uuid.UUID) (*model.User, error)
+	ReactivateUser(id uuid.UUID) (*model.User, error)
+}
+
+type userService struct {
+	userRepo       repository.UserRepository
+	invitationRepo repository.InvitationRepository
+	sessionRepo    repository.SessionRepository
+	passwords      *Passwords
+}
+
+// NewUserService creates a new user service
+func NewUserService(userRepo repository.UserRepository, invitationRepo repository.InvitationRepository, sessionRepo repository.SessionRepository, passwords *Passwords) UserService {
+	return &userService{userRepo: userRepo, invitationRepo: invitationRepo, sessionRepo: sessionRepo, passwords: passwords}
+}
+
+// CreateUser creates a staff account directly, without an invitation
+func (s *userService) CreateUser(fullName, email, password string, role model.Role) (*model.User, error) {
+	if !role.IsValid() {
+		return nil, ErrInvalidRole
+	}
+	return createUser(s.userRepo, s.passwords, fullName, email, password, role)
+}
+
+// InviteUser creates an invitation and returns it together with the plain invite token.
+// The token is only returned here; the database keeps its hash.
+func (s *userService) InviteUser(email string, role model.Role, invitedByID uuid.UUID) (*model.Invitation, string, error) {
+	if !role.IsValid() {
+		return nil, "", ErrInvalidRole
+	}
+
+	existingUser, err := s.userRepo.FindByEmail(email)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, "", err
+	}
+	if existingUser != nil {
+		return nil, "", ErrUserExists
+	}
+
+	token, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return nil, "", err
+	}
+	invitation := &model.Invitation{
+		Email:       email,
+		Role:        role,
+		TokenHash:   utils.HashToken(token),
+		ExpiresAt:   time.Now().Add(InvitationTTL),
+		InvitedByID: invitedByID,
+	}
+	if err := s.invitationRepo.Create(invitation); err != nil {
+		return nil, "", err
+	}
+	return invitation, token, nil
+}
+
+// ChangeRole assigns a new role to a user. The user's sessions are revoked so
+// the old role in already issued tokens cannot be used any more.
+func (s *userService) ChangeRole(id uuid.UUID, role model.Role) (*model.User, error) {
+	if !role.IsValid() {
+		return nil, ErrInvalidRole
+	}
+
+	user, err := s.userRepo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if user.Role == role {
+		return user, nil
+	}
+	if user.ServiceAccount && role == model.Admin {
+		return nil, ErrInvalidServiceAccountRole
+	}
+	user.Role = role
+	if err := s.updateKeepingAdmin(user); err != nil {
+		return nil, err
+	}
+	if err := s.sessionRepo.RevokeAllForUser(user.ID, time.Now()); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// ListUsers returns a page of users matching the filter and the total number of matches
+func (s *userService) ListUsers(filter repository.UserFilter) ([]model.User, int64, error) {
+	return s.userRepo.List(filter)
+}
+
+// GetUser returns a single user
+func (s *userService) GetUser(id uuid.UUID) (*model.User, error) {
+	return s.userRepo.FindByID(id)
+}
+
+// DeactivateUser offboards a user. They can no longer log in and all their
+// sessions are revoked, so existing access tokens stop working immediately.
+func (s *userService) DeactivateUser(id, actorID uuid.UUID) (*model.User, error) {
+	if id == actorID {
+		return nil, ErrDeactivateSelf
+	}
+
+	user, err := s.userRepo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if !user.Active {
+		return user, nil
+	}
+	now := time.Now()
+	user.Active = false
+	user.DeactivatedAt = &now
+	if err := s.updateKeepingAdmin(user); err != nil {
+		return nil, err
+	}
+	if err := s.sessionRepo.RevokeAllForUser(user.ID, now); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// ReactivateUser allows a deactivated user to log in again
+func (s *userService) ReactivateUser(id uuid.UUID) (*model.User, error) {
+	user, err := s.userRepo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if user.Active {
+		return user, nil
+	}
+
+	user.Active = true
+	user.DeactivatedAt = nil
+	if err := s.userRepo.Update(user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// updateKeepingAdmin saves a change to a user that may take away their
+// admin rights, failing if they are the only remaining active admin
+func (s *userService) updateKeepingAdmin(user *model.User) error {
+	err := s.userRepo.UpdateKeepingAdmin(user)
+	if errors.Is(err, repository.ErrNoAdminLeft) {
+		return ErrLastAdmin
+	}
+	return err
+}
+
+// createUser checks for an existing account, vets and hashes the password and saves a new user
+func createUser(userRepo repository.UserRepository, passwords *Passwords, fullName, email, password string, role model.Role) (*model.User, error) {
+	// 1. Check if user already exists
+	existingUser, err := userRepo.FindByEmail(email)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	if existingUser != nil {
+		return nil, ErrUserExists
+	}
+
+	// 2. Check the password against the policy and hash it
+	newUser := &model.User{
+		FullName: fullName,
+		Email:    email,
+		Role:     role,
+		Active:   true,
+	}
+	if err := passwords.Validate(newUser, password); err != nil {
+		return nil, err
+	}
+	hashedPassword, err := passwords.Hash(password)
+	if err != nil {
+		return nil, err
+	}
+	newUser.PasswordHash = hashedPassword
+
+	// 3. Save the new user to the database. The unique email catches an
+	// account created for the same email since the check above.
+	if err := userRepo.SaveUser(newUser); err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return nil, ErrUserExists
+		}
+		return nil, err
+	}
+	if err := passwords.Remember(newUser.ID, hashedPassword); err != nil {
+		return nil, err
+	}
+
+	return newUser, nil
+}
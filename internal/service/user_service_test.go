@@ -0,0 +1,79 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/RohanDSkaria/hospital-management-system/internal/model"
+	"github.com/RohanDSkaria/hospital-management-system/internal/password"
+	"github.com/RohanDSkaria/hospital-management-system/pkg/utils"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+func TestCreateUserReportsEmailTakenMeanwhile(t *testing.T) {
+	hasher, err := utils.NewPasswordHasher(utils.HashBcrypt, utils.MinBcryptCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	passwords := NewPasswords(hasher, password.DefaultPolicy(), nil)
+	dbErr := errors.New("connection reset")
+
+	cases := []struct {
+		name    string
+		saveErr error
+		wantErr error
+	}{
+		{name: "unique email violated", saveErr: gorm.ErrDuplicatedKey, wantErr: ErrUserExists},
+		{name: "other failure", saveErr: dbErr, wantErr: dbErr},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			users := &fakeUserRepository{saveErr: tc.saveErr}
+			_, err := createUser(users, passwords, "Jane Doe", "jane@hospital.local", "Quartz-Lantern-47-Orbit", model.Nurse)
+			if !errors.Is(err, tc.wantErr) {
+				t.Errorf("expected %v, got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestChangeRoleKeepsAnAdmin(t *testing.T) {
+	cases := []struct {
+		name        string
+		otherAdmins int
+		role        model.Role
+		wantErr     error
+	}{
+		{name: "demote one of two admins", otherAdmins: 1, role: model.Doctor},
+		{name: "demote the last admin", role: model.Doctor, wantErr: ErrLastAdmin},
+		{name: "keep the last admin an admin", role: model.Admin},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			admin := model.User{ID: uuid.New(), Role: model.Admin, Active: true}
+			users := &fakeUserRepository{users: map[uuid.UUID]model.User{admin.ID: admin}}
+			for i := 0; i < tc.otherAdmins; i++ {
+				other := model.User{ID: uuid.New(), Role: model.Admin, Active: true}
+				users.users[other.ID] = other
+			}
+			sessions := &fakeSessionRepository{}
+			service := NewUserService(users, nil, sessions, nil)
+
+			_, err := service.ChangeRole(admin.ID, tc.role)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("expected %v, got %v", tc.wantErr, err)
+			}
+			wantRole := tc.role
+			if tc.wantErr != nil {
+				wantRole = model.Admin
+			}
+			if got := users.users[admin.ID].Role; got != wantRole {
+				t.Errorf("role = %q, want %q", got, wantRole)
+			}
+			if revoked := len(sessions.revoked) > 0; revoked != (wantRole != model.Admin) {
+				t.Errorf("sessions revoked = %v, want %v", revoked, wantRole != model.Admin)
+			}
+		})
+	}
+}
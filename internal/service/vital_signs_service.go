@@ -0,0 +1,359 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/RohanDSkaria/hospital-management-system/internal/model"
+	"github.com/RohanDSkaria/hospital-management-system/internal/repository"
+	"github.com/RohanDSkaria/hospital-management-system/internal/vitals"
+	"github.com/google/uuid"
+)
+
+// maxVitalsBatch is how many sets of vital signs can be recorded at once,
+// enough for a ward round
+const maxVitalsBatch = 100
+
+// maxTrendPoints is how many values a trend returns at most; longer trends
+// keep the most recent values
+const maxTrendPoints = 1000
+
+// ErrInvalidVitals is returned when vital signs are missing, implausible or malformed
+var ErrInvalidVitals = errors.New("invalid vital signs")
+
+// VitalsInput is one set of vital signs as entered by a nurse or doctor
+type VitalsInput struct {
+	PatientID       uuid.UUID
+	TakenAt         time.Time // now if zero
+	SystolicBP      *float64
+	DiastolicBP     *float64
+	HeartRate       *float64
+	RespiratoryRate *float64
+	Temperature     *float64
+	SpO2            *float64
+	Weight          *float64
+	Height          *float64
+	Note            string
+}
+
+// VitalsObservation is a set of vital signs with the values outside the
+// patient's reference ranges flagged, by vital sign
+type VitalsObservation struct {
+	model.VitalSigns
+	Flags map[string]vitals.Flag
+}
+
+// VitalsQuery selects the vital signs to list
+type VitalsQuery struct {
+	From   *time.Time
+	To     *time.Time
+	Limit  int
+	Offset int
+}
+
+// TrendQuery selects the values of one vital sign to return
+type TrendQuery struct {
+	Vital string
+	From  *time.Time
+	To    *time.Time
+}
+
+// TrendPoint is one value of a trend
+type TrendPoint struct {
+	TakenAt time.Time
+	Value   float64
+	Flag    vitals.Flag
+}
+
+// VitalTrend is how one vital sign of a patient changed over time. Range is
+// the patient's reference range at their current age, if there is one.
+// Truncated is set when older values were left out.
+type VitalTrend struct {
+	Vital     string
+	Unit      string
+	Range     *vitals.Range
+	Points    []TrendPoint
+	Truncated bool
+}
+
+// VitalSignsService defines the interface for recording patients' vital signs
+type VitalSignsService interface {
+	RecordVitals(actor Actor, inputs []VitalsInput) ([]VitalsObservation, error)
+	ListVitals(actor Actor, patientID uuid.UUID, query VitalsQuery) ([]VitalsObservation, int64, error)
+	Trend(actor Actor, patientID uuid.UUID, query TrendQuery) (*VitalTrend, error)
+}
+
+type vitalSignsService struct {
+	vitalsRepo  repository.VitalSignsRepository
+	patientRepo repository.PatientRepository
+	transactor  repository.Transactor
+	access      *PatientAccess
+	audit       AuditService
+	ranges      vitals.Ranges
+}
+
+// NewVitalSignsService creates a new vital signs service that flags values
+// outside the given reference ranges
+func NewVitalSignsService(vitalsRepo repository.VitalSignsRepository, patientRepo repository.PatientRepository, transactor repository.Transactor, access *PatientAccess, audit AuditService, ranges vitals.Ranges) VitalSignsService {
+	return &vitalSignsService{vitalsRepo: vitalsRepo, patientRepo: patientRepo, transactor: transactor, access: access, audit: audit, ranges: ranges}
+}
+
+// chartAccess is a patient whose chart the actor may open, with the
+// break-glass grant that allowed it, if any
+type chartAccess struct {
+	patient      *model.Patient
+	breakGlassID *uuid.UUID
+}
+
+// RecordVitals records sets of vital signs, possibly of several patients,
+// all or none of them. The BMI is computed from the weight and the height;
+// for adults weighed without being measured the latest height on record is
+// used.
+func (s *vitalSignsService) RecordVitals(actor Actor, inputs []VitalsInput) ([]VitalsObservation, error) {
+	if len(inputs) == 0 {
+		return nil, fmt.Errorf("%w: at least one set of vital signs is required", ErrInvalidVitals)
+	}
+	if len(inputs) > maxVitalsBatch {
+		return nil, fmt.Errorf("%w: at most %d sets of vital signs can be recorded at once", ErrInvalidVitals, maxVitalsBatch)
+	}
+	now := time.Now()
+	for i := range inputs {
+		input, err := inputs[i].normalize(now)
+		if err != nil {
+			return nil, fmt.Errorf("entry %d: %w", i+1, err)
+		}
+		inputs[i] = input
+	}
+
+	charts := make(map[uuid.UUID]chartAccess)
+	for _, input := range inputs {
+		if _, ok := charts[input.PatientID]; ok {
+			continue
+		}
+		patient, breakGlassID, err := authorizeClinical(s.patientRepo, s.access, s.audit, actor, input.PatientID, ChartActionEditVitals, AuditVitalsRecord)
+		if err != nil {
+			return nil, fmt.Errorf("patient %s: %w", input.PatientID, err)
+		}
+		charts[input.PatientID] = chartAccess{patient: patient, breakGlassID: breakGlassID}
+	}
+
+	observations := make([]model.VitalSigns, len(inputs))
+	for i, input := range inputs {
+		input.apply(&observations[i])
+		observations[i].RecordedByID = actor.UserID
+		height := input.Height
+		if height == nil && input.Weight != nil && vitals.AgeAt(charts[input.PatientID].patient.DateOfBirth, input.TakenAt) >= s.ranges.AdultAge {
+			latest, err := s.latestHeight(input, inputs)
+			if err != nil {
+				return nil, err
+			}
+			height = latest
+		}
+		if input.Weight != nil && height != nil {
+			bmi := vitals.ComputeBMI(*input.Weight, *height)
+			observations[i].BMI = &bmi
+		}
+	}
+	err := s.transactor.Transaction(func(tx repository.Tx) error {
+		if err := tx.VitalSigns().CreateBatch(observations); err != nil {
+			return err
+		}
+		for i := range observations {
+			observation := &observations[i]
+			event := AuditEvent{Action: AuditVitalsRecord, PatientID: &observation.PatientID, BreakGlassAccessID: charts[observation.PatientID].breakGlassID, Changes: vitalsChanges(observation)}
+			if err := s.audit.RecordIn(tx, actor, event); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]VitalsObservation, 0, len(observations))
+	for i := range observations {
+		result = append(result, s.flag(&observations[i], charts[observations[i].PatientID].patient))
+	}
+	return result, nil
+}
+
+// ListVitals returns a page of a patient's vital signs, most recently taken
+// first
+func (s *vitalSignsService) ListVitals(actor Actor, patientID uuid.UUID, query VitalsQuery) ([]VitalsObservation, int64, error) {
+	if query.From != nil && query.To != nil && !query.From.Before(*query.To) {
+		return nil, 0, fmt.Errorf("%w: from must be before to", ErrInvalidVitals)
+	}
+	patient, breakGlassID, err := authorizeClinical(s.patientRepo, s.access, s.audit, actor, patientID, ChartActionViewVitals, AuditVitalsList)
+	if err != nil {
+		return nil, 0, err
+	}
+	filter := repository.VitalSignsFilter{PatientID: patientID, From: query.From, To: query.To, Limit: query.Limit, Offset: query.Offset}
+	observations, total, err := s.vitalsRepo.List(filter)
+	if err != nil {
+		return nil, 0, err
+	}
+	event := AuditEvent{Action: AuditVitalsList, PatientID: &patientID, BreakGlassAccessID: breakGlassID}
+	if err := recordAudit(s.audit, actor, event); err != nil {
+		return nil, 0, err
+	}
+	result := make([]VitalsObservation, 0, len(observations))
+	for i := range observations {
+		result = append(result, s.flag(&observations[i], patient))
+	}
+	return result, total, nil
+}
+
+// Trend returns the values of one vital sign taken from the patient,
+// oldest first, each flagged against the reference range for the patient's
+// age when it was taken
+func (s *vitalSignsService) Trend(actor Actor, patientID uuid.UUID, query TrendQuery) (*VitalTrend, error) {
+	if !vitals.IsKnown(query.Vital) {
+		return nil, fmt.Errorf("%w: vital must be one of %s", ErrInvalidVitals, strings.Join(vitals.Names(), ", "))
+	}
+	if query.From != nil && query.To != nil && !query.From.Before(*query.To) {
+		return nil, fmt.Errorf("%w: from must be before to", ErrInvalidVitals)
+	}
+	patient, breakGlassID, err := authorizeClinical(s.patientRepo, s.access, s.audit, actor, patientID, ChartActionViewVitals, AuditVitalsTrend)
+	if err != nil {
+		return nil, err
+	}
+	observations, err := s.vitalsRepo.Trend(patientID, query.Vital, query.From, query.To, maxTrendPoints+1)
+	if err != nil {
+		return nil, err
+	}
+	changes := map[string]model.FieldChange{"vital": {New: query.Vital}}
+	event := AuditEvent{Action: AuditVitalsTrend, PatientID: &patientID, BreakGlassAccessID: breakGlassID, Changes: changes}
+	if err := recordAudit(s.audit, actor, event); err != nil {
+		return nil, err
+	}
+
+	trend := &VitalTrend{Vital: query.Vital, Unit: vitals.Unit(query.Vital), Points: make([]TrendPoint, 0, len(observations))}
+	if len(observations) > maxTrendPoints {
+		observations = observations[1:]
+		trend.Truncated = true
+	}
+	if r, ok := s.ranges.For(vitals.AgeAt(patient.DateOfBirth, time.Now()))[query.Vital]; ok {
+		trend.Range = &r
+	}
+	for i := range observations {
+		value := observations[i].Values()[query.Vital]
+		if value == nil {
+			continue
+		}
+		point := TrendPoint{TakenAt: observations[i].TakenAt, Value: *value}
+		if r, ok := s.ranges.For(vitals.AgeAt(patient.DateOfBirth, point.TakenAt))[query.Vital]; ok {
+			point.Flag = r.Flag(point.Value)
+		}
+		trend.Points = append(trend.Points, point)
+	}
+	return trend, nil
+}
+
+// flag flags the values of an observation outside the reference ranges for
+// the patient's age when it was taken
+func (s *vitalSignsService) flag(observation *model.VitalSigns, patient *model.Patient) VitalsObservation {
+	ranges := s.ranges.For(vitals.AgeAt(patient.DateOfBirth, observation.TakenAt))
+	flags := make(map[string]vitals.Flag)
+	for name, value := range observation.Values() {
+		if value == nil {
+			continue
+		}
+		if r, ok := ranges[name]; ok {
+			if flag := r.Flag(*value); flag != "" {
+				flags[name] = flag
+			}
+		}
+	}
+	return VitalsObservation{VitalSigns: *observation, Flags: flags}
+}
+
+// latestHeight returns the patient's most recent height taken at or before
+// the input, from the other inputs of the batch or else from their record,
+// or nil if none was
+func (s *vitalSignsService) latestHeight(input VitalsInput, batch []VitalsInput) (*float64, error) {
+	latest, err := s.vitalsRepo.LatestHeight(input.PatientID, input.TakenAt)
+	if err != nil {
+		return nil, err
+	}
+	var height *float64
+	var takenAt time.Time
+	if latest != nil {
+		height, takenAt = latest.Height, latest.TakenAt
+	}
+	for _, other := range batch {
+		if other.PatientID == input.PatientID && other.Height != nil && !other.TakenAt.After(input.TakenAt) && !other.TakenAt.Before(takenAt) {
+			height, takenAt = other.Height, other.TakenAt
+		}
+	}
+	return height, nil
+}
+
+// vitalsChanges records a new observation in the audit log. The
+// measurements are only marked as recorded.
+func vitalsChanges(observation *model.VitalSigns) map[string]model.FieldChange {
+	changes := map[string]model.FieldChange{
+		"vital_signs_id": {New: observation.ID},
+		"taken_at":       {New: observation.TakenAt},
+	}
+	for name, value := range observation.Values() {
+		if value != nil {
+			changes[name] = model.FieldChange{Redacted: true}
+		}
+	}
+	return changes
+}
+
+// normalize trims the note, dates the observation now if it has no date and
+// checks that at least one plausible measurement was taken
+func (input VitalsInput) normalize(now time.Time) (VitalsInput, error) {
+	input.Note = strings.TrimSpace(input.Note)
+	if input.TakenAt.IsZero() {
+		input.TakenAt = now
+	}
+	if input.PatientID == uuid.Nil {
+		return input, fmt.Errorf("%w: patient is required", ErrInvalidVitals)
+	}
+	if input.TakenAt.After(now.Add(clockSkew)) {
+		return input, fmt.Errorf("%w: vital signs cannot be taken in the future", ErrInvalidVitals)
+	}
+	if len(input.Note) > 1000 {
+		return input, fmt.Errorf("%w: note must be at most 1000 characters", ErrInvalidVitals)
+	}
+	var measured model.VitalSigns
+	input.apply(&measured)
+	taken := 0
+	for name, value := range measured.Values() {
+		if value == nil {
+			continue
+		}
+		taken++
+		if err := vitals.CheckPlausible(name, *value); err != nil {
+			return input, fmt.Errorf("%w: %v", ErrInvalidVitals, err)
+		}
+	}
+	if taken == 0 {
+		return input, fmt.Errorf("%w: at least one measurement is required", ErrInvalidVitals)
+	}
+	if input.SystolicBP != nil && input.DiastolicBP != nil && *input.DiastolicBP >= *input.SystolicBP {
+		return input, fmt.Errorf("%w: diastolic blood pressure must be below systolic", ErrInvalidVitals)
+	}
+	return input, nil
+}
+
+// apply copies the input onto an observation
+func (input VitalsInput) apply(observation *model.VitalSigns) {
+	observation.PatientID = input.PatientID
+	observation.TakenAt = input.TakenAt
+	observation.SystolicBP = input.SystolicBP
+	observation.DiastolicBP = input.DiastolicBP
+	observation.HeartRate = input.HeartRate
+	observation.RespiratoryRate = input.RespiratoryRate
+	observation.Temperature = input.Temperature
+	observation.SpO2 = input.SpO2
+	observation.Weight = input.Weight
+	observation.Height = input.Height
+	observation.Note = input.Note
+}
@@ -0,0 +1,210 @@
+// Package vitals knows the vital signs the hospital records: their units,
+// the values that can be measured at all and the reference ranges outside
+// which a value is flagged as abnormal, which differ for adults and children
+package vitals
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"time"
+)
+
+// Vital signs, named as they are stored
+const (
+	SystolicBP      = "systolic_bp"
+	DiastolicBP     = "diastolic_bp"
+	HeartRate       = "heart_rate"
+	RespiratoryRate = "respiratory_rate"
+	Temperature     = "temperature"
+	SpO2            = "spo2"
+	Weight          = "weight"
+	Height          = "height"
+	// BMI is computed from the weight and height, never entered
+	BMI = "bmi"
+)
+
+// Flag marks a value outside its reference range. It is empty for a normal
+// value or one without a range.
+type Flag string
+
+const (
+	FlagLow  Flag = "low"
+	FlagHigh Flag = "high"
+)
+
+// ErrImplausible is returned when a value cannot have been measured on a
+// living patient, which is most likely a typing mistake or a wrong unit
+var ErrImplausible = errors.New("implausible value")
+
+// vital is what is known about one vital sign
+type vital struct {
+	unit string
+	// min and max bound the values that can be measured at all
+	min, max float64
+}
+
+var known = map[string]vital{
+	SystolicBP:      {"mmHg", 40, 300},
+	DiastolicBP:     {"mmHg", 20, 200},
+	HeartRate:       {"/min", 20, 300},
+	RespiratoryRate: {"/min", 2, 80},
+	Temperature:     {"°C", 25, 45},
+	SpO2:            {"%", 50, 100},
+	Weight:          {"kg", 0.2, 500},
+	Height:          {"cm", 20, 280},
+	BMI:             {"kg/m²", 5, 150},
+}
+
+// Names returns the names of all vital signs in alphabetical order
+func Names() []string {
+	names := make([]string, 0, len(known))
+	for name := range known {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// IsKnown reports whether name is one of the vital signs
+func IsKnown(name string) bool {
+	_, ok := known[name]
+	return ok
+}
+
+// Unit returns the unit a vital sign is recorded in
+func Unit(name string) string {
+	return known[name].unit
+}
+
+// CheckPlausible returns ErrImplausible if the value of the vital sign
+// cannot have been measured
+func CheckPlausible(name string, value float64) error {
+	v, ok := known[name]
+	if !ok {
+		return fmt.Errorf("unknown vital sign %q", name)
+	}
+	if math.IsNaN(value) || value < v.min || value > v.max {
+		return fmt.Errorf("%w: %s must be between %g and %g %s", ErrImplausible, name, v.min, v.max, v.unit)
+	}
+	return nil
+}
+
+// ComputeBMI returns the body mass index for a weight in kilograms and a
+// height in centimetres, rounded to one decimal
+func ComputeBMI(weightKg, heightCm float64) float64 {
+	meters := heightCm / 100
+	return math.Round(weightKg/(meters*meters)*10) / 10
+}
+
+// AgeAt returns the age in whole years of someone born on dob at the given
+// time
+func AgeAt(dob, at time.Time) int {
+	age := at.Year() - dob.Year()
+	if at.Month() < dob.Month() || (at.Month() == dob.Month() && at.Day() < dob.Day()) {
+		age--
+	}
+	return age
+}
+
+// Range is the reference range of a vital sign. Values from Low up to and
+// including High are normal.
+type Range struct {
+	Low  float64 `json:"low"`
+	High float64 `json:"high"`
+}
+
+// Flag returns whether the value is below or above the range
+func (r Range) Flag(value float64) Flag {
+	switch {
+	case value < r.Low:
+		return FlagLow
+	case value > r.High:
+		return FlagHigh
+	}
+	return ""
+}
+
+// Ranges are the reference ranges for adults and for children, by vital
+// sign. Patients are adults from AdultAge on. A vital sign without a range
+// is never flagged.
+type Ranges struct {
+	AdultAge  int              `json:"adult_age"`
+	Adult     map[string]Range `json:"adult"`
+	Pediatric map[string]Range `json:"pediatric"`
+}
+
+// DefaultRanges returns the reference ranges used unless others are
+// configured. The pediatric ranges are broad ones for school-age children;
+// weight, height and BMI depend too much on age to flag without growth
+// charts.
+func DefaultRanges() Ranges {
+	return Ranges{
+		AdultAge: 18,
+		Adult: map[string]Range{
+			SystolicBP:      {90, 140},
+			DiastolicBP:     {60, 90},
+			HeartRate:       {60, 100},
+			RespiratoryRate: {12, 20},
+			Temperature:     {36.1, 37.8},
+			SpO2:            {95, 100},
+			BMI:             {18.5, 25},
+		},
+		Pediatric: map[string]Range{
+			SystolicBP:      {90, 120},
+			DiastolicBP:     {55, 80},
+			HeartRate:       {70, 120},
+			RespiratoryRate: {18, 30},
+			Temperature:     {36.1, 37.8},
+			SpO2:            {95, 100},
+		},
+	}
+}
+
+// LoadRanges reads reference ranges from a JSON file shaped like Ranges.
+// Only the ranges in the file replace the defaults, so a hospital can change
+// a single one.
+func LoadRanges(path string) (Ranges, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return Ranges{}, err
+	}
+	defer file.Close()
+
+	ranges := DefaultRanges()
+	decoder := json.NewDecoder(file)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&ranges); err != nil {
+		return Ranges{}, fmt.Errorf("%s: %w", path, err)
+	}
+	return ranges, nil
+}
+
+// Validate reports whether the ranges can be used
+func (r Ranges) Validate() error {
+	if r.AdultAge < 1 || r.AdultAge > 25 {
+		return errors.New("adult age must be between 1 and 25")
+	}
+	for group, ranges := range map[string]map[string]Range{"adult": r.Adult, "pediatric": r.Pediatric} {
+		for name, rng := range ranges {
+			if !IsKnown(name) {
+				return fmt.Errorf("%s: unknown vital sign %q", group, name)
+			}
+			if rng.Low >= rng.High {
+				return fmt.Errorf("%s: %s must have a low below its high", group, name)
+			}
+		}
+	}
+	return nil
+}
+
+// For returns the reference ranges for a patient of the given age
+func (r Ranges) For(age int) map[string]Range {
+	if age >= r.AdultAge {
+		return r.Adult
+	}
+	return r.Pediatric
+}
@@ -0,0 +1,109 @@
+package vitals
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRangeFlagsValuesOutsideTheRange(t *testing.T) {
+	r := Range{Low: 60, High: 100}
+	cases := map[float64]Flag{59.9: FlagLow, 60: "", 80: "", 100: "", 100.1: FlagHigh}
+	for value, want := range cases {
+		if got := r.Flag(value); got != want {
+			t.Errorf("Flag(%g) = %q, want %q", value, got, want)
+		}
+	}
+}
+
+func TestComputeBMI(t *testing.T) {
+	if got := ComputeBMI(70, 175); got != 22.9 {
+		t.Errorf("ComputeBMI(70, 175) = %g, want 22.9", got)
+	}
+	if got := ComputeBMI(100, 200); got != 25 {
+		t.Errorf("ComputeBMI(100, 200) = %g, want 25", got)
+	}
+}
+
+func TestAgeAtCountsWholeYears(t *testing.T) {
+	dob := time.Date(2008, time.June, 15, 0, 0, 0, 0, time.UTC)
+	cases := map[time.Time]int{
+		time.Date(2026, time.June, 14, 23, 0, 0, 0, time.UTC):  17,
+		time.Date(2026, time.June, 15, 0, 0, 0, 0, time.UTC):   18,
+		time.Date(2026, time.January, 1, 0, 0, 0, 0, time.UTC): 17,
+	}
+	for at, want := range cases {
+		if got := AgeAt(dob, at); got != want {
+			t.Errorf("AgeAt(%s) = %d, want %d", at.Format("2006-01-02"), got, want)
+		}
+	}
+}
+
+func TestRangesForPicksAdultOrPediatric(t *testing.T) {
+	ranges := DefaultRanges()
+	if got := ranges.For(17)[HeartRate]; got != ranges.Pediatric[HeartRate] {
+		t.Errorf("expected pediatric heart rate range at 17, got %v", got)
+	}
+	if got := ranges.For(18)[HeartRate]; got != ranges.Adult[HeartRate] {
+		t.Errorf("expected adult heart rate range at 18, got %v", got)
+	}
+}
+
+func TestCheckPlausible(t *testing.T) {
+	if err := CheckPlausible(Temperature, 37.2); err != nil {
+		t.Errorf("expected 37.2 °C to be plausible, got %v", err)
+	}
+	// 98.6 is a temperature in Fahrenheit
+	if err := CheckPlausible(Temperature, 98.6); !errors.Is(err, ErrImplausible) {
+		t.Errorf("expected ErrImplausible for 98.6 °C, got %v", err)
+	}
+	if err := CheckPlausible(SpO2, 101); !errors.Is(err, ErrImplausible) {
+		t.Errorf("expected ErrImplausible for SpO2 101%%, got %v", err)
+	}
+}
+
+func TestDefaultRangesAreValid(t *testing.T) {
+	if err := DefaultRanges().Validate(); err != nil {
+		t.Fatalf("expected default ranges to be valid, got %v", err)
+	}
+}
+
+func TestLoadRangesMergesOverDefaults(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ranges.json")
+	body := `{"adult_age": 16, "adult": {"heart_rate": {"low": 50, "high": 90}}}`
+	if err := os.WriteFile(path, []byte(body), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	ranges, err := LoadRanges(path)
+	if err != nil {
+		t.Fatalf("LoadRanges: %v", err)
+	}
+	defaults := DefaultRanges()
+	if ranges.AdultAge != 16 {
+		t.Errorf("expected adult age 16, got %d", ranges.AdultAge)
+	}
+	if got := ranges.Adult[HeartRate]; got != (Range{Low: 50, High: 90}) {
+		t.Errorf("expected configured heart rate range, got %v", got)
+	}
+	if got := ranges.Adult[SpO2]; got != defaults.Adult[SpO2] {
+		t.Errorf("expected default SpO2 range to be kept, got %v", got)
+	}
+	if got := ranges.Pediatric[HeartRate]; got != defaults.Pediatric[HeartRate] {
+		t.Errorf("expected default pediatric ranges to be kept, got %v", got)
+	}
+}
+
+func TestValidateRejectsBadRanges(t *testing.T) {
+	ranges := DefaultRanges()
+	ranges.Adult["pulse"] = Range{Low: 60, High: 100}
+	if err := ranges.Validate(); err == nil {
+		t.Error("expected an unknown vital sign to be rejected")
+	}
+	ranges = DefaultRanges()
+	ranges.Pediatric[HeartRate] = Range{Low: 120, High: 70}
+	if err := ranges.Validate(); err == nil {
+		t.Error("expected a range with low above high to be rejected")
+	}
+}
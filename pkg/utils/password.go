@@ -1,15 +1,153 @@
 package utils
 
-import "golang.org/x/crypto/bcrypt"
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
 
-// HashPassword generates a bcrypt hash of the password
-func HashPassword(password string) (string, error) {
-	bytes, err := bcrypt.GenerateFromPassword([]byte(password), 14)
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// HashBcrypt selects bcrypt for new password hashes
+	HashBcrypt = "bcrypt"
+	// HashArgon2id selects argon2id for new password hashes
+	HashArgon2id = "argon2id"
+
+	// DefaultBcryptCost is the bcrypt cost used when none is configured
+	DefaultBcryptCost = 12
+	// MinBcryptCost is the lowest bcrypt cost that may be configured
+	MinBcryptCost = 10
+)
+
+// Argon2Params are the argon2id parameters. The defaults follow the OWASP
+// recommendation for argon2id.
+type Argon2Params struct {
+	Memory      uint32 // in KiB
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+// DefaultArgon2Params returns the argon2id parameters used when none are configured
+func DefaultArgon2Params() Argon2Params {
+	return Argon2Params{
+		Memory:      64 * 1024,
+		Iterations:  3,
+		Parallelism: 2,
+		SaltLength:  16,
+		KeyLength:   32,
+	}
+}
+
+// PasswordHasher hashes new passwords with the configured algorithm and
+// verifies hashes made by any supported algorithm, so the algorithm can be
+// changed without invalidating stored passwords
+type PasswordHasher struct {
+	Algorithm  string
+	BcryptCost int
+	Argon2     Argon2Params
+}
+
+// NewPasswordHasher creates a PasswordHasher for the given algorithm
+func NewPasswordHasher(algorithm string, bcryptCost int) (*PasswordHasher, error) {
+	switch algorithm {
+	case HashBcrypt:
+		if bcryptCost < MinBcryptCost || bcryptCost > bcrypt.MaxCost {
+			return nil, fmt.Errorf("bcrypt cost must be between %d and %d", MinBcryptCost, bcrypt.MaxCost)
+		}
+	case HashArgon2id:
+	default:
+		return nil, fmt.Errorf("unknown password hash algorithm %q", algorithm)
+	}
+	return &PasswordHasher{Algorithm: algorithm, BcryptCost: bcryptCost, Argon2: DefaultArgon2Params()}, nil
+}
+
+// Hash hashes a password with the configured algorithm
+func (h *PasswordHasher) Hash(password string) (string, error) {
+	if h.Algorithm == HashArgon2id {
+		return h.hashArgon2id(password)
+	}
+	bytes, err := bcrypt.GenerateFromPassword([]byte(password), h.BcryptCost)
 	return string(bytes), err
 }
 
-// CheckPasswordHash compares a password with its hash
-func CheckPasswordHash(password, hash string) bool {
-	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
-	return err == nil
+// Verify compares a password with a bcrypt or argon2id hash
+func (h *PasswordHasher) Verify(password, hash string) bool {
+	if strings.HasPrefix(hash, "$argon2id$") {
+		params, salt, key, err := decodeArgon2id(hash)
+		if err != nil {
+			return false
+		}
+		other := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, params.KeyLength)
+		return subtle.ConstantTimeCompare(key, other) == 1
+	}
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
+
+// NeedsRehash reports whether a hash was made with another algorithm or with
+// weaker parameters than the configured ones
+func (h *PasswordHasher) NeedsRehash(hash string) bool {
+	if h.Algorithm == HashArgon2id {
+		params, _, _, err := decodeArgon2id(hash)
+		if err != nil {
+			return true
+		}
+		return params.Memory < h.Argon2.Memory ||
+			params.Iterations < h.Argon2.Iterations ||
+			params.Parallelism < h.Argon2.Parallelism ||
+			params.KeyLength < h.Argon2.KeyLength
+	}
+	cost, err := bcrypt.Cost([]byte(hash))
+	if err != nil {
+		return true
+	}
+	return cost < h.BcryptCost
+}
+
+// hashArgon2id hashes a password into the PHC string format
+// $argon2id$v=19$m=65536,t=3,p=2$<salt>$<key>
+func (h *PasswordHasher) hashArgon2id(password string) (string, error) {
+	p := h.Argon2
+	salt := make([]byte, p.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(password), salt, p.Iterations, p.Memory, p.Parallelism, p.KeyLength)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, p.Memory, p.Iterations, p.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// decodeArgon2id parses a hash produced by hashArgon2id
+func decodeArgon2id(hash string) (Argon2Params, []byte, []byte, error) {
+	var p Argon2Params
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[1] != HashArgon2id {
+		return p, nil, nil, errors.New("not an argon2id hash")
+	}
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return p, nil, nil, errors.New("unsupported argon2 version")
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.Memory, &p.Iterations, &p.Parallelism); err != nil {
+		return p, nil, nil, err
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return p, nil, nil, err
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return p, nil, nil, err
+	}
+	p.SaltLength = uint32(len(salt))
+	p.KeyLength = uint32(len(key))
+	return p, salt, key, nil
 }
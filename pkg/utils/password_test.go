@@ -0,0 +1,53 @@
+package utils
+
+import "testing"
+
+func TestPasswordHasherVerifiesBothAlgorithms(t *testing.T) {
+	bcryptHasher, err := NewPasswordHasher(HashBcrypt, MinBcryptCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	argonHasher, err := NewPasswordHasher(HashArgon2id, MinBcryptCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, hasher := range []*PasswordHasher{bcryptHasher, argonHasher} {
+		hash, err := hasher.Hash("violet-lantern-orbit")
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Either hasher must verify hashes made by the other
+		for _, verifier := range []*PasswordHasher{bcryptHasher, argonHasher} {
+			if !verifier.Verify("violet-lantern-orbit", hash) {
+				t.Errorf("%s: expected %s hash to verify", verifier.Algorithm, hasher.Algorithm)
+			}
+			if verifier.Verify("wrong password", hash) {
+				t.Errorf("%s: expected wrong password to fail against %s hash", verifier.Algorithm, hasher.Algorithm)
+			}
+		}
+	}
+}
+
+func TestNeedsRehash(t *testing.T) {
+	weak, err := NewPasswordHasher(HashBcrypt, MinBcryptCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash, err := weak.Hash("violet-lantern-orbit")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if weak.NeedsRehash(hash) {
+		t.Error("expected a hash with the configured cost not to need a rehash")
+	}
+	stronger := &PasswordHasher{Algorithm: HashBcrypt, BcryptCost: MinBcryptCost + 1}
+	if !stronger.NeedsRehash(hash) {
+		t.Error("expected a hash with a lower cost to need a rehash")
+	}
+	argon := &PasswordHasher{Algorithm: HashArgon2id, Argon2: DefaultArgon2Params()}
+	if !argon.NeedsRehash(hash) {
+		t.Error("expected a bcrypt hash to need a rehash when argon2id is configured")
+	}
+}